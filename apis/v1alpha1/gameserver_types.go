@@ -33,6 +33,54 @@ const (
 	GameServerNetworkDisabled    = "game.kruise.io/network-disabled"
 	GameServerNetworkStatus      = "game.kruise.io/network-status"
 	GameServerNetworkTriggerTime = "game.kruise.io/network-trigger-time"
+	// GameServerNetworkRequeueAfter is the interval requested by the network plugin to be triggered again,
+	// which overrides the global network interval for the pod.
+	GameServerNetworkRequeueAfter = "game.kruise.io/network-requeue-after"
+	// GameServerNetworkConfOverride is set on GameServer as a JSON list of network conf params. The params are merged
+	// over the network conf of GameServerSet for the GameServer, replacing those with the same name.
+	GameServerNetworkConfOverride = "game.kruise.io/network-conf-override"
+	// GameServerNetworkReprovision is set on GameServer, usually to a timestamp. Each time its value changes,
+	// the network resources of the pod are released and provisioned again without recreating the pod.
+	GameServerNetworkReprovision = "game.kruise.io/network-reprovision"
+	// GameServerNetworkReprovisioned records the value of GameServerNetworkReprovision last handled on the pod.
+	GameServerNetworkReprovisioned = "game.kruise.io/network-reprovisioned"
+	// GameServerAllocatedPortsKey is set on pod by network plugins as the external ports allocated to it, in the format
+	// of {name}:{port}/{protocol},... It can be read by init containers through the downward API to set up network.
+	GameServerAllocatedPortsKey = "game.kruise.io/allocated-ports"
+	// GameServerExternalEndpointsKey is set on pod by network plugins once the network is ready, as the external
+	// endpoints of the pod in the format of {ip}:{port}/{protocol},... The hostname is used if the address has no IP.
+	// It can be read by game servers through a downwardAPI volume to announce their public address.
+	GameServerExternalEndpointsKey = "game.kruise.io/external-endpoints"
+	// GameServerServerNameKey is the server name of GameServer formatted by ServerNameFormat of GameServerSet.
+	GameServerServerNameKey = "game.kruise.io/server-name"
+	// GameServerScaleDownWeightKey is an optional pod annotation. When scaling down, among pods with the same
+	// opsState and deletion priority, the one with a higher weight is removed first.
+	GameServerScaleDownWeightKey = "game.kruise.io/scale-down-weight"
+	// GameServerNetworkCleanupFinalizer is added to pods handled by network plugins if their GameServerSet opts in by
+	// GameServerSetNetworkCleanupFinalizerKey. It keeps the deleting pod until its network resources are released,
+	// for at most a limited time beyond the grace period.
+	GameServerNetworkCleanupFinalizer = "game.kruise.io/network-cleanup"
+	// GameServerNetworkCleanedKey is set to "true" on the deleting pod once its network resources are released,
+	// so that GameServerNetworkCleanupFinalizer is removed by the controller.
+	GameServerNetworkCleanedKey = "game.kruise.io/network-cleaned"
+	// GameServerExternalFinalizersFinalizer is added to pods of GameServerSet with GameServerFinalizers. It keeps
+	// the pod deleted by scale-down until the finalizers of its GameServer are removed, or the wait times out.
+	GameServerExternalFinalizersFinalizer = "game.kruise.io/external-finalizers"
+	// GameServerOpsStateScheduledKey is set on GameServer to the opsState whose OpsStateScheduling its pod has been
+	// rescheduled for, so that the pod is rescheduled at most once each time GameServer enters the opsState.
+	GameServerOpsStateScheduledKey = "game.kruise.io/opsstate-scheduled"
+	// GameServerInPlaceUpdatingKey is set to "true" on pod while it is updated in place, that is in state PreUpdate
+	// or Updating, and to "false" once the update completes. The PodDisruptionBudget {gss}-in-place-updating with
+	// maxUnavailable 0 selects the pods labeled "true", so that they are not evicted during the update.
+	GameServerInPlaceUpdatingKey = "game.kruise.io/in-place-updating"
+	// GameServerForceDeleteKey set to "true" on GameServer allows it to be deleted by users in opsState Allocated or Maintaining.
+	GameServerForceDeleteKey = "game.kruise.io/force-delete"
+	// GameServerPreAllocatedTimeKey is set on GameServer to the time it is found in opsState PreAllocated, in RFC3339.
+	// GameServer not confirmed as Allocated within PreAllocatedTimeoutSeconds since then turns back to None.
+	GameServerPreAllocatedTimeKey = "game.kruise.io/pre-allocated-time"
+	// PodDeletionCostKey is set on pods according to opsState and deletion priority,
+	// so that cluster-autoscaler prefers removing pods waiting to be deleted.
+	PodDeletionCostKey = "controller.kubernetes.io/pod-deletion-cost"
 )
 
 // GameServerSpec defines the desired state of GameServer
@@ -79,6 +127,7 @@ const (
 	WaitToDelete OpsState = "WaitToBeDeleted"
 	None         OpsState = "None"
 	Allocated    OpsState = "Allocated"
+	PreAllocated OpsState = "PreAllocated"
 	Kill         OpsState = "Kill"
 )
 
@@ -129,6 +178,10 @@ type GameServerStatus struct {
 	// Conditions is an array of current observed GameServer conditions.
 	// +optional
 	Conditions []GameServerCondition `json:"conditions,omitempty" `
+	// Extra is the user state of the GameServer, such as the current map or player count,
+	// written by the game server itself through the status subresource. OKG preserves it across reconciles.
+	// +optional
+	Extra map[string]string `json:"extra,omitempty"`
 }
 
 type GameServerCondition struct {
@@ -157,6 +210,9 @@ const (
 	NodeNormal             GameServerConditionType = "NodeNormal"
 	PersistentVolumeNormal GameServerConditionType = "PersistentVolumeNormal"
 	PodNormal              GameServerConditionType = "PodNormal"
+	// GameServerReady is set on GameServers of GameServerSet with RequireNetworkReady, true when both the pod and
+	// the network are ready.
+	GameServerReady GameServerConditionType = "Ready"
 )
 
 type NetworkStatus struct {
@@ -183,6 +239,11 @@ type NetworkAddress struct {
 	Ports     []NetworkPort     `json:"ports,omitempty"`
 	PortRange *NetworkPortRange `json:"portRange,omitempty"`
 	EndPoint  string            `json:"endPoint,omitempty"`
+	// Weight is the relative preference of the address among the addresses of the same kind, set by the network plugin.
+	// The addresses without weight are preferred equally.
+	Weight *int32 `json:"weight,omitempty"`
+	// ISP is the Internet service provider of the address, such as BGP, set by the network plugin if known.
+	ISP string `json:"isp,omitempty"`
 }
 
 type NetworkPort struct {
@@ -20,6 +20,7 @@ import (
 	appspub "github.com/openkruise/kruise-api/apps/pub"
 	kruiseV1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	apps "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -34,6 +35,33 @@ const (
 	AstsHashKey                = "game.kruise.io/asts-hash"
 	PpmHashKey                 = "game.kruise.io/ppm-hash"
 	GsTemplateMetadataHashKey  = "game.kruise.io/gsTemplate-metadata-hash"
+	// PreDeleteHookFinalizer blocks the deletion of GameServerSet until PreDeleteHook is done.
+	PreDeleteHookFinalizer = "game.kruise.io/pre-delete-hook"
+	// GameServerImageOverrideKey records the containers of a GameServer whose images are managed by ImageOverrides.
+	GameServerImageOverrideKey = "game.kruise.io/image-override-containers"
+	// GameServerSetAllowNetworkMigrationKey must be set to "true" on GameServerSet to change its network type.
+	GameServerSetAllowNetworkMigrationKey = "game.kruise.io/allow-network-migration"
+	// GameServerSetPausedKey set to "true" on GameServerSet stops OKG from scaling and updating it, and from updating the network of its existing pods.
+	// The status of GameServerSet is still reported.
+	GameServerSetPausedKey = "game.kruise.io/paused"
+	// GameServerSetNetworkCleanupFinalizerKey set to "true" on GameServerSet adds GameServerNetworkCleanupFinalizer to
+	// its pods handled by network plugins, so that the deleting pods are kept until their network resources are released.
+	GameServerSetNetworkCleanupFinalizerKey = "game.kruise.io/network-cleanup-finalizer"
+	// GameServerTokenEnvName is the env of containers carrying the token of GameServer, injected by InjectGameServerToken.
+	GameServerTokenEnvName = "OKG_GAMESERVER_TOKEN"
+	// GameServerSetNetworkPollIntervalKey sets the interval, such as "2s", to check again the network of its pods until ready.
+	GameServerSetNetworkPollIntervalKey = "game.kruise.io/network-poll-interval"
+	// GameServerSetMaintainIdsKey lists the ids of GameServers to put into opsState Maintaining, such as "1,3,5-7".
+	GameServerSetMaintainIdsKey = "game.kruise.io/maintain-ids"
+	// AstsImageDigestsKey records on Advanced StatefulSet the images of GameServerTemplate resolved by ResolveImageDigest.
+	AstsImageDigestsKey = "game.kruise.io/image-digests"
+	// GameServerMaintainedByIdsKey is set to "true" on a GameServer put into opsState Maintaining by maintain-ids of GameServerSet.
+	GameServerMaintainedByIdsKey = "game.kruise.io/maintained-by-ids"
+	// GameServerSetAliasServiceKey names the alias Service shared by GameServerSets in blue-green swaps.
+	// It is also the label of the alias Service managed by OKG.
+	GameServerSetAliasServiceKey = "game.kruise.io/alias-service"
+	// GameServerSetAliasActiveKey set to "true" on GameServerSet makes its alias Service select its GameServers.
+	GameServerSetAliasActiveKey = "game.kruise.io/alias-active"
 )
 
 const (
@@ -53,11 +81,196 @@ type GameServerSetSpec struct {
 	GameServerTemplate   GameServerTemplate `json:"gameServerTemplate,omitempty"`
 	ServiceName          string             `json:"serviceName,omitempty"`
 	ReserveGameServerIds []int              `json:"reserveGameServerIds,omitempty"`
-	ServiceQualities     []ServiceQuality   `json:"serviceQualities,omitempty"`
-	UpdateStrategy       UpdateStrategy     `json:"updateStrategy,omitempty"`
-	ScaleStrategy        ScaleStrategy      `json:"scaleStrategy,omitempty"`
-	Network              *Network           `json:"network,omitempty"`
-	Lifecycle            *appspub.Lifecycle `json:"lifecycle,omitempty"`
+	// ExcludedGameServerIds are the ids of GameServers owned by external systems, which are never used
+	// whatever replicas is. Unlike ReserveGameServerIds, they are not backfilled by the ReserveIds
+	// scale down strategy, and they take precedence over ReserveGameServerIds.
+	// Once removed from ExcludedGameServerIds, an id is reused first when scaling up.
+	// +optional
+	ExcludedGameServerIds []int            `json:"excludedGameServerIds,omitempty"`
+	ServiceQualities      []ServiceQuality `json:"serviceQualities,omitempty"`
+	UpdateStrategy        UpdateStrategy   `json:"updateStrategy,omitempty"`
+	ScaleStrategy         ScaleStrategy    `json:"scaleStrategy,omitempty"`
+	// WarmPoolSize is the number of spare GameServers kept beyond replicas for instant allocation.
+	// The warm pool is drained as GameServers get Allocated, until replicas is raised to refill it.
+	// +optional
+	//+kubebuilder:validation:Minimum=0
+	WarmPoolSize *int32 `json:"warmPoolSize,omitempty"`
+	// OpsStateTransitionPolicy restricts the opsState transitions of GameServers.
+	// The opsState listed in From can only turn to the ones listed in To.
+	// The opsState not listed in any From is free to turn to any opsState.
+	// The transitions made by OKG itself, such as reverting timed-out PreAllocated GameServers, are not restricted.
+	// +optional
+	OpsStateTransitionPolicy []OpsStateTransition `json:"opsStateTransitionPolicy,omitempty"`
+	// OpsStateScheduling moves GameServers to other nodes when they enter the given opsState, e.g. the Maintaining
+	// ones to cheaper nodes. The pod not satisfying the scheduling is deleted once, and the recreated pod gets its
+	// nodeSelector and tolerations. It requires the Delete reclaimPolicy, so that the GameServer keeps its opsState.
+	// +optional
+	OpsStateScheduling []OpsStateScheduling `json:"opsStateScheduling,omitempty"`
+	// KillMaxUnavailable is the maximum number of GameServers in Kill opsState deleted per reconcile.
+	// Value can be an absolute number (ex: 5) or a percentage of replicas (ex: 10%), rounded up.
+	// The rest of GameServers to kill are deferred to the following reconciles.
+	// It should be greater than 0. Default is unlimited.
+	// +optional
+	KillMaxUnavailable *intstr.IntOrString `json:"killMaxUnavailable,omitempty"`
+	// PreAllocatedTimeoutSeconds is how long a GameServer may stay in opsState PreAllocated, reserved by a matchmaker
+	// but not confirmed as Allocated yet. GameServers not confirmed in time turn back to None.
+	// Default is 60.
+	// +optional
+	//+kubebuilder:validation:Minimum=1
+	PreAllocatedTimeoutSeconds *int32 `json:"preAllocatedTimeoutSeconds,omitempty"`
+	// ServerNameFormat is the format of server names of GameServers, with placeholders {gss} and {ordinal},
+	// e.g. "{gss}.{ordinal}". The server name is recorded in annotation game.kruise.io/server-name of
+	// GameServers when they are created, while GameServers and pods are still named {gss}-{ordinal}.
+	// +optional
+	ServerNameFormat string `json:"serverNameFormat,omitempty"`
+	// TopologySpread spreads GameServers across topology domains, such as zones. It is translated into
+	// a topologySpreadConstraint of the pod template, selecting the pods of the GameServerSet.
+	// A constraint with the same topologyKey in GameServerTemplate takes precedence.
+	// +optional
+	TopologySpread *TopologySpread `json:"topologySpread,omitempty"`
+	// DefaultContainerResources is applied to the containers of GameServerTemplate that leave resources unset.
+	// For each resource, the request is set only if the container has neither request nor limit of it, and
+	// the limit is set only if the container has no limit of it and its request does not exceed the limit.
+	// +optional
+	DefaultContainerResources *corev1.ResourceRequirements `json:"defaultContainerResources,omitempty"`
+	// ScalingSchedule raises the floor of replicas during the given time windows, e.g. at peak hours.
+	// When a window is active, GameServers are kept at least its MinReplicas without changing replicas,
+	// so that it works along with external scalers. GameServers go back to replicas when the window ends.
+	// +optional
+	ScalingSchedule []ScalingScheduleWindow `json:"scalingSchedule,omitempty"`
+	// PodDisruptionBudget generates a PodDisruptionBudget named after the GameServerSet, selecting its pods,
+	// to limit the voluntary disruptions such as node drains. It is deleted once PodDisruptionBudget is unset.
+	// +optional
+	PodDisruptionBudget *GameServerSetPodDisruptionBudget `json:"podDisruptionBudget,omitempty"`
+	// ResolveImageDigest pins the images of GameServerTemplate to their digests in the workload when the
+	// template is applied, so that the GameServers created later run the identical images even if the tags
+	// are moved. The digests are looked up from the images cached on nodes. The images failed to be resolved
+	// are left with their tags and resolved again, until the GameServers run them with their tags.
+	// +optional
+	ResolveImageDigest bool `json:"resolveImageDigest,omitempty"`
+	// InjectGameServerToken injects a stable unique token of each GameServer into its containers as env
+	// OKG_GAMESERVER_TOKEN, e.g. to authenticate against backend services. The tokens are random and kept per
+	// ordinal in Secret {gss}-gameserver-tokens, so that a GameServer recreated with the same ordinal gets the same token.
+	// +optional
+	InjectGameServerToken bool `json:"injectGameServerToken,omitempty"`
+	// RequireNetworkReady reports GameServers Ready only when both their pods are ready and their network is Ready,
+	// so that a GameServer is not taken as Ready before it can be reached. The result is exposed as condition Ready
+	// of GameServers.
+	// +optional
+	RequireNetworkReady bool `json:"requireNetworkReady,omitempty"`
+	// GameServerFinalizers are added to GameServers when they are created, for external integrations such as
+	// a matchmaker to clean up before GameServers are gone. A pod deleted by scale-down is kept until the finalizers
+	// of its GameServer are removed, or GameServerFinalizerTimeoutSeconds is over.
+	// +optional
+	GameServerFinalizers []string `json:"gameServerFinalizers,omitempty"`
+	// GameServerFinalizerTimeoutSeconds is how long a pod deleted by scale-down waits for the finalizers of its
+	// GameServer to be removed. The finalizers are not removed by OKG when it times out.
+	// Default is 300.
+	// +optional
+	//+kubebuilder:validation:Minimum=1
+	GameServerFinalizerTimeoutSeconds *int32 `json:"gameServerFinalizerTimeoutSeconds,omitempty"`
+	// PreDeleteHook runs a Job before the GameServerSet and its GameServers are deleted.
+	// +optional
+	PreDeleteHook *PreDeleteHook `json:"preDeleteHook,omitempty"`
+	// ImageOverrides pins the images of containers for the GameServers whose ids are in the given ranges.
+	// The overrides are applied via GameServer.Spec.Containers, so they take precedence over the image
+	// in GameServerTemplate. When the template image is updated, the overridden GameServers are still
+	// rolled by UpdateStrategy, and then switched back to the override image in place.
+	// +optional
+	ImageOverrides []ImageOverride    `json:"imageOverrides,omitempty"`
+	Network        *Network           `json:"network,omitempty"`
+	Lifecycle      *appspub.Lifecycle `json:"lifecycle,omitempty"`
+}
+
+type TopologySpread struct {
+	// TopologyKey is the key of node labels, e.g. topology.kubernetes.io/zone.
+	TopologyKey string `json:"topologyKey"`
+	// MaxSkew is the maximum difference of GameServer numbers between topology domains.
+	// Default is 1.
+	// +optional
+	//+kubebuilder:validation:Minimum=1
+	MaxSkew *int32 `json:"maxSkew,omitempty"`
+	// WhenUnsatisfiable indicates how to deal with a pod if it doesn't satisfy the spread constraint.
+	// Default is ScheduleAnyway, so that GameServers are not kept pending when a zone is down.
+	// +optional
+	//+kubebuilder:validation:Enum=DoNotSchedule;ScheduleAnyway
+	WhenUnsatisfiable corev1.UnsatisfiableConstraintAction `json:"whenUnsatisfiable,omitempty"`
+}
+
+type GameServerSetPodDisruptionBudget struct {
+	// MinAvailable is the number of pods that must still be available after an eviction.
+	// Value can be an absolute number (ex: 5) or a percentage of pods (ex: 80%).
+	// Only one of MinAvailable and MaxUnavailable can be set.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// MaxUnavailable is the number of pods that can be unavailable after an eviction.
+	// Value can be an absolute number (ex: 1) or a percentage of pods (ex: 10%).
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+type ScalingScheduleWindow struct {
+	// Name is the name of the window.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Schedule is a cron expression in UTC at which the window starts, e.g. "0 18 * * 1-5".
+	Schedule string `json:"schedule"`
+	// DurationSeconds is how long the window stays active after each start.
+	//+kubebuilder:validation:Minimum=60
+	DurationSeconds int32 `json:"durationSeconds"`
+	// MinReplicas is the floor of replicas while the window is active.
+	//+kubebuilder:validation:Minimum=0
+	MinReplicas int32 `json:"minReplicas"`
+}
+
+type PreDeleteHook struct {
+	// JobTemplate is the spec of the Job run when the GameServerSet is being deleted.
+	// The deletion is blocked until the Job completes or the timeout expires.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	JobTemplate batchv1.JobSpec `json:"jobTemplate"`
+	// TimeoutSeconds is the maximum time to wait for the Job since the deletion starts.
+	// Default is 300.
+	// +optional
+	//+kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+}
+
+type PreDeleteHookPhase string
+
+const (
+	PreDeleteHookRunning   PreDeleteHookPhase = "Running"
+	PreDeleteHookSucceeded PreDeleteHookPhase = "Succeeded"
+	PreDeleteHookFailed    PreDeleteHookPhase = "Failed"
+	PreDeleteHookTimeout   PreDeleteHookPhase = "Timeout"
+)
+
+type ImageOverride struct {
+	// StartId is the first GameServer id the override applies to.
+	//+kubebuilder:validation:Minimum=0
+	StartId int `json:"startId"`
+	// EndId is the last GameServer id the override applies to, inclusive.
+	//+kubebuilder:validation:Minimum=0
+	EndId int `json:"endId"`
+	// ContainerName is the name of the container whose image is overridden.
+	ContainerName string `json:"containerName"`
+	// Image is the image used by the container instead of the one in GameServerTemplate.
+	Image string `json:"image"`
+}
+
+type OpsStateTransition struct {
+	From OpsState   `json:"from"`
+	To   []OpsState `json:"to,omitempty"`
+}
+
+type OpsStateScheduling struct {
+	OpsState OpsState `json:"opsState"`
+	// NodeSelector is merged into the nodeSelector of pods.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations are added to the tolerations of pods.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 }
 
 type GameServerTemplate struct {
@@ -84,6 +297,14 @@ const (
 type Network struct {
 	NetworkType string              `json:"networkType,omitempty"`
 	NetworkConf []NetworkConfParams `json:"networkConf,omitempty"`
+	// NetworkDisabled disables the network of all GameServers of the GameServerSet,
+	// regardless of the NetworkDisabled of each GameServer.
+	NetworkDisabled bool `json:"networkDisabled,omitempty"`
+	// DNSDiscovery creates a Service named server-{GameServer name} for each GameServer once its network is Ready,
+	// so that it can be resolved as server-{GameServer name}.{namespace}.svc in the cluster. The Service is an
+	// ExternalName one for the endpoint of the first external address, or a headless one with the IP of it.
+	// The DNS name is also set as the endpoint of the external addresses having none in the network status.
+	DNSDiscovery bool `json:"dnsDiscovery,omitempty"`
 }
 
 type NetworkConfParams KVParams
@@ -105,6 +326,22 @@ type UpdateStrategy struct {
 	// RollingUpdate is used to communicate parameters when Type is RollingUpdateStatefulSetStrategyType.
 	// +optional
 	RollingUpdate *RollingUpdateStatefulSetStrategy `json:"rollingUpdate,omitempty"`
+	// MaintenanceWindows are the time windows in which rolling updates are allowed to proceed.
+	// Out of the windows, the rolling update of the workload is paused and its partition is not lowered,
+	// until the next window starts. Rolling updates are always allowed if MaintenanceWindows is empty.
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+}
+
+type MaintenanceWindow struct {
+	// Name is the name of the window.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Schedule is a cron expression in UTC at which the window starts, e.g. "0 2 * * *".
+	Schedule string `json:"schedule"`
+	// DurationSeconds is how long the window stays open after each start.
+	//+kubebuilder:validation:Minimum=60
+	DurationSeconds int32 `json:"durationSeconds"`
 }
 
 type RollingUpdateStatefulSetStrategy struct {
@@ -188,6 +425,24 @@ type GameServerSetStatus struct {
 	WaitToBeDeletedReplicas *int32 `json:"waitToBeDeletedReplicas,omitempty"`
 	// LabelSelector is label selectors for query over pods that should match the replica count used by HPA.
 	LabelSelector string `json:"labelSelector,omitempty"`
+	// PreDeleteHookPhase is the phase of PreDeleteHook when the GameServerSet is being deleted.
+	PreDeleteHookPhase PreDeleteHookPhase `json:"preDeleteHookPhase,omitempty"`
+	// NetworkConfigError is the error of parsing the network config of GameServerSet, reported when the network
+	// plugin handles its pods. It is cleared once the network config is parsed successfully.
+	NetworkConfigError string `json:"networkConfigError,omitempty"`
+	// NetworkSummary lists the external addresses of the Ready GameServers whose network is Ready, in the order of
+	// their ids, so that service discovery needs not list all the GameServers. It is bounded by MaxNetworkSummaryLength.
+	// +optional
+	NetworkSummary []GameServerNetworkSummary `json:"networkSummary,omitempty"`
+}
+
+// MaxNetworkSummaryLength is the maximal number of GameServers listed in NetworkSummary of GameServerSetStatus.
+const MaxNetworkSummaryLength = 500
+
+type GameServerNetworkSummary struct {
+	// Name is the name of the GameServer.
+	Name              string           `json:"name"`
+	ExternalAddresses []NetworkAddress `json:"externalAddresses,omitempty"`
 }
 
 //+genclient
@@ -199,6 +454,7 @@ type GameServerSetStatus struct {
 //+kubebuilder:printcolumn:name="Maintaining",type="integer",JSONPath=".status.maintainingReplicas",description="The number of GameServers Maintaining."
 //+kubebuilder:printcolumn:name="WaitToBeDeleted",type="integer",JSONPath=".status.waitToBeDeletedReplicas",description="The number of GameServers WaitToBeDeleted."
 //+kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp",description="The age of GameServerSet."
+//+kubebuilder:printcolumn:name="NetworkConfigError",type="string",JSONPath=".status.networkConfigError",priority=1,description="The error of parsing the network config."
 //+kubebuilder:subresource:status
 //+kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.labelSelector
 //+kubebuilder:resource:shortName=gss
@@ -120,6 +120,28 @@ func (in *GameServerList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerNetworkSummary) DeepCopyInto(out *GameServerNetworkSummary) {
+	*out = *in
+	if in.ExternalAddresses != nil {
+		in, out := &in.ExternalAddresses, &out.ExternalAddresses
+		*out = make([]NetworkAddress, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerNetworkSummary.
+func (in *GameServerNetworkSummary) DeepCopy() *GameServerNetworkSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerNetworkSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GameServerSet) DeepCopyInto(out *GameServerSet) {
 	*out = *in
@@ -179,6 +201,31 @@ func (in *GameServerSetList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GameServerSetPodDisruptionBudget) DeepCopyInto(out *GameServerSetPodDisruptionBudget) {
+	*out = *in
+	if in.MinAvailable != nil {
+		in, out := &in.MinAvailable, &out.MinAvailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerSetPodDisruptionBudget.
+func (in *GameServerSetPodDisruptionBudget) DeepCopy() *GameServerSetPodDisruptionBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(GameServerSetPodDisruptionBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GameServerSetSpec) DeepCopyInto(out *GameServerSetSpec) {
 	*out = *in
@@ -193,6 +240,11 @@ func (in *GameServerSetSpec) DeepCopyInto(out *GameServerSetSpec) {
 		*out = make([]int, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExcludedGameServerIds != nil {
+		in, out := &in.ExcludedGameServerIds, &out.ExcludedGameServerIds
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
 	if in.ServiceQualities != nil {
 		in, out := &in.ServiceQualities, &out.ServiceQualities
 		*out = make([]ServiceQuality, len(*in))
@@ -202,6 +254,75 @@ func (in *GameServerSetSpec) DeepCopyInto(out *GameServerSetSpec) {
 	}
 	in.UpdateStrategy.DeepCopyInto(&out.UpdateStrategy)
 	in.ScaleStrategy.DeepCopyInto(&out.ScaleStrategy)
+	if in.WarmPoolSize != nil {
+		in, out := &in.WarmPoolSize, &out.WarmPoolSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.OpsStateTransitionPolicy != nil {
+		in, out := &in.OpsStateTransitionPolicy, &out.OpsStateTransitionPolicy
+		*out = make([]OpsStateTransition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.OpsStateScheduling != nil {
+		in, out := &in.OpsStateScheduling, &out.OpsStateScheduling
+		*out = make([]OpsStateScheduling, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.KillMaxUnavailable != nil {
+		in, out := &in.KillMaxUnavailable, &out.KillMaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.PreAllocatedTimeoutSeconds != nil {
+		in, out := &in.PreAllocatedTimeoutSeconds, &out.PreAllocatedTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TopologySpread != nil {
+		in, out := &in.TopologySpread, &out.TopologySpread
+		*out = new(TopologySpread)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DefaultContainerResources != nil {
+		in, out := &in.DefaultContainerResources, &out.DefaultContainerResources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScalingSchedule != nil {
+		in, out := &in.ScalingSchedule, &out.ScalingSchedule
+		*out = make([]ScalingScheduleWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.PodDisruptionBudget != nil {
+		in, out := &in.PodDisruptionBudget, &out.PodDisruptionBudget
+		*out = new(GameServerSetPodDisruptionBudget)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GameServerFinalizers != nil {
+		in, out := &in.GameServerFinalizers, &out.GameServerFinalizers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GameServerFinalizerTimeoutSeconds != nil {
+		in, out := &in.GameServerFinalizerTimeoutSeconds, &out.GameServerFinalizerTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PreDeleteHook != nil {
+		in, out := &in.PreDeleteHook, &out.PreDeleteHook
+		*out = new(PreDeleteHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageOverrides != nil {
+		in, out := &in.ImageOverrides, &out.ImageOverrides
+		*out = make([]ImageOverride, len(*in))
+		copy(*out, *in)
+	}
 	if in.Network != nil {
 		in, out := &in.Network, &out.Network
 		*out = new(Network)
@@ -237,6 +358,13 @@ func (in *GameServerSetStatus) DeepCopyInto(out *GameServerSetStatus) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.NetworkSummary != nil {
+		in, out := &in.NetworkSummary, &out.NetworkSummary
+		*out = make([]GameServerNetworkSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerSetStatus.
@@ -311,6 +439,13 @@ func (in *GameServerStatus) DeepCopyInto(out *GameServerStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Extra != nil {
+		in, out := &in.Extra, &out.Extra
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GameServerStatus.
@@ -346,6 +481,21 @@ func (in *GameServerTemplate) DeepCopy() *GameServerTemplate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageOverride) DeepCopyInto(out *ImageOverride) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageOverride.
+func (in *ImageOverride) DeepCopy() *ImageOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KVParams) DeepCopyInto(out *KVParams) {
 	*out = *in
@@ -361,6 +511,21 @@ func (in *KVParams) DeepCopy() *KVParams {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Network) DeepCopyInto(out *Network) {
 	*out = *in
@@ -396,6 +561,11 @@ func (in *NetworkAddress) DeepCopyInto(out *NetworkAddress) {
 		*out = new(NetworkPortRange)
 		**out = **in
 	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkAddress.
@@ -489,6 +659,76 @@ func (in *NetworkStatus) DeepCopy() *NetworkStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpsStateScheduling) DeepCopyInto(out *OpsStateScheduling) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpsStateScheduling.
+func (in *OpsStateScheduling) DeepCopy() *OpsStateScheduling {
+	if in == nil {
+		return nil
+	}
+	out := new(OpsStateScheduling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpsStateTransition) DeepCopyInto(out *OpsStateTransition) {
+	*out = *in
+	if in.To != nil {
+		in, out := &in.To, &out.To
+		*out = make([]OpsState, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpsStateTransition.
+func (in *OpsStateTransition) DeepCopy() *OpsStateTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(OpsStateTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreDeleteHook) DeepCopyInto(out *PreDeleteHook) {
+	*out = *in
+	in.JobTemplate.DeepCopyInto(&out.JobTemplate)
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreDeleteHook.
+func (in *PreDeleteHook) DeepCopy() *PreDeleteHook {
+	if in == nil {
+		return nil
+	}
+	out := new(PreDeleteHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RollingUpdateStatefulSetStrategy) DeepCopyInto(out *RollingUpdateStatefulSetStrategy) {
 	*out = *in
@@ -540,6 +780,21 @@ func (in *ScaleStrategy) DeepCopy() *ScaleStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScalingScheduleWindow) DeepCopyInto(out *ScalingScheduleWindow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScalingScheduleWindow.
+func (in *ScalingScheduleWindow) DeepCopy() *ScalingScheduleWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(ScalingScheduleWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceQuality) DeepCopyInto(out *ServiceQuality) {
 	*out = *in
@@ -611,6 +866,26 @@ func (in *ServiceQualityCondition) DeepCopy() *ServiceQualityCondition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologySpread) DeepCopyInto(out *TopologySpread) {
+	*out = *in
+	if in.MaxSkew != nil {
+		in, out := &in.MaxSkew, &out.MaxSkew
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologySpread.
+func (in *TopologySpread) DeepCopy() *TopologySpread {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologySpread)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UpdateStrategy) DeepCopyInto(out *UpdateStrategy) {
 	*out = *in
@@ -619,6 +894,11 @@ func (in *UpdateStrategy) DeepCopyInto(out *UpdateStrategy) {
 		*out = new(RollingUpdateStatefulSetStrategy)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdateStrategy.
@@ -0,0 +1,387 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alibabacloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
+	cperrors "github.com/openkruise/kruise-game/cloudprovider/errors"
+	"github.com/openkruise/kruise-game/cloudprovider/utils"
+	"github.com/openkruise/kruise-game/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+	"strings"
+)
+
+const (
+	AlbNetwork = "AlibabaCloud-ALB"
+
+	// AlbDomainConfigName is the domain under which each game server is served as server-<id>.{Domain}.
+	AlbDomainConfigName = "Domain"
+	// AlbPathPortsConfigName routes the paths to the target ports of the pod, in the format of {path}:{port},...
+	// All the paths share the listener of ALB.
+	AlbPathPortsConfigName = "PathPorts"
+	// AlbListenProtocolPortConfigName is the listener of ALB, in the format of {protocol}:{port}, HTTP:80 by default.
+	AlbListenProtocolPortConfigName = "ListenProtocolPort"
+	// AlbIngressClassNameConfigName is the IngressClass of ALB Ingress, alb by default.
+	AlbIngressClassNameConfigName = "IngressClassName"
+
+	AlbListenPortsAnnotationKey = "alb.ingress.kubernetes.io/listen-ports"
+	AlbHostPrefix               = "server-"
+	DefaultAlbIngressClassName  = "alb"
+	DefaultAlbListenProtocol    = "HTTP"
+	DefaultAlbListenPort        = 80
+)
+
+type AlbPlugin struct {
+}
+
+type albConfig struct {
+	host             string
+	paths            []string
+	ports            []int32
+	listenProtocol   string
+	listenPort       int32
+	ingressClassName string
+	isFixed          bool
+}
+
+func init() {
+	alibabaCloudProvider.registerPlugin(&AlbPlugin{})
+}
+
+func (a *AlbPlugin) Name() string {
+	return AlbNetwork
+}
+
+func (a *AlbPlugin) Alias() string {
+	return ""
+}
+
+func (a *AlbPlugin) Init(c client.Client, options cloudprovider.CloudProviderOptions, ctx context.Context) error {
+	return nil
+}
+
+func (a *AlbPlugin) OnPodAdded(c client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, cperrors.PluginError) {
+	return pod, nil
+}
+
+func (a *AlbPlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, cperrors.PluginError) {
+	networkManager := utils.NewNetworkManager(pod, c)
+	networkStatus, _ := networkManager.GetNetworkStatus()
+	if networkStatus == nil {
+		pod, err := networkManager.UpdateNetworkStatus(gamekruiseiov1alpha1.NetworkStatus{
+			CurrentNetworkState: gamekruiseiov1alpha1.NetworkNotReady,
+		}, pod)
+		return pod, cperrors.ToPluginError(err, cperrors.InternalError)
+	}
+
+	ac, err := parseAlbConfig(networkManager.GetNetworkConfig(), util.GetIndexFromGsName(pod.GetName()))
+	if err != nil {
+		return pod, cperrors.NewPluginError(cperrors.ParameterError, err.Error())
+	}
+
+	// get svc
+	svc := &corev1.Service{}
+	err = c.Get(ctx, types.NamespacedName{
+		Name:      pod.GetName(),
+		Namespace: pod.GetNamespace(),
+	}, svc)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return pod, cperrors.ToPluginError(c.Create(ctx, consAlbSvc(ac, pod, c, ctx)), cperrors.ApiCallError)
+		}
+		return pod, cperrors.NewPluginError(cperrors.ApiCallError, err.Error())
+	}
+
+	// update svc
+	if util.GetHash(ac) != svc.GetAnnotations()[SlbConfigHashKey] {
+		networkStatus.CurrentNetworkState = gamekruiseiov1alpha1.NetworkNotReady
+		pod, err = networkManager.UpdateNetworkStatus(*networkStatus, pod)
+		if err != nil {
+			return pod, cperrors.NewPluginError(cperrors.InternalError, err.Error())
+		}
+
+		newSvc := consAlbSvc(ac, pod, c, ctx)
+		patchSvc := map[string]interface{}{"metadata": map[string]map[string]string{"annotations": newSvc.Annotations}, "spec": newSvc.Spec}
+		patchSvcBytes, err := json.Marshal(patchSvc)
+		if err != nil {
+			return pod, cperrors.NewPluginError(cperrors.InternalError, err.Error())
+		}
+		return pod, cperrors.ToPluginError(c.Patch(ctx, svc, client.RawPatch(types.MergePatchType, patchSvcBytes)), cperrors.ApiCallError)
+	}
+
+	// get ingress
+	ing := &networkingv1.Ingress{}
+	err = c.Get(ctx, types.NamespacedName{
+		Name:      pod.GetName(),
+		Namespace: pod.GetNamespace(),
+	}, ing)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return pod, cperrors.ToPluginError(c.Create(ctx, consAlbIngress(ac, pod, c, ctx)), cperrors.ApiCallError)
+		}
+		return pod, cperrors.NewPluginError(cperrors.ApiCallError, err.Error())
+	}
+
+	// update ingress
+	if util.GetHash(ac) != ing.GetAnnotations()[SlbConfigHashKey] {
+		networkStatus.CurrentNetworkState = gamekruiseiov1alpha1.NetworkNotReady
+		pod, err = networkManager.UpdateNetworkStatus(*networkStatus, pod)
+		if err != nil {
+			return pod, cperrors.NewPluginError(cperrors.InternalError, err.Error())
+		}
+		newIng := consAlbIngress(ac, pod, c, ctx)
+		ing.Annotations = newIng.Annotations
+		ing.Spec = newIng.Spec
+		return pod, cperrors.ToPluginError(c.Update(ctx, ing), cperrors.ApiCallError)
+	}
+
+	// wait until ALB is bound to the ingress
+	if len(ing.Status.LoadBalancer.Ingress) == 0 {
+		networkStatus.CurrentNetworkState = gamekruiseiov1alpha1.NetworkNotReady
+		pod, err = networkManager.UpdateNetworkStatus(*networkStatus, pod)
+		return pod, cperrors.ToPluginError(err, cperrors.InternalError)
+	}
+
+	// network ready
+	internalAddresses, externalAddresses := consAlbNetworkAddresses(ac, pod)
+	networkStatus.InternalAddresses = internalAddresses
+	networkStatus.ExternalAddresses = externalAddresses
+	networkStatus.CurrentNetworkState = gamekruiseiov1alpha1.NetworkReady
+	pod = networkManager.SetExternalEndpoints(externalAddresses, pod)
+	pod, err = networkManager.UpdateNetworkStatus(*networkStatus, pod)
+	return pod, cperrors.ToPluginError(err, cperrors.InternalError)
+}
+
+func (a *AlbPlugin) OnPodDeleted(c client.Client, pod *corev1.Pod, ctx context.Context) cperrors.PluginError {
+	return nil
+}
+
+// ValidateConfig validates the network conf of AlibabaCloud-ALB when GameServerSet is applied.
+func (a *AlbPlugin) ValidateConfig(conf []gamekruiseiov1alpha1.NetworkConfParams) error {
+	_, err := parseAlbConfig(conf, 0)
+	return err
+}
+
+func parseAlbConfig(conf []gamekruiseiov1alpha1.NetworkConfParams, id int) (*albConfig, error) {
+	ac := &albConfig{
+		paths:            make([]string, 0),
+		ports:            make([]int32, 0),
+		listenProtocol:   DefaultAlbListenProtocol,
+		listenPort:       DefaultAlbListenPort,
+		ingressClassName: DefaultAlbIngressClassName,
+	}
+	domain := ""
+	for _, c := range conf {
+		switch c.Name {
+		case AlbDomainConfigName:
+			domain = strings.Trim(c.Value, ".")
+		case AlbPathPortsConfigName:
+			paths, ports, err := parseAlbPathPorts(c.Value)
+			if err != nil {
+				return nil, err
+			}
+			ac.paths = paths
+			ac.ports = ports
+		case AlbListenProtocolPortConfigName:
+			protocolPort := strings.Split(c.Value, ":")
+			if len(protocolPort) != 2 {
+				return nil, fmt.Errorf("invalid ListenProtocolPort %s. You should input as the format {protocol}:{port}", c.Value)
+			}
+			protocol := strings.ToUpper(protocolPort[0])
+			if protocol != "HTTP" && protocol != "HTTPS" {
+				return nil, fmt.Errorf("invalid listen protocol %s, which should be HTTP or HTTPS", protocolPort[0])
+			}
+			port, err := strconv.ParseInt(protocolPort[1], 10, 32)
+			if err != nil || port <= 0 || port > 65535 {
+				return nil, fmt.Errorf("invalid listen port %s", protocolPort[1])
+			}
+			ac.listenProtocol = protocol
+			ac.listenPort = int32(port)
+		case AlbIngressClassNameConfigName:
+			ac.ingressClassName = c.Value
+		case FixedConfigName:
+			v, err := strconv.ParseBool(c.Value)
+			if err != nil {
+				return nil, err
+			}
+			ac.isFixed = v
+		}
+	}
+
+	if domain == "" {
+		return nil, fmt.Errorf("%s is required", AlbDomainConfigName)
+	}
+	if len(ac.paths) == 0 {
+		return nil, fmt.Errorf("%s is required", AlbPathPortsConfigName)
+	}
+	ac.host = AlbHostPrefix + strconv.Itoa(id) + "." + domain
+	return ac, nil
+}
+
+// parseAlbPathPorts parses PathPorts in the format of {path}:{port},... such as /:8080,/chat:8081.
+func parseAlbPathPorts(value string) ([]string, []int32, error) {
+	paths := make([]string, 0)
+	ports := make([]int32, 0)
+	for _, pathPort := range strings.Split(value, ",") {
+		idx := strings.LastIndex(pathPort, ":")
+		if idx <= 0 || !strings.HasPrefix(pathPort, "/") {
+			return nil, nil, fmt.Errorf("invalid PathPorts %s. You should input as the format {path}:{port},...", value)
+		}
+		port, err := strconv.ParseInt(pathPort[idx+1:], 10, 32)
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, nil, fmt.Errorf("invalid port %s of path %s", pathPort[idx+1:], pathPort[:idx])
+		}
+		for _, p := range paths {
+			if p == pathPort[:idx] {
+				return nil, nil, fmt.Errorf("duplicate path %s in PathPorts", p)
+			}
+		}
+		paths = append(paths, pathPort[:idx])
+		ports = append(ports, int32(port))
+	}
+	return paths, ports, nil
+}
+
+func consAlbSvc(ac *albConfig, pod *corev1.Pod, c client.Client, ctx context.Context) *corev1.Service {
+	svcPorts := make([]corev1.ServicePort, 0)
+	for _, port := range ac.ports {
+		duplicated := false
+		for _, svcPort := range svcPorts {
+			if svcPort.Port == port {
+				duplicated = true
+				break
+			}
+		}
+		if duplicated {
+			continue
+		}
+		svcPorts = append(svcPorts, corev1.ServicePort{
+			Name:       strconv.Itoa(int(port)),
+			Port:       port,
+			Protocol:   corev1.ProtocolTCP,
+			TargetPort: intstr.FromInt(int(port)),
+		})
+	}
+
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.GetName(),
+			Namespace: pod.GetNamespace(),
+			Annotations: map[string]string{
+				SlbConfigHashKey: util.GetHash(ac),
+			},
+			OwnerReferences: getSvcOwnerReference(c, ctx, pod, ac.isFixed),
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeClusterIP,
+			Selector: map[string]string{
+				cloudprovider.GetSvcSelectorKey(): pod.GetName(),
+			},
+			Ports: svcPorts,
+		},
+	}
+}
+
+// consAlbIngress routes server-<id>.{Domain} to the service of the pod, one path per target port.
+func consAlbIngress(ac *albConfig, pod *corev1.Pod, c client.Client, ctx context.Context) *networkingv1.Ingress {
+	pathType := networkingv1.PathTypePrefix
+	ingPaths := make([]networkingv1.HTTPIngressPath, 0)
+	for i, path := range ac.paths {
+		ingPaths = append(ingPaths, networkingv1.HTTPIngressPath{
+			Path:     path,
+			PathType: &pathType,
+			Backend: networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{
+					Name: pod.GetName(),
+					Port: networkingv1.ServiceBackendPort{
+						Number: ac.ports[i],
+					},
+				},
+			},
+		})
+	}
+
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.GetName(),
+			Namespace: pod.GetNamespace(),
+			Annotations: map[string]string{
+				SlbConfigHashKey:            util.GetHash(ac),
+				AlbListenPortsAnnotationKey: fmt.Sprintf("[{\"%s\": %d}]", ac.listenProtocol, ac.listenPort),
+			},
+			OwnerReferences: getSvcOwnerReference(c, ctx, pod, ac.isFixed),
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: ptr.To[string](ac.ingressClassName),
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: ac.host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: ingPaths,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// consAlbNetworkAddresses names each port by its path. The external ports are all the listen port of ALB.
+func consAlbNetworkAddresses(ac *albConfig, pod *corev1.Pod) ([]gamekruiseiov1alpha1.NetworkAddress, []gamekruiseiov1alpha1.NetworkAddress) {
+	internalPorts := make([]gamekruiseiov1alpha1.NetworkPort, 0)
+	externalPorts := make([]gamekruiseiov1alpha1.NetworkPort, 0)
+	for i, path := range ac.paths {
+		internalPort := intstr.FromInt(int(ac.ports[i]))
+		externalPort := intstr.FromInt(int(ac.listenPort))
+		internalPorts = append(internalPorts, gamekruiseiov1alpha1.NetworkPort{
+			Name:     path,
+			Port:     &internalPort,
+			Protocol: corev1.ProtocolTCP,
+		})
+		externalPorts = append(externalPorts, gamekruiseiov1alpha1.NetworkPort{
+			Name:     path,
+			Port:     &externalPort,
+			Protocol: corev1.ProtocolTCP,
+		})
+	}
+	internalAddresses := []gamekruiseiov1alpha1.NetworkAddress{
+		{
+			IP:    pod.Status.PodIP,
+			Ports: internalPorts,
+		},
+	}
+	externalAddresses := []gamekruiseiov1alpha1.NetworkAddress{
+		{
+			EndPoint: ac.host,
+			Ports:    externalPorts,
+		},
+	}
+	return internalAddresses, externalAddresses
+}
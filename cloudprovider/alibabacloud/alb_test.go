@@ -0,0 +1,252 @@
+package alibabacloud
+
+import (
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"reflect"
+	"testing"
+)
+
+func TestParseAlbConfig(t *testing.T) {
+	tests := []struct {
+		conf []gamekruiseiov1alpha1.NetworkConfParams
+		id   int
+		ac   *albConfig
+		err  bool
+	}{
+		// case 0: defaults
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  AlbDomainConfigName,
+					Value: "game.example.com",
+				},
+				{
+					Name:  AlbPathPortsConfigName,
+					Value: "/:8080",
+				},
+			},
+			id: 3,
+			ac: &albConfig{
+				host:             "server-3.game.example.com",
+				paths:            []string{"/"},
+				ports:            []int32{8080},
+				listenProtocol:   "HTTP",
+				listenPort:       80,
+				ingressClassName: "alb",
+			},
+		},
+		// case 1: multiple paths sharing the HTTPS listener
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  AlbDomainConfigName,
+					Value: ".game.example.com",
+				},
+				{
+					Name:  AlbPathPortsConfigName,
+					Value: "/api:8080,/chat:8081",
+				},
+				{
+					Name:  AlbListenProtocolPortConfigName,
+					Value: "https:443",
+				},
+				{
+					Name:  AlbIngressClassNameConfigName,
+					Value: "alb-internal",
+				},
+				{
+					Name:  FixedConfigName,
+					Value: "true",
+				},
+			},
+			id: 0,
+			ac: &albConfig{
+				host:             "server-0.game.example.com",
+				paths:            []string{"/api", "/chat"},
+				ports:            []int32{8080, 8081},
+				listenProtocol:   "HTTPS",
+				listenPort:       443,
+				ingressClassName: "alb-internal",
+				isFixed:          true,
+			},
+		},
+		// case 2: Domain missing
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  AlbPathPortsConfigName,
+					Value: "/:8080",
+				},
+			},
+			err: true,
+		},
+		// case 3: path without leading slash
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  AlbDomainConfigName,
+					Value: "game.example.com",
+				},
+				{
+					Name:  AlbPathPortsConfigName,
+					Value: "api:8080",
+				},
+			},
+			err: true,
+		},
+		// case 4: duplicate path
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  AlbDomainConfigName,
+					Value: "game.example.com",
+				},
+				{
+					Name:  AlbPathPortsConfigName,
+					Value: "/api:8080,/api:8081",
+				},
+			},
+			err: true,
+		},
+		// case 5: invalid listen protocol
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  AlbDomainConfigName,
+					Value: "game.example.com",
+				},
+				{
+					Name:  AlbPathPortsConfigName,
+					Value: "/:8080",
+				},
+				{
+					Name:  AlbListenProtocolPortConfigName,
+					Value: "UDP:80",
+				},
+			},
+			err: true,
+		},
+	}
+
+	for i, test := range tests {
+		ac, err := parseAlbConfig(test.conf, test.id)
+		if (err != nil) != test.err {
+			t.Errorf("case %d: expect err %v but actually got %v", i, test.err, err)
+		}
+		if !reflect.DeepEqual(test.ac, ac) {
+			t.Errorf("case %d: expect albConfig %v but actually got %v", i, test.ac, ac)
+		}
+	}
+}
+
+func TestConsAlbIngress(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "case-1",
+			Namespace: "ns",
+			UID:       "bff0afd6-bb30-4641-8607-8329547324eb",
+		},
+	}
+	ac := &albConfig{
+		host:             "server-1.game.example.com",
+		paths:            []string{"/api", "/chat"},
+		ports:            []int32{8080, 8081},
+		listenProtocol:   "HTTP",
+		listenPort:       80,
+		ingressClassName: "alb",
+	}
+	pathType := networkingv1.PathTypePrefix
+	expect := networkingv1.IngressSpec{
+		IngressClassName: ptr.To[string]("alb"),
+		Rules: []networkingv1.IngressRule{
+			{
+				Host: "server-1.game.example.com",
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{
+							{
+								Path:     "/api",
+								PathType: &pathType,
+								Backend: networkingv1.IngressBackend{
+									Service: &networkingv1.IngressServiceBackend{
+										Name: "case-1",
+										Port: networkingv1.ServiceBackendPort{Number: 8080},
+									},
+								},
+							},
+							{
+								Path:     "/chat",
+								PathType: &pathType,
+								Backend: networkingv1.IngressBackend{
+									Service: &networkingv1.IngressServiceBackend{
+										Name: "case-1",
+										Port: networkingv1.ServiceBackendPort{Number: 8081},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	ing := consAlbIngress(ac, pod, nil, nil)
+	if !reflect.DeepEqual(expect, ing.Spec) {
+		t.Errorf("expect ingress spec %v but actually got %v", expect, ing.Spec)
+	}
+	if ing.GetAnnotations()[AlbListenPortsAnnotationKey] != `[{"HTTP": 80}]` {
+		t.Errorf("expect listen ports [{\"HTTP\": 80}] but actually got %s", ing.GetAnnotations()[AlbListenPortsAnnotationKey])
+	}
+	if len(ing.GetOwnerReferences()) != 1 || ing.GetOwnerReferences()[0].UID != pod.GetUID() {
+		t.Errorf("expect ingress owned by pod but actually got %v", ing.GetOwnerReferences())
+	}
+}
+
+func TestConsAlbNetworkAddresses(t *testing.T) {
+	pod := &corev1.Pod{
+		Status: corev1.PodStatus{
+			PodIP: "10.0.0.1",
+		},
+	}
+	ac := &albConfig{
+		host:       "server-1.game.example.com",
+		paths:      []string{"/api", "/chat"},
+		ports:      []int32{8080, 8081},
+		listenPort: 443,
+	}
+	internalApi := intstr.FromInt(8080)
+	internalChat := intstr.FromInt(8081)
+	external := intstr.FromInt(443)
+	expectInternal := []gamekruiseiov1alpha1.NetworkAddress{
+		{
+			IP: "10.0.0.1",
+			Ports: []gamekruiseiov1alpha1.NetworkPort{
+				{Name: "/api", Port: &internalApi, Protocol: corev1.ProtocolTCP},
+				{Name: "/chat", Port: &internalChat, Protocol: corev1.ProtocolTCP},
+			},
+		},
+	}
+	expectExternal := []gamekruiseiov1alpha1.NetworkAddress{
+		{
+			EndPoint: "server-1.game.example.com",
+			Ports: []gamekruiseiov1alpha1.NetworkPort{
+				{Name: "/api", Port: &external, Protocol: corev1.ProtocolTCP},
+				{Name: "/chat", Port: &external, Protocol: corev1.ProtocolTCP},
+			},
+		},
+	}
+
+	internalAddresses, externalAddresses := consAlbNetworkAddresses(ac, pod)
+	if !reflect.DeepEqual(expectInternal, internalAddresses) {
+		t.Errorf("expect internal addresses %v but actually got %v", expectInternal, internalAddresses)
+	}
+	if !reflect.DeepEqual(expectExternal, externalAddresses) {
+		t.Errorf("expect external addresses %v but actually got %v", expectExternal, externalAddresses)
+	}
+}
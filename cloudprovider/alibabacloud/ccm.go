@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alibabacloud
+
+import (
+	"context"
+	"strconv"
+
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SkipReconcileConfigName freezes the load balancers of a game server, so that CCM stops reconciling them.
+	SkipReconcileConfigName = "SkipReconcile"
+	// GameServerSetSkipReconcileKey is the annotation of GameServerSet freezing the load balancers of all its game servers.
+	GameServerSetSkipReconcileKey = "game.kruise.io/skip-lb-reconcile"
+	// LBSkipReconcileAnnotationKey is the annotation of Service telling CCM not to reconcile its load balancer.
+	LBSkipReconcileAnnotationKey = "service.beta.kubernetes.io/alibaba-cloud-loadbalancer-skip-reconcile"
+)
+
+// isReconcileSkipped returns whether CCM should skip reconciling the load balancers of the pod,
+// which is set by the network conf SkipReconcile of the pod, or the annotation of its GameServerSet.
+func isReconcileSkipped(c client.Client, ctx context.Context, pod *corev1.Pod, conf []gamekruiseiov1alpha1.NetworkConfParams) bool {
+	for _, nc := range conf {
+		if nc.Name == SkipReconcileConfigName {
+			if skipped, err := strconv.ParseBool(nc.Value); err == nil && skipped {
+				return true
+			}
+		}
+	}
+	gss, err := util.GetGameServerSetOfPod(pod, c, ctx)
+	if err != nil {
+		return false
+	}
+	return gss.GetAnnotations()[GameServerSetSkipReconcileKey] == "true"
+}
+
+// setReconcileSkipped adds or removes the annotation skipping CCM reconciliation on svc, and returns whether svc is changed.
+func setReconcileSkipped(svc *corev1.Service, skipped bool) bool {
+	_, exist := svc.GetAnnotations()[LBSkipReconcileAnnotationKey]
+	if skipped == exist {
+		return false
+	}
+	if skipped {
+		if svc.Annotations == nil {
+			svc.Annotations = make(map[string]string)
+		}
+		svc.Annotations[LBSkipReconcileAnnotationKey] = "true"
+		return true
+	}
+	delete(svc.Annotations, LBSkipReconcileAnnotationKey)
+	return true
+}
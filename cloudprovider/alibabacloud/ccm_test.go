@@ -0,0 +1,115 @@
+package alibabacloud
+
+import (
+	"context"
+	"testing"
+
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileSkipped(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := gamekruiseiov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "gss-0",
+			Namespace: "default",
+			Labels: map[string]string{
+				gamekruiseiov1alpha1.GameServerOwnerGssKey: "gss",
+			},
+		},
+	}
+	newGss := func(annotations map[string]string) *gamekruiseiov1alpha1.GameServerSet {
+		return &gamekruiseiov1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "gss",
+				Namespace:   "default",
+				Annotations: annotations,
+			},
+		}
+	}
+
+	tests := []struct {
+		gss            *gamekruiseiov1alpha1.GameServerSet
+		conf           []gamekruiseiov1alpha1.NetworkConfParams
+		svcAnnotations map[string]string
+		skipped        bool
+		changed        bool
+	}{
+		// case 0: skipped by network conf
+		{
+			gss: newGss(nil),
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  SkipReconcileConfigName,
+					Value: "true",
+				},
+			},
+			svcAnnotations: map[string]string{SlbIdAnnotationKey: "lb-xxx"},
+			skipped:        true,
+			changed:        true,
+		},
+		// case 1: skipped by GameServerSet
+		{
+			gss:            newGss(map[string]string{GameServerSetSkipReconcileKey: "true"}),
+			svcAnnotations: map[string]string{SlbIdAnnotationKey: "lb-xxx"},
+			skipped:        true,
+			changed:        true,
+		},
+		// case 2: already skipped
+		{
+			gss:            newGss(map[string]string{GameServerSetSkipReconcileKey: "true"}),
+			svcAnnotations: map[string]string{SlbIdAnnotationKey: "lb-xxx", LBSkipReconcileAnnotationKey: "true"},
+			skipped:        true,
+			changed:        false,
+		},
+		// case 3: resumed
+		{
+			gss: newGss(nil),
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  SkipReconcileConfigName,
+					Value: "false",
+				},
+			},
+			svcAnnotations: map[string]string{SlbIdAnnotationKey: "lb-xxx", LBSkipReconcileAnnotationKey: "true"},
+			skipped:        false,
+			changed:        true,
+		},
+		// case 4: never skipped
+		{
+			gss:            newGss(nil),
+			svcAnnotations: nil,
+			skipped:        false,
+			changed:        false,
+		},
+	}
+
+	for i, test := range tests {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(test.gss).Build()
+		skipped := isReconcileSkipped(c, context.Background(), pod, test.conf)
+		if skipped != test.skipped {
+			t.Errorf("case %d: expect skipped %v but actually got %v", i, test.skipped, skipped)
+		}
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: test.svcAnnotations,
+			},
+		}
+		if changed := setReconcileSkipped(svc, skipped); changed != test.changed {
+			t.Errorf("case %d: expect changed %v but actually got %v", i, test.changed, changed)
+		}
+		if _, exist := svc.GetAnnotations()[LBSkipReconcileAnnotationKey]; exist != test.skipped {
+			t.Errorf("case %d: expect annotation %s exists %v but actually %v", i, LBSkipReconcileAnnotationKey, test.skipped, exist)
+		}
+		if test.svcAnnotations != nil && svc.GetAnnotations()[SlbIdAnnotationKey] != "lb-xxx" {
+			t.Errorf("case %d: expect other annotations kept but actually got %v", i, svc.GetAnnotations())
+		}
+	}
+}
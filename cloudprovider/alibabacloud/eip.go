@@ -2,14 +2,19 @@ package alibabacloud
 
 import (
 	"context"
+	"fmt"
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	"github.com/openkruise/kruise-game/cloudprovider"
 	"github.com/openkruise/kruise-game/cloudprovider/alibabacloud/apis/v1beta1"
 	"github.com/openkruise/kruise-game/cloudprovider/errors"
 	"github.com/openkruise/kruise-game/cloudprovider/utils"
+	"github.com/openkruise/kruise-game/pkg/metrics"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
@@ -22,6 +27,7 @@ const (
 	BandwidthPackageIdConfigName    = "BandwidthPackageId"
 	ChargeTypeConfigName            = "ChargeType"
 	DescriptionConfigName           = "Description"
+	EipIspWeightsConfigName         = "EipIspWeights"
 	WithEIPAnnotationKey            = "k8s.aliyun.com/pod-with-eip"
 	ReleaseStrategyAnnotationkey    = "k8s.aliyun.com/pod-eip-release-strategy"
 	PoolIdAnnotationkey             = "k8s.aliyun.com/eip-public-ip-address-pool-id"
@@ -33,6 +39,9 @@ const (
 	EIPDescriptionAnnotationKey     = "k8s.aliyun.com/eip-description"
 )
 
+// DefaultEipIspWeight is the weight of the ISPs not listed in EipIspWeights.
+const DefaultEipIspWeight int32 = 1
+
 type EipPlugin struct {
 }
 
@@ -97,16 +106,20 @@ func (E EipPlugin) OnPodUpdated(client client.Client, pod *corev1.Pod, ctx conte
 		return pod, nil
 	}
 
+	if networkStatus.CurrentNetworkState != gamekruiseiov1alpha1.NetworkReady {
+		observeEipAllocation(podEip, pod, time.Now())
+	}
+
 	networkStatus.InternalAddresses = []gamekruiseiov1alpha1.NetworkAddress{
 		{
 			IP: podEip.Status.PrivateIPAddress,
 		},
 	}
-	networkStatus.ExternalAddresses = []gamekruiseiov1alpha1.NetworkAddress{
-		{
-			IP: podEip.Status.EipAddress,
-		},
+	externalAddresses, err := consEipExternalAddresses(podEip, networkManager.GetNetworkConfig())
+	if err != nil {
+		return pod, errors.NewPluginError(errors.ParameterError, err.Error())
 	}
+	networkStatus.ExternalAddresses = externalAddresses
 
 	networkStatus.CurrentNetworkState = gamekruiseiov1alpha1.NetworkReady
 
@@ -114,10 +127,102 @@ func (E EipPlugin) OnPodUpdated(client client.Client, pod *corev1.Pod, ctx conte
 	return pod, errors.ToPluginError(err, errors.InternalError)
 }
 
+// ValidateConfig validates the network conf of AlibabaCloud-EIP when GameServerSet is applied.
+func (E EipPlugin) ValidateConfig(conf []gamekruiseiov1alpha1.NetworkConfParams) error {
+	for _, c := range conf {
+		switch c.Name {
+		case EipIspWeightsConfigName:
+			if _, err := parseEipIspWeights(c.Value); err != nil {
+				return err
+			}
+		case ReleaseStrategyConfigName:
+			if err := validateReleaseStrategy(c.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateReleaseStrategy validates ReleaseStrategy, which is Follow, Never, or a positive duration after which
+// the EIP is released once the pod is deleted, such as 5m30s.
+func validateReleaseStrategy(value string) error {
+	switch v1beta1.ReleaseStrategy(value) {
+	case v1beta1.ReleaseStrategyFollow, v1beta1.ReleaseStrategyNever:
+		return nil
+	}
+	if ttl, err := time.ParseDuration(value); err != nil || ttl <= 0 {
+		return fmt.Errorf("invalid ReleaseStrategy %s, which should be %s, %s, or a positive duration such as 5m30s", value, v1beta1.ReleaseStrategyFollow, v1beta1.ReleaseStrategyNever)
+	}
+	return nil
+}
+
 func (E EipPlugin) OnPodDeleted(client client.Client, pod *corev1.Pod, ctx context.Context) errors.PluginError {
 	return nil
 }
 
+// consEipExternalAddresses returns the external addresses of PodEIP labelled with its ISP, weighted by the ISP if
+// EipIspWeights is set.
+func consEipExternalAddresses(podEip *v1beta1.PodEIP, conf []gamekruiseiov1alpha1.NetworkConfParams) ([]gamekruiseiov1alpha1.NetworkAddress, error) {
+	address := gamekruiseiov1alpha1.NetworkAddress{
+		IP:  podEip.Status.EipAddress,
+		ISP: podEip.Status.ISP,
+	}
+	for _, c := range conf {
+		if c.Name != EipIspWeightsConfigName {
+			continue
+		}
+		weights, err := parseEipIspWeights(c.Value)
+		if err != nil {
+			return nil, err
+		}
+		weight, ok := weights[strings.ToUpper(podEip.Status.ISP)]
+		if !ok {
+			weight = DefaultEipIspWeight
+		}
+		address.Weight = &weight
+	}
+	return []gamekruiseiov1alpha1.NetworkAddress{address}, nil
+}
+
+// parseEipIspWeights parses EipIspWeights in the format of {isp}:{weight},... such as BGP:10,BGP_PRO:1.
+// The ISPs are case-insensitive.
+func parseEipIspWeights(value string) (map[string]int32, error) {
+	weights := make(map[string]int32)
+	for _, item := range strings.Split(value, ",") {
+		if item == "" {
+			continue
+		}
+		ispWeight := strings.Split(item, ":")
+		if len(ispWeight) != 2 || ispWeight[0] == "" {
+			return nil, fmt.Errorf("invalid EipIspWeights %s. You should input as the format {isp}:{weight},...", value)
+		}
+		weight, err := strconv.ParseInt(ispWeight[1], 10, 32)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight %s of isp %s, which should be a non-negative integer", ispWeight[1], ispWeight[0])
+		}
+		weights[strings.ToUpper(ispWeight[0])] = int32(weight)
+	}
+	return weights, nil
+}
+
+// observeEipAllocation records how long the EIP of PodEIP takes to be allocated.
+// It is called on the first reconcile seeing the address, when the network is not ready yet.
+// The PodEIP created before the pod is retained from the previous one, whose EIP is reused rather than allocated,
+// so no sample is recorded and false is returned.
+func observeEipAllocation(podEip *v1beta1.PodEIP, pod *corev1.Pod, now time.Time) (float64, bool) {
+	created := podEip.GetCreationTimestamp().Time
+	if created.Before(pod.GetCreationTimestamp().Time) {
+		return 0, false
+	}
+	duration := now.Sub(created).Seconds()
+	if duration < 0 {
+		duration = 0
+	}
+	metrics.EipAllocationDurationSeconds.WithLabelValues(podEip.Status.ISP).Observe(duration)
+	return duration, true
+}
+
 func init() {
 	alibabaCloudProvider.registerPlugin(&EipPlugin{})
 }
@@ -0,0 +1,194 @@
+package alibabacloud
+
+import (
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider/alibabacloud/apis/v1beta1"
+	"github.com/openkruise/kruise-game/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestObserveEipAllocation(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		podCreated time.Time
+		now        time.Time
+		duration   float64
+		observed   bool
+	}{
+		// case 0
+		{
+			podCreated: created,
+			now:        created.Add(3 * time.Second),
+			duration:   3,
+			observed:   true,
+		},
+		// case 1: clock skew
+		{
+			podCreated: created.Add(-time.Second),
+			now:        created.Add(-time.Second),
+			duration:   0,
+			observed:   true,
+		},
+		// case 2: PodEIP is retained from the previous pod
+		{
+			podCreated: created.Add(time.Hour),
+			now:        created.Add(time.Hour + 3*time.Second),
+			duration:   0,
+			observed:   false,
+		},
+	}
+
+	metrics.EipAllocationDurationSeconds.Reset()
+	for i, test := range tests {
+		podEip := &v1beta1.PodEIP{
+			ObjectMeta: metav1.ObjectMeta{
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Status: v1beta1.PodEIPStatus{
+				ISP: "BGP",
+			},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				CreationTimestamp: metav1.NewTime(test.podCreated),
+			},
+		}
+		actual, observed := observeEipAllocation(podEip, pod, test.now)
+		if actual != test.duration || observed != test.observed {
+			t.Errorf("case %d: expect duration %v observed %v, but actually got %v observed %v", i, test.duration, test.observed, actual, observed)
+		}
+	}
+
+	expect := `
+# HELP okg_eip_allocation_duration_seconds The duration from the EIP requested to its address allocated
+# TYPE okg_eip_allocation_duration_seconds histogram
+okg_eip_allocation_duration_seconds_bucket{isp="BGP",le="1"} 1
+okg_eip_allocation_duration_seconds_bucket{isp="BGP",le="2"} 1
+okg_eip_allocation_duration_seconds_bucket{isp="BGP",le="4"} 2
+okg_eip_allocation_duration_seconds_bucket{isp="BGP",le="8"} 2
+okg_eip_allocation_duration_seconds_bucket{isp="BGP",le="16"} 2
+okg_eip_allocation_duration_seconds_bucket{isp="BGP",le="32"} 2
+okg_eip_allocation_duration_seconds_bucket{isp="BGP",le="64"} 2
+okg_eip_allocation_duration_seconds_bucket{isp="BGP",le="128"} 2
+okg_eip_allocation_duration_seconds_bucket{isp="BGP",le="256"} 2
+okg_eip_allocation_duration_seconds_bucket{isp="BGP",le="512"} 2
+okg_eip_allocation_duration_seconds_bucket{isp="BGP",le="+Inf"} 2
+okg_eip_allocation_duration_seconds_sum{isp="BGP"} 3
+okg_eip_allocation_duration_seconds_count{isp="BGP"} 2
+`
+	if err := testutil.CollectAndCompare(metrics.EipAllocationDurationSeconds, strings.NewReader(expect)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConsEipExternalAddresses(t *testing.T) {
+	tests := []struct {
+		isp     string
+		conf    []gamekruiseiov1alpha1.NetworkConfParams
+		expect  []gamekruiseiov1alpha1.NetworkAddress
+		invalid bool
+	}{
+		// case 0: no weights
+		{
+			isp:    "BGP",
+			conf:   nil,
+			expect: []gamekruiseiov1alpha1.NetworkAddress{{IP: "1.1.1.1", ISP: "BGP"}},
+		},
+		// case 1: weight of the isp
+		{
+			isp: "BGP_PRO",
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{Name: EipIspWeightsConfigName, Value: "BGP:10,bgp_pro:3"},
+			},
+			expect: []gamekruiseiov1alpha1.NetworkAddress{{IP: "1.1.1.1", ISP: "BGP_PRO", Weight: ptr.To[int32](3)}},
+		},
+		// case 2: the isp is not listed
+		{
+			isp: "ChinaTelecom",
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{Name: EipIspWeightsConfigName, Value: "BGP:10"},
+			},
+			expect: []gamekruiseiov1alpha1.NetworkAddress{{IP: "1.1.1.1", ISP: "ChinaTelecom", Weight: ptr.To(DefaultEipIspWeight)}},
+		},
+		// case 3: invalid weights
+		{
+			isp: "BGP",
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{Name: EipIspWeightsConfigName, Value: "BGP:-1"},
+			},
+			invalid: true,
+		},
+		// case 4: the isp is unknown
+		{
+			isp:    "",
+			conf:   nil,
+			expect: []gamekruiseiov1alpha1.NetworkAddress{{IP: "1.1.1.1"}},
+		},
+	}
+
+	for i, test := range tests {
+		podEip := &v1beta1.PodEIP{
+			Status: v1beta1.PodEIPStatus{
+				EipAddress: "1.1.1.1",
+				ISP:        test.isp,
+			},
+		}
+		actual, err := consEipExternalAddresses(podEip, test.conf)
+		if (err != nil) != test.invalid {
+			t.Errorf("case %d: expect invalid %v, but actually got err %v", i, test.invalid, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.expect) {
+			t.Errorf("case %d: expect addresses %v, but actually got %v", i, test.expect, actual)
+		}
+	}
+}
+
+func TestEipValidateConfig(t *testing.T) {
+	tests := []struct {
+		conf    []gamekruiseiov1alpha1.NetworkConfParams
+		invalid bool
+	}{
+		// case 0: Follow
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{{Name: ReleaseStrategyConfigName, Value: "Follow"}},
+		},
+		// case 1: Never
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{{Name: ReleaseStrategyConfigName, Value: "Never"}},
+		},
+		// case 2: TTL
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{{Name: ReleaseStrategyConfigName, Value: "5m30s"}},
+		},
+		// case 3: unknown strategy
+		{
+			conf:    []gamekruiseiov1alpha1.NetworkConfParams{{Name: ReleaseStrategyConfigName, Value: "OnDelete"}},
+			invalid: true,
+		},
+		// case 4: non-positive TTL
+		{
+			conf:    []gamekruiseiov1alpha1.NetworkConfParams{{Name: ReleaseStrategyConfigName, Value: "0s"}},
+			invalid: true,
+		},
+		// case 5: invalid isp weights
+		{
+			conf:    []gamekruiseiov1alpha1.NetworkConfParams{{Name: EipIspWeightsConfigName, Value: "BGP"}},
+			invalid: true,
+		},
+	}
+
+	for i, test := range tests {
+		err := EipPlugin{}.ValidateConfig(test.conf)
+		if (err != nil) != test.invalid {
+			t.Errorf("case %d: expect invalid %v but actually got error %v", i, test.invalid, err)
+		}
+	}
+}
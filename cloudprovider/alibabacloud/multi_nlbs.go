@@ -130,7 +130,7 @@ func initMultiLBCache(svcList []corev1.Service, maxPort, minPort int32, blockPor
 			targetPorts = append(targetPorts, port.TargetPort.IntValue())
 		}
 
-		nsName := svc.GetNamespace() + "/" + svc.Spec.Selector[SvcSelectorKey]
+		nsName := svc.GetNamespace() + "/" + svc.Spec.Selector[cloudprovider.GetSvcSelectorKey()]
 		if podAllocate[nsName] == nil {
 			podAllocate[nsName] = &lbsPorts{
 				index:      index,
@@ -166,6 +166,7 @@ func (m *MultiNlbsPlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx con
 		return pod, cperrors.ToPluginError(err, cperrors.InternalError)
 	}
 
+	skipped := isReconcileSkipped(c, ctx, pod, networkConfig)
 	endPoints := ""
 	for i, lbId := range conf.idList[0] {
 		// get svc
@@ -203,9 +204,15 @@ func (m *MultiNlbsPlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx con
 			if err != nil {
 				return pod, cperrors.ToPluginError(err, cperrors.ParameterError)
 			}
+			setReconcileSkipped(service, skipped)
 			return pod, cperrors.ToPluginError(c.Update(ctx, service), cperrors.ApiCallError)
 		}
 
+		// skip or resume the reconciliation of CCM
+		if setReconcileSkipped(svc, skipped) {
+			return pod, cperrors.ToPluginError(c.Update(ctx, svc), cperrors.ApiCallError)
+		}
+
 		// disable network
 		if networkManager.GetNetworkDisabled() && svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
 			svc.Spec.Type = corev1.ServiceTypeClusterIP
@@ -326,6 +333,13 @@ func init() {
 		mutex: sync.RWMutex{},
 	}
 	alibabaCloudProvider.registerPlugin(&multiNlbsPlugin)
+	cloudprovider.RegisterParamSchemas(MultiNlbsNetwork, append([]cloudprovider.ParamSchema{
+		{Name: NlbIdNamesConfigName, Type: cloudprovider.ParamTypeString, Required: true},
+		{Name: PortProtocolsConfigName, Type: cloudprovider.ParamTypeString, Required: true},
+		{Name: FixedConfigName, Type: cloudprovider.ParamTypeBool, Default: "false"},
+		{Name: LBIdleTimeoutConfigName, Type: cloudprovider.ParamTypeInt, Min: ptr.To(1), Max: ptr.To(900)},
+		{Name: SkipReconcileConfigName, Type: cloudprovider.ParamTypeBool, Default: "false"},
+	}, nlbHealthParamSchemas...))
 }
 
 type multiNLBsConfig struct {
@@ -334,6 +348,7 @@ type multiNLBsConfig struct {
 	targetPorts []int
 	protocols   []corev1.Protocol
 	isFixed     bool
+	idleTimeout string
 	*nlbHealthConfig
 }
 
@@ -398,6 +413,9 @@ func (m *MultiNlbsPlugin) consSvc(conf *multiNLBsConfig, pod *corev1.Pod, lbName
 			svcAnnotations[LBHealthCheckMethodAnnotationKey] = conf.lBHealthCheckMethod
 		}
 	}
+	if conf.idleTimeout != "" {
+		svcAnnotations[LBIdleTimeoutAnnotationKey] = conf.idleTimeout
+	}
 	svcAnnotations[LBIDBelongIndexKey] = strconv.Itoa(podLbsPorts.index)
 
 	return &corev1.Service{
@@ -415,7 +433,7 @@ func (m *MultiNlbsPlugin) consSvc(conf *multiNLBsConfig, pod *corev1.Pod, lbName
 			ExternalTrafficPolicy:         corev1.ServiceExternalTrafficPolicyTypeLocal,
 			Type:                          corev1.ServiceTypeLoadBalancer,
 			Selector: map[string]string{
-				SvcSelectorKey: pod.GetName(),
+				cloudprovider.GetSvcSelectorKey(): pod.GetName(),
 			},
 			Ports:             svcPorts,
 			LoadBalancerClass: &loadBalancerClass,
@@ -571,6 +589,11 @@ func parseMultiNLBsConfig(conf []gamekruiseiov1alpha1.NetworkConfParams) (*multi
 		return nil, fmt.Errorf("invalid PortProtocols, which can not be empty")
 	}
 
+	idleTimeout, err := parseNlbIdleTimeout(conf)
+	if err != nil {
+		return nil, err
+	}
+
 	nlbHealthConfig, err := parseNlbHealthConfig(conf)
 	if err != nil {
 		return nil, err
@@ -582,6 +605,7 @@ func parseMultiNLBsConfig(conf []gamekruiseiov1alpha1.NetworkConfParams) (*multi
 		targetPorts:     ports,
 		protocols:       protocols,
 		isFixed:         isFixed,
+		idleTimeout:     idleTimeout,
 		nlbHealthConfig: nlbHealthConfig,
 	}, nil
 }
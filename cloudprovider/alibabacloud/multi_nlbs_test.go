@@ -18,6 +18,7 @@ package alibabacloud
 
 import (
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -387,7 +388,7 @@ func TestInitMultiLBCache(t *testing.T) {
 					Spec: corev1.ServiceSpec{
 						Type: corev1.ServiceTypeLoadBalancer,
 						Selector: map[string]string{
-							SvcSelectorKey: "pod-A",
+							cloudprovider.DefaultSvcSelectorKey: "pod-A",
 						},
 						Ports: []corev1.ServicePort{
 							{
@@ -413,7 +414,7 @@ func TestInitMultiLBCache(t *testing.T) {
 					Spec: corev1.ServiceSpec{
 						Type: corev1.ServiceTypeLoadBalancer,
 						Selector: map[string]string{
-							SvcSelectorKey: "pod-A",
+							cloudprovider.DefaultSvcSelectorKey: "pod-A",
 						},
 						Ports: []corev1.ServicePort{
 							{
@@ -18,19 +18,24 @@ package alibabacloud
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	"github.com/openkruise/kruise-game/cloudprovider"
 	cperrors "github.com/openkruise/kruise-game/cloudprovider/errors"
 	provideroptions "github.com/openkruise/kruise-game/cloudprovider/options"
 	"github.com/openkruise/kruise-game/cloudprovider/utils"
+	"github.com/openkruise/kruise-game/pkg/metrics"
 	"github.com/openkruise/kruise-game/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	log "k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
 	"regexp"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strconv"
@@ -53,6 +58,9 @@ const (
 	LBHealthCheckUriAnnotationKey            = "service.beta.kubernetes.io/alibaba-cloud-loadbalancer-health-check-uri"
 	LBHealthCheckDomainAnnotationKey         = "service.beta.kubernetes.io/alibaba-cloud-loadbalancer-health-check-domain"
 	LBHealthCheckMethodAnnotationKey         = "service.beta.kubernetes.io/alibaba-cloud-loadbalancer-health-check-method"
+	LBProtocolPortAnnotationKey              = "service.beta.kubernetes.io/alibaba-cloud-loadbalancer-protocol-port"
+	LBCertIdAnnotationKey                    = "service.beta.kubernetes.io/alibaba-cloud-loadbalancer-cert-id"
+	LBIdleTimeoutAnnotationKey               = "service.beta.kubernetes.io/alibaba-cloud-loadbalancer-idle-timeout"
 
 	// ConfigNames defined by OKG
 	LBHealthCheckFlagConfigName           = "LBHealthCheckFlag"
@@ -65,22 +73,42 @@ const (
 	LBHealthCheckMethodConfigName         = "LBHealthCheckMethod"
 	LBHealthyThresholdConfigName          = "LBHealthyThreshold"
 	LBUnhealthyThresholdConfigName        = "LBUnhealthyThreshold"
+	CertIdConfigName                      = "CertId"
+	LBIdleTimeoutConfigName               = "LBIdleTimeout"
+	OrdinalAffinityConfigName             = "OrdinalAffinity"
+
+	// NlbOrdinalPortsAnnotationPrefix is the prefix of the annotations of GameServerSet recording the ports allocated
+	// to each ordinal when OrdinalAffinity is enabled, as {prefix}{ordinal}: {lbId}:{port},...
+	NlbOrdinalPortsAnnotationPrefix = "game.kruise.io/nlb-ordinal-ports-"
+
+	// ProtocolTCPSSL means the listener terminates TLS with the cert defined by CertId, while the backend still receives TCP.
+	ProtocolTCPSSL corev1.Protocol = "TCPSSL"
+
+	// DefaultNlbPortUtilizationThreshold is the port utilization of an NLB above which a warning event is emitted,
+	// if port_utilization_threshold is not set.
+	DefaultNlbPortUtilizationThreshold = 0.9
+	NlbPortNearlyExhaustedReason       = "NlbPortNearlyExhausted"
 )
 
 type NlbPlugin struct {
-	maxPort     int32
-	minPort     int32
-	blockPorts  []int32
-	cache       map[string]portAllocated
-	podAllocate map[string]string
-	mutex       sync.RWMutex
+	maxPort              int32
+	minPort              int32
+	blockPorts           []int32
+	utilizationThreshold float64
+	cache                map[string]portAllocated
+	podAllocate          map[string]string
+	recorder             record.EventRecorder
+	mutex                sync.RWMutex
 }
 
 type nlbConfig struct {
-	lbIds       []string
-	targetPorts []int
-	protocols   []corev1.Protocol
-	isFixed     bool
+	lbIds           []string
+	targetPorts     []int
+	protocols       []corev1.Protocol
+	isFixed         bool
+	ordinalAffinity bool
+	certId          string
+	idleTimeout     string
 	*nlbHealthConfig
 }
 
@@ -101,6 +129,12 @@ func (n *NlbPlugin) Name() string {
 	return NlbNetwork
 }
 
+func (n *NlbPlugin) DebugState() interface{} {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return newPortAllocationState(n.minPort, n.maxPort, n.blockPorts, n.cache, n.podAllocate)
+}
+
 func (n *NlbPlugin) Alias() string {
 	return AliasNLB
 }
@@ -112,6 +146,10 @@ func (n *NlbPlugin) Init(c client.Client, options cloudprovider.CloudProviderOpt
 	n.minPort = slbOptions.MinPort
 	n.maxPort = slbOptions.MaxPort
 	n.blockPorts = slbOptions.BlockPorts
+	n.utilizationThreshold = slbOptions.PortUtilizationThreshold
+	if n.utilizationThreshold == 0 {
+		n.utilizationThreshold = DefaultNlbPortUtilizationThreshold
+	}
 
 	svcList := &corev1.ServiceList{}
 	err := c.List(ctx, svcList)
@@ -120,10 +158,28 @@ func (n *NlbPlugin) Init(c client.Client, options cloudprovider.CloudProviderOpt
 	}
 
 	n.cache, n.podAllocate = initLbCache(svcList.Items, n.minPort, n.maxPort, n.blockPorts)
+
+	gssList := &gamekruiseiov1alpha1.GameServerSetList{}
+	err = c.List(ctx, gssList)
+	if err != nil {
+		return err
+	}
+	n.restoreOrdinalAllocations(gssList.Items)
+
+	for lbId := range n.cache {
+		n.updatePortUtilization(lbId)
+	}
 	log.Infof("[%s] podAllocate cache complete initialization: %v", NlbNetwork, n.podAllocate)
 	return nil
 }
 
+// SetEventRecorder sets the recorder emitting the warning events when the ports of an NLB are nearly exhausted.
+func (n *NlbPlugin) SetEventRecorder(recorder record.EventRecorder) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.recorder = recorder
+}
+
 func (n *NlbPlugin) OnPodAdded(client client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, cperrors.PluginError) {
 	return pod, nil
 }
@@ -167,8 +223,13 @@ func (n *NlbPlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx context.C
 		return pod, nil
 	}
 
+	// repair the allocation drifted from the live svc, or reallocate when the live ports belong to another pod
+	repaired := n.repairDrift(svc)
+
+	skipped := isReconcileSkipped(c, ctx, pod, networkConfig)
+
 	// update svc
-	if util.GetHash(sc) != svc.GetAnnotations()[SlbConfigHashKey] {
+	if !repaired || util.GetHash(sc) != svc.GetAnnotations()[SlbConfigHashKey] {
 		networkStatus.CurrentNetworkState = gamekruiseiov1alpha1.NetworkNotReady
 		pod, err = networkManager.UpdateNetworkStatus(*networkStatus, pod)
 		if err != nil {
@@ -178,9 +239,15 @@ func (n *NlbPlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx context.C
 		if err != nil {
 			return pod, cperrors.ToPluginError(err, cperrors.ParameterError)
 		}
+		setReconcileSkipped(service, skipped)
 		return pod, cperrors.ToPluginError(c.Update(ctx, service), cperrors.ApiCallError)
 	}
 
+	// skip or resume the reconciliation of CCM
+	if setReconcileSkipped(svc, skipped) {
+		return pod, cperrors.ToPluginError(c.Update(ctx, svc), cperrors.ApiCallError)
+	}
+
 	// disable network
 	if networkManager.GetNetworkDisabled() && svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
 		svc.Spec.Type = corev1.ServiceTypeClusterIP
@@ -248,6 +315,7 @@ func (n *NlbPlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx context.C
 	networkStatus.InternalAddresses = internalAddresses
 	networkStatus.ExternalAddresses = externalAddresses
 	networkStatus.CurrentNetworkState = gamekruiseiov1alpha1.NetworkReady
+	pod = networkManager.SetExternalEndpoints(externalAddresses, pod)
 	pod, err = networkManager.UpdateNetworkStatus(*networkStatus, pod)
 	return pod, cperrors.ToPluginError(err, cperrors.InternalError)
 }
@@ -261,13 +329,18 @@ func (n *NlbPlugin) OnPodDeleted(c client.Client, pod *corev1.Pod, ctx context.C
 	}
 
 	var podKeys []string
-	if sc.isFixed {
+	if sc.isFixed || sc.ordinalAffinity {
 		gss, err := util.GetGameServerSetOfPod(pod, c, ctx)
 		if err != nil && !errors.IsNotFound(err) {
 			return cperrors.ToPluginError(err, cperrors.ApiCallError)
 		}
 		// gss exists in cluster, do not deAllocate.
 		if err == nil && gss.GetDeletionTimestamp() == nil {
+			// with OrdinalAffinity only, the ports are kept for the recreated pod until the ordinal leaves gss.
+			if sc.isFixed || isOrdinalInGss(gss, util.GetIndexFromGsName(pod.GetName())) {
+				return nil
+			}
+			n.deAllocate(pod.GetNamespace() + "/" + pod.GetName())
 			return nil
 		}
 		// gss not exists in cluster, deAllocate all the ports related to it.
@@ -288,11 +361,41 @@ func (n *NlbPlugin) OnPodDeleted(c client.Client, pod *corev1.Pod, ctx context.C
 	return nil
 }
 
+// ValidateConfig validates the network conf of AlibabaCloud-NLB when GameServerSet is applied.
+func (n *NlbPlugin) ValidateConfig(conf []gamekruiseiov1alpha1.NetworkConfParams) error {
+	_, err := parseNlbConfig(conf)
+	return err
+}
+
+// nlbHealthParamSchemas are the health check params shared by the NLB plugins.
+var nlbHealthParamSchemas = []cloudprovider.ParamSchema{
+	{Name: LBHealthCheckFlagConfigName, Type: cloudprovider.ParamTypeString, Default: "on", Enum: []string{"on", "off"}},
+	{Name: LBHealthCheckTypeConfigName, Type: cloudprovider.ParamTypeString, Default: "tcp", Enum: []string{"tcp", "http"}},
+	{Name: LBHealthCheckConnectPortConfigName, Type: cloudprovider.ParamTypeInt, Default: "0", Min: ptr.To(0), Max: ptr.To(65535)},
+	{Name: LBHealthCheckConnectTimeoutConfigName, Type: cloudprovider.ParamTypeInt, Default: "5", Min: ptr.To(1), Max: ptr.To(300)},
+	{Name: LBHealthCheckIntervalConfigName, Type: cloudprovider.ParamTypeInt, Default: "10", Min: ptr.To(1), Max: ptr.To(50)},
+	{Name: LBHealthCheckUriConfigName, Type: cloudprovider.ParamTypeString},
+	{Name: LBHealthCheckDomainConfigName, Type: cloudprovider.ParamTypeString},
+	{Name: LBHealthCheckMethodConfigName, Type: cloudprovider.ParamTypeString, Enum: []string{"get", "head"}},
+	{Name: LBHealthyThresholdConfigName, Type: cloudprovider.ParamTypeInt, Default: "2", Min: ptr.To(2), Max: ptr.To(10)},
+	{Name: LBUnhealthyThresholdConfigName, Type: cloudprovider.ParamTypeInt, Default: "2", Min: ptr.To(2), Max: ptr.To(10)},
+	{Name: gamekruiseiov1alpha1.AllowNotReadyContainersNetworkConfName, Type: cloudprovider.ParamTypeString},
+}
+
 func init() {
 	nlbPlugin := NlbPlugin{
 		mutex: sync.RWMutex{},
 	}
 	alibabaCloudProvider.registerPlugin(&nlbPlugin)
+	cloudprovider.RegisterParamSchemas(NlbNetwork, append([]cloudprovider.ParamSchema{
+		{Name: NlbIdsConfigName, Type: cloudprovider.ParamTypeString, Required: true},
+		{Name: PortProtocolsConfigName, Type: cloudprovider.ParamTypeString, Required: true},
+		{Name: FixedConfigName, Type: cloudprovider.ParamTypeBool, Default: "false"},
+		{Name: CertIdConfigName, Type: cloudprovider.ParamTypeString},
+		{Name: LBIdleTimeoutConfigName, Type: cloudprovider.ParamTypeInt, Min: ptr.To(1), Max: ptr.To(900)},
+		{Name: OrdinalAffinityConfigName, Type: cloudprovider.ParamTypeBool, Default: "false"},
+		{Name: SkipReconcileConfigName, Type: cloudprovider.ParamTypeBool, Default: "false"},
+	}, nlbHealthParamSchemas...))
 }
 
 func (n *NlbPlugin) consSvc(nc *nlbConfig, pod *corev1.Pod, c client.Client, ctx context.Context) (*corev1.Service, error) {
@@ -309,14 +412,42 @@ func (n *NlbPlugin) consSvc(nc *nlbConfig, pod *corev1.Pod, c client.Client, ctx
 		if lbId == "" && ports == nil {
 			return nil, fmt.Errorf("there are no avaialable ports for %v", nc.lbIds)
 		}
+		n.warnPortUtilization(lbId, pod, c, ctx)
+	}
+	if nc.ordinalAffinity {
+		if err := recordOrdinalAllocation(pod, c, ctx, lbId+":"+util.Int32SliceToString(ports, ",")); err != nil {
+			log.Warningf("[%s] failed to record the ports of pod %s to its GameServerSet, because of %s", NlbNetwork, podKey, err.Error())
+		}
 	}
 
 	svcPorts := make([]corev1.ServicePort, 0)
+	var sslPorts []string
 	for i := 0; i < len(nc.targetPorts); i++ {
+		protocol := nc.protocols[i]
+		if protocol == ProtocolTCPSSL {
+			protocol = corev1.ProtocolTCP
+			sslPorts = append(sslPorts, string(ProtocolTCPSSL)+":"+strconv.Itoa(int(ports[i])))
+		}
+		// NLB listens on the same port with both TCP and UDP, sharing one allocated port
+		if protocol == ProtocolTCPUDP {
+			svcPorts = append(svcPorts, corev1.ServicePort{
+				Name:       strconv.Itoa(nc.targetPorts[i]) + "-" + strings.ToLower(string(corev1.ProtocolTCP)),
+				Port:       ports[i],
+				Protocol:   corev1.ProtocolTCP,
+				TargetPort: intstr.FromInt(nc.targetPorts[i]),
+			})
+			svcPorts = append(svcPorts, corev1.ServicePort{
+				Name:       strconv.Itoa(nc.targetPorts[i]) + "-" + strings.ToLower(string(corev1.ProtocolUDP)),
+				Port:       ports[i],
+				Protocol:   corev1.ProtocolUDP,
+				TargetPort: intstr.FromInt(nc.targetPorts[i]),
+			})
+			continue
+		}
 		svcPorts = append(svcPorts, corev1.ServicePort{
 			Name:       strconv.Itoa(nc.targetPorts[i]),
 			Port:       ports[i],
-			Protocol:   nc.protocols[i],
+			Protocol:   protocol,
 			TargetPort: intstr.FromInt(nc.targetPorts[i]),
 		})
 	}
@@ -342,6 +473,13 @@ func (n *NlbPlugin) consSvc(nc *nlbConfig, pod *corev1.Pod, c client.Client, ctx
 			svcAnnotations[LBHealthCheckMethodAnnotationKey] = nc.lBHealthCheckMethod
 		}
 	}
+	if len(sslPorts) != 0 {
+		svcAnnotations[LBProtocolPortAnnotationKey] = strings.Join(sslPorts, ",")
+		svcAnnotations[LBCertIdAnnotationKey] = nc.certId
+	}
+	if nc.idleTimeout != "" {
+		svcAnnotations[LBIdleTimeoutAnnotationKey] = nc.idleTimeout
+	}
 
 	svc := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
@@ -354,7 +492,7 @@ func (n *NlbPlugin) consSvc(nc *nlbConfig, pod *corev1.Pod, c client.Client, ctx
 			ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
 			Type:                  corev1.ServiceTypeLoadBalancer,
 			Selector: map[string]string{
-				SvcSelectorKey: pod.GetName(),
+				cloudprovider.GetSvcSelectorKey(): pod.GetName(),
 			},
 			Ports:             svcPorts,
 			LoadBalancerClass: &loadBalancerClass,
@@ -413,10 +551,115 @@ func (n *NlbPlugin) allocate(lbIds []string, num int, nsName string) (string, []
 	}
 
 	n.podAllocate[nsName] = lbId + ":" + util.Int32SliceToString(ports, ",")
+	n.updatePortUtilization(lbId)
 	log.Infof("pod %s allocate nlb %s ports %v", nsName, lbId, ports)
 	return lbId, ports
 }
 
+// isOrdinalInGss returns whether the ordinal is managed by gss, i.e. it is not reserved and is less than replicas
+// plus the number of reserved ordinals.
+func isOrdinalInGss(gss *gamekruiseiov1alpha1.GameServerSet, ordinal int) bool {
+	if util.IsNumInList(ordinal, gss.Spec.ReserveGameServerIds) {
+		return false
+	}
+	replicas := 0
+	if gss.Spec.Replicas != nil {
+		replicas = int(*gss.Spec.Replicas)
+	}
+	return ordinal < replicas+len(gss.Spec.ReserveGameServerIds)
+}
+
+// parseOrdinalPortsKey returns the ordinal of the annotation recording the ports of an ordinal, or false if the
+// annotation is not one of them.
+func parseOrdinalPortsKey(key string) (int, bool) {
+	if !strings.HasPrefix(key, NlbOrdinalPortsAnnotationPrefix) {
+		return 0, false
+	}
+	ordinal, err := strconv.Atoi(strings.TrimPrefix(key, NlbOrdinalPortsAnnotationPrefix))
+	if err != nil || ordinal < 0 {
+		return 0, false
+	}
+	return ordinal, true
+}
+
+// recordOrdinalAllocation records the ports allocated to the ordinal of the pod in the annotation of its GameServerSet,
+// and removes the records of the ordinals no longer in it. Every ordinal has its own annotation, so that the records
+// of different pods are merge patched without conflicts.
+func recordOrdinalAllocation(pod *corev1.Pod, c client.Client, ctx context.Context, allocated string) error {
+	gss, err := util.GetGameServerSetOfPod(pod, c, ctx)
+	if err != nil {
+		return err
+	}
+	annotations := make(map[string]interface{})
+	for key := range gss.GetAnnotations() {
+		if ordinal, ok := parseOrdinalPortsKey(key); ok && !isOrdinalInGss(gss, ordinal) {
+			annotations[key] = nil
+		}
+	}
+	ordinal := util.GetIndexFromGsName(pod.GetName())
+	key := NlbOrdinalPortsAnnotationPrefix + strconv.Itoa(ordinal)
+	if isOrdinalInGss(gss, ordinal) && gss.GetAnnotations()[key] != allocated {
+		annotations[key] = allocated
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+	return c.Patch(ctx, gss, client.RawPatch(types.MergePatchType, patch))
+}
+
+// restoreOrdinalAllocations restores the ports recorded for the ordinals in the GameServerSets, whose pods have no
+// Service when the plugin starts, e.g. being recreated, so that the ports are not allocated to other pods.
+func (n *NlbPlugin) restoreOrdinalAllocations(gssList []gamekruiseiov1alpha1.GameServerSet) {
+	for _, gss := range gssList {
+		for key, allocated := range gss.GetAnnotations() {
+			ordinal, ok := parseOrdinalPortsKey(key)
+			if !ok || !isOrdinalInGss(&gss, ordinal) {
+				continue
+			}
+			podKey := gss.GetNamespace() + "/" + util.GetGsName(gss.GetName(), ordinal)
+			if _, exist := n.podAllocate[podKey]; exist {
+				continue
+			}
+			lbPorts := strings.Split(allocated, ":")
+			if len(lbPorts) != 2 {
+				continue
+			}
+			lbId := lbPorts[0]
+			ports := util.StringToInt32Slice(lbPorts[1], ",")
+			if n.cache[lbId] == nil {
+				// init cache for new lb
+				n.cache[lbId] = make(portAllocated, n.maxPort-n.minPort+1)
+				for i := n.minPort; i <= n.maxPort; i++ {
+					n.cache[lbId][i] = false
+				}
+				// block ports
+				for _, blockPort := range n.blockPorts {
+					n.cache[lbId][blockPort] = true
+				}
+			}
+			available := len(ports) != 0
+			for _, port := range ports {
+				if port < n.minPort || port > n.maxPort || n.cache[lbId][port] {
+					available = false
+					break
+				}
+			}
+			if !available {
+				log.Warningf("[%s] ports %s recorded for %s are not available, which are not restored", NlbNetwork, allocated, podKey)
+				continue
+			}
+			for _, port := range ports {
+				n.cache[lbId][port] = true
+			}
+			n.podAllocate[podKey] = allocated
+		}
+	}
+}
+
 func (n *NlbPlugin) deAllocate(nsName string) {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
@@ -438,14 +681,164 @@ func (n *NlbPlugin) deAllocate(nsName string) {
 	}
 
 	delete(n.podAllocate, nsName)
+	n.updatePortUtilization(lbId)
 	log.Infof("pod %s deallocate nlb %s ports %v", nsName, lbId, ports)
 }
 
+// updatePortUtilization refreshes the port utilization metric of the NLB. It must be called with the mutex held.
+func (n *NlbPlugin) updatePortUtilization(lbId string) float64 {
+	utilization := portUtilization(n.cache[lbId], n.minPort, n.maxPort, n.blockPorts)
+	metrics.NlbPortUtilization.WithLabelValues(lbId).Set(utilization)
+	return utilization
+}
+
+// RegisterMetrics publishes okg_nlb_total, the number of NLB instances with ports allocated by the plugin.
+func (n *NlbPlugin) RegisterMetrics(registry prometheus.Registerer) error {
+	return registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "okg_nlb_total",
+		Help: "The number of NLB instances with ports allocated by " + NlbNetwork,
+	}, func() float64 {
+		n.mutex.RLock()
+		defer n.mutex.RUnlock()
+		return float64(len(n.cache))
+	}))
+}
+
+// warnPortUtilization emits a warning event on the GameServerSet of the pod
+// if the port utilization of the NLB allocated to the pod exceeds the threshold.
+func (n *NlbPlugin) warnPortUtilization(lbId string, pod *corev1.Pod, c client.Client, ctx context.Context) {
+	n.mutex.RLock()
+	utilization := portUtilization(n.cache[lbId], n.minPort, n.maxPort, n.blockPorts)
+	threshold := n.utilizationThreshold
+	recorder := n.recorder
+	n.mutex.RUnlock()
+
+	if utilization < threshold {
+		return
+	}
+	log.Warningf("[%s] port utilization of nlb %s reaches %.2f", NlbNetwork, lbId, utilization)
+	if recorder == nil {
+		return
+	}
+	gss, err := util.GetGameServerSetOfPod(pod, c, ctx)
+	if err != nil {
+		return
+	}
+	recorder.Eventf(gss, corev1.EventTypeWarning, NlbPortNearlyExhaustedReason,
+		"port utilization of nlb %s reaches %.0f%%, add more nlbs to NlbIds before the ports are exhausted", lbId, utilization*100)
+}
+
+// portUtilization returns the ratio of the allocated ports to the ports available in [minPort, maxPort] except the blocked ones.
+func portUtilization(ports portAllocated, minPort, maxPort int32, blockPorts []int32) float64 {
+	blocked := make(map[int32]bool, len(blockPorts))
+	for _, port := range blockPorts {
+		if port >= minPort && port <= maxPort {
+			blocked[port] = true
+		}
+	}
+	total := int(maxPort-minPort+1) - len(blocked)
+	if total <= 0 {
+		return 0
+	}
+	allocated := 0
+	for port, ok := range ports {
+		if ok && !blocked[port] && port >= minPort && port <= maxPort {
+			allocated++
+		}
+	}
+	return float64(allocated) / float64(total)
+}
+
+// repairDrift makes the recorded allocation of the pod consistent with the ports of its live svc,
+// which may be changed manually and lead to duplicate port assignment otherwise.
+// It returns false if the live ports are allocated to another pod, in which case nothing is changed
+// and the svc should be rebuilt with the ports allocated to the pod itself.
+func (n *NlbPlugin) repairDrift(svc *corev1.Service) bool {
+	lbId := svc.GetAnnotations()[SlbIdAnnotationKey]
+	if lbId == "" {
+		return true
+	}
+	var ports []int32
+	seen := make(map[int32]bool)
+	for _, port := range getPorts(svc.Spec.Ports) {
+		if port <= n.maxPort && port >= n.minPort && !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) == 0 {
+		return true
+	}
+	podKey := svc.GetNamespace() + "/" + svc.GetName()
+	live := lbId + ":" + util.Int32SliceToString(ports, ",")
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	recorded, exist := n.podAllocate[podKey]
+	if exist && recorded == live {
+		return true
+	}
+
+	// the live ports must not be owned by another pod
+	for key, allocated := range n.podAllocate {
+		if key == podKey {
+			continue
+		}
+		lbPorts := strings.Split(allocated, ":")
+		if len(lbPorts) != 2 || lbPorts[0] != lbId {
+			continue
+		}
+		for _, port := range util.StringToInt32Slice(lbPorts[1], ",") {
+			if seen[port] {
+				log.Warningf("[%s] pod %s live port %d on %s conflicts with pod %s, reallocating", NlbNetwork, podKey, port, lbId, key)
+				return false
+			}
+		}
+	}
+
+	// release the recorded ports
+	if exist {
+		lbPorts := strings.Split(recorded, ":")
+		if n.cache[lbPorts[0]] != nil {
+			for _, port := range util.StringToInt32Slice(lbPorts[1], ",") {
+				n.cache[lbPorts[0]][port] = false
+			}
+			for _, blockPort := range n.blockPorts {
+				n.cache[lbPorts[0]][blockPort] = true
+			}
+		}
+	}
+
+	// occupy the live ports
+	if n.cache[lbId] == nil {
+		n.cache[lbId] = make(portAllocated, n.maxPort-n.minPort+1)
+		for i := n.minPort; i <= n.maxPort; i++ {
+			n.cache[lbId][i] = false
+		}
+		for _, blockPort := range n.blockPorts {
+			n.cache[lbId][blockPort] = true
+		}
+	}
+	for _, port := range ports {
+		n.cache[lbId][port] = true
+	}
+	n.podAllocate[podKey] = live
+	if exist {
+		n.updatePortUtilization(strings.Split(recorded, ":")[0])
+	}
+	n.updatePortUtilization(lbId)
+	metrics.NlbPortDriftTotal.Inc()
+	log.Warningf("[%s] pod %s allocation drifts from %s to %s, repaired with the live svc", NlbNetwork, podKey, recorded, live)
+	return true
+}
+
 func parseNlbConfig(conf []gamekruiseiov1alpha1.NetworkConfParams) (*nlbConfig, error) {
 	var lbIds []string
 	ports := make([]int, 0)
 	protocols := make([]corev1.Protocol, 0)
 	isFixed := false
+	ordinalAffinity := false
+	certId := ""
 
 	for _, c := range conf {
 		switch c.Name {
@@ -475,9 +868,28 @@ func parseNlbConfig(conf []gamekruiseiov1alpha1.NetworkConfParams) (*nlbConfig,
 				continue
 			}
 			isFixed = v
+		case OrdinalAffinityConfigName:
+			v, err := strconv.ParseBool(c.Value)
+			if err != nil {
+				continue
+			}
+			ordinalAffinity = v
+		case CertIdConfigName:
+			certId = c.Value
 		}
 	}
 
+	for _, protocol := range protocols {
+		if protocol == ProtocolTCPSSL && certId == "" {
+			return nil, fmt.Errorf("%s is required when protocol %s is used in %s", CertIdConfigName, ProtocolTCPSSL, PortProtocolsConfigName)
+		}
+	}
+
+	idleTimeout, err := parseNlbIdleTimeout(conf)
+	if err != nil {
+		return nil, err
+	}
+
 	nlbHealthConfig, err := parseNlbHealthConfig(conf)
 	if err != nil {
 		return nil, err
@@ -488,10 +900,32 @@ func parseNlbConfig(conf []gamekruiseiov1alpha1.NetworkConfParams) (*nlbConfig,
 		protocols:       protocols,
 		targetPorts:     ports,
 		isFixed:         isFixed,
+		ordinalAffinity: ordinalAffinity,
+		certId:          certId,
+		idleTimeout:     idleTimeout,
 		nlbHealthConfig: nlbHealthConfig,
 	}, nil
 }
 
+// parseNlbIdleTimeout returns the listener idle timeout in seconds set by LBIdleTimeout, or empty if unset.
+// NLB supports the idle timeout from 1 to 900 seconds.
+func parseNlbIdleTimeout(conf []gamekruiseiov1alpha1.NetworkConfParams) (string, error) {
+	for _, c := range conf {
+		if c.Name != LBIdleTimeoutConfigName {
+			continue
+		}
+		timeoutInt, err := strconv.Atoi(c.Value)
+		if err != nil {
+			return "", fmt.Errorf("invalid lb idle timeout: %s", c.Value)
+		}
+		if timeoutInt < 1 || timeoutInt > 900 {
+			return "", fmt.Errorf("invalid lb idle timeout: %d", timeoutInt)
+		}
+		return c.Value, nil
+	}
+	return "", nil
+}
+
 func parseNlbHealthConfig(conf []gamekruiseiov1alpha1.NetworkConfParams) (*nlbHealthConfig, error) {
 	lBHealthCheckFlag := "on"
 	lBHealthCheckType := "tcp"
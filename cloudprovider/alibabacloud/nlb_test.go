@@ -2,14 +2,22 @@ package alibabacloud
 
 import (
 	"context"
+	"encoding/json"
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
+	"github.com/openkruise/kruise-game/pkg/metrics"
 	"github.com/openkruise/kruise-game/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/ptr"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sync"
 	"testing"
 )
@@ -56,6 +64,141 @@ func TestNLBAllocateDeAllocate(t *testing.T) {
 	}
 }
 
+func TestNlbPlugin_DebugState(t *testing.T) {
+	nlb := &NlbPlugin{
+		maxPort:    int32(515),
+		minPort:    int32(512),
+		blockPorts: []int32{513},
+		cache: map[string]portAllocated{
+			"xxx-A": {512: true, 513: true, 514: true, 515: false},
+		},
+		podAllocate: map[string]string{
+			"xxx/xxx-0": "xxx-A:512,514",
+		},
+		mutex: sync.RWMutex{},
+	}
+
+	body, err := json.Marshal(nlb.DebugState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"minPort":512,"maxPort":515,"blockPorts":[513],"allocatedPorts":{"xxx-A":[512,514]},"podAllocate":{"xxx/xxx-0":"xxx-A:512,514"}}`
+	if string(body) != expect {
+		t.Errorf("expect debug state %s, but actually got %s", expect, string(body))
+	}
+}
+
+func TestNlbPlugin_repairDrift(t *testing.T) {
+	newSvc := func(lbId string, ports ...int32) *corev1.Service {
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        "xxx-0",
+				Annotations: map[string]string{SlbIdAnnotationKey: lbId},
+			},
+		}
+		for _, port := range ports {
+			svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{Port: port})
+		}
+		return svc
+	}
+	tests := []struct {
+		podAllocate       map[string]string
+		cache             map[string]portAllocated
+		svc               *corev1.Service
+		expectPodAllocate map[string]string
+		expectCache       map[string]portAllocated
+		expectRepaired    bool
+		expectDrift       float64
+	}{
+		// case 0: no drift
+		{
+			podAllocate:       map[string]string{"xxx/xxx-0": "xxx-A:512"},
+			cache:             map[string]portAllocated{"xxx-A": {512: true, 513: false, 514: false}},
+			svc:               newSvc("xxx-A", 512),
+			expectPodAllocate: map[string]string{"xxx/xxx-0": "xxx-A:512"},
+			expectCache:       map[string]portAllocated{"xxx-A": {512: true, 513: false, 514: false}},
+			expectRepaired:    true,
+		},
+		// case 1: svc port is edited manually
+		{
+			podAllocate:       map[string]string{"xxx/xxx-0": "xxx-A:512"},
+			cache:             map[string]portAllocated{"xxx-A": {512: true, 513: false, 514: false}},
+			svc:               newSvc("xxx-A", 513),
+			expectPodAllocate: map[string]string{"xxx/xxx-0": "xxx-A:513"},
+			expectCache:       map[string]portAllocated{"xxx-A": {512: false, 513: true, 514: false}},
+			expectRepaired:    true,
+			expectDrift:       1,
+		},
+		// case 2: svc lb is edited manually
+		{
+			podAllocate:       map[string]string{"xxx/xxx-0": "xxx-A:512"},
+			cache:             map[string]portAllocated{"xxx-A": {512: true, 513: false, 514: false}},
+			svc:               newSvc("xxx-B", 514),
+			expectPodAllocate: map[string]string{"xxx/xxx-0": "xxx-B:514"},
+			expectCache: map[string]portAllocated{
+				"xxx-A": {512: false, 513: false, 514: false},
+				"xxx-B": {512: false, 513: false, 514: true},
+			},
+			expectRepaired: true,
+			expectDrift:    1,
+		},
+		// case 3: allocation is missing
+		{
+			podAllocate:       map[string]string{},
+			cache:             map[string]portAllocated{},
+			svc:               newSvc("xxx-A", 512, 514),
+			expectPodAllocate: map[string]string{"xxx/xxx-0": "xxx-A:512,514"},
+			expectCache:       map[string]portAllocated{"xxx-A": {512: true, 513: false, 514: true}},
+			expectRepaired:    true,
+			expectDrift:       1,
+		},
+		// case 4: svc port is edited to the one allocated to another pod
+		{
+			podAllocate:       map[string]string{"xxx/xxx-0": "xxx-A:512", "xxx/xxx-1": "xxx-A:513"},
+			cache:             map[string]portAllocated{"xxx-A": {512: true, 513: true, 514: false}},
+			svc:               newSvc("xxx-A", 513),
+			expectPodAllocate: map[string]string{"xxx/xxx-0": "xxx-A:512", "xxx/xxx-1": "xxx-A:513"},
+			expectCache:       map[string]portAllocated{"xxx-A": {512: true, 513: true, 514: false}},
+			expectRepaired:    false,
+		},
+		// case 5: same port on another lb is not a conflict
+		{
+			podAllocate:       map[string]string{"xxx/xxx-0": "xxx-A:512", "xxx/xxx-1": "xxx-B:513"},
+			cache:             map[string]portAllocated{"xxx-A": {512: true, 513: false, 514: false}, "xxx-B": {512: false, 513: true, 514: false}},
+			svc:               newSvc("xxx-A", 513),
+			expectPodAllocate: map[string]string{"xxx/xxx-0": "xxx-A:513", "xxx/xxx-1": "xxx-B:513"},
+			expectCache:       map[string]portAllocated{"xxx-A": {512: false, 513: true, 514: false}, "xxx-B": {512: false, 513: true, 514: false}},
+			expectRepaired:    true,
+			expectDrift:       1,
+		},
+	}
+
+	for i, test := range tests {
+		nlb := &NlbPlugin{
+			maxPort:     int32(514),
+			minPort:     int32(512),
+			cache:       test.cache,
+			podAllocate: test.podAllocate,
+			mutex:       sync.RWMutex{},
+		}
+		before := testutil.ToFloat64(metrics.NlbPortDriftTotal)
+		repaired := nlb.repairDrift(test.svc)
+		if repaired != test.expectRepaired {
+			t.Errorf("case %d: expect repaired %v, but actually got %v", i, test.expectRepaired, repaired)
+		}
+		if drift := testutil.ToFloat64(metrics.NlbPortDriftTotal) - before; drift != test.expectDrift {
+			t.Errorf("case %d: expect drift %v, but actually got %v", i, test.expectDrift, drift)
+		}
+		if !reflect.DeepEqual(nlb.podAllocate, test.expectPodAllocate) {
+			t.Errorf("case %d: expect podAllocate %v, but actually got %v", i, test.expectPodAllocate, nlb.podAllocate)
+		}
+		if !reflect.DeepEqual(nlb.cache, test.expectCache) {
+			t.Errorf("case %d: expect cache %v, but actually got %v", i, test.expectCache, nlb.cache)
+		}
+	}
+}
+
 func TestParseNlbConfig(t *testing.T) {
 	tests := []struct {
 		conf      []gamekruiseiov1alpha1.NetworkConfParams
@@ -296,7 +439,7 @@ func TestNlbPlugin_consSvc(t *testing.T) {
 					ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
 					LoadBalancerClass:     &loadBalancerClass,
 					Selector: map[string]string{
-						SvcSelectorKey: "test-pod",
+						cloudprovider.DefaultSvcSelectorKey: "test-pod",
 					},
 					Ports: []corev1.ServicePort{{
 						Name:     "82",
@@ -311,6 +454,194 @@ func TestNlbPlugin_consSvc(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "convert svc with TCPSSL",
+			fields: fields{
+				maxPort: 3000,
+				minPort: 1,
+				cache:   map[string]portAllocated{},
+				podAllocate: map[string]string{
+					"default/test-pod": "nlb-xxx:80,81",
+				},
+			},
+			args: args{
+				config: &nlbConfig{
+					lbIds:       []string{"nlb-xxx"},
+					targetPorts: []int{443, 7000},
+					protocols: []corev1.Protocol{
+						ProtocolTCPSSL,
+						corev1.ProtocolUDP,
+					},
+					isFixed: false,
+					certId:  "cert-xxx",
+					nlbHealthConfig: &nlbHealthConfig{
+						lBHealthCheckFlag: "off",
+					},
+				},
+				pod: &corev1.Pod{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "pod",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-pod",
+						Namespace: "default",
+						UID:       "32fqwfqfew",
+					},
+				},
+				client: nil,
+				ctx:    context.Background(),
+			},
+			want: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod",
+					Namespace: "default",
+					Annotations: map[string]string{
+						SlbListenerOverrideKey: "true",
+						SlbIdAnnotationKey:     "nlb-xxx",
+						SlbConfigHashKey: util.GetHash(&nlbConfig{
+							lbIds:       []string{"nlb-xxx"},
+							targetPorts: []int{443, 7000},
+							protocols: []corev1.Protocol{
+								ProtocolTCPSSL,
+								corev1.ProtocolUDP,
+							},
+							isFixed: false,
+							certId:  "cert-xxx",
+							nlbHealthConfig: &nlbHealthConfig{
+								lBHealthCheckFlag: "off",
+							},
+						}),
+						LBHealthCheckFlagAnnotationKey: "off",
+						LBProtocolPortAnnotationKey:    "TCPSSL:80",
+						LBCertIdAnnotationKey:          "cert-xxx",
+					},
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion:         "v1",
+							Kind:               "pod",
+							Name:               "test-pod",
+							UID:                "32fqwfqfew",
+							Controller:         ptr.To[bool](true),
+							BlockOwnerDeletion: ptr.To[bool](true),
+						},
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:                  corev1.ServiceTypeLoadBalancer,
+					ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+					LoadBalancerClass:     &loadBalancerClass,
+					Selector: map[string]string{
+						cloudprovider.DefaultSvcSelectorKey: "test-pod",
+					},
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "443",
+							Port:       80,
+							Protocol:   corev1.ProtocolTCP,
+							TargetPort: intstr.FromInt(443),
+						},
+						{
+							Name:       "7000",
+							Port:       81,
+							Protocol:   corev1.ProtocolUDP,
+							TargetPort: intstr.FromInt(7000),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "convert svc with TCPUDP",
+			fields: fields{
+				maxPort: 3000,
+				minPort: 1,
+				cache:   map[string]portAllocated{},
+				podAllocate: map[string]string{
+					"default/test-pod": "nlb-xxx:80",
+				},
+			},
+			args: args{
+				config: &nlbConfig{
+					lbIds:       []string{"nlb-xxx"},
+					targetPorts: []int{8080},
+					protocols: []corev1.Protocol{
+						ProtocolTCPUDP,
+					},
+					isFixed: false,
+					nlbHealthConfig: &nlbHealthConfig{
+						lBHealthCheckFlag: "off",
+					},
+				},
+				pod: &corev1.Pod{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "pod",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-pod",
+						Namespace: "default",
+						UID:       "32fqwfqfew",
+					},
+				},
+				client: nil,
+				ctx:    context.Background(),
+			},
+			want: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod",
+					Namespace: "default",
+					Annotations: map[string]string{
+						SlbListenerOverrideKey: "true",
+						SlbIdAnnotationKey:     "nlb-xxx",
+						SlbConfigHashKey: util.GetHash(&nlbConfig{
+							lbIds:       []string{"nlb-xxx"},
+							targetPorts: []int{8080},
+							protocols: []corev1.Protocol{
+								ProtocolTCPUDP,
+							},
+							isFixed: false,
+							nlbHealthConfig: &nlbHealthConfig{
+								lBHealthCheckFlag: "off",
+							},
+						}),
+						LBHealthCheckFlagAnnotationKey: "off",
+					},
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion:         "v1",
+							Kind:               "pod",
+							Name:               "test-pod",
+							UID:                "32fqwfqfew",
+							Controller:         ptr.To[bool](true),
+							BlockOwnerDeletion: ptr.To[bool](true),
+						},
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:                  corev1.ServiceTypeLoadBalancer,
+					ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+					LoadBalancerClass:     &loadBalancerClass,
+					Selector: map[string]string{
+						cloudprovider.DefaultSvcSelectorKey: "test-pod",
+					},
+					Ports: []corev1.ServicePort{
+						{
+							Name:       "8080-tcp",
+							Port:       80,
+							Protocol:   corev1.ProtocolTCP,
+							TargetPort: intstr.FromInt(8080),
+						},
+						{
+							Name:       "8080-udp",
+							Port:       80,
+							Protocol:   corev1.ProtocolUDP,
+							TargetPort: intstr.FromInt(8080),
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		c := &NlbPlugin{
@@ -328,3 +659,387 @@ func TestNlbPlugin_consSvc(t *testing.T) {
 		}
 	}
 }
+
+func TestParseNlbConfigTCPSSL(t *testing.T) {
+	tests := []struct {
+		conf   []gamekruiseiov1alpha1.NetworkConfParams
+		certId string
+		isErr  bool
+	}{
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  NlbIdsConfigName,
+					Value: "nlb-xxx",
+				},
+				{
+					Name:  PortProtocolsConfigName,
+					Value: "443/TCPSSL,7000/UDP",
+				},
+				{
+					Name:  CertIdConfigName,
+					Value: "cert-xxx",
+				},
+			},
+			certId: "cert-xxx",
+			isErr:  false,
+		},
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  NlbIdsConfigName,
+					Value: "nlb-xxx",
+				},
+				{
+					Name:  PortProtocolsConfigName,
+					Value: "443/TCPSSL",
+				},
+			},
+			isErr: true,
+		},
+	}
+
+	for i, test := range tests {
+		sc, err := parseNlbConfig(test.conf)
+		if (err != nil) != test.isErr {
+			t.Errorf("case %d: expect err %v, but got %v", i, test.isErr, err)
+		}
+		if test.isErr {
+			continue
+		}
+		if sc.certId != test.certId {
+			t.Errorf("case %d: expect certId %s, but got %s", i, test.certId, sc.certId)
+		}
+		if sc.protocols[0] != ProtocolTCPSSL {
+			t.Errorf("case %d: expect protocol %s, but got %s", i, ProtocolTCPSSL, sc.protocols[0])
+		}
+	}
+}
+
+func TestPortUtilization(t *testing.T) {
+	allocate := func(ports ...int32) portAllocated {
+		allocated := make(portAllocated)
+		for i := int32(500); i <= int32(509); i++ {
+			allocated[i] = false
+		}
+		for _, port := range ports {
+			allocated[port] = true
+		}
+		return allocated
+	}
+
+	tests := []struct {
+		ports       portAllocated
+		minPort     int32
+		maxPort     int32
+		blockPorts  []int32
+		utilization float64
+	}{
+		// case 0: lb not allocated yet
+		{
+			ports:       nil,
+			minPort:     500,
+			maxPort:     509,
+			utilization: 0,
+		},
+		// case 1: half allocated
+		{
+			ports:       allocate(500, 501, 502, 503, 504),
+			minPort:     500,
+			maxPort:     509,
+			utilization: 0.5,
+		},
+		// case 2: blocked ports are excluded from both allocated and total
+		{
+			ports:       allocate(500, 501, 502, 503, 509),
+			minPort:     500,
+			maxPort:     509,
+			blockPorts:  []int32{508, 509},
+			utilization: 0.5,
+		},
+		// case 3: nearly exhausted
+		{
+			ports:       allocate(500, 501, 502, 503, 504, 505, 506, 507, 508),
+			minPort:     500,
+			maxPort:     509,
+			utilization: 0.9,
+		},
+		// case 4: exhausted
+		{
+			ports:       allocate(500, 501, 502, 503, 504, 505, 506, 507),
+			minPort:     500,
+			maxPort:     509,
+			blockPorts:  []int32{508, 509},
+			utilization: 1,
+		},
+	}
+
+	for i, test := range tests {
+		utilization := portUtilization(test.ports, test.minPort, test.maxPort, test.blockPorts)
+		if utilization != test.utilization {
+			t.Errorf("case %d: expect utilization %v but actually got %v", i, test.utilization, utilization)
+		}
+	}
+}
+
+func TestNlbIdleTimeout(t *testing.T) {
+	tests := []struct {
+		idleTimeout *string
+		annotation  string
+		exist       bool
+		isErr       bool
+	}{
+		// case 0: idle timeout set
+		{
+			idleTimeout: ptr.To("600"),
+			annotation:  "600",
+			exist:       true,
+		},
+		// case 1: idle timeout unset
+		{
+			idleTimeout: nil,
+			exist:       false,
+		},
+		// case 2: below the range
+		{
+			idleTimeout: ptr.To("0"),
+			isErr:       true,
+		},
+		// case 3: above the range
+		{
+			idleTimeout: ptr.To("901"),
+			isErr:       true,
+		},
+		// case 4: not an integer
+		{
+			idleTimeout: ptr.To("10s"),
+			isErr:       true,
+		},
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "default",
+			UID:       "32fqwfqfew",
+		},
+	}
+	for i, test := range tests {
+		conf := []gamekruiseiov1alpha1.NetworkConfParams{
+			{
+				Name:  NlbIdsConfigName,
+				Value: "nlb-xxx",
+			},
+			{
+				Name:  PortProtocolsConfigName,
+				Value: "80/TCP",
+			},
+		}
+		if test.idleTimeout != nil {
+			conf = append(conf, gamekruiseiov1alpha1.NetworkConfParams{
+				Name:  LBIdleTimeoutConfigName,
+				Value: *test.idleTimeout,
+			})
+		}
+		nc, err := parseNlbConfig(conf)
+		if (err != nil) != test.isErr {
+			t.Errorf("case %d: expect err %v but actually got %v", i, test.isErr, err)
+		}
+		if test.isErr {
+			continue
+		}
+
+		plugin := &NlbPlugin{
+			maxPort: 3000,
+			minPort: 1,
+			cache: map[string]portAllocated{
+				"nlb-xxx": {},
+			},
+			podAllocate: map[string]string{
+				"default/test-pod": "nlb-xxx:1000",
+			},
+		}
+		svc, err := plugin.consSvc(nc, pod, nil, context.Background())
+		if err != nil {
+			t.Errorf("case %d: expect no err but actually got %v", i, err)
+			continue
+		}
+		annotation, exist := svc.GetAnnotations()[LBIdleTimeoutAnnotationKey]
+		if exist != test.exist || annotation != test.annotation {
+			t.Errorf("case %d: expect idle timeout annotation %s (exist %v) but actually got %s (exist %v)", i, test.annotation, test.exist, annotation, exist)
+		}
+	}
+}
+
+func TestNlbOrdinalAffinity(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := gamekruiseiov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	gss := &gamekruiseiov1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "gss",
+			Namespace: "default",
+		},
+		Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+			Replicas: ptr.To[int32](3),
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build()
+	conf := []gamekruiseiov1alpha1.NetworkConfParams{
+		{
+			Name:  NlbIdsConfigName,
+			Value: "nlb-xxx",
+		},
+		{
+			Name:  PortProtocolsConfigName,
+			Value: "80/TCP,81/UDP",
+		},
+		{
+			Name:  OrdinalAffinityConfigName,
+			Value: "true",
+		},
+	}
+	confBytes, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				UID:       types.UID(name),
+				Labels: map[string]string{
+					gamekruiseiov1alpha1.GameServerOwnerGssKey: "gss",
+				},
+				Annotations: map[string]string{
+					gamekruiseiov1alpha1.GameServerNetworkType: NlbNetwork,
+					gamekruiseiov1alpha1.GameServerNetworkConf: string(confBytes),
+				},
+			},
+		}
+	}
+	newPlugin := func() *NlbPlugin {
+		return &NlbPlugin{
+			maxPort:     600,
+			minPort:     500,
+			blockPorts:  []int32{501},
+			cache:       make(map[string]portAllocated),
+			podAllocate: make(map[string]string),
+		}
+	}
+	getGss := func() *gamekruiseiov1alpha1.GameServerSet {
+		got := &gamekruiseiov1alpha1.GameServerSet{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "gss"}, got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	nc, err := parseNlbConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	plugin := newPlugin()
+	svc, err := plugin.consSvc(nc, newPod("gss-1"), c, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ports := getPorts(svc.Spec.Ports)
+
+	// the ports of ordinal 1 are recorded in gss
+	expectRecord := "nlb-xxx:" + util.Int32SliceToString(ports, ",")
+	if record := getGss().GetAnnotations()[NlbOrdinalPortsAnnotationPrefix+"1"]; record != expectRecord {
+		t.Errorf("expect annotation %s but actually got %s", expectRecord, record)
+	}
+
+	// case 0: the ports are kept for the recreated pod, and not allocated to other pods meanwhile
+	if err := plugin.OnPodDeleted(c, newPod("gss-1"), ctx); err != nil {
+		t.Fatal(err)
+	}
+	svc, err = plugin.consSvc(nc, newPod("gss-2"), c, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, port := range getPorts(svc.Spec.Ports) {
+		if util.IsNumInList(int(port), []int{int(ports[0]), int(ports[1])}) {
+			t.Errorf("case 0: expect ports other than %v but actually got %d", ports, port)
+		}
+	}
+	svc, err = plugin.consSvc(nc, newPod("gss-1"), c, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recreated := getPorts(svc.Spec.Ports); !reflect.DeepEqual(recreated, ports) {
+		t.Errorf("case 0: expect ports %v but actually got %v", ports, recreated)
+	}
+
+	// case 1: the ports are restored from gss after the plugin restarted
+	plugin = newPlugin()
+	plugin.restoreOrdinalAllocations([]gamekruiseiov1alpha1.GameServerSet{*getGss()})
+	if allocated := plugin.podAllocate["default/gss-1"]; allocated != expectRecord {
+		t.Errorf("case 1: expect ports %s restored but actually got %s", expectRecord, allocated)
+	}
+	svc, err = plugin.consSvc(nc, newPod("gss-1"), c, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recreated := getPorts(svc.Spec.Ports); !reflect.DeepEqual(recreated, ports) {
+		t.Errorf("case 1: expect ports %v but actually got %v", ports, recreated)
+	}
+
+	// case 2: the ports are released and the record is pruned once the ordinal is scaled down
+	scaled := getGss()
+	scaled.Spec.Replicas = ptr.To[int32](1)
+	if err := c.Update(ctx, scaled); err != nil {
+		t.Fatal(err)
+	}
+	if err := plugin.OnPodDeleted(c, newPod("gss-1"), ctx); err != nil {
+		t.Fatal(err)
+	}
+	if allocated, exist := plugin.podAllocate["default/gss-1"]; exist {
+		t.Errorf("case 2: expect ports released but actually got %s", allocated)
+	}
+	if _, err := plugin.consSvc(nc, newPod("gss-0"), c, ctx); err != nil {
+		t.Fatal(err)
+	}
+	annotations := getGss().GetAnnotations()
+	if _, exist := annotations[NlbOrdinalPortsAnnotationPrefix+"0"]; !exist {
+		t.Errorf("case 2: expect ports of ordinal 0 recorded but actually got %v", annotations)
+	}
+	for _, ordinal := range []string{"1", "2"} {
+		if record, exist := annotations[NlbOrdinalPortsAnnotationPrefix+ordinal]; exist {
+			t.Errorf("case 2: expect record of ordinal %s pruned but actually got %s", ordinal, record)
+		}
+	}
+}
+
+func TestNlbRegisterMetrics(t *testing.T) {
+	plugin := &NlbPlugin{
+		cache: map[string]portAllocated{
+			"nlb-a": {500: true},
+			"nlb-b": {},
+		},
+	}
+	registry := prometheus.NewRegistry()
+	if err := plugin.RegisterMetrics(registry); err != nil {
+		t.Fatalf("expect metrics registered, but actually got %s", err.Error())
+	}
+	if err := plugin.RegisterMetrics(registry); err == nil {
+		t.Errorf("expect an error when registering metrics twice, but actually got nil")
+	}
+
+	plugin.cache["nlb-c"] = portAllocated{}
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mfs) != 1 || mfs[0].GetName() != "okg_nlb_total" {
+		t.Fatalf("expect metric okg_nlb_total gathered, but actually got %v", mfs)
+	}
+	if value := mfs[0].GetMetric()[0].GetGauge().GetValue(); value != 3 {
+		t.Errorf("expect okg_nlb_total 3, but actually got %v", value)
+	}
+}
@@ -19,6 +19,7 @@ package alibabacloud
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -49,7 +50,6 @@ const (
 	SlbListenerOverrideKey              = "service.beta.kubernetes.io/alibaba-cloud-loadbalancer-force-override-listeners"
 	SlbIdAnnotationKey                  = "service.beta.kubernetes.io/alibaba-cloud-loadbalancer-id"
 	SlbIdLabelKey                       = "service.k8s.alibaba/loadbalancer-id"
-	SvcSelectorKey                      = "statefulset.kubernetes.io/pod-name"
 	SlbConfigHashKey                    = "game.kruise.io/network-config-hash"
 )
 
@@ -65,6 +65,45 @@ const (
 
 type portAllocated map[int32]bool
 
+// portAllocationState is the debug snapshot of the port allocation of a load balancer plugin.
+type portAllocationState struct {
+	MinPort    int32   `json:"minPort"`
+	MaxPort    int32   `json:"maxPort"`
+	BlockPorts []int32 `json:"blockPorts,omitempty"`
+	// AllocatedPorts is the sorted allocated ports of each load balancer, excluding the block ports.
+	AllocatedPorts map[string][]int32 `json:"allocatedPorts"`
+	// PodAllocate is the allocated load balancer and ports of each pod.
+	PodAllocate map[string]string `json:"podAllocate"`
+}
+
+func newPortAllocationState(minPort, maxPort int32, blockPorts []int32, cache map[string]portAllocated, podAllocate map[string]string) portAllocationState {
+	state := portAllocationState{
+		MinPort:        minPort,
+		MaxPort:        maxPort,
+		BlockPorts:     blockPorts,
+		AllocatedPorts: make(map[string][]int32, len(cache)),
+		PodAllocate:    make(map[string]string, len(podAllocate)),
+	}
+	blocked := make(map[int32]bool, len(blockPorts))
+	for _, port := range blockPorts {
+		blocked[port] = true
+	}
+	for lbId, ports := range cache {
+		allocated := make([]int32, 0)
+		for port, ok := range ports {
+			if ok && !blocked[port] {
+				allocated = append(allocated, port)
+			}
+		}
+		sort.Slice(allocated, func(i, j int) bool { return allocated[i] < allocated[j] })
+		state.AllocatedPorts[lbId] = allocated
+	}
+	for podKey, allocated := range podAllocate {
+		state.PodAllocate[podKey] = allocated
+	}
+	return state
+}
+
 type SlbPlugin struct {
 	maxPort     int32
 	minPort     int32
@@ -98,6 +137,12 @@ func (s *SlbPlugin) Name() string {
 	return SlbNetwork
 }
 
+func (s *SlbPlugin) DebugState() interface{} {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return newPortAllocationState(s.minPort, s.maxPort, s.blockPorts, s.cache, s.podAllocate)
+}
+
 func (s *SlbPlugin) Alias() string {
 	return AliasSLB
 }
@@ -199,6 +244,8 @@ func (s *SlbPlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx context.C
 		return pod, nil
 	}
 
+	skipped := isReconcileSkipped(c, ctx, pod, networkConfig)
+
 	// update svc
 	if util.GetHash(sc) != svc.GetAnnotations()[SlbConfigHashKey] {
 		networkStatus.CurrentNetworkState = gamekruiseiov1alpha1.NetworkNotReady
@@ -210,9 +257,15 @@ func (s *SlbPlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx context.C
 		if err != nil {
 			return pod, cperrors.NewPluginError(cperrors.ParameterError, err.Error())
 		}
+		setReconcileSkipped(service, skipped)
 		return pod, cperrors.ToPluginError(c.Update(ctx, service), cperrors.ApiCallError)
 	}
 
+	// skip or resume the reconciliation of CCM
+	if setReconcileSkipped(svc, skipped) {
+		return pod, cperrors.ToPluginError(c.Update(ctx, svc), cperrors.ApiCallError)
+	}
+
 	// disable network
 	if networkManager.GetNetworkDisabled() && svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
 		svc.Spec.Type = corev1.ServiceTypeClusterIP
@@ -617,7 +670,7 @@ func (s *SlbPlugin) consSvc(sc *slbConfig, pod *corev1.Pod, c client.Client, ctx
 			Type:                  corev1.ServiceTypeLoadBalancer,
 			ExternalTrafficPolicy: sc.externalTrafficPolicyType,
 			Selector: map[string]string{
-				SvcSelectorKey: pod.GetName(),
+				cloudprovider.GetSvcSelectorKey(): pod.GetName(),
 			},
 			Ports: svcPorts,
 		},
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -241,7 +242,7 @@ func TestInitLbCache(t *testing.T) {
 				Spec: corev1.ServiceSpec{
 					Type: corev1.ServiceTypeLoadBalancer,
 					Selector: map[string]string{
-						SvcSelectorKey: "pod-A",
+						cloudprovider.DefaultSvcSelectorKey: "pod-A",
 					},
 					Ports: []corev1.ServicePort{
 						{
@@ -263,7 +264,7 @@ func TestInitLbCache(t *testing.T) {
 				Spec: corev1.ServiceSpec{
 					Type: corev1.ServiceTypeLoadBalancer,
 					Selector: map[string]string{
-						SvcSelectorKey: "pod-B",
+						cloudprovider.DefaultSvcSelectorKey: "pod-B",
 					},
 					Ports: []corev1.ServicePort{
 						{
@@ -716,7 +716,7 @@ func (n *NlbPlugin) syncTargetGroupAndService(config *nlbConfig,
 		Spec: corev1.ServiceSpec{
 			Type: corev1.ServiceTypeClusterIP,
 			Selector: map[string]string{
-				SvcSelectorKey: pod.GetName(),
+				cloudprovider.GetSvcSelectorKey(): pod.GetName(),
 			},
 			Ports: svcPorts,
 		},
@@ -18,8 +18,11 @@ package cloudprovider
 
 import (
 	"context"
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	"github.com/openkruise/kruise-game/cloudprovider/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	client "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -43,6 +46,35 @@ type Plugin interface {
 	OnPodDeleted(client client.Client, pod *corev1.Pod, ctx context.Context) errors.PluginError
 }
 
+// Debuggable is an optional interface of Plugin, which exposes the in-memory allocation state for debugging.
+type Debuggable interface {
+	// DebugState returns a JSON-serializable snapshot of the allocation state.
+	DebugState() interface{}
+}
+
+// ConfigValidator is an optional interface of Plugin, which validates the network conf when GameServerSet is applied,
+// rather than when its pods are handled.
+type ConfigValidator interface {
+	ValidateConfig(conf []gamekruiseiov1alpha1.NetworkConfParams) error
+}
+
+// PodTemplateValidator is an optional interface of Plugin, which validates the network conf against the pod template
+// of GameServerSet when it is applied, such as the container ports referenced by the network conf.
+type PodTemplateValidator interface {
+	ValidatePodTemplate(conf []gamekruiseiov1alpha1.NetworkConfParams, template *corev1.PodTemplateSpec) error
+}
+
+// EventRecordable is an optional interface of Plugin, which emits events through the recorder set.
+type EventRecordable interface {
+	SetEventRecorder(recorder record.EventRecorder)
+}
+
+// MetricsRegistrable is an optional interface of Plugin, which publishes its own metrics.
+// The registry is the one the metrics of pkg/metrics are registered to.
+type MetricsRegistrable interface {
+	RegisterMetrics(registry prometheus.Registerer) error
+}
+
 type CloudProvider interface {
 	Name() string
 	ListPlugins() (map[string]Plugin, error)
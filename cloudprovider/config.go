@@ -26,16 +26,32 @@ import (
 
 var Opt *Options
 
+// DefaultSvcSelectorKey is the pod label selecting the pod of a game server by its name, set by Advanced StatefulSet.
+const DefaultSvcSelectorKey = "statefulset.kubernetes.io/pod-name"
+
 type Options struct {
 	CloudProviderConfigFile string
+	EnableMockProvider      bool
+	// SvcSelectorKey is the pod label used by network plugins to select the pod of a game server in Services.
+	SvcSelectorKey string
 }
 
 func init() {
-	Opt = &Options{}
+	Opt = &Options{SvcSelectorKey: DefaultSvcSelectorKey}
+}
+
+// GetSvcSelectorKey returns the pod label used by network plugins to select the pod of a game server in Services.
+func GetSvcSelectorKey() string {
+	if Opt == nil || Opt.SvcSelectorKey == "" {
+		return DefaultSvcSelectorKey
+	}
+	return Opt.SvcSelectorKey
 }
 
 func InitCloudProviderFlags() {
 	flag.StringVar(&Opt.CloudProviderConfigFile, "provider-config", "/etc/kruise-game/config.toml", "Cloud Provider Config File Path.")
+	flag.BoolVar(&Opt.EnableMockProvider, "enable-mock-provider", false, "Register the Mock cloud provider, whose plugins fabricate network status for development and testing. Never enable it in production.")
+	flag.StringVar(&Opt.SvcSelectorKey, "svc-selector-key", DefaultSvcSelectorKey, "The pod label whose value is the pod name, used by network plugins to select the pod of a game server in Services. Change it only if pods are not labeled by Advanced StatefulSet.")
 }
 
 type ConfigFile struct {
@@ -49,6 +65,7 @@ type CloudProviderConfig struct {
 	AmazonsWebServicesOptions CloudProviderOptions
 	TencentCloudOptions       CloudProviderOptions
 	JdCloudOptions            CloudProviderOptions
+	HuaweiCloudOptions        CloudProviderOptions
 }
 
 type tomlConfigs struct {
@@ -58,6 +75,7 @@ type tomlConfigs struct {
 	AmazonsWebServices options.AmazonsWebServicesOptions `toml:"aws"`
 	TencentCloud       options.TencentCloudOptions       `toml:"tencentcloud"`
 	JdCloud            options.JdCloudOptions            `toml:"jdcloud"`
+	HuaweiCloud        options.HuaweiCloudOptions        `toml:"huaweicloud"`
 }
 
 func (cf *ConfigFile) Parse() *CloudProviderConfig {
@@ -73,6 +91,7 @@ func (cf *ConfigFile) Parse() *CloudProviderConfig {
 		AmazonsWebServicesOptions: config.AmazonsWebServices,
 		TencentCloudOptions:       config.TencentCloud,
 		JdCloudOptions:            config.JdCloud,
+		HuaweiCloudOptions:        config.HuaweiCloud,
 	}
 }
 
@@ -16,7 +16,10 @@ limitations under the License.
 
 package errors
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // PluginErrorType describes a high-level category of a given error
 type PluginErrorType string
@@ -30,6 +33,9 @@ const (
 	ParameterError PluginErrorType = "parameterError"
 	// NotImplementedError an error related to be not implemented by developers
 	NotImplementedError PluginErrorType = "notImplementedError"
+	// RequeueError is not a failure. It asks to call the plugin again for the pod after an interval,
+	// instead of the global network interval. The pod patched by the plugin is still admitted.
+	RequeueError PluginErrorType = "requeueError"
 )
 
 type PluginError interface {
@@ -41,8 +47,9 @@ type PluginError interface {
 }
 
 type pluginErrorImplErrorImpl struct {
-	errorType PluginErrorType
-	msg       string
+	errorType    PluginErrorType
+	msg          string
+	requeueAfter time.Duration
 }
 
 func (c pluginErrorImplErrorImpl) Error() string {
@@ -70,3 +77,25 @@ func ToPluginError(err error, errorType PluginErrorType) PluginError {
 		msg:       err.Error(),
 	}
 }
+
+// NewRequeueError returns a plugin error asking to call the plugin again for the pod after the given interval.
+// Plugins returning nil or other errors are not affected, and keep following the global network interval.
+func NewRequeueError(after time.Duration, msg string, args ...interface{}) PluginError {
+	return pluginErrorImplErrorImpl{
+		errorType:    RequeueError,
+		msg:          fmt.Sprintf(msg, args...),
+		requeueAfter: after,
+	}
+}
+
+// GetRequeueAfter returns the interval carried by a RequeueError.
+func GetRequeueAfter(err PluginError) (time.Duration, bool) {
+	if err == nil || err.Type() != RequeueError {
+		return 0, false
+	}
+	e, ok := err.(pluginErrorImplErrorImpl)
+	if !ok || e.requeueAfter <= 0 {
+		return 0, false
+	}
+	return e.requeueAfter, true
+}
@@ -0,0 +1,539 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package huaweicloud
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	log "k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
+	cperrors "github.com/openkruise/kruise-game/cloudprovider/errors"
+	provideroptions "github.com/openkruise/kruise-game/cloudprovider/options"
+	"github.com/openkruise/kruise-game/cloudprovider/utils"
+	"github.com/openkruise/kruise-game/pkg/util"
+)
+
+const (
+	ElbNetwork                  = "HuaweiCloud-ELB"
+	AliasELB                    = "ELB-Network"
+	ElbIdsConfigName            = "ElbIds"
+	PortProtocolsConfigName     = "PortProtocols"
+	FixedConfigName             = "Fixed"
+	ElbClassConfigName          = "ElbClass"
+	LbAlgorithmConfigName       = "LbAlgorithm"
+	ElbAnnotationsConfigName    = "Annotations"
+	ElbConfigHashKey            = "game.kruise.io/network-config-hash"
+	ElbIdAnnotationKey          = "kubernetes.io/elb.id"
+	ElbClassAnnotationKey       = "kubernetes.io/elb.class"
+	ElbLbAlgorithmAnnotationKey = "kubernetes.io/elb.lb-algorithm"
+	ElbClassPerformance         = "performance"
+	ElbClassUnion               = "union"
+	ElbLbAlgorithmRoundRobin    = "ROUND_ROBIN"
+)
+
+type portAllocated map[int32]bool
+
+// ElbPlugin exposes each pod by a LoadBalancer Service on an ELB shared by pods, and allocates
+// the ports of ELB in [minPort, maxPort) to pods.
+type ElbPlugin struct {
+	maxPort     int32
+	minPort     int32
+	blockPorts  []int32
+	cache       map[string]portAllocated
+	podAllocate map[string]string
+	mutex       sync.RWMutex
+}
+
+type elbConfig struct {
+	lbIds       []string
+	targetPorts []int
+	protocols   []corev1.Protocol
+	isFixed     bool
+	elbClass    string
+	lbAlgorithm string
+	annotations map[string]string
+}
+
+func (e *ElbPlugin) Name() string {
+	return ElbNetwork
+}
+
+func (e *ElbPlugin) Alias() string {
+	return AliasELB
+}
+
+func (e *ElbPlugin) Init(c client.Client, options cloudprovider.CloudProviderOptions, ctx context.Context) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	huaweiOptions, ok := options.(provideroptions.HuaweiCloudOptions)
+	if !ok {
+		return cperrors.ToPluginError(fmt.Errorf("failed to convert options to elbOptions"), cperrors.InternalError)
+	}
+	e.minPort = huaweiOptions.ELBOptions.MinPort
+	e.maxPort = huaweiOptions.ELBOptions.MaxPort
+	e.blockPorts = huaweiOptions.ELBOptions.BlockPorts
+
+	svcList := &corev1.ServiceList{}
+	err := c.List(ctx, svcList)
+	if err != nil {
+		return err
+	}
+
+	e.cache, e.podAllocate = initLbCache(svcList.Items, e.minPort, e.maxPort, e.blockPorts)
+	return nil
+}
+
+func initLbCache(svcList []corev1.Service, minPort, maxPort int32, blockPorts []int32) (map[string]portAllocated, map[string]string) {
+	newCache := make(map[string]portAllocated)
+	newPodAllocate := make(map[string]string)
+	for _, svc := range svcList {
+		lbId := svc.GetAnnotations()[ElbIdAnnotationKey]
+		if lbId == "" || svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		if newCache[lbId] == nil {
+			newCache[lbId] = newPortAllocated(minPort, maxPort, blockPorts)
+		}
+
+		var ports []int32
+		for _, port := range svc.Spec.Ports {
+			if port.Port < maxPort && port.Port >= minPort {
+				newCache[lbId][port.Port] = true
+				ports = append(ports, port.Port)
+			}
+		}
+		if len(ports) != 0 {
+			newPodAllocate[svc.GetNamespace()+"/"+svc.GetName()] = lbId + ":" + util.Int32SliceToString(ports, ",")
+		}
+	}
+	log.Infof("[%s] podAllocate cache complete initialization: %v", ElbNetwork, newPodAllocate)
+	return newCache, newPodAllocate
+}
+
+func newPortAllocated(minPort, maxPort int32, blockPorts []int32) portAllocated {
+	pa := make(portAllocated, maxPort-minPort)
+	for i := minPort; i < maxPort; i++ {
+		pa[i] = false
+	}
+	for _, blockPort := range blockPorts {
+		pa[blockPort] = true
+	}
+	return pa
+}
+
+func (e *ElbPlugin) OnPodAdded(c client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, cperrors.PluginError) {
+	return pod, nil
+}
+
+func (e *ElbPlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, cperrors.PluginError) {
+	networkManager := utils.NewNetworkManager(pod, c)
+
+	networkStatus, _ := networkManager.GetNetworkStatus()
+	config, err := parseElbConfig(networkManager.GetNetworkConfig())
+	if err != nil {
+		return pod, cperrors.NewPluginError(cperrors.ParameterError, err.Error())
+	}
+	if networkStatus == nil {
+		pod, err := networkManager.UpdateNetworkStatus(gamekruiseiov1alpha1.NetworkStatus{
+			CurrentNetworkState: gamekruiseiov1alpha1.NetworkNotReady,
+		}, pod)
+		return pod, cperrors.ToPluginError(err, cperrors.InternalError)
+	}
+
+	// get svc
+	svc := &corev1.Service{}
+	err = c.Get(ctx, types.NamespacedName{
+		Name:      pod.GetName(),
+		Namespace: pod.GetNamespace(),
+	}, svc)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			service, err := e.consSvc(config, pod, c, ctx)
+			if err != nil {
+				return pod, cperrors.ToPluginError(err, cperrors.ParameterError)
+			}
+			return pod, cperrors.ToPluginError(c.Create(ctx, service), cperrors.ApiCallError)
+		}
+		return pod, cperrors.NewPluginError(cperrors.ApiCallError, err.Error())
+	}
+
+	// old svc remain
+	if len(svc.OwnerReferences) > 0 && svc.OwnerReferences[0].Kind == "Pod" && svc.OwnerReferences[0].UID != pod.UID {
+		log.Infof("[%s] waitting old svc %s/%s deleted. old owner pod uid is %s, but now is %s", ElbNetwork, svc.Namespace, svc.Name, svc.OwnerReferences[0].UID, pod.UID)
+		return pod, nil
+	}
+
+	// update svc
+	if util.GetHash(config) != svc.GetAnnotations()[ElbConfigHashKey] {
+		networkStatus.CurrentNetworkState = gamekruiseiov1alpha1.NetworkNotReady
+		pod, err = networkManager.UpdateNetworkStatus(*networkStatus, pod)
+		if err != nil {
+			return pod, cperrors.NewPluginError(cperrors.InternalError, err.Error())
+		}
+		service, err := e.consSvc(config, pod, c, ctx)
+		if err != nil {
+			return pod, cperrors.ToPluginError(err, cperrors.ParameterError)
+		}
+		return pod, cperrors.ToPluginError(c.Update(ctx, service), cperrors.ApiCallError)
+	}
+
+	// disable network
+	if networkManager.GetNetworkDisabled() && svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		svc.Spec.Type = corev1.ServiceTypeClusterIP
+		return pod, cperrors.ToPluginError(c.Update(ctx, svc), cperrors.ApiCallError)
+	}
+
+	// enable network
+	if !networkManager.GetNetworkDisabled() && svc.Spec.Type == corev1.ServiceTypeClusterIP {
+		svc.Spec.Type = corev1.ServiceTypeLoadBalancer
+		return pod, cperrors.ToPluginError(c.Update(ctx, svc), cperrors.ApiCallError)
+	}
+
+	// network not ready
+	if len(svc.Status.LoadBalancer.Ingress) == 0 {
+		networkStatus.CurrentNetworkState = gamekruiseiov1alpha1.NetworkNotReady
+		pod, err = networkManager.UpdateNetworkStatus(*networkStatus, pod)
+		return pod, cperrors.ToPluginError(err, cperrors.InternalError)
+	}
+
+	// allow not ready containers
+	if util.IsAllowNotReadyContainers(networkManager.GetNetworkConfig()) {
+		toUpDateSvc, err := utils.AllowNotReadyContainers(c, ctx, pod, svc, false)
+		if err != nil {
+			return pod, err
+		}
+
+		if toUpDateSvc {
+			err := c.Update(ctx, svc)
+			if err != nil {
+				return pod, cperrors.ToPluginError(err, cperrors.ApiCallError)
+			}
+		}
+	}
+
+	// network ready
+	internalAddresses := make([]gamekruiseiov1alpha1.NetworkAddress, 0)
+	externalAddresses := make([]gamekruiseiov1alpha1.NetworkAddress, 0)
+	for _, port := range svc.Spec.Ports {
+		instrIPort := port.TargetPort
+		instrEPort := intstr.FromInt(int(port.Port))
+		internalAddress := gamekruiseiov1alpha1.NetworkAddress{
+			IP: pod.Status.PodIP,
+			Ports: []gamekruiseiov1alpha1.NetworkPort{
+				{
+					Name:     instrIPort.String(),
+					Port:     &instrIPort,
+					Protocol: port.Protocol,
+				},
+			},
+		}
+		externalAddress := gamekruiseiov1alpha1.NetworkAddress{
+			IP: svc.Status.LoadBalancer.Ingress[0].IP,
+			Ports: []gamekruiseiov1alpha1.NetworkPort{
+				{
+					Name:     instrIPort.String(),
+					Port:     &instrEPort,
+					Protocol: port.Protocol,
+				},
+			},
+		}
+		internalAddresses = append(internalAddresses, internalAddress)
+		externalAddresses = append(externalAddresses, externalAddress)
+	}
+	networkStatus.InternalAddresses = internalAddresses
+	networkStatus.ExternalAddresses = externalAddresses
+	networkStatus.CurrentNetworkState = gamekruiseiov1alpha1.NetworkReady
+	pod, err = networkManager.UpdateNetworkStatus(*networkStatus, pod)
+	return pod, cperrors.ToPluginError(err, cperrors.InternalError)
+}
+
+func (e *ElbPlugin) OnPodDeleted(c client.Client, pod *corev1.Pod, ctx context.Context) cperrors.PluginError {
+	networkManager := utils.NewNetworkManager(pod, c)
+	config, err := parseElbConfig(networkManager.GetNetworkConfig())
+	if err != nil {
+		return cperrors.NewPluginError(cperrors.ParameterError, err.Error())
+	}
+
+	var podKeys []string
+	if config.isFixed {
+		gss, err := util.GetGameServerSetOfPod(pod, c, ctx)
+		if err != nil && !errors.IsNotFound(err) {
+			return cperrors.ToPluginError(err, cperrors.ApiCallError)
+		}
+		// gss exists in cluster, do not deAllocate.
+		if err == nil && gss.GetDeletionTimestamp() == nil {
+			return nil
+		}
+		// gss not exists in cluster, deAllocate all the ports related to it.
+		// The keys are matched by {namespace}/{gss name}-{ordinal}, not to take the ones of another gss sharing the prefix.
+		prefix := pod.GetNamespace() + "/" + pod.GetLabels()[gamekruiseiov1alpha1.GameServerOwnerGssKey] + "-"
+		for key := range e.podAllocate {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			if _, err := strconv.Atoi(strings.TrimPrefix(key, prefix)); err == nil {
+				podKeys = append(podKeys, key)
+			}
+		}
+	} else {
+		podKeys = append(podKeys, pod.GetNamespace()+"/"+pod.GetName())
+	}
+
+	for _, podKey := range podKeys {
+		e.deAllocate(podKey)
+	}
+
+	return nil
+}
+
+func (e *ElbPlugin) allocate(lbIds []string, num int, nsName string) (string, []int32) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	// find lb with adequate ports
+	var lbId string
+	for _, elbId := range lbIds {
+		if e.cache[elbId] == nil {
+			e.cache[elbId] = newPortAllocated(e.minPort, e.maxPort, e.blockPorts)
+		}
+		sum := 0
+		for i := e.minPort; i < e.maxPort; i++ {
+			if !e.cache[elbId][i] {
+				sum++
+			}
+		}
+		if sum >= num {
+			lbId = elbId
+			break
+		}
+	}
+	if lbId == "" {
+		return "", nil
+	}
+
+	// select ports
+	var ports []int32
+	for i := e.minPort; i < e.maxPort && len(ports) < num; i++ {
+		if !e.cache[lbId][i] {
+			e.cache[lbId][i] = true
+			ports = append(ports, i)
+		}
+	}
+
+	e.podAllocate[nsName] = lbId + ":" + util.Int32SliceToString(ports, ",")
+	log.Infof("pod %s allocate elb %s ports %v", nsName, lbId, ports)
+	return lbId, ports
+}
+
+func (e *ElbPlugin) deAllocate(nsName string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	allocatedPorts, exist := e.podAllocate[nsName]
+	if !exist {
+		return
+	}
+
+	elbPorts := strings.Split(allocatedPorts, ":")
+	lbId := elbPorts[0]
+	ports := util.StringToInt32Slice(elbPorts[1], ",")
+	for _, port := range ports {
+		e.cache[lbId][port] = false
+	}
+	// block ports
+	for _, blockPort := range e.blockPorts {
+		e.cache[lbId][blockPort] = true
+	}
+
+	delete(e.podAllocate, nsName)
+	log.Infof("pod %s deallocate elb %s ports %v", nsName, lbId, ports)
+}
+
+func init() {
+	elbPlugin := ElbPlugin{
+		mutex: sync.RWMutex{},
+	}
+	huaweiCloudProvider.registerPlugin(&elbPlugin)
+}
+
+func parseElbConfig(conf []gamekruiseiov1alpha1.NetworkConfParams) (*elbConfig, error) {
+	var lbIds []string
+	ports := make([]int, 0)
+	protocols := make([]corev1.Protocol, 0)
+	isFixed := false
+	elbClass := ElbClassPerformance
+	lbAlgorithm := ElbLbAlgorithmRoundRobin
+	annotations := map[string]string{}
+	for _, c := range conf {
+		switch c.Name {
+		case ElbIdsConfigName:
+			for _, elbId := range strings.Split(c.Value, ",") {
+				if elbId != "" {
+					lbIds = append(lbIds, elbId)
+				}
+			}
+		case PortProtocolsConfigName:
+			for _, pp := range strings.Split(c.Value, ",") {
+				ppSlice := strings.Split(pp, "/")
+				port, err := strconv.Atoi(ppSlice[0])
+				if err != nil {
+					continue
+				}
+				ports = append(ports, port)
+				if len(ppSlice) != 2 {
+					protocols = append(protocols, corev1.ProtocolTCP)
+				} else {
+					protocols = append(protocols, corev1.Protocol(ppSlice[1]))
+				}
+			}
+		case FixedConfigName:
+			v, err := strconv.ParseBool(c.Value)
+			if err != nil {
+				continue
+			}
+			isFixed = v
+		case ElbClassConfigName:
+			if c.Value != ElbClassPerformance && c.Value != ElbClassUnion {
+				return nil, fmt.Errorf("invalid %s %s, it should be %s or %s", ElbClassConfigName, c.Value, ElbClassPerformance, ElbClassUnion)
+			}
+			elbClass = c.Value
+		case LbAlgorithmConfigName:
+			lbAlgorithm = c.Value
+		case ElbAnnotationsConfigName:
+			for _, anno := range strings.Split(c.Value, ",") {
+				annoKV := strings.Split(anno, ":")
+				if len(annoKV) == 2 {
+					annotations[annoKV[0]] = annoKV[1]
+				} else {
+					log.Warningf("elb annotation %s is invalid", annoKV[0])
+				}
+			}
+		}
+	}
+	if len(lbIds) == 0 {
+		return nil, fmt.Errorf("%s is required", ElbIdsConfigName)
+	}
+	return &elbConfig{
+		lbIds:       lbIds,
+		targetPorts: ports,
+		protocols:   protocols,
+		isFixed:     isFixed,
+		elbClass:    elbClass,
+		lbAlgorithm: lbAlgorithm,
+		annotations: annotations,
+	}, nil
+}
+
+func (e *ElbPlugin) consSvc(config *elbConfig, pod *corev1.Pod, c client.Client, ctx context.Context) (*corev1.Service, error) {
+	var ports []int32
+	var lbId string
+	podKey := pod.GetNamespace() + "/" + pod.GetName()
+	allocatedPorts, exist := e.podAllocate[podKey]
+	if exist {
+		elbPorts := strings.Split(allocatedPorts, ":")
+		lbId = elbPorts[0]
+		ports = util.StringToInt32Slice(elbPorts[1], ",")
+	} else {
+		lbId, ports = e.allocate(config.lbIds, len(config.targetPorts), podKey)
+		if lbId == "" && ports == nil {
+			return nil, fmt.Errorf("there are no available ports for %v", config.lbIds)
+		}
+	}
+	if len(ports) < len(config.targetPorts) {
+		return nil, fmt.Errorf("pod %s has %d ports allocated, but %d ports are required", podKey, len(ports), len(config.targetPorts))
+	}
+
+	svcPorts := make([]corev1.ServicePort, 0)
+	for i := 0; i < len(config.targetPorts); i++ {
+		svcPorts = append(svcPorts, corev1.ServicePort{
+			Name:       strconv.Itoa(config.targetPorts[i]),
+			Port:       ports[i],
+			Protocol:   config.protocols[i],
+			TargetPort: intstr.FromInt(config.targetPorts[i]),
+		})
+	}
+
+	annotations := map[string]string{
+		ElbIdAnnotationKey:          lbId,
+		ElbClassAnnotationKey:       config.elbClass,
+		ElbLbAlgorithmAnnotationKey: config.lbAlgorithm,
+		ElbConfigHashKey:            util.GetHash(config),
+	}
+	for key, value := range config.annotations {
+		annotations[key] = value
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pod.GetName(),
+			Namespace:       pod.GetNamespace(),
+			Annotations:     annotations,
+			OwnerReferences: getSvcOwnerReference(c, ctx, pod, config.isFixed),
+		},
+		Spec: corev1.ServiceSpec{
+			Type:                  corev1.ServiceTypeLoadBalancer,
+			ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+			Selector: map[string]string{
+				cloudprovider.GetSvcSelectorKey(): pod.GetName(),
+			},
+			Ports: svcPorts,
+		},
+	}
+	return svc, nil
+}
+
+func getSvcOwnerReference(c client.Client, ctx context.Context, pod *corev1.Pod, isFixed bool) []metav1.OwnerReference {
+	ownerReferences := []metav1.OwnerReference{
+		{
+			APIVersion:         pod.APIVersion,
+			Kind:               pod.Kind,
+			Name:               pod.GetName(),
+			UID:                pod.GetUID(),
+			Controller:         ptr.To[bool](true),
+			BlockOwnerDeletion: ptr.To[bool](true),
+		},
+	}
+	if isFixed {
+		gss, err := util.GetGameServerSetOfPod(pod, c, ctx)
+		if err == nil {
+			ownerReferences = []metav1.OwnerReference{
+				{
+					APIVersion:         gss.APIVersion,
+					Kind:               gss.Kind,
+					Name:               gss.GetName(),
+					UID:                gss.GetUID(),
+					Controller:         ptr.To[bool](true),
+					BlockOwnerDeletion: ptr.To[bool](true),
+				},
+			}
+		}
+	}
+	return ownerReferences
+}
@@ -0,0 +1,399 @@
+package huaweicloud
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
+	"github.com/openkruise/kruise-game/cloudprovider/utils"
+	"github.com/openkruise/kruise-game/pkg/util"
+)
+
+func newElbPlugin(minPort, maxPort int32, blockPorts []int32) *ElbPlugin {
+	return &ElbPlugin{
+		minPort:     minPort,
+		maxPort:     maxPort,
+		blockPorts:  blockPorts,
+		cache:       make(map[string]portAllocated),
+		podAllocate: make(map[string]string),
+		mutex:       sync.RWMutex{},
+	}
+}
+
+func TestElbAllocateDeAllocate(t *testing.T) {
+	elb := newElbPlugin(512, 712, []int32{513})
+	podKey := "xxx/xxx"
+
+	lbId, ports := elb.allocate([]string{"elb-A"}, 3, podKey)
+	if lbId != "elb-A" {
+		t.Errorf("expect lbId elb-A but actually got %s", lbId)
+	}
+	if !reflect.DeepEqual(ports, []int32{512, 514, 515}) {
+		t.Errorf("expect ports [512 514 515] but actually got %v", ports)
+	}
+	if _, exist := elb.podAllocate[podKey]; !exist {
+		t.Errorf("podAllocate[%s] is empty after allocated", podKey)
+	}
+
+	elb.deAllocate(podKey)
+	for _, port := range ports {
+		if elb.cache[lbId][port] {
+			t.Errorf("deAllocate port %d failed", port)
+		}
+	}
+	if !elb.cache[lbId][513] {
+		t.Errorf("block port 513 should remain allocated")
+	}
+	if _, exist := elb.podAllocate[podKey]; exist {
+		t.Errorf("podAllocate[%s] is not empty after deallocated", podKey)
+	}
+
+	// no lb has enough ports
+	lbId, ports = elb.allocate([]string{"elb-A"}, 200, podKey)
+	if lbId != "" || ports != nil {
+		t.Errorf("expect no allocation but actually got %s %v", lbId, ports)
+	}
+}
+
+func TestParseElbConfig(t *testing.T) {
+	tests := []struct {
+		conf    []gamekruiseiov1alpha1.NetworkConfParams
+		elbConf *elbConfig
+		wantErr bool
+	}{
+		// case 0
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  ElbIdsConfigName,
+					Value: "elb-A",
+				},
+				{
+					Name:  PortProtocolsConfigName,
+					Value: "80",
+				},
+			},
+			elbConf: &elbConfig{
+				lbIds:       []string{"elb-A"},
+				targetPorts: []int{80},
+				protocols:   []corev1.Protocol{corev1.ProtocolTCP},
+				elbClass:    ElbClassPerformance,
+				lbAlgorithm: ElbLbAlgorithmRoundRobin,
+				annotations: map[string]string{},
+			},
+		},
+		// case 1
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  ElbIdsConfigName,
+					Value: "elb-A,elb-B,",
+				},
+				{
+					Name:  PortProtocolsConfigName,
+					Value: "81/UDP,82",
+				},
+				{
+					Name:  FixedConfigName,
+					Value: "true",
+				},
+				{
+					Name:  ElbClassConfigName,
+					Value: ElbClassUnion,
+				},
+				{
+					Name:  LbAlgorithmConfigName,
+					Value: "LEAST_CONNECTIONS",
+				},
+				{
+					Name:  ElbAnnotationsConfigName,
+					Value: "kubernetes.io/elb.health-check-flag:on",
+				},
+			},
+			elbConf: &elbConfig{
+				lbIds:       []string{"elb-A", "elb-B"},
+				targetPorts: []int{81, 82},
+				protocols:   []corev1.Protocol{corev1.ProtocolUDP, corev1.ProtocolTCP},
+				isFixed:     true,
+				elbClass:    ElbClassUnion,
+				lbAlgorithm: "LEAST_CONNECTIONS",
+				annotations: map[string]string{"kubernetes.io/elb.health-check-flag": "on"},
+			},
+		},
+		// case 2
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  PortProtocolsConfigName,
+					Value: "80",
+				},
+			},
+			wantErr: true,
+		},
+		// case 3
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  ElbIdsConfigName,
+					Value: "elb-A",
+				},
+				{
+					Name:  ElbClassConfigName,
+					Value: "dedicated",
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for i, test := range tests {
+		elbConf, err := parseElbConfig(test.conf)
+		if (err != nil) != test.wantErr {
+			t.Errorf("case %d: expect err %v but actually got %v", i, test.wantErr, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.elbConf, elbConf) {
+			t.Errorf("case %d: expect elbConfig %v but actually got %v", i, test.elbConf, elbConf)
+		}
+	}
+}
+
+func TestElbInitLbCache(t *testing.T) {
+	svcList := []corev1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{ElbIdAnnotationKey: "elb-A"},
+				Namespace:   "ns-0",
+				Name:        "name-0",
+			},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeLoadBalancer,
+				Ports: []corev1.ServicePort{{Port: 666, Protocol: corev1.ProtocolTCP}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{ElbIdAnnotationKey: "elb-B"},
+				Namespace:   "ns-1",
+				Name:        "name-1",
+			},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeClusterIP,
+				Ports: []corev1.ServicePort{{Port: 555, Protocol: corev1.ProtocolTCP}},
+			},
+		},
+	}
+
+	cache, podAllocate := initLbCache(svcList, 512, 712, []int32{593})
+	if !cache["elb-A"][666] || !cache["elb-A"][593] {
+		t.Errorf("expect ports 666 and 593 of elb-A allocated but actually got %v", cache["elb-A"])
+	}
+	if _, exist := cache["elb-B"]; exist {
+		t.Errorf("expect elb-B ignored but actually got %v", cache["elb-B"])
+	}
+	if !reflect.DeepEqual(podAllocate, map[string]string{"ns-0/name-0": "elb-A:666"}) {
+		t.Errorf("expect podAllocate map[ns-0/name-0:elb-A:666] but actually got %v", podAllocate)
+	}
+}
+
+func TestElbPlugin_consSvc(t *testing.T) {
+	elb := newElbPlugin(512, 712, nil)
+	elb.cache["elb-A"] = portAllocated{512: true}
+	elb.podAllocate["ns-0/pod-0"] = "elb-A:512"
+	config := &elbConfig{
+		lbIds:       []string{"elb-A"},
+		targetPorts: []int{82},
+		protocols:   []corev1.Protocol{corev1.ProtocolUDP},
+		elbClass:    ElbClassPerformance,
+		lbAlgorithm: ElbLbAlgorithmRoundRobin,
+		annotations: map[string]string{"kubernetes.io/elb.health-check-flag": "on"},
+	}
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Pod",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-0",
+			Namespace: "ns-0",
+			UID:       "32fqwfqfew",
+		},
+	}
+
+	svc, err := elb.consSvc(config, pod, nil, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-0",
+			Namespace: "ns-0",
+			Annotations: map[string]string{
+				ElbIdAnnotationKey:                    "elb-A",
+				ElbClassAnnotationKey:                 ElbClassPerformance,
+				ElbLbAlgorithmAnnotationKey:           ElbLbAlgorithmRoundRobin,
+				ElbConfigHashKey:                      util.GetHash(config),
+				"kubernetes.io/elb.health-check-flag": "on",
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion:         "v1",
+					Kind:               "Pod",
+					Name:               "pod-0",
+					UID:                "32fqwfqfew",
+					Controller:         ptr.To[bool](true),
+					BlockOwnerDeletion: ptr.To[bool](true),
+				},
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:                  corev1.ServiceTypeLoadBalancer,
+			ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+			Selector: map[string]string{
+				cloudprovider.DefaultSvcSelectorKey: "pod-0",
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "82",
+					Port:       512,
+					Protocol:   corev1.ProtocolUDP,
+					TargetPort: intstr.FromInt(82),
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(svc, expect) {
+		t.Errorf("expect svc %v but actually got %v", expect, svc)
+	}
+}
+
+func TestElbPlugin_OnPodUpdated(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	networkConf := `[{"name":"ElbIds","value":"elb-A"},{"name":"PortProtocols","value":"80"}]`
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Pod",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-0",
+			Namespace: "ns-0",
+			UID:       "uid-0",
+			Annotations: map[string]string{
+				gamekruiseiov1alpha1.GameServerNetworkType: ElbNetwork,
+				gamekruiseiov1alpha1.GameServerNetworkConf: networkConf,
+			},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+	elb := newElbPlugin(512, 712, nil)
+	ctx := context.Background()
+
+	// case 0: network status initialized as NotReady
+	pod, pErr := elb.OnPodUpdated(c, pod, ctx)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	status, _ := utils.NewNetworkManager(pod, c).GetNetworkStatus()
+	if status == nil || status.CurrentNetworkState != gamekruiseiov1alpha1.NetworkNotReady {
+		t.Errorf("case 0: expect network NotReady but actually got %v", status)
+	}
+
+	// case 1: svc created
+	pod, pErr = elb.OnPodUpdated(c, pod, ctx)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	svc := &corev1.Service{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "ns-0", Name: "pod-0"}, svc); err != nil {
+		t.Fatalf("case 1: expect svc created but actually got %v", err)
+	}
+	if svc.Annotations[ElbIdAnnotationKey] != "elb-A" || svc.Spec.Ports[0].Port != 512 {
+		t.Errorf("case 1: expect svc on elb-A port 512 but actually got %v", svc)
+	}
+
+	// case 2: network ready after ingress assigned
+	svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "1.2.3.4"}}
+	if err := c.Status().Update(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	pod, pErr = elb.OnPodUpdated(c, pod, ctx)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	status, _ = utils.NewNetworkManager(pod, c).GetNetworkStatus()
+	if status.CurrentNetworkState != gamekruiseiov1alpha1.NetworkReady {
+		t.Errorf("case 2: expect network Ready but actually got %s", status.CurrentNetworkState)
+	}
+	if len(status.ExternalAddresses) != 1 || status.ExternalAddresses[0].IP != "1.2.3.4" || status.ExternalAddresses[0].Ports[0].Port.IntVal != 512 {
+		t.Errorf("case 2: expect external address 1.2.3.4:512 but actually got %v", status.ExternalAddresses)
+	}
+	if len(status.InternalAddresses) != 1 || status.InternalAddresses[0].IP != "10.0.0.1" {
+		t.Errorf("case 2: expect internal address 10.0.0.1 but actually got %v", status.InternalAddresses)
+	}
+
+	// case 3: svc without owner references
+	svc.OwnerReferences = nil
+	if err := c.Update(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	if _, pErr = elb.OnPodUpdated(c, pod, ctx); pErr != nil {
+		t.Errorf("case 3: unexpected error %v", pErr)
+	}
+}
+
+func TestElbPlugin_OnPodDeleted(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := gamekruiseiov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	elb := newElbPlugin(512, 712, nil)
+	elb.cache["elb-A"] = portAllocated{512: true, 513: true, 514: true, 515: true}
+	elb.podAllocate = map[string]string{
+		"ns-0/gss-0":   "elb-A:512",
+		"ns-0/gss-1":   "elb-A:513",
+		"ns-0/gss-a-0": "elb-A:514",
+		"ns-1/gss-0":   "elb-A:515",
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "gss-0",
+			Namespace: "ns-0",
+			Labels: map[string]string{
+				gamekruiseiov1alpha1.GameServerOwnerGssKey: "gss",
+			},
+			Annotations: map[string]string{
+				gamekruiseiov1alpha1.GameServerNetworkType: ElbNetwork,
+				gamekruiseiov1alpha1.GameServerNetworkConf: `[{"name":"ElbIds","value":"elb-A"},{"name":"PortProtocols","value":"80"},{"name":"Fixed","value":"true"}]`,
+			},
+		},
+	}
+
+	// the ports of the deleted gss are released, while the ones of gss-a and another namespace are kept
+	if err := elb.OnPodDeleted(c, pod, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]string{
+		"ns-0/gss-a-0": "elb-A:514",
+		"ns-1/gss-0":   "elb-A:515",
+	}
+	if !reflect.DeepEqual(elb.podAllocate, expect) {
+		t.Errorf("expect podAllocate %v but actually got %v", expect, elb.podAllocate)
+	}
+}
@@ -89,7 +89,6 @@ const (
 	NlbAnnotations                = "Annotations"
 	NlbConfigHashKey              = "game.kruise.io/network-config-hash"
 	NlbSpecAnnotationKey          = "service.beta.kubernetes.io/jdcloud-load-balancer-spec"
-	SvcSelectorKey                = "statefulset.kubernetes.io/pod-name"
 	NlbAlgorithm                  = "service.beta.kubernetes.io/jdcloud-lb-algorithm"
 	NlbConnectionIdleTime         = "service.beta.kubernetes.io/jdcloud-lb-idle-time"
 )
@@ -513,7 +512,7 @@ func (c *NlbPlugin) consSvc(config *nlbConfig, pod *corev1.Pod, client client.Cl
 		Spec: corev1.ServiceSpec{
 			Type: corev1.ServiceTypeLoadBalancer,
 			Selector: map[string]string{
-				SvcSelectorKey: pod.GetName(),
+				cloudprovider.GetSvcSelectorKey(): pod.GetName(),
 			},
 			Ports:                         svcPorts,
 			AllocateLoadBalancerNodePorts: ptr.To[bool](config.allocateLoadBalancerNodePorts),
@@ -29,6 +29,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
 	"github.com/openkruise/kruise-game/pkg/util"
 )
 
@@ -172,7 +173,7 @@ func TestInitLbCache(t *testing.T) {
 				Spec: corev1.ServiceSpec{
 					Type: corev1.ServiceTypeLoadBalancer,
 					Selector: map[string]string{
-						SvcSelectorKey: "pod-A",
+						cloudprovider.DefaultSvcSelectorKey: "pod-A",
 					},
 					Ports: []corev1.ServicePort{
 						{
@@ -194,7 +195,7 @@ func TestInitLbCache(t *testing.T) {
 				Spec: corev1.ServiceSpec{
 					Type: corev1.ServiceTypeLoadBalancer,
 					Selector: map[string]string{
-						SvcSelectorKey: "pod-B",
+						cloudprovider.DefaultSvcSelectorKey: "pod-B",
 					},
 					Ports: []corev1.ServicePort{
 						{
@@ -313,7 +314,7 @@ func TestNlbPlugin_consSvc(t *testing.T) {
 				Spec: corev1.ServiceSpec{
 					Type: corev1.ServiceTypeLoadBalancer,
 					Selector: map[string]string{
-						SvcSelectorKey: "test-pod",
+						cloudprovider.DefaultSvcSelectorKey: "test-pod",
 					},
 					Ports: []corev1.ServicePort{{
 						Name:     "82",
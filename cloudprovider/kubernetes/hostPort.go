@@ -18,6 +18,7 @@ package kubernetes
 
 import (
 	"context"
+	"fmt"
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	"github.com/openkruise/kruise-game/cloudprovider"
 	"github.com/openkruise/kruise-game/cloudprovider/errors"
@@ -41,6 +42,8 @@ const (
 	//ContainerPortsKey represents the configuration key when using hostPort.
 	//Its corresponding value format is as follows, containerName:port1/protocol1,port2/protocol2,... e.g. game-server:25565/TCP
 	//When no protocol is specified, TCP is used by default
+	//It can be repeated for each container, and each (container, port, protocol) is allocated a distinct host port,
+	//even if different containers expose the same port.
 	ContainerPortsKey = "ContainerPorts"
 )
 
@@ -61,6 +64,31 @@ func init() {
 	kubernetesProvider.registerPlugin(&hostPortPlugin)
 }
 
+// hostPortAllocationState is the debug snapshot of the host port allocation.
+type hostPortAllocationState struct {
+	MinPort int32 `json:"minPort"`
+	MaxPort int32 `json:"maxPort"`
+	// PodAllocated is the allocated host ports of each pod.
+	PodAllocated map[string]string `json:"podAllocated"`
+	// AmountStat is the number of host ports indexed by how many pods use them.
+	AmountStat []int `json:"amountStat"`
+}
+
+func (hpp *HostPortPlugin) DebugState() interface{} {
+	hpp.mutex.RLock()
+	defer hpp.mutex.RUnlock()
+	state := hostPortAllocationState{
+		MinPort:      hpp.minPort,
+		MaxPort:      hpp.maxPort,
+		PodAllocated: make(map[string]string, len(hpp.podAllocated)),
+		AmountStat:   append([]int{}, hpp.amountStat...),
+	}
+	for podKey, ports := range hpp.podAllocated {
+		state.PodAllocated[podKey] = ports
+	}
+	return state
+}
+
 func (hpp *HostPortPlugin) Name() string {
 	return HostPortNetwork
 }
@@ -89,16 +117,22 @@ func (hpp *HostPortPlugin) OnPodAdded(c client.Client, pod *corev1.Pod, ctx cont
 	containerPortsMap, containerProtocolsMap, numToAlloc := parseConfig(conf, pod)
 
 	var hostPorts []int32
-	if str, ok := hpp.podAllocated[pod.GetNamespace()+"/"+pod.GetName()]; ok {
+	podKey := pod.GetNamespace() + "/" + pod.GetName()
+	if str, ok := hpp.podAllocated[podKey]; ok {
 		hostPorts = util.StringToInt32Slice(str, ",")
 		log.Infof("pod %s/%s use hostPorts %v , which are allocated before", pod.GetNamespace(), pod.GetName(), hostPorts)
 	} else {
-		hostPorts = hpp.allocate(numToAlloc, pod.GetNamespace()+"/"+pod.GetName())
+		hostPorts = hpp.allocate(numToAlloc, podKey)
 		log.Infof("pod %s/%s allocated hostPorts %v", pod.GetNamespace(), pod.GetName(), hostPorts)
 	}
+	if len(hostPorts) < numToAlloc {
+		hpp.deAllocate(hostPorts, podKey)
+		return pod, errors.NewPluginError(errors.InternalError, fmt.Sprintf("pod %s requires %d hostPorts, but only %d are available", podKey, numToAlloc, len(hostPorts)))
+	}
 
 	// patch pod container ports
 	containers := pod.Spec.Containers
+	allocatedPorts := make([]gamekruiseiov1alpha1.NetworkPort, 0, numToAlloc)
 	for cIndex, container := range pod.Spec.Containers {
 		if ports, ok := containerPortsMap[container.Name]; ok {
 			containerPorts := container.Ports
@@ -109,13 +143,19 @@ func (hpp *HostPortPlugin) OnPodAdded(c client.Client, pod *corev1.Pod, ctx cont
 					Protocol:      containerProtocolsMap[container.Name][i],
 				}
 				containerPorts = append(containerPorts, containerPort)
+				hostPortIs := intstr.FromInt(int(containerPort.HostPort))
+				allocatedPorts = append(allocatedPorts, gamekruiseiov1alpha1.NetworkPort{
+					Name:     containerPortKey(container.Name, port),
+					Port:     &hostPortIs,
+					Protocol: containerPort.Protocol,
+				})
 				numToAlloc--
 			}
 			containers[cIndex].Ports = containerPorts
 		}
 	}
 	pod.Spec.Containers = containers
-	return pod, nil
+	return networkManager.SetAllocatedPorts(allocatedPorts, pod), nil
 }
 
 func (hpp *HostPortPlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, errors.PluginError) {
@@ -139,12 +179,12 @@ func (hpp *HostPortPlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx co
 				containerPortIs := intstr.FromInt(int(port.ContainerPort))
 				hostPortIs := intstr.FromInt(int(port.HostPort))
 				iNetworkPorts = append(iNetworkPorts, gamekruiseiov1alpha1.NetworkPort{
-					Name:     container.Name + "-" + containerPortIs.String(),
+					Name:     containerPortKey(container.Name, port.ContainerPort),
 					Port:     &containerPortIs,
 					Protocol: port.Protocol,
 				})
 				eNetworkPorts = append(eNetworkPorts, gamekruiseiov1alpha1.NetworkPort{
-					Name:     container.Name + "-" + containerPortIs.String(),
+					Name:     containerPortKey(container.Name, port.ContainerPort),
 					Port:     &hostPortIs,
 					Protocol: port.Protocol,
 				})
@@ -182,19 +222,16 @@ func (hpp *HostPortPlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx co
 
 func (hpp *HostPortPlugin) OnPodDeleted(c client.Client, pod *corev1.Pod, ctx context.Context) errors.PluginError {
 	log.Infof("Receiving pod %s/%s DELETE Operation", pod.GetNamespace(), pod.GetName())
-	if _, ok := hpp.podAllocated[pod.GetNamespace()+"/"+pod.GetName()]; !ok {
+	hpp.mutex.RLock()
+	allocated, ok := hpp.podAllocated[pod.GetNamespace()+"/"+pod.GetName()]
+	hpp.mutex.RUnlock()
+	if !ok {
 		return nil
 	}
 
-	hostPorts := make([]int32, 0)
-	for _, container := range pod.Spec.Containers {
-		for _, port := range container.Ports {
-			if port.HostPort >= hpp.minPort && port.HostPort <= hpp.maxPort {
-				hostPorts = append(hostPorts, port.HostPort)
-			}
-		}
-	}
-
+	// free the recorded host ports rather than the ones found in pod spec,
+	// which may also contain host ports not allocated by the plugin.
+	hostPorts := util.StringToInt32Slice(allocated, ",")
 	hpp.deAllocate(hostPorts, pod.GetNamespace()+"/"+pod.GetName())
 	log.Infof("pod %s/%s deallocated hostPorts %v", pod.GetNamespace(), pod.GetName(), hostPorts)
 	return nil
@@ -283,6 +320,11 @@ func (hpp *HostPortPlugin) deAllocate(hostPorts []int32, nsname string) {
 	delete(hpp.podAllocated, nsname)
 }
 
+// containerPortKey is the name of the network port exposed by the container port of a container.
+func containerPortKey(containerName string, containerPort int32) string {
+	return containerName + "-" + strconv.Itoa(int(containerPort))
+}
+
 func verifyContainerName(containerName string, pod *corev1.Pod) bool {
 	for _, container := range pod.Spec.Containers {
 		if container.Name == containerName {
@@ -333,8 +375,9 @@ func parseConfig(conf []gamekruiseiov1alpha1.NetworkConfParams, pod *corev1.Pod)
 			cpSlice := strings.Split(c.Value, ":")
 			containerName := cpSlice[0]
 			if verifyContainerName(containerName, pod) && len(cpSlice) == 2 {
-				ports := make([]int32, 0)
-				protocols := make([]corev1.Protocol, 0)
+				// ports of the same container may be given by multiple ContainerPorts
+				ports := containerPortsMap[containerName]
+				protocols := containerProtocolsMap[containerName]
 				for _, portString := range strings.Split(cpSlice[1], ",") {
 					ppSlice := strings.Split(portString, "/")
 					// handle port
@@ -342,14 +385,17 @@ func parseConfig(conf []gamekruiseiov1alpha1.NetworkConfParams, pod *corev1.Pod)
 					if err != nil {
 						continue
 					}
-					numToAlloc++
-					ports = append(ports, int32(port))
 					// handle protocol
+					protocol := corev1.ProtocolTCP
 					if len(ppSlice) == 2 {
-						protocols = append(protocols, corev1.Protocol(ppSlice[1]))
-					} else {
-						protocols = append(protocols, corev1.ProtocolTCP)
+						protocol = corev1.Protocol(ppSlice[1])
 					}
+					if containsContainerPort(ports, protocols, int32(port), protocol) {
+						continue
+					}
+					numToAlloc++
+					ports = append(ports, int32(port))
+					protocols = append(protocols, protocol)
 				}
 				containerPortsMap[containerName] = ports
 				containerProtocolsMap[containerName] = protocols
@@ -359,6 +405,71 @@ func parseConfig(conf []gamekruiseiov1alpha1.NetworkConfParams, pod *corev1.Pod)
 	return containerPortsMap, containerProtocolsMap, numToAlloc
 }
 
+// ValidatePodTemplate checks that the containers referenced by ContainerPorts exist in the pod template,
+// and that their ports are declared if the containers declare any ports.
+func (hpp *HostPortPlugin) ValidatePodTemplate(conf []gamekruiseiov1alpha1.NetworkConfParams, template *corev1.PodTemplateSpec) error {
+	for _, c := range conf {
+		if c.Name != ContainerPortsKey {
+			continue
+		}
+		cpSlice := strings.Split(c.Value, ":")
+		if len(cpSlice) != 2 {
+			return fmt.Errorf("invalid %s %s. You should input as the format {containerName}:{port1}/{protocol1},{port2}/{protocol2},...", ContainerPortsKey, c.Value)
+		}
+		var container *corev1.Container
+		for i := range template.Spec.Containers {
+			if template.Spec.Containers[i].Name == cpSlice[0] {
+				container = &template.Spec.Containers[i]
+			}
+		}
+		if container == nil {
+			return fmt.Errorf("container %s referenced by %s is not found in the pod template", cpSlice[0], ContainerPortsKey)
+		}
+		if len(container.Ports) == 0 {
+			continue
+		}
+		for _, portString := range strings.Split(cpSlice[1], ",") {
+			ppSlice := strings.Split(portString, "/")
+			port, err := strconv.ParseInt(ppSlice[0], 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid port %s of container %s in %s", ppSlice[0], cpSlice[0], ContainerPortsKey)
+			}
+			protocol := corev1.ProtocolTCP
+			if len(ppSlice) == 2 {
+				protocol = corev1.Protocol(ppSlice[1])
+			}
+			if !isContainerPortDeclared(container.Ports, int32(port), protocol) {
+				return fmt.Errorf("port %d/%s referenced by %s is not declared in the ports of container %s", port, protocol, ContainerPortsKey, cpSlice[0])
+			}
+		}
+	}
+	return nil
+}
+
+// isContainerPortDeclared returns whether the port with the protocol is in the declared container ports,
+// whose protocol is TCP if not set.
+func isContainerPortDeclared(declared []corev1.ContainerPort, port int32, protocol corev1.Protocol) bool {
+	for _, cp := range declared {
+		cpProtocol := cp.Protocol
+		if cpProtocol == "" {
+			cpProtocol = corev1.ProtocolTCP
+		}
+		if cp.ContainerPort == port && cpProtocol == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+func containsContainerPort(ports []int32, protocols []corev1.Protocol, port int32, protocol corev1.Protocol) bool {
+	for i := range ports {
+		if ports[i] == port && protocols[i] == protocol {
+			return true
+		}
+	}
+	return false
+}
+
 func selectPorts(amountStat []int, portAmount map[int32]int, num int) ([]int32, int) {
 	var index int
 	for i, total := range amountStat {
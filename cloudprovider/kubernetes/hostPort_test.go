@@ -17,7 +17,17 @@ limitations under the License.
 package kubernetes
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
 	"testing"
+
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestSelectPorts(t *testing.T) {
@@ -57,3 +67,124 @@ func TestSelectPorts(t *testing.T) {
 		}
 	}
 }
+
+func TestParseConfig(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "game-a"},
+				{Name: "game-b"},
+			},
+		},
+	}
+	tests := []struct {
+		conf         []gamekruiseiov1alpha1.NetworkConfParams
+		portsMap     map[string][]int32
+		protocolsMap map[string][]corev1.Protocol
+		numToAlloc   int
+	}{
+		// case 0
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{Name: ContainerPortsKey, Value: "game-a:8080"},
+				{Name: ContainerPortsKey, Value: "game-b:8080/UDP"},
+			},
+			portsMap:     map[string][]int32{"game-a": {8080}, "game-b": {8080}},
+			protocolsMap: map[string][]corev1.Protocol{"game-a": {corev1.ProtocolTCP}, "game-b": {corev1.ProtocolUDP}},
+			numToAlloc:   2,
+		},
+		// case 1
+		{
+			conf: []gamekruiseiov1alpha1.NetworkConfParams{
+				{Name: ContainerPortsKey, Value: "game-a:8080,8080/UDP"},
+				{Name: ContainerPortsKey, Value: "game-a:8080/TCP,9090"},
+				{Name: ContainerPortsKey, Value: "unknown:8080"},
+			},
+			portsMap:     map[string][]int32{"game-a": {8080, 8080, 9090}},
+			protocolsMap: map[string][]corev1.Protocol{"game-a": {corev1.ProtocolTCP, corev1.ProtocolUDP, corev1.ProtocolTCP}},
+			numToAlloc:   3,
+		},
+	}
+
+	for i, test := range tests {
+		portsMap, protocolsMap, numToAlloc := parseConfig(test.conf, pod)
+		if !reflect.DeepEqual(portsMap, test.portsMap) {
+			t.Errorf("case %d: expect ports %v but actually got %v", i, test.portsMap, portsMap)
+		}
+		if !reflect.DeepEqual(protocolsMap, test.protocolsMap) {
+			t.Errorf("case %d: expect protocols %v but actually got %v", i, test.protocolsMap, protocolsMap)
+		}
+		if numToAlloc != test.numToAlloc {
+			t.Errorf("case %d: expect numToAlloc %d but actually got %d", i, test.numToAlloc, numToAlloc)
+		}
+	}
+}
+
+func TestHostPortPlugin_SamePortInContainers(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	hpp := &HostPortPlugin{
+		minPort:      8000,
+		maxPort:      8004,
+		podAllocated: make(map[string]string),
+		portAmount:   map[int32]int{8000: 0, 8001: 0, 8002: 0, 8003: 0, 8004: 0},
+		amountStat:   []int{5},
+		mutex:        sync.RWMutex{},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-0",
+			Namespace: "ns",
+			Annotations: map[string]string{
+				gamekruiseiov1alpha1.GameServerNetworkType: HostPortNetwork,
+				gamekruiseiov1alpha1.GameServerNetworkConf: `[{"name":"ContainerPorts","value":"game-a:8080"},{"name":"ContainerPorts","value":"game-b:8080"}]`,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "game-a"},
+				{Name: "game-b"},
+			},
+		},
+	}
+
+	pod, err := hpp.OnPodAdded(c, pod, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hostPorts []int32
+	for _, container := range pod.Spec.Containers {
+		if len(container.Ports) != 1 || container.Ports[0].ContainerPort != 8080 {
+			t.Fatalf("expect container %s exposing 8080 but actually got %v", container.Name, container.Ports)
+		}
+		hostPorts = append(hostPorts, container.Ports[0].HostPort)
+	}
+	if hostPorts[0] == hostPorts[1] {
+		t.Errorf("expect distinct hostPorts but actually got %v", hostPorts)
+	}
+	if _, ok := hpp.podAllocated["ns/pod-0"]; !ok {
+		t.Errorf("expect hostPorts of ns/pod-0 recorded but actually not")
+	}
+	expectAllocated := fmt.Sprintf("game-a-8080:%d/TCP,game-b-8080:%d/TCP", hostPorts[0], hostPorts[1])
+	if pod.Annotations[gamekruiseiov1alpha1.GameServerAllocatedPortsKey] != expectAllocated {
+		t.Errorf("expect allocated ports %s but actually got %s", expectAllocated, pod.Annotations[gamekruiseiov1alpha1.GameServerAllocatedPortsKey])
+	}
+
+	if err := hpp.OnPodDeleted(c, pod, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, hostPort := range hostPorts {
+		if hpp.portAmount[hostPort] != 0 {
+			t.Errorf("expect hostPort %d freed but actually got amount %d", hostPort, hpp.portAmount[hostPort])
+		}
+	}
+	if _, ok := hpp.podAllocated["ns/pod-0"]; ok {
+		t.Errorf("expect record of ns/pod-0 removed but actually not")
+	}
+	if !reflect.DeepEqual(hpp.amountStat, []int{5, 0}) {
+		t.Errorf("expect amountStat [5 0] but actually got %v", hpp.amountStat)
+	}
+}
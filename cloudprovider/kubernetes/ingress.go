@@ -51,7 +51,6 @@ const (
 )
 
 const (
-	SvcSelectorKey = "statefulset.kubernetes.io/pod-name"
 	IngressHashKey = "game.kruise.io/ingress-hash"
 	ServiceHashKey = "game.kruise.io/svc-hash"
 )
@@ -344,7 +343,7 @@ func consSvc(ic ingConfig, pod *corev1.Pod, c client.Client, ctx context.Context
 		Spec: corev1.ServiceSpec{
 			Type: corev1.ServiceTypeClusterIP,
 			Selector: map[string]string{
-				SvcSelectorKey: pod.GetName(),
+				cloudprovider.GetSvcSelectorKey(): pod.GetName(),
 			},
 			Ports: ports,
 		},
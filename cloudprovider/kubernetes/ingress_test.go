@@ -11,6 +11,7 @@ import (
 	"k8s.io/utils/ptr"
 
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
 	"github.com/openkruise/kruise-game/pkg/util"
 )
 
@@ -402,7 +403,7 @@ func TestConsSvc(t *testing.T) {
 		Spec: corev1.ServiceSpec{
 			Type: corev1.ServiceTypeClusterIP,
 			Selector: map[string]string{
-				SvcSelectorKey: "pod-3",
+				cloudprovider.DefaultSvcSelectorKey: "pod-3",
 			},
 			Ports: []corev1.ServicePort{
 				{
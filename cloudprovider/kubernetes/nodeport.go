@@ -18,9 +18,11 @@ package kubernetes
 
 import (
 	"context"
+	"fmt"
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	"github.com/openkruise/kruise-game/cloudprovider"
 	cperrors "github.com/openkruise/kruise-game/cloudprovider/errors"
+	provideroptions "github.com/openkruise/kruise-game/cloudprovider/options"
 	"github.com/openkruise/kruise-game/cloudprovider/utils"
 	"github.com/openkruise/kruise-game/pkg/util"
 	corev1 "k8s.io/api/core/v1"
@@ -28,9 +30,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	log "k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -39,9 +44,21 @@ const (
 	PortProtocolsConfigName = "PortProtocols"
 
 	SvcSelectorDisabledKey = "game.kruise.io/svc-selector-disabled"
+
+	// nodePortConflictRequeueInterval is the interval to retry after the node ports allocated by the plugin
+	// are found already allocated by others.
+	nodePortConflictRequeueInterval = 5 * time.Second
 )
 
 type NodePortPlugin struct {
+	// the fields below are only used when the node port range is set in options,
+	// otherwise node ports are allocated by Kubernetes.
+	minPort       int32
+	maxPort       int32
+	blockPorts    []int32
+	portAllocated map[int32]bool
+	podAllocated  map[string][]int32
+	mutex         sync.RWMutex
 }
 
 func (n *NodePortPlugin) Name() string {
@@ -53,9 +70,122 @@ func (n *NodePortPlugin) Alias() string {
 }
 
 func (n *NodePortPlugin) Init(client client.Client, options cloudprovider.CloudProviderOptions, ctx context.Context) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	nodePortOptions := options.(provideroptions.KubernetesOptions).NodePort
+	n.minPort = nodePortOptions.MinPort
+	n.maxPort = nodePortOptions.MaxPort
+	n.blockPorts = nodePortOptions.BlockPorts
+	if !nodePortOptions.Enabled() {
+		return nil
+	}
+	err := n.syncAllocated(client, ctx)
+	if err != nil {
+		return err
+	}
+	log.Infof("[%s] podAllocated init: %v", NodePortNetwork, n.podAllocated)
+	return nil
+}
+
+// syncAllocated rebuilds the allocation from the node ports of live services within the range.
+// The ones of the services created by the plugin are recorded by the pod names. It should be called with the lock held.
+func (n *NodePortPlugin) syncAllocated(c client.Client, ctx context.Context) error {
+	svcList := &corev1.ServiceList{}
+	err := c.List(ctx, svcList)
+	if err != nil {
+		return err
+	}
+	portAllocated := make(map[int32]bool)
+	podAllocated := make(map[string][]int32)
+	for _, blockPort := range n.blockPorts {
+		portAllocated[blockPort] = true
+	}
+	for _, svc := range svcList.Items {
+		var nodePorts []int32
+		for _, port := range svc.Spec.Ports {
+			if port.NodePort >= n.minPort && port.NodePort <= n.maxPort {
+				portAllocated[port.NodePort] = true
+				nodePorts = append(nodePorts, port.NodePort)
+			}
+		}
+		if len(nodePorts) == 0 {
+			continue
+		}
+		if svc.Spec.Selector[cloudprovider.GetSvcSelectorKey()] == svc.GetName() || svc.Spec.Selector[SvcSelectorDisabledKey] == svc.GetName() {
+			podAllocated[svc.GetNamespace()+"/"+svc.GetName()] = nodePorts
+		}
+	}
+	n.portAllocated = portAllocated
+	n.podAllocated = podAllocated
 	return nil
 }
 
+// allocate returns num node ports for the pod, reusing the ones allocated before if the number is the same.
+// It returns nil if the node port range is not set, and an error if there are not enough node ports.
+func (n *NodePortPlugin) allocate(podKey string, num int) ([]int32, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.portAllocated == nil {
+		return nil, nil
+	}
+
+	if allocated, ok := n.podAllocated[podKey]; ok {
+		if len(allocated) == num {
+			return allocated, nil
+		}
+		n.release(podKey)
+	}
+	nodePorts := make([]int32, 0, num)
+	for port := n.minPort; port <= n.maxPort && len(nodePorts) < num; port++ {
+		if !n.portAllocated[port] {
+			nodePorts = append(nodePorts, port)
+		}
+	}
+	if len(nodePorts) < num {
+		return nil, fmt.Errorf("pod %s requires %d node ports, but only %d are available in [%d, %d]", podKey, num, len(nodePorts), n.minPort, n.maxPort)
+	}
+	for _, port := range nodePorts {
+		n.portAllocated[port] = true
+	}
+	n.podAllocated[podKey] = nodePorts
+	log.Infof("pod %s allocated node ports %v", podKey, nodePorts)
+	return nodePorts, nil
+}
+
+func (n *NodePortPlugin) deAllocate(podKey string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.release(podKey)
+}
+
+// release frees the node ports of the pod. It should be called with the lock held.
+func (n *NodePortPlugin) release(podKey string) {
+	for _, port := range n.podAllocated[podKey] {
+		delete(n.portAllocated, port)
+	}
+	delete(n.podAllocated, podKey)
+}
+
+// handleApplyError turns the error of creating or updating the service into a plugin error.
+// If the allocated node ports are taken by others, the allocation is synced from live services,
+// and the plugin is asked to retry later.
+func (n *NodePortPlugin) handleApplyError(c client.Client, ctx context.Context, podKey string, err error) cperrors.PluginError {
+	if err == nil {
+		return nil
+	}
+	if errors.IsInvalid(err) && strings.Contains(err.Error(), "already allocated") {
+		n.mutex.Lock()
+		syncErr := n.syncAllocated(c, ctx)
+		n.mutex.Unlock()
+		if syncErr != nil {
+			return cperrors.ToPluginError(syncErr, cperrors.ApiCallError)
+		}
+		return cperrors.NewRequeueError(nodePortConflictRequeueInterval, "node ports of pod %s conflict with others and will be reallocated: %s", podKey, err.Error())
+	}
+	return cperrors.ToPluginError(err, cperrors.ApiCallError)
+}
+
 func (n *NodePortPlugin) OnPodAdded(client client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, cperrors.PluginError) {
 	return pod, nil
 }
@@ -83,9 +213,14 @@ func (n *NodePortPlugin) OnPodUpdated(client client.Client, pod *corev1.Pod, ctx
 		Name:      pod.GetName(),
 		Namespace: pod.GetNamespace(),
 	}, svc)
+	podKey := pod.GetNamespace() + "/" + pod.GetName()
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return pod, cperrors.ToPluginError(client.Create(ctx, consNodePortSvc(npc, pod, client, ctx)), cperrors.ApiCallError)
+			nodePorts, err := n.allocate(podKey, len(npc.ports))
+			if err != nil {
+				return pod, cperrors.NewPluginError(cperrors.InternalError, err.Error())
+			}
+			return pod, n.handleApplyError(client, ctx, podKey, client.Create(ctx, consNodePortSvc(npc, pod, client, ctx, nodePorts)))
 		}
 		return pod, cperrors.NewPluginError(cperrors.ApiCallError, err.Error())
 	}
@@ -97,14 +232,18 @@ func (n *NodePortPlugin) OnPodUpdated(client client.Client, pod *corev1.Pod, ctx
 		if err != nil {
 			return pod, cperrors.NewPluginError(cperrors.InternalError, err.Error())
 		}
-		return pod, cperrors.ToPluginError(client.Update(ctx, consNodePortSvc(npc, pod, client, ctx)), cperrors.ApiCallError)
+		nodePorts, err := n.allocate(podKey, len(npc.ports))
+		if err != nil {
+			return pod, cperrors.NewPluginError(cperrors.InternalError, err.Error())
+		}
+		return pod, n.handleApplyError(client, ctx, podKey, client.Update(ctx, consNodePortSvc(npc, pod, client, ctx, nodePorts)))
 	}
 
 	// disable network
-	if networkManager.GetNetworkDisabled() && svc.Spec.Selector[SvcSelectorKey] == pod.GetName() {
+	if networkManager.GetNetworkDisabled() && svc.Spec.Selector[cloudprovider.GetSvcSelectorKey()] == pod.GetName() {
 		newSelector := svc.Spec.Selector
 		newSelector[SvcSelectorDisabledKey] = pod.GetName()
-		delete(svc.Spec.Selector, SvcSelectorKey)
+		delete(svc.Spec.Selector, cloudprovider.GetSvcSelectorKey())
 		svc.Spec.Selector = newSelector
 		return pod, cperrors.ToPluginError(client.Update(ctx, svc), cperrors.ApiCallError)
 	}
@@ -112,7 +251,7 @@ func (n *NodePortPlugin) OnPodUpdated(client client.Client, pod *corev1.Pod, ctx
 	// enable network
 	if !networkManager.GetNetworkDisabled() && svc.Spec.Selector[SvcSelectorDisabledKey] == pod.GetName() {
 		newSelector := svc.Spec.Selector
-		newSelector[SvcSelectorKey] = pod.GetName()
+		newSelector[cloudprovider.GetSvcSelectorKey()] = pod.GetName()
 		delete(svc.Spec.Selector, SvcSelectorDisabledKey)
 		svc.Spec.Selector = newSelector
 		return pod, cperrors.ToPluginError(client.Update(ctx, svc), cperrors.ApiCallError)
@@ -185,16 +324,38 @@ func (n *NodePortPlugin) OnPodUpdated(client client.Client, pod *corev1.Pod, ctx
 	networkStatus.InternalAddresses = internalAddresses
 	networkStatus.ExternalAddresses = externalAddresses
 	networkStatus.CurrentNetworkState = gamekruiseiov1alpha1.NetworkReady
+	// node ports are allocated once svc created, init containers could read them through downward API volume.
+	allocatedPorts := make([]gamekruiseiov1alpha1.NetworkPort, 0, len(externalAddresses))
+	for _, externalAddress := range externalAddresses {
+		allocatedPorts = append(allocatedPorts, externalAddress.Ports...)
+	}
+	pod = networkManager.SetAllocatedPorts(allocatedPorts, pod)
+	pod = networkManager.SetExternalEndpoints(externalAddresses, pod)
 	pod, err = networkManager.UpdateNetworkStatus(*networkStatus, pod)
 	return pod, cperrors.ToPluginError(err, cperrors.InternalError)
 }
 
 func (n *NodePortPlugin) OnPodDeleted(client client.Client, pod *corev1.Pod, ctx context.Context) cperrors.PluginError {
+	networkManager := utils.NewNetworkManager(pod, client)
+	if networkManager == nil {
+		return nil
+	}
+	npc, err := parseNodePortConfig(networkManager.GetNetworkConfig())
+	if err != nil {
+		return cperrors.NewPluginError(cperrors.ParameterError, err.Error())
+	}
+	// the service of fixed network is kept for the next pod with the same name, and so are its node ports
+	if !npc.isFixed {
+		n.deAllocate(pod.GetNamespace() + "/" + pod.GetName())
+	}
 	return nil
 }
 
 func init() {
-	kubernetesProvider.registerPlugin(&NodePortPlugin{})
+	kubernetesProvider.registerPlugin(&NodePortPlugin{
+		mutex:        sync.RWMutex{},
+		podAllocated: make(map[string][]int32),
+	})
 }
 
 type nodePortConfig struct {
@@ -228,6 +389,30 @@ func parseNodePortConfig(conf []gamekruiseiov1alpha1.NetworkConfParams) (*nodePo
 	}, nil
 }
 
+// ValidatePodTemplate checks that the ports of PortProtocols are declared in the pod template,
+// if its containers declare any ports.
+func (n *NodePortPlugin) ValidatePodTemplate(conf []gamekruiseiov1alpha1.NetworkConfParams, template *corev1.PodTemplateSpec) error {
+	var declared []corev1.ContainerPort
+	for _, container := range template.Spec.Containers {
+		declared = append(declared, container.Ports...)
+	}
+	if len(declared) == 0 {
+		return nil
+	}
+	for _, c := range conf {
+		if c.Name != PortProtocolsConfigName {
+			continue
+		}
+		ports, protocols := parsePortProtocols(c.Value)
+		for i, port := range ports {
+			if !isContainerPortDeclared(declared, int32(port), protocols[i]) {
+				return fmt.Errorf("port %d/%s referenced by %s is not declared in the ports of any container", port, protocols[i], PortProtocolsConfigName)
+			}
+		}
+	}
+	return nil
+}
+
 func parsePortProtocols(value string) ([]int, []corev1.Protocol) {
 	ports := make([]int, 0)
 	protocols := make([]corev1.Protocol, 0)
@@ -247,15 +432,21 @@ func parsePortProtocols(value string) ([]int, []corev1.Protocol) {
 	return ports, protocols
 }
 
-func consNodePortSvc(npc *nodePortConfig, pod *corev1.Pod, c client.Client, ctx context.Context) *corev1.Service {
+// consNodePortSvc constructs the service of the pod. The node ports are set explicitly if given,
+// and are otherwise allocated by Kubernetes.
+func consNodePortSvc(npc *nodePortConfig, pod *corev1.Pod, c client.Client, ctx context.Context, nodePorts []int32) *corev1.Service {
 	svcPorts := make([]corev1.ServicePort, 0)
 	for i := 0; i < len(npc.ports); i++ {
-		svcPorts = append(svcPorts, corev1.ServicePort{
+		svcPort := corev1.ServicePort{
 			Name:       strconv.Itoa(npc.ports[i]),
 			Port:       int32(npc.ports[i]),
 			Protocol:   npc.protocols[i],
 			TargetPort: intstr.FromInt(npc.ports[i]),
-		})
+		}
+		if i < len(nodePorts) {
+			svcPort.NodePort = nodePorts[i]
+		}
+		svcPorts = append(svcPorts, svcPort)
 	}
 
 	svc := &corev1.Service{
@@ -270,7 +461,7 @@ func consNodePortSvc(npc *nodePortConfig, pod *corev1.Pod, c client.Client, ctx
 		Spec: corev1.ServiceSpec{
 			Type: corev1.ServiceTypeNodePort,
 			Selector: map[string]string{
-				SvcSelectorKey: pod.GetName(),
+				cloudprovider.GetSvcSelectorKey(): pod.GetName(),
 			},
 			Ports: svcPorts,
 		},
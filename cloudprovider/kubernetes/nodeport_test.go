@@ -1,15 +1,21 @@
 package kubernetes
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
+	provideroptions "github.com/openkruise/kruise-game/cloudprovider/options"
 	"github.com/openkruise/kruise-game/pkg/util"
 )
 
@@ -99,7 +105,7 @@ func TestConsNPSvc(t *testing.T) {
 		Spec: corev1.ServiceSpec{
 			Type: corev1.ServiceTypeNodePort,
 			Selector: map[string]string{
-				SvcSelectorKey: "pod-3",
+				cloudprovider.DefaultSvcSelectorKey: "pod-3",
 			},
 			Ports: []corev1.ServicePort{
 				{
@@ -149,7 +155,7 @@ func TestConsNPSvc(t *testing.T) {
 		Spec: corev1.ServiceSpec{
 			Type: corev1.ServiceTypeNodePort,
 			Selector: map[string]string{
-				SvcSelectorKey: "pod-3",
+				cloudprovider.DefaultSvcSelectorKey: "pod-3",
 			},
 			Ports: []corev1.ServicePort{
 				{
@@ -177,9 +183,223 @@ func TestConsNPSvc(t *testing.T) {
 	}
 
 	for i, test := range tests {
-		actual := consNodePortSvc(test.npc, pod, nil, nil)
+		actual := consNodePortSvc(test.npc, pod, nil, nil, nil)
 		if !reflect.DeepEqual(actual, test.svc) {
 			t.Errorf("case %d: expect service: %v , but actual: %v", i, test.svc, actual)
 		}
 	}
 }
+
+func TestNodePortAllocatedPorts(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Pod",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-0",
+			Namespace: "ns",
+			UID:       "uid-0",
+			Annotations: map[string]string{
+				gamekruiseiov1alpha1.GameServerNetworkType:   NodePortNetwork,
+				gamekruiseiov1alpha1.GameServerNetworkConf:   `[{"name":"PortProtocols","value":"80,90/UDP"}]`,
+				gamekruiseiov1alpha1.GameServerNetworkStatus: `{"currentNetworkState":"NotReady"}`,
+			},
+		},
+		Spec:   corev1.PodSpec{NodeName: "node-0"},
+		Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-0"},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeExternalIP, Address: "1.2.3.4"}},
+		},
+	}
+	npc := &nodePortConfig{
+		ports:     []int{80, 90},
+		protocols: []corev1.Protocol{corev1.ProtocolTCP, corev1.ProtocolUDP},
+	}
+	svc := consNodePortSvc(npc, pod, nil, context.Background(), nil)
+	svc.Spec.Ports[0].NodePort = 30080
+	svc.Spec.Ports[1].NodePort = 30090
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod, node, svc).Build()
+
+	np := &NodePortPlugin{}
+	pod, err := np.OnPodUpdated(c, pod, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "80:30080/TCP,90:30090/UDP"
+	if pod.Annotations[gamekruiseiov1alpha1.GameServerAllocatedPortsKey] != expect {
+		t.Errorf("expect allocated ports %s but actually got %s", expect, pod.Annotations[gamekruiseiov1alpha1.GameServerAllocatedPortsKey])
+	}
+}
+
+func TestNodePortAllocate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	// node port 30001 is used by a service not created by the plugin, and 30002 is blocked
+	other := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns"},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeNodePort,
+			Ports: []corev1.ServicePort{{Port: 80, NodePort: 30001}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(other).Build()
+	np := &NodePortPlugin{}
+	err := np.Init(c, provideroptions.KubernetesOptions{
+		NodePort: provideroptions.NodePortOptions{
+			MinPort:    30000,
+			MaxPort:    30004,
+			BlockPorts: []int32{30002},
+		},
+	}, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		podKey    string
+		num       int
+		release   string
+		expect    []int32
+		exhausted bool
+	}{
+		// case 0: allocate the free node ports within the range
+		{
+			podKey: "ns/pod-0",
+			num:    2,
+			expect: []int32{30000, 30003},
+		},
+		// case 1: the node ports allocated before are reused
+		{
+			podKey: "ns/pod-0",
+			num:    2,
+			expect: []int32{30000, 30003},
+		},
+		// case 2: exhausted
+		{
+			podKey:    "ns/pod-1",
+			num:       2,
+			exhausted: true,
+		},
+		// case 3: the node ports released are allocated again
+		{
+			podKey:  "ns/pod-1",
+			num:     2,
+			release: "ns/pod-0",
+			expect:  []int32{30000, 30003},
+		},
+	}
+
+	for i, test := range tests {
+		if test.release != "" {
+			np.deAllocate(test.release)
+		}
+		actual, err := np.allocate(test.podKey, test.num)
+		if (err != nil) != test.exhausted {
+			t.Errorf("case %d: expect exhausted %v but actually got error %v", i, test.exhausted, err)
+		}
+		if !reflect.DeepEqual(actual, test.expect) {
+			t.Errorf("case %d: expect node ports %v but actually got %v", i, test.expect, actual)
+		}
+	}
+}
+
+func TestNodePortCreateSvcInRange(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-0",
+			Namespace: "ns",
+			UID:       "uid-0",
+			Annotations: map[string]string{
+				gamekruiseiov1alpha1.GameServerNetworkType:   NodePortNetwork,
+				gamekruiseiov1alpha1.GameServerNetworkConf:   `[{"name":"PortProtocols","value":"80,90/UDP"}]`,
+				gamekruiseiov1alpha1.GameServerNetworkStatus: `{"currentNetworkState":"NotReady"}`,
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+	np := &NodePortPlugin{}
+	err := np.Init(c, provideroptions.KubernetesOptions{
+		NodePort: provideroptions.NodePortOptions{MinPort: 31000, MaxPort: 31100},
+	}, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, perr := np.OnPodUpdated(c, pod, context.Background()); perr != nil {
+		t.Fatal(perr)
+	}
+	svc := &corev1.Service{}
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: "ns", Name: "pod-0"}, svc); err != nil {
+		t.Fatal(err)
+	}
+	for i, port := range svc.Spec.Ports {
+		if port.NodePort != int32(31000+i) {
+			t.Errorf("expect node port %d of port %d but actually got %d", 31000+i, port.Port, port.NodePort)
+		}
+	}
+
+	// the node ports are released when the pod is deleted
+	if perr := np.OnPodDeleted(c, pod, context.Background()); perr != nil {
+		t.Fatal(perr)
+	}
+	if _, ok := np.podAllocated["ns/pod-0"]; ok || len(np.portAllocated) != 0 {
+		t.Errorf("expect node ports released but actually got %v", np.podAllocated)
+	}
+}
+
+func TestNodePortCustomSvcSelectorKey(t *testing.T) {
+	defer func(key string) { cloudprovider.Opt.SvcSelectorKey = key }(cloudprovider.Opt.SvcSelectorKey)
+	cloudprovider.Opt.SvcSelectorKey = "example.com/pod-name"
+
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Pod",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-0",
+			Namespace: "ns",
+			UID:       "uid-0",
+		},
+	}
+	npc := &nodePortConfig{
+		ports:     []int{80},
+		protocols: []corev1.Protocol{corev1.ProtocolTCP},
+	}
+	svc := consNodePortSvc(npc, pod, nil, context.Background(), []int32{31000})
+	expect := map[string]string{"example.com/pod-name": "pod-0"}
+	if !reflect.DeepEqual(svc.Spec.Selector, expect) {
+		t.Errorf("expect selector %v but actually got %v", expect, svc.Spec.Selector)
+	}
+
+	// the node ports of the Service selecting by the custom key are recorded on init
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(svc).Build()
+	np := &NodePortPlugin{}
+	err := np.Init(c, provideroptions.KubernetesOptions{
+		NodePort: provideroptions.NodePortOptions{MinPort: 31000, MaxPort: 31100},
+	}, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ports := np.podAllocated["ns/pod-0"]; !reflect.DeepEqual(ports, []int32{31000}) {
+		t.Errorf("expect node ports [31000] of pod allocated but actually got %v", ports)
+	}
+}
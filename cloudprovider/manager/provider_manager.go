@@ -18,16 +18,27 @@ package manager
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/openkruise/kruise-game/cloudprovider/jdcloud"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/openkruise/kruise-game/apis/v1alpha1"
 	"github.com/openkruise/kruise-game/cloudprovider"
 	"github.com/openkruise/kruise-game/cloudprovider/alibabacloud"
 	aws "github.com/openkruise/kruise-game/cloudprovider/amazonswebservices"
+	"github.com/openkruise/kruise-game/cloudprovider/huaweicloud"
 	"github.com/openkruise/kruise-game/cloudprovider/kubernetes"
+	"github.com/openkruise/kruise-game/cloudprovider/mock"
+	"github.com/openkruise/kruise-game/cloudprovider/options"
 	"github.com/openkruise/kruise-game/cloudprovider/tencentcloud"
 	volcengine "github.com/openkruise/kruise-game/cloudprovider/volcengine"
+	"github.com/prometheus/client_golang/prometheus"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	log "k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -35,6 +46,12 @@ import (
 type ProviderManager struct {
 	CloudProviders map[string]cloudprovider.CloudProvider
 	CPOptions      map[string]cloudprovider.CloudProviderOptions
+
+	initLock sync.RWMutex
+	// initialized is true once Init has gone through all the plugins.
+	initialized bool
+	// initResults records the init error of each plugin by name, nil for success.
+	initResults map[string]error
 }
 
 func (pm *ProviderManager) FindConfigs(cpName string) cloudprovider.CloudProviderOptions {
@@ -57,6 +74,11 @@ func (pm *ProviderManager) FindAvailablePlugins(pod *corev1.Pod) (cloudprovider.
 		return nil, false
 	}
 
+	return pm.FindPlugin(pluginType)
+}
+
+// FindPlugin returns the plugin of the network type.
+func (pm *ProviderManager) FindPlugin(networkType string) (cloudprovider.Plugin, bool) {
 	for _, cp := range pm.CloudProviders {
 		plugins, err := cp.ListPlugins()
 		if err != nil {
@@ -65,7 +87,7 @@ func (pm *ProviderManager) FindAvailablePlugins(pod *corev1.Pod) (cloudprovider.
 		}
 		for _, p := range plugins {
 			// TODO add multi plugins supported
-			if p.Name() == pluginType {
+			if p.Name() == networkType {
 				return p, true
 			}
 		}
@@ -73,7 +95,9 @@ func (pm *ProviderManager) FindAvailablePlugins(pod *corev1.Pod) (cloudprovider.
 	return nil, false
 }
 
-func (pm *ProviderManager) Init(client client.Client) {
+// Init initializes all the plugins of registered cloud providers.
+// It returns promptly once ctx is done, leaving the remaining plugins uninitialized.
+func (pm *ProviderManager) Init(client client.Client, ctx context.Context) {
 	for _, cp := range pm.CloudProviders {
 		name := cp.Name()
 		plugins, err := cp.ListPlugins()
@@ -82,13 +106,120 @@ func (pm *ProviderManager) Init(client client.Client) {
 		}
 		log.Infof("Cloud Provider [%s] has been registered with %d plugins", name, len(plugins))
 		for _, p := range plugins {
-			err := p.Init(client, pm.FindConfigs(cp.Name()), context.Background())
+			if ctx.Err() != nil {
+				log.Warningf("cloud provider manager stops init before plugin [%s], because of %s", p.Name(), ctx.Err().Error())
+				return
+			}
+			err := p.Init(client, pm.FindConfigs(cp.Name()), ctx)
+			pm.setInitResult(p.Name(), err)
 			if err != nil {
+				log.Errorf("plugin [%s] failed to init, because of %s", p.Name(), err.Error())
 				continue
 			}
 			log.Infof("plugin [%s] has been registered", p.Name())
 		}
 	}
+	pm.initLock.Lock()
+	pm.initialized = true
+	pm.initLock.Unlock()
+}
+
+func (pm *ProviderManager) setInitResult(pluginName string, err error) {
+	pm.initLock.Lock()
+	defer pm.initLock.Unlock()
+	if pm.initResults == nil {
+		pm.initResults = make(map[string]error)
+	}
+	pm.initResults[pluginName] = err
+}
+
+// ReadyzCheck is a healthz.Checker reporting the init status of plugins.
+// It fails until Init has gone through all the plugins, or if any of them failed to init,
+// with the failed plugins and their errors in the message.
+func (pm *ProviderManager) ReadyzCheck(_ *http.Request) error {
+	pm.initLock.RLock()
+	defer pm.initLock.RUnlock()
+	if !pm.initialized {
+		return fmt.Errorf("cloud provider manager is not initialized yet")
+	}
+	var failed []string
+	for name, err := range pm.initResults {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("plugin [%s] failed to init: %s", name, err.Error()))
+		}
+	}
+	if len(failed) != 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// DebugState returns the allocation state of the plugins implementing cloudprovider.Debuggable, keyed by plugin name.
+func (pm *ProviderManager) DebugState() map[string]interface{} {
+	state := make(map[string]interface{})
+	for _, cp := range pm.CloudProviders {
+		plugins, err := cp.ListPlugins()
+		if err != nil {
+			continue
+		}
+		for _, p := range plugins {
+			if d, ok := p.(cloudprovider.Debuggable); ok {
+				state[p.Name()] = d.DebugState()
+			}
+		}
+	}
+	return state
+}
+
+// SetEventRecorder sets the event recorder of the plugins implementing cloudprovider.EventRecordable.
+func (pm *ProviderManager) SetEventRecorder(recorder record.EventRecorder) {
+	for _, cp := range pm.CloudProviders {
+		plugins, err := cp.ListPlugins()
+		if err != nil {
+			continue
+		}
+		for _, p := range plugins {
+			if r, ok := p.(cloudprovider.EventRecordable); ok {
+				r.SetEventRecorder(recorder)
+			}
+		}
+	}
+}
+
+// RegisterMetrics registers the metrics of the plugins implementing cloudprovider.MetricsRegistrable.
+// The plugins failing to register are logged, without affecting the others.
+func (pm *ProviderManager) RegisterMetrics(registry prometheus.Registerer) {
+	for _, cp := range pm.CloudProviders {
+		plugins, err := cp.ListPlugins()
+		if err != nil {
+			continue
+		}
+		for _, p := range plugins {
+			if r, ok := p.(cloudprovider.MetricsRegistrable); ok {
+				if err := r.RegisterMetrics(registry); err != nil {
+					log.Errorf("plugin [%s] failed to register metrics, because of %s", p.Name(), err.Error())
+				}
+			}
+		}
+	}
+}
+
+// DebugHandler serves the allocation state of plugins as JSON. It is read-only.
+func (pm *ProviderManager) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := json.Marshal(pm.DebugState())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
 }
 
 // NewProviderManager return a new cloud provider manager instance
@@ -161,5 +292,26 @@ func NewProviderManager() (*ProviderManager, error) {
 		}
 	}
 
+	if configs.HuaweiCloudOptions.Valid() && configs.HuaweiCloudOptions.Enabled() {
+		// build and register huawei cloud provider
+		hcp, err := huaweicloud.NewHuaweiCloudProvider()
+		if err != nil {
+			log.Errorf("Failed to initialize huaweicloud provider.because of %s", err.Error())
+		} else {
+			pm.RegisterCloudProvider(hcp, configs.HuaweiCloudOptions)
+		}
+	}
+
+	if cloudprovider.Opt.EnableMockProvider {
+		// build and register mock provider, only for development and testing
+		mp, err := mock.NewMockProvider()
+		if err != nil {
+			log.Errorf("Failed to initialize mock provider.because of %s", err.Error())
+		} else {
+			log.Warning("Mock cloud provider is enabled, it should never be used in production")
+			pm.RegisterCloudProvider(mp, options.MockOptions{})
+		}
+	}
+
 	return pm, nil
 }
@@ -0,0 +1,248 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"github.com/openkruise/kruise-game/cloudprovider"
+	cperrors "github.com/openkruise/kruise-game/cloudprovider/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"net/http"
+	"net/http/httptest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"strings"
+	"testing"
+)
+
+type fakePlugin struct {
+	name      string
+	initTimes *int
+	initErr   error
+}
+
+func (f fakePlugin) Name() string {
+	return f.name
+}
+
+func (f fakePlugin) Alias() string {
+	return f.name
+}
+
+func (f fakePlugin) Init(client client.Client, options cloudprovider.CloudProviderOptions, ctx context.Context) error {
+	if f.initTimes != nil {
+		*f.initTimes++
+	}
+	return f.initErr
+}
+
+func (f fakePlugin) OnPodAdded(client client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, cperrors.PluginError) {
+	return pod, nil
+}
+
+func (f fakePlugin) OnPodUpdated(client client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, cperrors.PluginError) {
+	return pod, nil
+}
+
+func (f fakePlugin) OnPodDeleted(client client.Client, pod *corev1.Pod, ctx context.Context) cperrors.PluginError {
+	return nil
+}
+
+type fakeDebuggablePlugin struct {
+	fakePlugin
+	state map[string]string
+}
+
+func (f fakeDebuggablePlugin) DebugState() interface{} {
+	return f.state
+}
+
+type fakeMetricsPlugin struct {
+	fakePlugin
+	metricName string
+}
+
+func (f fakeMetricsPlugin) RegisterMetrics(registry prometheus.Registerer) error {
+	return registry.Register(prometheus.NewCounter(prometheus.CounterOpts{Name: f.metricName, Help: f.name}))
+}
+
+type fakeCloudProvider struct {
+	plugins map[string]cloudprovider.Plugin
+}
+
+func (f fakeCloudProvider) Name() string {
+	return "Fake"
+}
+
+func (f fakeCloudProvider) ListPlugins() (map[string]cloudprovider.Plugin, error) {
+	return f.plugins, nil
+}
+
+func TestProviderManagerInit(t *testing.T) {
+	tests := []struct {
+		canceled  bool
+		initTimes int
+	}{
+		{
+			canceled:  false,
+			initTimes: 2,
+		},
+		{
+			canceled:  true,
+			initTimes: 0,
+		},
+	}
+
+	for i, test := range tests {
+		initTimes := 0
+		pm := &ProviderManager{
+			CloudProviders: map[string]cloudprovider.CloudProvider{
+				"Fake": fakeCloudProvider{
+					plugins: map[string]cloudprovider.Plugin{
+						"Fake-A": fakePlugin{name: "Fake-A", initTimes: &initTimes},
+						"Fake-B": fakePlugin{name: "Fake-B", initTimes: &initTimes},
+					},
+				},
+			},
+			CPOptions: map[string]cloudprovider.CloudProviderOptions{},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		if test.canceled {
+			cancel()
+		}
+		pm.Init(nil, ctx)
+		cancel()
+		if initTimes != test.initTimes {
+			t.Errorf("case %d: expect %d plugins inited, but actually %d", i, test.initTimes, initTimes)
+		}
+	}
+}
+
+func TestProviderManagerDebugHandler(t *testing.T) {
+	tests := []struct {
+		method string
+		code   int
+		body   string
+	}{
+		{
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			body:   `{"Fake-B":{"default/pod-0":"1.1.1.1:8000"}}`,
+		},
+		{
+			method: http.MethodPost,
+			code:   http.StatusMethodNotAllowed,
+		},
+	}
+
+	pm := &ProviderManager{
+		CloudProviders: map[string]cloudprovider.CloudProvider{
+			"Fake": fakeCloudProvider{
+				plugins: map[string]cloudprovider.Plugin{
+					"Fake-A": fakePlugin{name: "Fake-A"},
+					"Fake-B": fakeDebuggablePlugin{
+						fakePlugin: fakePlugin{name: "Fake-B"},
+						state:      map[string]string{"default/pod-0": "1.1.1.1:8000"},
+					},
+				},
+			},
+		},
+		CPOptions: map[string]cloudprovider.CloudProviderOptions{},
+	}
+	for i, test := range tests {
+		recorder := httptest.NewRecorder()
+		pm.DebugHandler().ServeHTTP(recorder, httptest.NewRequest(test.method, "/debug/network", nil))
+		if recorder.Code != test.code {
+			t.Errorf("case %d: expect code %d, but actually got %d", i, test.code, recorder.Code)
+		}
+		if test.body != "" && recorder.Body.String() != test.body {
+			t.Errorf("case %d: expect body %s, but actually got %s", i, test.body, recorder.Body.String())
+		}
+	}
+}
+
+func TestProviderManagerReadyzCheck(t *testing.T) {
+	tests := []struct {
+		initialized bool
+		initErr     error
+		code        int
+		body        string
+	}{
+		// case 0: not initialized yet
+		{
+			initialized: false,
+			code:        http.StatusInternalServerError,
+			body:        "cloud provider manager is not initialized yet",
+		},
+		// case 1: all plugins initialized
+		{
+			initialized: true,
+			code:        http.StatusOK,
+			body:        "ok",
+		},
+		// case 2: a plugin failed to init
+		{
+			initialized: true,
+			initErr:     fmt.Errorf("prewarm failed"),
+			code:        http.StatusInternalServerError,
+			body:        "plugin [Fake-B] failed to init: prewarm failed",
+		},
+	}
+
+	for i, test := range tests {
+		pm := &ProviderManager{
+			CloudProviders: map[string]cloudprovider.CloudProvider{
+				"Fake": fakeCloudProvider{
+					plugins: map[string]cloudprovider.Plugin{
+						"Fake-A": fakePlugin{name: "Fake-A"},
+						"Fake-B": fakePlugin{name: "Fake-B", initErr: test.initErr},
+					},
+				},
+			},
+			CPOptions: map[string]cloudprovider.CloudProviderOptions{},
+		}
+		if test.initialized {
+			pm.Init(nil, context.Background())
+		}
+		handler := &healthz.Handler{Checks: map[string]healthz.Checker{"cloudprovider": pm.ReadyzCheck}}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/cloudprovider", nil))
+		if recorder.Code != test.code {
+			t.Errorf("case %d: expect code %d, but actually got %d", i, test.code, recorder.Code)
+		}
+		if !strings.Contains(recorder.Body.String(), test.body) {
+			t.Errorf("case %d: expect body containing %s, but actually got %s", i, test.body, recorder.Body.String())
+		}
+		if test.initErr != nil && strings.Contains(recorder.Body.String(), "Fake-A") {
+			t.Errorf("case %d: expect only failed plugins reported, but actually got %s", i, recorder.Body.String())
+		}
+	}
+}
+
+func TestProviderManagerRegisterMetrics(t *testing.T) {
+	pm := &ProviderManager{
+		CloudProviders: map[string]cloudprovider.CloudProvider{
+			"Fake": fakeCloudProvider{
+				plugins: map[string]cloudprovider.Plugin{
+					"Fake-A": fakePlugin{name: "Fake-A"},
+					"Fake-B": fakeMetricsPlugin{fakePlugin: fakePlugin{name: "Fake-B"}, metricName: "okg_fake_b_total"},
+					"Fake-C": fakeMetricsPlugin{fakePlugin: fakePlugin{name: "Fake-C"}, metricName: "okg_fake_c_total"},
+				},
+			},
+		},
+		CPOptions: map[string]cloudprovider.CloudProviderOptions{},
+	}
+	registry := prometheus.NewRegistry()
+	pm.RegisterMetrics(registry)
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, mf := range mfs {
+		names = append(names, mf.GetName())
+	}
+	if strings.Join(names, ",") != "okg_fake_b_total,okg_fake_c_total" {
+		t.Errorf("expect metrics okg_fake_b_total and okg_fake_c_total registered, but actually got %v", names)
+	}
+}
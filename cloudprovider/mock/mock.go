@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mock
+
+import (
+	"github.com/openkruise/kruise-game/cloudprovider"
+	"k8s.io/klog/v2"
+)
+
+const (
+	Mock = "Mock"
+)
+
+var (
+	mockProvider = &Provider{
+		plugins: make(map[string]cloudprovider.Plugin),
+	}
+)
+
+// Provider is a cloud provider for local development and e2e tests, whose plugins fabricate network resources.
+// It is registered only when --enable-mock-provider is set, and must never be used in production.
+type Provider struct {
+	plugins map[string]cloudprovider.Plugin
+}
+
+func (mp *Provider) Name() string {
+	return Mock
+}
+
+func (mp *Provider) ListPlugins() (map[string]cloudprovider.Plugin, error) {
+	if mp.plugins == nil {
+		return make(map[string]cloudprovider.Plugin), nil
+	}
+
+	return mp.plugins, nil
+}
+
+// register plugin of cloud provider and different cloud providers
+func (mp *Provider) registerPlugin(plugin cloudprovider.Plugin) {
+	name := plugin.Name()
+	if name == "" {
+		klog.Fatal("empty plugin name")
+	}
+	mp.plugins[name] = plugin
+}
+
+func NewMockProvider() (cloudprovider.CloudProvider, error) {
+	return mockProvider, nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mock
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"time"
+
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
+	"github.com/openkruise/kruise-game/cloudprovider/errors"
+	"github.com/openkruise/kruise-game/cloudprovider/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	MockNetwork                    = "Mock-Network"
+	AliasMockNetwork               = "Mock-Network"
+	SimulateDelaySecondsConfigName = "SimulateDelaySeconds"
+	SimulateFailRateConfigName     = "SimulateFailRate"
+)
+
+// NetworkPlugin marks the network Ready with fabricated addresses. The external IP is taken from
+// 192.0.2.0/24 (TEST-NET-1), and the ports are the container ports of the pod.
+type NetworkPlugin struct {
+	// randFloat returns a number in [0.0,1.0), used to simulate failures.
+	randFloat func() float64
+}
+
+type mockConfig struct {
+	delay    time.Duration
+	failRate float64
+}
+
+func (m *NetworkPlugin) Name() string {
+	return MockNetwork
+}
+
+func (m *NetworkPlugin) Alias() string {
+	return AliasMockNetwork
+}
+
+func (m *NetworkPlugin) Init(client client.Client, options cloudprovider.CloudProviderOptions, ctx context.Context) error {
+	return nil
+}
+
+func (m *NetworkPlugin) OnPodAdded(client client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, errors.PluginError) {
+	return pod, nil
+}
+
+func (m *NetworkPlugin) OnPodUpdated(client client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, errors.PluginError) {
+	networkManager := utils.NewNetworkManager(pod, client)
+
+	networkStatus, _ := networkManager.GetNetworkStatus()
+	if networkStatus == nil {
+		pod, err := networkManager.UpdateNetworkStatus(gamekruiseiov1alpha1.NetworkStatus{
+			CurrentNetworkState: gamekruiseiov1alpha1.NetworkWaiting,
+		}, pod)
+		return pod, errors.ToPluginError(err, errors.InternalError)
+	}
+	if networkStatus.CurrentNetworkState == gamekruiseiov1alpha1.NetworkReady {
+		return pod, nil
+	}
+
+	conf, err := parseMockConfig(networkManager.GetNetworkConfig())
+	if err != nil {
+		return pod, errors.ToPluginError(err, errors.ParameterError)
+	}
+
+	if remaining := conf.delay - time.Since(pod.GetCreationTimestamp().Time); remaining > 0 {
+		return pod, errors.NewRequeueError(remaining, "pod %s/%s network is delayed for %v", pod.GetNamespace(), pod.GetName(), remaining)
+	}
+
+	if conf.failRate > 0 && m.random() < conf.failRate {
+		return pod, errors.NewPluginError(errors.ApiCallError, "simulated failure of pod %s/%s", pod.GetNamespace(), pod.GetName())
+	}
+
+	var ports []gamekruiseiov1alpha1.NetworkPort
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			port := intstr.FromInt(int(p.ContainerPort))
+			name := p.Name
+			if name == "" {
+				name = strconv.Itoa(int(p.ContainerPort))
+			}
+			ports = append(ports, gamekruiseiov1alpha1.NetworkPort{
+				Name:     name,
+				Protocol: p.Protocol,
+				Port:     &port,
+			})
+		}
+	}
+	networkStatus.InternalAddresses = []gamekruiseiov1alpha1.NetworkAddress{
+		{
+			IP:    pod.Status.PodIP,
+			Ports: ports,
+		},
+	}
+	networkStatus.ExternalAddresses = []gamekruiseiov1alpha1.NetworkAddress{
+		{
+			IP:    fakeExternalIP(pod),
+			Ports: ports,
+		},
+	}
+	networkStatus.CurrentNetworkState = gamekruiseiov1alpha1.NetworkReady
+
+	pod, err = networkManager.UpdateNetworkStatus(*networkStatus, pod)
+	return pod, errors.ToPluginError(err, errors.InternalError)
+}
+
+func (m *NetworkPlugin) OnPodDeleted(client client.Client, pod *corev1.Pod, ctx context.Context) errors.PluginError {
+	return nil
+}
+
+func (m *NetworkPlugin) random() float64 {
+	if m.randFloat != nil {
+		return m.randFloat()
+	}
+	return rand.Float64()
+}
+
+func parseMockConfig(conf []gamekruiseiov1alpha1.NetworkConfParams) (*mockConfig, error) {
+	mc := &mockConfig{}
+	for _, c := range conf {
+		switch c.Name {
+		case SimulateDelaySecondsConfigName:
+			seconds, err := strconv.Atoi(c.Value)
+			if err != nil || seconds < 0 {
+				return nil, fmt.Errorf("invalid %s %s", SimulateDelaySecondsConfigName, c.Value)
+			}
+			mc.delay = time.Duration(seconds) * time.Second
+		case SimulateFailRateConfigName:
+			rate, err := strconv.ParseFloat(c.Value, 64)
+			if err != nil || rate < 0 || rate > 1 {
+				return nil, fmt.Errorf("invalid %s %s, it should be in [0,1]", SimulateFailRateConfigName, c.Value)
+			}
+			mc.failRate = rate
+		}
+	}
+	return mc, nil
+}
+
+// fakeExternalIP returns a stable address in 192.0.2.0/24 for the pod.
+func fakeExternalIP(pod *corev1.Pod) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(pod.GetNamespace() + "/" + pod.GetName()))
+	return fmt.Sprintf("192.0.2.%d", h.Sum32()%254+1)
+}
+
+func init() {
+	mockProvider.registerPlugin(&NetworkPlugin{})
+}
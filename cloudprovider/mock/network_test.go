@@ -0,0 +1,130 @@
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider/errors"
+	"github.com/openkruise/kruise-game/cloudprovider/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNetworkPlugin_OnPodUpdated(t *testing.T) {
+	waiting := `{"currentNetworkState":"Waiting"}`
+	tests := []struct {
+		conf          string
+		status        string
+		created       time.Time
+		randFloat     float64
+		expectState   gamekruiseiov1alpha1.NetworkState
+		expectErrType errors.PluginErrorType
+	}{
+		// case 0: no network status yet
+		{
+			conf:        `[]`,
+			created:     time.Now(),
+			expectState: gamekruiseiov1alpha1.NetworkWaiting,
+		},
+		// case 1: ready immediately
+		{
+			conf:        `[]`,
+			status:      waiting,
+			created:     time.Now(),
+			expectState: gamekruiseiov1alpha1.NetworkReady,
+		},
+		// case 2: delayed
+		{
+			conf:          `[{"name":"SimulateDelaySeconds","value":"60"}]`,
+			status:        waiting,
+			created:       time.Now(),
+			expectState:   gamekruiseiov1alpha1.NetworkWaiting,
+			expectErrType: errors.RequeueError,
+		},
+		// case 3: delay passed
+		{
+			conf:        `[{"name":"SimulateDelaySeconds","value":"60"}]`,
+			status:      waiting,
+			created:     time.Now().Add(-2 * time.Minute),
+			expectState: gamekruiseiov1alpha1.NetworkReady,
+		},
+		// case 4: simulated failure
+		{
+			conf:          `[{"name":"SimulateFailRate","value":"0.5"}]`,
+			status:        waiting,
+			created:       time.Now(),
+			randFloat:     0.2,
+			expectState:   gamekruiseiov1alpha1.NetworkWaiting,
+			expectErrType: errors.ApiCallError,
+		},
+		// case 5: failure not hit
+		{
+			conf:        `[{"name":"SimulateFailRate","value":"0.5"}]`,
+			status:      waiting,
+			created:     time.Now(),
+			randFloat:   0.8,
+			expectState: gamekruiseiov1alpha1.NetworkReady,
+		},
+		// case 6: invalid config
+		{
+			conf:          `[{"name":"SimulateFailRate","value":"2"}]`,
+			status:        waiting,
+			created:       time.Now(),
+			expectState:   gamekruiseiov1alpha1.NetworkWaiting,
+			expectErrType: errors.ParameterError,
+		},
+	}
+
+	for i, test := range tests {
+		annotations := map[string]string{
+			gamekruiseiov1alpha1.GameServerNetworkType: MockNetwork,
+			gamekruiseiov1alpha1.GameServerNetworkConf: test.conf,
+		}
+		if test.status != "" {
+			annotations[gamekruiseiov1alpha1.GameServerNetworkStatus] = test.status
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "xxx-0",
+				Namespace:         "xxx",
+				Annotations:       annotations,
+				CreationTimestamp: metav1.NewTime(test.created),
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  "game",
+						Ports: []corev1.ContainerPort{{ContainerPort: 7777, Protocol: corev1.ProtocolUDP}},
+					},
+				},
+			},
+			Status: corev1.PodStatus{
+				PodIP: "10.0.0.1",
+			},
+		}
+		randFloat := test.randFloat
+		plugin := &NetworkPlugin{randFloat: func() float64 { return randFloat }}
+
+		newPod, pErr := plugin.OnPodUpdated(nil, pod, context.Background())
+		var errType errors.PluginErrorType
+		if pErr != nil {
+			errType = pErr.Type()
+		}
+		if errType != test.expectErrType {
+			t.Errorf("case %d: expect error type %s, but actually got %s", i, test.expectErrType, errType)
+		}
+
+		status, _ := utils.NewNetworkManager(newPod, nil).GetNetworkStatus()
+		if status == nil || status.CurrentNetworkState != test.expectState {
+			t.Errorf("case %d: expect network state %s, but actually got %v", i, test.expectState, status)
+			continue
+		}
+		if test.expectState == gamekruiseiov1alpha1.NetworkReady {
+			if len(status.ExternalAddresses) != 1 || status.ExternalAddresses[0].IP != fakeExternalIP(pod) || len(status.ExternalAddresses[0].Ports) != 1 {
+				t.Errorf("case %d: unexpected external addresses %v", i, status.ExternalAddresses)
+			}
+		}
+	}
+}
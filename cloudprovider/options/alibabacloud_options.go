@@ -16,6 +16,8 @@ type NLBOptions struct {
 	MaxPort    int32   `toml:"max_port"`
 	MinPort    int32   `toml:"min_port"`
 	BlockPorts []int32 `toml:"block_ports"`
+	// PortUtilizationThreshold is the port utilization of an NLB, in (0, 1], above which a warning event is emitted.
+	PortUtilizationThreshold float64 `toml:"port_utilization_threshold"`
 }
 
 func (o AlibabaCloudOptions) Valid() bool {
@@ -45,6 +47,9 @@ func (o AlibabaCloudOptions) Valid() bool {
 	if nlbOptions.MinPort <= 0 {
 		return false
 	}
+	if nlbOptions.PortUtilizationThreshold < 0 || nlbOptions.PortUtilizationThreshold > 1 {
+		return false
+	}
 	return true
 }
 
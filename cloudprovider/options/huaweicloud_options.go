@@ -0,0 +1,35 @@
+package options
+
+type HuaweiCloudOptions struct {
+	Enable     bool       `toml:"enable"`
+	ELBOptions ELBOptions `toml:"elb"`
+}
+
+type ELBOptions struct {
+	MaxPort    int32   `toml:"max_port"`
+	MinPort    int32   `toml:"min_port"`
+	BlockPorts []int32 `toml:"block_ports"`
+}
+
+func (o HuaweiCloudOptions) Valid() bool {
+	elbOptions := o.ELBOptions
+
+	for _, blockPort := range elbOptions.BlockPorts {
+		if blockPort >= elbOptions.MaxPort || blockPort < elbOptions.MinPort {
+			return false
+		}
+	}
+
+	if elbOptions.MaxPort > 65535 {
+		return false
+	}
+
+	if elbOptions.MinPort < 1 {
+		return false
+	}
+	return true
+}
+
+func (o HuaweiCloudOptions) Enabled() bool {
+	return o.Enable
+}
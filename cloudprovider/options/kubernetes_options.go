@@ -3,6 +3,7 @@ package options
 type KubernetesOptions struct {
 	Enable   bool            `toml:"enable"`
 	HostPort HostPortOptions `toml:"hostPort"`
+	NodePort NodePortOptions `toml:"nodePort"`
 }
 
 type HostPortOptions struct {
@@ -10,6 +11,19 @@ type HostPortOptions struct {
 	MinPort int32 `toml:"min_port"`
 }
 
+// NodePortOptions restricts the node ports allocated by Kubernetes-NodePort to [MinPort, MaxPort] except BlockPorts.
+// It should be a sub-range of the node port range of the cluster. If not set, node ports are allocated by Kubernetes.
+type NodePortOptions struct {
+	MaxPort    int32   `toml:"max_port"`
+	MinPort    int32   `toml:"min_port"`
+	BlockPorts []int32 `toml:"block_ports"`
+}
+
+// Enabled returns whether the node port range is set.
+func (o NodePortOptions) Enabled() bool {
+	return o.MaxPort != 0 || o.MinPort != 0
+}
+
 func (o KubernetesOptions) Valid() bool {
 	// HostPort valid
 	slbOptions := o.HostPort
@@ -19,6 +33,19 @@ func (o KubernetesOptions) Valid() bool {
 	if slbOptions.MinPort <= 0 {
 		return false
 	}
+
+	// NodePort valid
+	nodePortOptions := o.NodePort
+	if nodePortOptions.Enabled() {
+		if nodePortOptions.MaxPort < nodePortOptions.MinPort || nodePortOptions.MinPort <= 0 || nodePortOptions.MaxPort > 65535 {
+			return false
+		}
+		for _, blockPort := range nodePortOptions.BlockPorts {
+			if blockPort > nodePortOptions.MaxPort || blockPort < nodePortOptions.MinPort {
+				return false
+			}
+		}
+	}
 	return true
 }
 
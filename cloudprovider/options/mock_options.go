@@ -0,0 +1,14 @@
+package options
+
+// MockOptions is used by the mock cloud provider, which is enabled by the --enable-mock-provider flag
+// instead of the config file.
+type MockOptions struct {
+}
+
+func (m MockOptions) Valid() bool {
+	return true
+}
+
+func (m MockOptions) Enabled() bool {
+	return true
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"fmt"
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type ParamType string
+
+const (
+	ParamTypeString ParamType = "string"
+	ParamTypeInt    ParamType = "int"
+	ParamTypeBool   ParamType = "bool"
+)
+
+// ParamSchema declares a network conf param accepted by a plugin.
+type ParamSchema struct {
+	Name string
+	Type ParamType
+	// Default is the value used by the plugin when the param is not set, for documentation only.
+	Default  string
+	Required bool
+	// Enum lists the valid values, compared case-insensitively. Any value is valid if it is empty.
+	Enum []string
+	// Min and Max bound the value of int params if they are set.
+	Min *int
+	Max *int
+}
+
+var (
+	paramSchemasMutex sync.RWMutex
+	paramSchemas      = make(map[string][]ParamSchema)
+)
+
+// RegisterParamSchemas records the network conf params accepted by the plugin of networkType.
+// It is usually called in the init function of the plugin.
+func RegisterParamSchemas(networkType string, schemas []ParamSchema) {
+	paramSchemasMutex.Lock()
+	defer paramSchemasMutex.Unlock()
+	paramSchemas[networkType] = schemas
+}
+
+// GetParamSchemas returns the network conf params accepted by the plugin of networkType,
+// and false if the plugin has not registered them.
+func GetParamSchemas(networkType string) ([]ParamSchema, bool) {
+	paramSchemasMutex.RLock()
+	defer paramSchemasMutex.RUnlock()
+	schemas, ok := paramSchemas[networkType]
+	return schemas, ok
+}
+
+// ValidateNetworkConf checks network conf against the param schemas. Unknown params, missing required params,
+// and values not matching the type, enum or bounds are rejected.
+func ValidateNetworkConf(schemas []ParamSchema, conf []gamekruiseiov1alpha1.NetworkConfParams) error {
+	set := make(map[string]bool)
+	for _, c := range conf {
+		schema := findParamSchema(schemas, c.Name)
+		if schema == nil {
+			return fmt.Errorf("network conf %s is unknown, valid names are %v", c.Name, paramSchemaNames(schemas))
+		}
+		if err := schema.validate(c.Value); err != nil {
+			return err
+		}
+		set[c.Name] = true
+	}
+	for _, schema := range schemas {
+		if schema.Required && !set[schema.Name] {
+			return fmt.Errorf("network conf %s is required", schema.Name)
+		}
+	}
+	return nil
+}
+
+func (s ParamSchema) validate(value string) error {
+	if len(s.Enum) != 0 {
+		valid := false
+		for _, e := range s.Enum {
+			if strings.EqualFold(value, e) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("network conf %s should be one of %v. Now it is %s", s.Name, s.Enum, value)
+		}
+	}
+	switch s.Type {
+	case ParamTypeInt:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("network conf %s should be an integer. Now it is %s", s.Name, value)
+		}
+		if s.Min != nil && v < *s.Min {
+			return fmt.Errorf("network conf %s should be greater or equal to %d. Now it is %d", s.Name, *s.Min, v)
+		}
+		if s.Max != nil && v > *s.Max {
+			return fmt.Errorf("network conf %s should be less or equal to %d. Now it is %d", s.Name, *s.Max, v)
+		}
+	case ParamTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("network conf %s should be true or false. Now it is %s", s.Name, value)
+		}
+	}
+	return nil
+}
+
+func findParamSchema(schemas []ParamSchema, name string) *ParamSchema {
+	for i := range schemas {
+		if schemas[i].Name == name {
+			return &schemas[i]
+		}
+	}
+	return nil
+}
+
+func paramSchemaNames(schemas []ParamSchema) []string {
+	names := make([]string, 0, len(schemas))
+	for _, schema := range schemas {
+		names = append(names, schema.Name)
+	}
+	return names
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// RateLimitedEventRecorder is an EventRecorder emitting at most one event per type and reason
+// for an object in an interval. The others are dropped, so that plugins waiting for cloud resources
+// and reconciled repeatedly do not spam events.
+type RateLimitedEventRecorder struct {
+	recorder  record.EventRecorder
+	interval  time.Duration
+	now       func() time.Time
+	mutex     sync.Mutex
+	lastSeen  map[string]time.Time
+	lastPrune time.Time
+}
+
+func NewRateLimitedEventRecorder(recorder record.EventRecorder, interval time.Duration) *RateLimitedEventRecorder {
+	return &RateLimitedEventRecorder{
+		recorder: recorder,
+		interval: interval,
+		now:      time.Now,
+		lastSeen: make(map[string]time.Time),
+	}
+}
+
+func (r *RateLimitedEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.allow(object, eventtype, reason) {
+		r.recorder.Event(object, eventtype, reason, message)
+	}
+}
+
+func (r *RateLimitedEventRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.allow(object, eventtype, reason) {
+		r.recorder.Eventf(object, eventtype, reason, messageFmt, args...)
+	}
+}
+
+func (r *RateLimitedEventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.allow(object, eventtype, reason) {
+		r.recorder.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+	}
+}
+
+func (r *RateLimitedEventRecorder) allow(object runtime.Object, eventtype, reason string) bool {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		// can not identify the object, never drop its events
+		return true
+	}
+	key := accessor.GetNamespace() + "/" + accessor.GetName() + "/" + string(accessor.GetUID()) + "/" + eventtype + "/" + reason
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	now := r.now()
+	r.prune(now)
+	if last, ok := r.lastSeen[key]; ok && now.Sub(last) < r.interval {
+		return false
+	}
+	r.lastSeen[key] = now
+	return true
+}
+
+// prune removes the expired records at most once an interval, to keep the records of deleted objects from piling up.
+func (r *RateLimitedEventRecorder) prune(now time.Time) {
+	if now.Sub(r.lastPrune) < r.interval {
+		return
+	}
+	for key, last := range r.lastSeen {
+		if now.Sub(last) >= r.interval {
+			delete(r.lastSeen, key)
+		}
+	}
+	r.lastPrune = now
+}
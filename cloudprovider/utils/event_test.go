@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRateLimitedEventRecorder(t *testing.T) {
+	fakeRecorder := record.NewFakeRecorder(10)
+	recorder := NewRateLimitedEventRecorder(fakeRecorder, time.Minute)
+	now := time.Now()
+	recorder.now = func() time.Time { return now }
+
+	podA := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod-a", UID: "uid-a"}}
+	podB := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod-b", UID: "uid-b"}}
+
+	tests := []struct {
+		after  time.Duration
+		pod    *corev1.Pod
+		reason string
+		expect bool
+	}{
+		// case 0: first event
+		{pod: podA, reason: "ApiCallError", expect: true},
+		// case 1: same reason within the interval
+		{after: 10 * time.Second, pod: podA, reason: "ApiCallError", expect: false},
+		// case 2: another reason of the same object
+		{pod: podA, reason: "ParameterError", expect: true},
+		// case 3: same reason of another object
+		{pod: podB, reason: "ApiCallError", expect: true},
+		// case 4: same reason after the interval
+		{after: time.Minute, pod: podA, reason: "ApiCallError", expect: true},
+		// case 5: suppressed again in the new interval
+		{after: 30 * time.Second, pod: podA, reason: "ApiCallError", expect: false},
+	}
+
+	for i, test := range tests {
+		now = now.Add(test.after)
+		recorder.Eventf(test.pod, corev1.EventTypeWarning, test.reason, "waiting for %s", "lb")
+		emitted := false
+		select {
+		case <-fakeRecorder.Events:
+			emitted = true
+		default:
+		}
+		if emitted != test.expect {
+			t.Errorf("case %d: expect emitted %v but actually got %v", i, test.expect, emitted)
+		}
+	}
+}
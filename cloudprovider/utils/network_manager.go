@@ -21,10 +21,19 @@ import (
 	"errors"
 	"github.com/openkruise/kruise-game/apis/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/json"
 	log "k8s.io/klog/v2"
+	"net"
+	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strconv"
+	"strings"
+)
+
+var (
+	networkStatusFields  = jsonFieldNames(v1alpha1.NetworkStatus{})
+	networkAddressFields = jsonFieldNames(v1alpha1.NetworkAddress{})
 )
 
 type NetworkManager struct {
@@ -77,8 +86,11 @@ func (nm *NetworkManager) GetNetworkStatus() (*v1alpha1.NetworkStatus, error) {
 	return networkStatus, nil
 }
 
+// UpdateNetworkStatus sets the network status in annotation of the pod.
+// The fields of NetworkStatus are replaced, while the extra fields set by external controllers are preserved.
 func (nm *NetworkManager) UpdateNetworkStatus(networkStatus v1alpha1.NetworkStatus, pod *corev1.Pod) (*corev1.Pod, error) {
-	networkStatusBytes, err := json.Marshal(networkStatus)
+	setLastTransitionTime(pod.Annotations[v1alpha1.GameServerNetworkStatus], &networkStatus, metav1.Now())
+	networkStatusBytes, err := mergeNetworkStatus(pod.Annotations[v1alpha1.GameServerNetworkStatus], networkStatus)
 	if err != nil {
 		log.Errorf("pod %s can not update networkStatus,because of %s", nm.pod.Name, err.Error())
 		return pod, err
@@ -87,6 +99,171 @@ func (nm *NetworkManager) UpdateNetworkStatus(networkStatus v1alpha1.NetworkStat
 	return pod, nil
 }
 
+// setLastTransitionTime keeps the lastTransitionTime of the existing status in annotation if CurrentNetworkState
+// is not changed, otherwise sets it to now.
+func setLastTransitionTime(existing string, networkStatus *v1alpha1.NetworkStatus, now metav1.Time) {
+	old := v1alpha1.NetworkStatus{}
+	if existing != "" && json.Unmarshal([]byte(existing), &old) == nil &&
+		old.CurrentNetworkState == networkStatus.CurrentNetworkState && !old.LastTransitionTime.IsZero() {
+		networkStatus.LastTransitionTime = old.LastTransitionTime
+		return
+	}
+	networkStatus.LastTransitionTime = now
+}
+
+// mergeNetworkStatus merges networkStatus into the existing one in annotation.
+// The extra fields of the existing one, which are not defined in NetworkStatus, are kept, and so are the extra
+// fields of each address if an address with the same IP is still there. Ports are always replaced as a whole.
+// If there is no extra field, networkStatus is returned as is.
+func mergeNetworkStatus(existing string, networkStatus v1alpha1.NetworkStatus) ([]byte, error) {
+	networkStatusBytes, err := json.Marshal(networkStatus)
+	if err != nil || existing == "" {
+		return networkStatusBytes, err
+	}
+	old := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(existing), &old); err != nil {
+		// an invalid existing status is simply replaced
+		return networkStatusBytes, nil
+	}
+	if !hasExtraNetworkStatusFields(old) {
+		return networkStatusBytes, nil
+	}
+
+	merged := make(map[string]interface{})
+	if err := json.Unmarshal(networkStatusBytes, &merged); err != nil {
+		return nil, err
+	}
+	for key, value := range old {
+		if !networkStatusFields[key] {
+			merged[key] = value
+		}
+	}
+	for _, key := range []string{"internalAddresses", "externalAddresses"} {
+		mergeAddressExtraFields(merged[key], old[key])
+	}
+	return json.Marshal(merged)
+}
+
+func hasExtraNetworkStatusFields(status map[string]interface{}) bool {
+	for key, value := range status {
+		if !networkStatusFields[key] {
+			return true
+		}
+		addresses, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, address := range addresses {
+			if a, ok := address.(map[string]interface{}); ok && len(extraAddressFields(a)) != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mergeAddressExtraFields copies the extra fields of old addresses to the merged ones with the same IP.
+// The address at the same index is preferred when several ones share the IP.
+func mergeAddressExtraFields(merged, old interface{}) {
+	mergedAddresses, ok := merged.([]interface{})
+	if !ok {
+		return
+	}
+	oldAddresses, _ := old.([]interface{})
+	for i, address := range mergedAddresses {
+		m, ok := address.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var match map[string]interface{}
+		if i < len(oldAddresses) {
+			if o, ok := oldAddresses[i].(map[string]interface{}); ok && o["ip"] == m["ip"] {
+				match = o
+			}
+		}
+		for j := 0; match == nil && j < len(oldAddresses); j++ {
+			if o, ok := oldAddresses[j].(map[string]interface{}); ok && o["ip"] == m["ip"] {
+				match = o
+			}
+		}
+		for key, value := range extraAddressFields(match) {
+			m[key] = value
+		}
+	}
+}
+
+func extraAddressFields(address map[string]interface{}) map[string]interface{} {
+	extra := make(map[string]interface{})
+	for key, value := range address {
+		if !networkAddressFields[key] {
+			extra[key] = value
+		}
+	}
+	return extra
+}
+
+// jsonFieldNames returns the json names of the fields of struct v.
+func jsonFieldNames(v interface{}) map[string]bool {
+	names := make(map[string]bool)
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// SetAllocatedPorts records the external ports allocated to the pod in annotation GameServerAllocatedPortsKey.
+func (nm *NetworkManager) SetAllocatedPorts(ports []v1alpha1.NetworkPort, pod *corev1.Pod) *corev1.Pod {
+	allocated := make([]string, 0, len(ports))
+	for _, port := range ports {
+		if port.Port == nil {
+			continue
+		}
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+		allocated = append(allocated, port.Name+":"+port.Port.String()+"/"+string(protocol))
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[v1alpha1.GameServerAllocatedPortsKey] = strings.Join(allocated, ",")
+	return pod
+}
+
+// SetExternalEndpoints records the external endpoints of the pod in annotation GameServerExternalEndpointsKey.
+func (nm *NetworkManager) SetExternalEndpoints(addresses []v1alpha1.NetworkAddress, pod *corev1.Pod) *corev1.Pod {
+	endpoints := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		host := address.IP
+		if host == "" {
+			host = address.EndPoint
+		}
+		if host == "" {
+			continue
+		}
+		for _, port := range address.Ports {
+			if port.Port == nil {
+				continue
+			}
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = corev1.ProtocolTCP
+			}
+			endpoints = append(endpoints, net.JoinHostPort(host, port.Port.String())+"/"+string(protocol))
+		}
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[v1alpha1.GameServerExternalEndpointsKey] = strings.Join(endpoints, ",")
+	return pod
+}
+
 func (nm *NetworkManager) GetNetworkConfig() []v1alpha1.NetworkConfParams {
 	return nm.networkConf
 }
@@ -95,6 +272,26 @@ func (nm *NetworkManager) GetNetworkType() string {
 	return nm.networkType
 }
 
+// MergeNetworkConf merges override over base. A param of override replaces the one of base with the same name,
+// which is compared case-insensitively, and the others are appended.
+func MergeNetworkConf(base, override []v1alpha1.NetworkConfParams) []v1alpha1.NetworkConfParams {
+	merged := make([]v1alpha1.NetworkConfParams, len(base))
+	copy(merged, base)
+	for _, o := range override {
+		replaced := false
+		for i := range merged {
+			if strings.EqualFold(merged[i].Name, o.Name) {
+				merged[i].Value = o.Value
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged = append(merged, o)
+		}
+	}
+	return merged
+}
+
 func NewNetworkManager(pod *corev1.Pod, client client.Client) *NetworkManager {
 	var ok bool
 	var err error
@@ -114,6 +311,14 @@ func NewNetworkManager(pod *corev1.Pod, client client.Client) *NetworkManager {
 			return nil
 		}
 	}
+	if overrideStr := pod.Annotations[v1alpha1.GameServerNetworkConfOverride]; overrideStr != "" {
+		var override []v1alpha1.NetworkConfParams
+		if err = json.Unmarshal([]byte(overrideStr), &override); err != nil {
+			log.Warningf("Pod %s has invalid network conf override and ignores it, err: %s", pod.Name, err.Error())
+		} else {
+			networkConf = MergeNetworkConf(networkConf, override)
+		}
+	}
 
 	// If valid and use status as default
 	var networkStatusStr string
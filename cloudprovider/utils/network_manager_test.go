@@ -0,0 +1,267 @@
+package utils
+
+import (
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNetworkManagerGetNetworkConfigWithOverride(t *testing.T) {
+	tests := []struct {
+		conf     string
+		override string
+		expect   []gamekruiseiov1alpha1.NetworkConfParams
+	}{
+		// case 0: no override
+		{
+			conf: `[{"name":"NlbIds","value":"nlb-a"},{"name":"PortProtocols","value":"80"}]`,
+			expect: []gamekruiseiov1alpha1.NetworkConfParams{
+				{Name: "NlbIds", Value: "nlb-a"},
+				{Name: "PortProtocols", Value: "80"},
+			},
+		},
+		// case 1: override replaces the param with the same name, and appends the others
+		{
+			conf:     `[{"name":"NlbIds","value":"nlb-a"},{"name":"PortProtocols","value":"80"}]`,
+			override: `[{"name":"portprotocols","value":"8080/UDP"},{"name":"Fixed","value":"true"}]`,
+			expect: []gamekruiseiov1alpha1.NetworkConfParams{
+				{Name: "NlbIds", Value: "nlb-a"},
+				{Name: "PortProtocols", Value: "8080/UDP"},
+				{Name: "Fixed", Value: "true"},
+			},
+		},
+		// case 2: invalid override is ignored
+		{
+			conf:     `[{"name":"NlbIds","value":"nlb-a"}]`,
+			override: `{"name":"NlbIds"}`,
+			expect: []gamekruiseiov1alpha1.NetworkConfParams{
+				{Name: "NlbIds", Value: "nlb-a"},
+			},
+		},
+	}
+
+	for i, test := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "xxx-0",
+				Annotations: map[string]string{
+					gamekruiseiov1alpha1.GameServerNetworkType:         "AlibabaCloud-NLB",
+					gamekruiseiov1alpha1.GameServerNetworkConf:         test.conf,
+					gamekruiseiov1alpha1.GameServerNetworkConfOverride: test.override,
+				},
+			},
+		}
+		actual := NewNetworkManager(pod, nil).GetNetworkConfig()
+		if !reflect.DeepEqual(actual, test.expect) {
+			t.Errorf("case %d: expect network conf %v, but actually got %v", i, test.expect, actual)
+		}
+	}
+}
+
+func TestNetworkManagerUpdateNetworkStatus(t *testing.T) {
+	status := gamekruiseiov1alpha1.NetworkStatus{
+		ExternalAddresses: []gamekruiseiov1alpha1.NetworkAddress{
+			{IP: "1.1.1.1", EndPoint: "a.example.com"},
+			{IP: "2.2.2.2"},
+		},
+		CurrentNetworkState: gamekruiseiov1alpha1.NetworkReady,
+	}
+	statusBytes, _ := json.Marshal(status)
+	tests := []struct {
+		existing string
+		expect   string
+	}{
+		// case 0: no existing status
+		{
+			existing: "",
+			expect:   string(statusBytes),
+		},
+		// case 1: no extra fields, the status is replaced
+		{
+			existing: `{"externalAddresses":[{"ip":"3.3.3.3"}],"currentNetworkState":"Waiting","createTime":null,"lastTransitionTime":null}`,
+			expect:   string(statusBytes),
+		},
+		// case 2: extra fields set by external controllers are kept
+		{
+			existing: `{"currentNetworkState":"Waiting","region":"cn-hangzhou","createTime":null,"lastTransitionTime":null}`,
+			expect:   `{"externalAddresses":[{"ip":"1.1.1.1","endPoint":"a.example.com"},{"ip":"2.2.2.2"}],"currentNetworkState":"Ready","region":"cn-hangzhou","createTime":null,"lastTransitionTime":null}`,
+		},
+		// case 3: extra fields of addresses are kept for the same IP only
+		{
+			existing: `{"externalAddresses":[{"ip":"2.2.2.2","zone":"cn-hangzhou-a"},{"ip":"3.3.3.3","zone":"cn-hangzhou-b"}],"currentNetworkState":"Ready","createTime":null,"lastTransitionTime":null}`,
+			expect:   `{"externalAddresses":[{"ip":"1.1.1.1","endPoint":"a.example.com"},{"ip":"2.2.2.2","zone":"cn-hangzhou-a"}],"currentNetworkState":"Ready","createTime":null,"lastTransitionTime":null}`,
+		},
+		// case 4: invalid existing status is replaced
+		{
+			existing: `{"currentNetworkState":`,
+			expect:   string(statusBytes),
+		},
+	}
+
+	for i, test := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "xxx-0",
+				Annotations: map[string]string{
+					gamekruiseiov1alpha1.GameServerNetworkType:   "AlibabaCloud-NLB",
+					gamekruiseiov1alpha1.GameServerNetworkStatus: test.existing,
+				},
+			},
+		}
+		pod, err := NewNetworkManager(pod, nil).UpdateNetworkStatus(status, pod)
+		if err != nil {
+			t.Errorf("case %d: unexpected error %s", i, err.Error())
+			continue
+		}
+		actual := make(map[string]interface{})
+		expect := make(map[string]interface{})
+		_ = json.Unmarshal([]byte(pod.Annotations[gamekruiseiov1alpha1.GameServerNetworkStatus]), &actual)
+		_ = json.Unmarshal([]byte(test.expect), &expect)
+		// lastTransitionTime is checked in TestNetworkManagerUpdateNetworkStatusLastTransitionTime
+		delete(actual, "lastTransitionTime")
+		delete(expect, "lastTransitionTime")
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("case %d: expect network status %s, but actually got %s", i, test.expect, pod.Annotations[gamekruiseiov1alpha1.GameServerNetworkStatus])
+		}
+	}
+}
+
+func TestNetworkManagerUpdateNetworkStatusLastTransitionTime(t *testing.T) {
+	oldTime := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	tests := []struct {
+		existing *gamekruiseiov1alpha1.NetworkStatus
+		state    gamekruiseiov1alpha1.NetworkState
+		changed  bool
+	}{
+		// case 0: no existing status
+		{
+			existing: nil,
+			state:    gamekruiseiov1alpha1.NetworkWaiting,
+			changed:  true,
+		},
+		// case 1: state changed
+		{
+			existing: &gamekruiseiov1alpha1.NetworkStatus{
+				CurrentNetworkState: gamekruiseiov1alpha1.NetworkWaiting,
+				LastTransitionTime:  oldTime,
+			},
+			state:   gamekruiseiov1alpha1.NetworkReady,
+			changed: true,
+		},
+		// case 2: state not changed
+		{
+			existing: &gamekruiseiov1alpha1.NetworkStatus{
+				CurrentNetworkState: gamekruiseiov1alpha1.NetworkNotReady,
+				LastTransitionTime:  oldTime,
+			},
+			state:   gamekruiseiov1alpha1.NetworkNotReady,
+			changed: false,
+		},
+		// case 3: state not changed, but no time recorded before
+		{
+			existing: &gamekruiseiov1alpha1.NetworkStatus{
+				CurrentNetworkState: gamekruiseiov1alpha1.NetworkNotReady,
+			},
+			state:   gamekruiseiov1alpha1.NetworkNotReady,
+			changed: true,
+		},
+	}
+
+	for i, test := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "xxx-0",
+				Annotations: map[string]string{
+					gamekruiseiov1alpha1.GameServerNetworkType: "AlibabaCloud-NLB",
+				},
+			},
+		}
+		if test.existing != nil {
+			existingBytes, _ := json.Marshal(test.existing)
+			pod.Annotations[gamekruiseiov1alpha1.GameServerNetworkStatus] = string(existingBytes)
+		}
+		before := time.Now().Truncate(time.Second)
+		nm := NewNetworkManager(pod, nil)
+		pod, err := nm.UpdateNetworkStatus(gamekruiseiov1alpha1.NetworkStatus{CurrentNetworkState: test.state}, pod)
+		if err != nil {
+			t.Errorf("case %d: unexpected error %s", i, err.Error())
+			continue
+		}
+		actual, err := nm.GetNetworkStatus()
+		if err != nil {
+			t.Errorf("case %d: unexpected error %s", i, err.Error())
+			continue
+		}
+		if test.changed && actual.LastTransitionTime.Time.Before(before) {
+			t.Errorf("case %d: expect lastTransitionTime updated, but actually got %v", i, actual.LastTransitionTime)
+		}
+		if !test.changed && !actual.LastTransitionTime.Equal(&oldTime) {
+			t.Errorf("case %d: expect lastTransitionTime %v, but actually got %v", i, oldTime, actual.LastTransitionTime)
+		}
+	}
+}
+
+func TestNetworkManagerSetExternalEndpoints(t *testing.T) {
+	port0 := intstr.FromInt(8080)
+	port1 := intstr.FromInt(9000)
+	tests := []struct {
+		addresses []gamekruiseiov1alpha1.NetworkAddress
+		expect    string
+	}{
+		// case 0: IP with ports of different protocols
+		{
+			addresses: []gamekruiseiov1alpha1.NetworkAddress{
+				{
+					IP: "47.98.1.2",
+					Ports: []gamekruiseiov1alpha1.NetworkPort{
+						{Name: "80", Port: &port0, Protocol: corev1.ProtocolTCP},
+						{Name: "90", Port: &port1, Protocol: corev1.ProtocolUDP},
+					},
+				},
+			},
+			expect: "47.98.1.2:8080/TCP,47.98.1.2:9000/UDP",
+		},
+		// case 1: hostname is used without IP, and protocol is TCP by default
+		{
+			addresses: []gamekruiseiov1alpha1.NetworkAddress{
+				{
+					EndPoint: "nlb-xxx.cn-hangzhou.nlb.aliyuncs.com",
+					Ports: []gamekruiseiov1alpha1.NetworkPort{
+						{Name: "80", Port: &port0},
+					},
+				},
+			},
+			expect: "nlb-xxx.cn-hangzhou.nlb.aliyuncs.com:8080/TCP",
+		},
+		// case 2: IPv6
+		{
+			addresses: []gamekruiseiov1alpha1.NetworkAddress{
+				{
+					IP: "2408::1",
+					Ports: []gamekruiseiov1alpha1.NetworkPort{
+						{Name: "80", Port: &port0, Protocol: corev1.ProtocolUDP},
+					},
+				},
+			},
+			expect: "[2408::1]:8080/UDP",
+		},
+		// case 3: no address
+		{
+			addresses: nil,
+			expect:    "",
+		},
+	}
+
+	for i, test := range tests {
+		pod := &corev1.Pod{}
+		actual := NewNetworkManager(pod, nil).SetExternalEndpoints(test.addresses, pod).Annotations[gamekruiseiov1alpha1.GameServerExternalEndpointsKey]
+		if actual != test.expect {
+			t.Errorf("case %d: expect external endpoints %s, but actually got %s", i, test.expect, actual)
+		}
+	}
+}
@@ -55,7 +55,6 @@ const (
 	ClbAddressTypePublic          = "PUBLIC"
 	ClbSchedulerKey               = "service.beta.kubernetes.io/volcengine-loadbalancer-scheduler"
 	ClbSchedulerWRR               = "wrr"
-	SvcSelectorKey                = "statefulset.kubernetes.io/pod-name"
 )
 
 type portAllocated map[int32]bool
@@ -482,7 +481,7 @@ func (c *ClbPlugin) consSvc(config *clbConfig, pod *corev1.Pod, client client.Cl
 		Spec: corev1.ServiceSpec{
 			Type: corev1.ServiceTypeLoadBalancer,
 			Selector: map[string]string{
-				SvcSelectorKey: pod.GetName(),
+				cloudprovider.GetSvcSelectorKey(): pod.GetName(),
 			},
 			Ports:                         svcPorts,
 			AllocateLoadBalancerNodePorts: ptr.To[bool](config.allocateLoadBalancerNodePorts),
@@ -25,10 +25,14 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
+	"github.com/openkruise/kruise-game/cloudprovider/utils"
 	"github.com/openkruise/kruise-game/pkg/util"
 )
 
@@ -176,7 +180,7 @@ func TestInitLbCache(t *testing.T) {
 				Spec: corev1.ServiceSpec{
 					Type: corev1.ServiceTypeLoadBalancer,
 					Selector: map[string]string{
-						SvcSelectorKey: "pod-A",
+						cloudprovider.DefaultSvcSelectorKey: "pod-A",
 					},
 					Ports: []corev1.ServicePort{
 						{
@@ -198,7 +202,7 @@ func TestInitLbCache(t *testing.T) {
 				Spec: corev1.ServiceSpec{
 					Type: corev1.ServiceTypeLoadBalancer,
 					Selector: map[string]string{
-						SvcSelectorKey: "pod-B",
+						cloudprovider.DefaultSvcSelectorKey: "pod-B",
 					},
 					Ports: []corev1.ServicePort{
 						{
@@ -327,7 +331,7 @@ func TestClbPlugin_consSvc(t *testing.T) {
 				Spec: corev1.ServiceSpec{
 					Type: corev1.ServiceTypeLoadBalancer,
 					Selector: map[string]string{
-						SvcSelectorKey: "test-pod",
+						cloudprovider.DefaultSvcSelectorKey: "test-pod",
 					},
 					Ports: []corev1.ServicePort{{
 						Name:     "82",
@@ -356,3 +360,69 @@ func TestClbPlugin_consSvc(t *testing.T) {
 		}
 	}
 }
+
+func TestClbPlugin_OnPodUpdated(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-0",
+			Namespace: "ns",
+			UID:       "32fqwfqfew",
+			Annotations: map[string]string{
+				gamekruiseiov1alpha1.GameServerNetworkType:   ClbNetwork,
+				gamekruiseiov1alpha1.GameServerNetworkConf:   `[{"name":"ClbIds","value":"clb-xxx"},{"name":"PortProtocols","value":"80"}]`,
+				gamekruiseiov1alpha1.GameServerNetworkStatus: `{"currentNetworkState":"NotReady"}`,
+			},
+		},
+		Status: corev1.PodStatus{
+			PodIP: "10.0.0.1",
+		},
+	}
+	c := fake.NewClientBuilder().WithObjects(pod.DeepCopy()).Build()
+	clb := &ClbPlugin{
+		maxPort:     int32(600),
+		minPort:     int32(500),
+		cache:       make(map[string]portAllocated),
+		podAllocate: make(map[string]string),
+		mutex:       sync.RWMutex{},
+	}
+
+	// the service is created with an allocated port
+	pod, pErr := clb.OnPodUpdated(c, pod, context.Background())
+	if pErr != nil {
+		t.Fatalf("unexpected error %s", pErr.Error())
+	}
+	svc := &corev1.Service{}
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: "ns", Name: "pod-0"}, svc); err != nil {
+		t.Fatalf("expect service created, but got %s", err.Error())
+	}
+	if len(svc.Spec.Ports) != 1 || svc.Spec.Ports[0].Port < clb.minPort || svc.Spec.Ports[0].Port > clb.maxPort {
+		t.Errorf("expect one port in [%d, %d], but got %v", clb.minPort, clb.maxPort, svc.Spec.Ports)
+	}
+	if svc.GetAnnotations()[ClbIdAnnotationKey] != "clb-xxx" {
+		t.Errorf("expect clb id clb-xxx, but got %s", svc.GetAnnotations()[ClbIdAnnotationKey])
+	}
+
+	// the network status is ready once the load balancer ingress is assigned
+	svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "1.1.1.1"}}
+	if err := c.Update(context.Background(), svc); err != nil {
+		t.Fatal(err)
+	}
+	pod, pErr = clb.OnPodUpdated(c, pod, context.Background())
+	if pErr != nil {
+		t.Fatalf("unexpected error %s", pErr.Error())
+	}
+	networkStatus, err := utils.NewNetworkManager(pod, c).GetNetworkStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if networkStatus.CurrentNetworkState != gamekruiseiov1alpha1.NetworkReady {
+		t.Errorf("expect network ready, but got %s", networkStatus.CurrentNetworkState)
+	}
+	if len(networkStatus.ExternalAddresses) != 1 || networkStatus.ExternalAddresses[0].IP != "1.1.1.1" ||
+		networkStatus.ExternalAddresses[0].Ports[0].Port.IntValue() != int(svc.Spec.Ports[0].Port) {
+		t.Errorf("unexpected external addresses %v", networkStatus.ExternalAddresses)
+	}
+	if len(networkStatus.InternalAddresses) != 1 || networkStatus.InternalAddresses[0].IP != "10.0.0.1" {
+		t.Errorf("unexpected internal addresses %v", networkStatus.InternalAddresses)
+	}
+}
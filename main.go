@@ -38,6 +38,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	"github.com/openkruise/kruise-game/cloudprovider"
@@ -83,6 +84,7 @@ func main() {
 	var namespace string
 	var syncPeriodStr string
 	var scaleServerAddr string
+	var enableNetworkDebug bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8082", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -92,7 +94,8 @@ func main() {
 		"Namespace if specified restricts the manager's cache to watch objects in the desired namespace. Defaults to all namespaces.")
 	flag.StringVar(&syncPeriodStr, "sync-period", "", "Determines the minimum frequency at which watched resources are reconciled.")
 	flag.StringVar(&scaleServerAddr, "scale-server-bind-address", ":6000", "The address the scale server endpoint binds to.")
-	flag.IntVar(&apiServerSustainedQPSFlag, "api-server-qps", 0, "Maximum sustained queries per second to send to the API server")
+	flag.BoolVar(&enableNetworkDebug, "enable-network-debug", false, "Enable the read-only endpoint /debug/network on the metrics server, which dumps the allocation state of network plugins.")
+	flag.IntVar(&apiServerSustainedQPSFlag, "api-server-qps", 0, "Maximum sustained queries per second to send to the API server, shared by all the clients. Throttled requests are counted by the metric okg_apiserver_client_throttled_total")
 	flag.IntVar(&apiServerBurstQPSFlag, "api-server-qps-burst", 0, "Maximum burst queries per second to send to the API server")
 
 	// Add cloud provider flags
@@ -151,6 +154,14 @@ func main() {
 		setupLog.Error(err, "unable to set up cloud provider manager")
 		os.Exit(1)
 	}
+	cloudProviderManager.RegisterMetrics(ctrlmetrics.Registry)
+
+	if enableNetworkDebug {
+		if err := mgr.AddMetricsExtraHandler("/debug/network", cloudProviderManager.DebugHandler()); err != nil {
+			setupLog.Error(err, "unable to set up network debug endpoint")
+			os.Exit(1)
+		}
+	}
 
 	// create webhook server
 	wss := webhook.NewWebhookServer(mgr, cloudProviderManager)
@@ -169,6 +180,10 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if err := mgr.AddReadyzCheck("cloudprovider", cloudProviderManager.ReadyzCheck); err != nil {
+		setupLog.Error(err, "unable to set up cloud provider ready check")
+		os.Exit(1)
+	}
 
 	signal := ctrl.SetupSignalHandler()
 	go func() {
@@ -181,7 +196,7 @@ func main() {
 		setupLog.Info("waiting for cache sync")
 		if mgr.GetCache().WaitForCacheSync(signal) {
 			setupLog.Info("cache synced, cloud provider manager start to init")
-			cloudProviderManager.Init(mgr.GetClient())
+			cloudProviderManager.Init(mgr.GetClient(), signal)
 		}
 	}()
 
@@ -225,4 +240,8 @@ func setRestConfig(c *rest.Config) {
 	if apiServerBurstQPSFlag > 0 {
 		c.Burst = apiServerBurstQPSFlag
 	}
+	// report client-side throttling, with a rate limiter shared by all the clients
+	if apiServerSustainedQPSFlag > 0 {
+		c.RateLimiter = utilclient.NewThrottleReportingRateLimiter(c.QPS, c.Burst)
+	}
 }
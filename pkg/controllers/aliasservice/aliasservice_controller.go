@@ -0,0 +1,234 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aliasservice
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	utildiscovery "github.com/openkruise/kruise-game/pkg/util/discovery"
+)
+
+const (
+	FlipAliasServiceReason     = "FlipAliasService"
+	MultipleActiveAliasReason  = "MultipleActiveAlias"
+	concurrentReconciles       = 1
+	aliasServiceControllerName = "aliasservice-controller"
+)
+
+var (
+	gssKind = gamekruiseiov1alpha1.SchemeGroupVersion.WithKind("GameServerSet")
+)
+
+func Add(mgr manager.Manager) error {
+	if !utildiscovery.DiscoverGVK(gssKind) {
+		return nil
+	}
+	return add(mgr, newReconciler(mgr))
+}
+
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &AliasServiceReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		recorder: mgr.GetEventRecorderFor(aliasServiceControllerName),
+	}
+}
+
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	klog.Info("Starting AliasService Controller")
+	c, err := controller.New(aliasServiceControllerName, mgr, controller.Options{Reconciler: r, MaxConcurrentReconciles: concurrentReconciles})
+	if err != nil {
+		klog.Error(err)
+		return err
+	}
+
+	// the alias Services referred by GameServerSets, including the ones referred before the update
+	if err = c.Watch(&source.Kind{Type: &gamekruiseiov1alpha1.GameServerSet{}}, &handler.Funcs{
+		CreateFunc: func(createEvent event.CreateEvent, limitingInterface workqueue.RateLimitingInterface) {
+			enqueueAliasService(createEvent.Object, limitingInterface)
+		},
+		UpdateFunc: func(updateEvent event.UpdateEvent, limitingInterface workqueue.RateLimitingInterface) {
+			enqueueAliasService(updateEvent.ObjectOld, limitingInterface)
+			enqueueAliasService(updateEvent.ObjectNew, limitingInterface)
+		},
+		DeleteFunc: func(deleteEvent event.DeleteEvent, limitingInterface workqueue.RateLimitingInterface) {
+			enqueueAliasService(deleteEvent.Object, limitingInterface)
+		},
+	}); err != nil {
+		klog.Error(err)
+		return err
+	}
+
+	// the alias Services changed by others
+	if err = c.Watch(&source.Kind{Type: &corev1.Service{}}, &handler.Funcs{
+		UpdateFunc: func(updateEvent event.UpdateEvent, limitingInterface workqueue.RateLimitingInterface) {
+			if name, exist := updateEvent.ObjectNew.GetLabels()[gamekruiseiov1alpha1.GameServerSetAliasServiceKey]; exist {
+				limitingInterface.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+					Name:      name,
+					Namespace: updateEvent.ObjectNew.GetNamespace(),
+				}})
+			}
+		},
+		DeleteFunc: func(deleteEvent event.DeleteEvent, limitingInterface workqueue.RateLimitingInterface) {
+			if name, exist := deleteEvent.Object.GetLabels()[gamekruiseiov1alpha1.GameServerSetAliasServiceKey]; exist {
+				limitingInterface.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+					Name:      name,
+					Namespace: deleteEvent.Object.GetNamespace(),
+				}})
+			}
+		},
+	}); err != nil {
+		klog.Error(err)
+		return err
+	}
+	return nil
+}
+
+func enqueueAliasService(gss client.Object, limitingInterface workqueue.RateLimitingInterface) {
+	if name := gss.GetAnnotations()[gamekruiseiov1alpha1.GameServerSetAliasServiceKey]; name != "" {
+		limitingInterface.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+			Name:      name,
+			Namespace: gss.GetNamespace(),
+		}})
+	}
+}
+
+// AliasServiceReconciler reconciles the alias Services, which select the GameServers of the active GameServerSet
+// among the ones sharing them, so that their DNS names stay the same when traffic is flipped between GameServerSets.
+type AliasServiceReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;delete
+
+func (r *AliasServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	namespacedName := req.NamespacedName
+
+	gssList := &gamekruiseiov1alpha1.GameServerSetList{}
+	if err := r.List(ctx, gssList, client.InNamespace(namespacedName.Namespace)); err != nil {
+		klog.Errorf("failed to list GameServerSets in %s, because of %s.", namespacedName.Namespace, err.Error())
+		return reconcile.Result{}, err
+	}
+	var referred, active []gamekruiseiov1alpha1.GameServerSet
+	for _, gss := range gssList.Items {
+		if gss.GetDeletionTimestamp() != nil || gss.GetAnnotations()[gamekruiseiov1alpha1.GameServerSetAliasServiceKey] != namespacedName.Name {
+			continue
+		}
+		referred = append(referred, gss)
+		if gss.GetAnnotations()[gamekruiseiov1alpha1.GameServerSetAliasActiveKey] == "true" {
+			active = append(active, gss)
+		}
+	}
+
+	svc := &corev1.Service{}
+	err := r.Get(ctx, namespacedName, svc)
+	if err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("failed to get alias Service %s in %s, because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+		return reconcile.Result{}, err
+	}
+	exist := err == nil
+	if exist && svc.GetLabels()[gamekruiseiov1alpha1.GameServerSetAliasServiceKey] != namespacedName.Name {
+		klog.Warningf("Service %s in %s is not an alias Service managed by OKG, skip it.", namespacedName.Name, namespacedName.Namespace)
+		return reconcile.Result{}, nil
+	}
+
+	// no GameServerSet refers to the alias Service any more
+	if len(referred) == 0 {
+		if exist {
+			return reconcile.Result{}, client.IgnoreNotFound(r.Delete(ctx, svc))
+		}
+		return reconcile.Result{}, nil
+	}
+
+	// keep the current selector until exactly one GameServerSet is active
+	if len(active) != 1 {
+		if len(active) > 1 {
+			names := make([]string, 0, len(active))
+			for _, gss := range active {
+				names = append(names, gss.GetName())
+			}
+			sort.Strings(names)
+			for i := range active {
+				r.recorder.Eventf(&active[i], corev1.EventTypeWarning, MultipleActiveAliasReason,
+					"GameServerSets %s are all active for alias Service %s, which is not flipped until only one is active", strings.Join(names, ","), namespacedName.Name)
+			}
+		}
+		return reconcile.Result{}, nil
+	}
+
+	desired := consAliasService(namespacedName, &active[0])
+	if !exist {
+		if err := r.Create(ctx, desired); err != nil {
+			klog.Errorf("failed to create alias Service %s in %s, because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+			return reconcile.Result{}, err
+		}
+		r.recorder.Eventf(&active[0], corev1.EventTypeNormal, FlipAliasServiceReason, "alias Service %s selects GameServerSet %s", namespacedName.Name, active[0].GetName())
+		return reconcile.Result{}, nil
+	}
+	if reflect.DeepEqual(svc.Spec.Selector, desired.Spec.Selector) {
+		return reconcile.Result{}, nil
+	}
+	svc.Spec.Selector = desired.Spec.Selector
+	if err := r.Update(ctx, svc); err != nil {
+		klog.Errorf("failed to update alias Service %s in %s, because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+		return reconcile.Result{}, err
+	}
+	r.recorder.Eventf(&active[0], corev1.EventTypeNormal, FlipAliasServiceReason, "alias Service %s selects GameServerSet %s", namespacedName.Name, active[0].GetName())
+	return reconcile.Result{}, nil
+}
+
+// consAliasService returns the headless alias Service selecting the GameServers of gss.
+func consAliasService(namespacedName types.NamespacedName, gss *gamekruiseiov1alpha1.GameServerSet) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      namespacedName.Name,
+			Namespace: namespacedName.Namespace,
+			Labels: map[string]string{
+				gamekruiseiov1alpha1.GameServerSetAliasServiceKey: namespacedName.Name,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector: map[string]string{
+				gamekruiseiov1alpha1.GameServerOwnerGssKey: gss.GetName(),
+			},
+		},
+	}
+}
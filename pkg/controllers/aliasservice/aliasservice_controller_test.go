@@ -0,0 +1,185 @@
+package aliasservice
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	gameKruiseV1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var (
+	scheme = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(gameKruiseV1alpha1.AddToScheme(scheme))
+}
+
+func TestAliasServiceReconcile(t *testing.T) {
+	newGss := func(name string, active bool) *gameKruiseV1alpha1.GameServerSet {
+		annotations := map[string]string{
+			gameKruiseV1alpha1.GameServerSetAliasServiceKey: "game",
+		}
+		if active {
+			annotations[gameKruiseV1alpha1.GameServerSetAliasActiveKey] = "true"
+		}
+		return &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        name,
+				Annotations: annotations,
+			},
+		}
+	}
+	aliasService := func(selectedGss string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "game",
+				Labels: map[string]string{
+					gameKruiseV1alpha1.GameServerSetAliasServiceKey: "game",
+				},
+			},
+			Spec: corev1.ServiceSpec{
+				ClusterIP: corev1.ClusterIPNone,
+				Selector: map[string]string{
+					gameKruiseV1alpha1.GameServerOwnerGssKey: selectedGss,
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		objs     []client.Object
+		selected string
+		exist    bool
+	}{
+		// case 0: create alias Service selecting the active blue
+		{
+			objs: []client.Object{
+				newGss("blue", true),
+				newGss("green", false),
+			},
+			selected: "blue",
+			exist:    true,
+		},
+		// case 1: flip to green
+		{
+			objs: []client.Object{
+				newGss("blue", false),
+				newGss("green", true),
+				aliasService("blue"),
+			},
+			selected: "green",
+			exist:    true,
+		},
+		// case 2: both active, keep blue
+		{
+			objs: []client.Object{
+				newGss("blue", true),
+				newGss("green", true),
+				aliasService("blue"),
+			},
+			selected: "blue",
+			exist:    true,
+		},
+		// case 3: none active, keep blue
+		{
+			objs: []client.Object{
+				newGss("blue", false),
+				newGss("green", false),
+				aliasService("blue"),
+			},
+			selected: "blue",
+			exist:    true,
+		},
+		// case 4: no GameServerSet refers to it, delete it
+		{
+			objs: []client.Object{
+				aliasService("blue"),
+			},
+			exist: false,
+		},
+	}
+
+	for i, test := range tests {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(test.objs...).Build()
+		r := &AliasServiceReconciler{
+			Client:   c,
+			Scheme:   scheme,
+			recorder: record.NewFakeRecorder(10),
+		}
+		if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "xxx", Name: "game"}}); err != nil {
+			t.Errorf("case %d: expect no error but actually got %v", i, err)
+			continue
+		}
+
+		svc := &corev1.Service{}
+		err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "game"}, svc)
+		if !test.exist {
+			if !errors.IsNotFound(err) {
+				t.Errorf("case %d: expect alias Service deleted but actually got %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d: expect alias Service exists but actually got %v", i, err)
+			continue
+		}
+		expectSelector := map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: test.selected}
+		if !reflect.DeepEqual(svc.Spec.Selector, expectSelector) {
+			t.Errorf("case %d: expect selector %v but actually got %v", i, expectSelector, svc.Spec.Selector)
+		}
+	}
+}
+
+func TestAliasServiceNotManaged(t *testing.T) {
+	gss := &gameKruiseV1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "blue",
+			Annotations: map[string]string{
+				gameKruiseV1alpha1.GameServerSetAliasServiceKey: "game",
+				gameKruiseV1alpha1.GameServerSetAliasActiveKey:  "true",
+			},
+		},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "game",
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "game"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss, svc).Build()
+	r := &AliasServiceReconciler{
+		Client:   c,
+		Scheme:   scheme,
+		recorder: record.NewFakeRecorder(10),
+	}
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "xxx", Name: "game"}}); err != nil {
+		t.Fatal(err)
+	}
+	got := &corev1.Service{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "game"}, got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Spec.Selector, svc.Spec.Selector) {
+		t.Errorf("expect the Service not managed by OKG unchanged but selector got %v", got.Spec.Selector)
+	}
+}
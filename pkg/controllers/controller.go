@@ -18,6 +18,7 @@ package controller
 
 import (
 	"context"
+	"github.com/openkruise/kruise-game/pkg/controllers/aliasservice"
 	"github.com/openkruise/kruise-game/pkg/controllers/gameserver"
 	"github.com/openkruise/kruise-game/pkg/controllers/gameserverset"
 	corev1 "k8s.io/api/core/v1"
@@ -32,6 +33,7 @@ var controllerAddFuncs []func(manager.Manager) error
 func init() {
 	controllerAddFuncs = append(controllerAddFuncs, gameserver.Add)
 	controllerAddFuncs = append(controllerAddFuncs, gameserverset.Add)
+	controllerAddFuncs = append(controllerAddFuncs, aliasservice.Add)
 }
 
 func SetupWithManager(m manager.Manager) error {
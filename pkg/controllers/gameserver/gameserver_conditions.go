@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider/utils"
 	"github.com/openkruise/kruise-game/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -35,6 +36,10 @@ import (
 const (
 	pvNotFoundReason  string = "PersistentVolume Not Found"
 	pvcNotFoundReason string = "PersistentVolumeClaim Not Found"
+
+	podAndNetworkNotReadyReason string = "PodAndNetworkNotReady"
+	podNotReadyReason           string = "PodNotReady"
+	networkNotReadyReason       string = "NetworkNotReady"
 )
 
 func getConditions(ctx context.Context, c client.Client, gs *gamekruiseiov1alpha1.GameServer, eventRecorder record.EventRecorder) ([]gamekruiseiov1alpha1.GameServerCondition, error) {
@@ -136,6 +141,79 @@ func getConditions(ctx context.Context, c client.Client, gs *gamekruiseiov1alpha
 	return gsConditions, nil
 }
 
+// getServiceQualityConditions mirrors the pod conditions set by the probes of serviceQualities as GameServer conditions,
+// typed as the pod conditions, such as game.kruise.io/healthy. Those not probed yet are left out.
+func getServiceQualityConditions(serviceQualities []gamekruiseiov1alpha1.ServiceQuality, pod *corev1.Pod, oldConditions []gamekruiseiov1alpha1.GameServerCondition, now metav1.Time) []gamekruiseiov1alpha1.GameServerCondition {
+	var sqConditions []gamekruiseiov1alpha1.GameServerCondition
+	for _, sq := range serviceQualities {
+		conditionType := util.AddPrefixGameKruise(sq.Name)
+		index, podCondition := util.GetPodConditionFromList(pod.Status.Conditions, corev1.PodConditionType(conditionType))
+		if index == -1 {
+			continue
+		}
+		sqCondition := gamekruiseiov1alpha1.GameServerCondition{
+			Type:          gamekruiseiov1alpha1.GameServerConditionType(conditionType),
+			Status:        podCondition.Status,
+			LastProbeTime: podCondition.LastProbeTime,
+			Reason:        podCondition.Reason,
+			Message:       podCondition.Message,
+		}
+		oldSqCondition := getGsCondition(oldConditions, sqCondition.Type)
+		if isConditionEqual(sqCondition, oldSqCondition) {
+			sqCondition.LastTransitionTime = oldSqCondition.LastTransitionTime
+		} else {
+			sqCondition.LastTransitionTime = now
+		}
+		sqConditions = append(sqConditions, sqCondition)
+	}
+	return sqConditions
+}
+
+// getReadyCondition returns condition Ready of RequireNetworkReady, which is true only when both the pod is ready
+// and the network is Ready.
+func getReadyCondition(pod *corev1.Pod, oldConditions []gamekruiseiov1alpha1.GameServerCondition, now metav1.Time) gamekruiseiov1alpha1.GameServerCondition {
+	_, podReadyCondition := util.GetPodConditionFromList(pod.Status.Conditions, corev1.PodReady)
+	podReady := podReadyCondition != nil && podReadyCondition.Status == corev1.ConditionTrue
+	networkReady := isNetworkReady(pod)
+
+	readyCondition := gamekruiseiov1alpha1.GameServerCondition{
+		Type:   gamekruiseiov1alpha1.GameServerReady,
+		Status: corev1.ConditionTrue,
+	}
+	switch {
+	case !podReady && !networkReady:
+		readyCondition.Status = corev1.ConditionFalse
+		readyCondition.Reason = podAndNetworkNotReadyReason
+		readyCondition.Message = "pod is not ready and network is not ready"
+	case !podReady:
+		readyCondition.Status = corev1.ConditionFalse
+		readyCondition.Reason = podNotReadyReason
+		readyCondition.Message = "pod is not ready"
+	case !networkReady:
+		readyCondition.Status = corev1.ConditionFalse
+		readyCondition.Reason = networkNotReadyReason
+		readyCondition.Message = "network is not ready"
+	}
+
+	oldReadyCondition := getGsCondition(oldConditions, readyCondition.Type)
+	if isConditionEqual(readyCondition, oldReadyCondition) {
+		readyCondition.LastTransitionTime = oldReadyCondition.LastTransitionTime
+	} else {
+		readyCondition.LastTransitionTime = now
+	}
+	return readyCondition
+}
+
+// isNetworkReady returns whether the network of the pod is Ready. The pod without network is always ready.
+func isNetworkReady(pod *corev1.Pod) bool {
+	nm := utils.NewNetworkManager(pod, nil)
+	if nm == nil {
+		return true
+	}
+	networkStatus, _ := nm.GetNetworkStatus()
+	return networkStatus != nil && networkStatus.CurrentNetworkState == gamekruiseiov1alpha1.NetworkReady
+}
+
 func getPodConditions(pod *corev1.Pod) gamekruiseiov1alpha1.GameServerCondition {
 	var message string
 	var reason string
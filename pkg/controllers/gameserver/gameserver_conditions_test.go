@@ -6,6 +6,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestPolyMessageReason(t *testing.T) {
@@ -748,3 +749,173 @@ func TestGetGsCondition(t *testing.T) {
 		}
 	}
 }
+
+func TestGetServiceQualityConditions(t *testing.T) {
+	serviceQualities := []gamekruiseiov1alpha1.ServiceQuality{{Name: "healthy"}}
+	before := metav1.NewTime(time.Now().Add(-time.Hour))
+	now := metav1.Now()
+	pod := &corev1.Pod{
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{
+				{
+					Type:    "game.kruise.io/healthy",
+					Status:  corev1.ConditionTrue,
+					Message: "ok",
+				},
+			},
+		},
+	}
+	tests := []struct {
+		oldConditions []gamekruiseiov1alpha1.GameServerCondition
+		result        []gamekruiseiov1alpha1.GameServerCondition
+	}{
+		// case 0: new condition
+		{
+			oldConditions: nil,
+			result: []gamekruiseiov1alpha1.GameServerCondition{
+				{
+					Type:               "game.kruise.io/healthy",
+					Status:             corev1.ConditionTrue,
+					Message:            "ok",
+					LastTransitionTime: now,
+				},
+			},
+		},
+		// case 1: condition not changed
+		{
+			oldConditions: []gamekruiseiov1alpha1.GameServerCondition{
+				{
+					Type:               "game.kruise.io/healthy",
+					Status:             corev1.ConditionTrue,
+					Message:            "ok",
+					LastTransitionTime: before,
+				},
+			},
+			result: []gamekruiseiov1alpha1.GameServerCondition{
+				{
+					Type:               "game.kruise.io/healthy",
+					Status:             corev1.ConditionTrue,
+					Message:            "ok",
+					LastTransitionTime: before,
+				},
+			},
+		},
+		// case 2: condition changed
+		{
+			oldConditions: []gamekruiseiov1alpha1.GameServerCondition{
+				{
+					Type:               "game.kruise.io/healthy",
+					Status:             corev1.ConditionFalse,
+					Message:            "timeout",
+					LastTransitionTime: before,
+				},
+			},
+			result: []gamekruiseiov1alpha1.GameServerCondition{
+				{
+					Type:               "game.kruise.io/healthy",
+					Status:             corev1.ConditionTrue,
+					Message:            "ok",
+					LastTransitionTime: now,
+				},
+			},
+		},
+	}
+
+	for i, test := range tests {
+		actual := getServiceQualityConditions(serviceQualities, pod, test.oldConditions, now)
+		if !reflect.DeepEqual(test.result, actual) {
+			t.Errorf("case %d: expect conditions %v, but actually got %v", i, test.result, actual)
+		}
+	}
+}
+
+func TestGetReadyCondition(t *testing.T) {
+	now := metav1.Now()
+	before := metav1.NewTime(now.Add(-time.Minute))
+	tests := []struct {
+		podReady      corev1.ConditionStatus
+		networkState  gamekruiseiov1alpha1.NetworkState
+		oldConditions []gamekruiseiov1alpha1.GameServerCondition
+		expect        gamekruiseiov1alpha1.GameServerCondition
+	}{
+		// case 0: pod ready and network ready
+		{
+			podReady:     corev1.ConditionTrue,
+			networkState: gamekruiseiov1alpha1.NetworkReady,
+			expect: gamekruiseiov1alpha1.GameServerCondition{
+				Type:               gamekruiseiov1alpha1.GameServerReady,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: now,
+			},
+		},
+		// case 1: pod ready but network not ready
+		{
+			podReady:     corev1.ConditionTrue,
+			networkState: gamekruiseiov1alpha1.NetworkWaiting,
+			expect: gamekruiseiov1alpha1.GameServerCondition{
+				Type:               gamekruiseiov1alpha1.GameServerReady,
+				Status:             corev1.ConditionFalse,
+				Reason:             networkNotReadyReason,
+				Message:            "network is not ready",
+				LastTransitionTime: now,
+			},
+		},
+		// case 2: pod not ready but network ready
+		{
+			podReady:     corev1.ConditionFalse,
+			networkState: gamekruiseiov1alpha1.NetworkReady,
+			expect: gamekruiseiov1alpha1.GameServerCondition{
+				Type:               gamekruiseiov1alpha1.GameServerReady,
+				Status:             corev1.ConditionFalse,
+				Reason:             podNotReadyReason,
+				Message:            "pod is not ready",
+				LastTransitionTime: now,
+			},
+		},
+		// case 3: neither pod nor network ready, keep the last transition time of the same condition
+		{
+			podReady:     corev1.ConditionFalse,
+			networkState: gamekruiseiov1alpha1.NetworkNotReady,
+			oldConditions: []gamekruiseiov1alpha1.GameServerCondition{
+				{
+					Type:               gamekruiseiov1alpha1.GameServerReady,
+					Status:             corev1.ConditionFalse,
+					Reason:             podAndNetworkNotReadyReason,
+					Message:            "pod is not ready and network is not ready",
+					LastTransitionTime: before,
+				},
+			},
+			expect: gamekruiseiov1alpha1.GameServerCondition{
+				Type:               gamekruiseiov1alpha1.GameServerReady,
+				Status:             corev1.ConditionFalse,
+				Reason:             podAndNetworkNotReadyReason,
+				Message:            "pod is not ready and network is not ready",
+				LastTransitionTime: before,
+			},
+		},
+	}
+
+	for i, test := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "xxx-0",
+				Annotations: map[string]string{
+					gamekruiseiov1alpha1.GameServerNetworkType:   "Kubernetes-HostPort",
+					gamekruiseiov1alpha1.GameServerNetworkStatus: `{"networkType":"Kubernetes-HostPort","currentNetworkState":"` + string(test.networkState) + `"}`,
+				},
+			},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{
+					{
+						Type:   corev1.PodReady,
+						Status: test.podReady,
+					},
+				},
+			},
+		}
+		actual := getReadyCondition(pod, test.oldConditions, now)
+		if !reflect.DeepEqual(test.expect, actual) {
+			t.Errorf("case %d: expect ready condition %v but actually got %v", i, test.expect, actual)
+		}
+	}
+}
@@ -18,6 +18,7 @@ package gameserver
 
 import (
 	"context"
+	"flag"
 	"reflect"
 	"time"
 
@@ -36,6 +37,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -54,6 +56,10 @@ var (
 	concurrentReconciles = 10
 )
 
+func init() {
+	flag.IntVar(&concurrentReconciles, "gameserver-workers", concurrentReconciles, "The number of GameServers reconciled concurrently.")
+}
+
 func Add(mgr manager.Manager) error {
 	if !utildiscovery.DiscoverGVK(controllerKind) {
 		return nil
@@ -70,10 +76,15 @@ func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 	}
 }
 
+// newControllerOptions returns the options of the controller, with the workers set by flag.
+func newControllerOptions(r reconcile.Reconciler) controller.Options {
+	return controller.Options{Reconciler: r, MaxConcurrentReconciles: concurrentReconciles}
+}
+
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
 	klog.Info("Starting GameServer Controller")
-	c, err := controller.New("gameserver-controller", mgr, controller.Options{Reconciler: r, MaxConcurrentReconciles: concurrentReconciles})
+	c, err := controller.New("gameserver-controller", mgr, newControllerOptions(r))
 	if err != nil {
 		klog.Error(err)
 		return err
@@ -90,6 +101,10 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		klog.Error(err)
 		return err
 	}
+	if err = watchGameServerSet(c, mgr.GetClient()); err != nil {
+		klog.Error(err)
+		return err
+	}
 
 	return nil
 }
@@ -172,9 +187,48 @@ func watchNode(c controller.Controller, cli client.Client) error {
 	return nil
 }
 
+// watchGameServerSet enqueues the pods of GameServerSet whose network is disabled or enabled.
+func watchGameServerSet(c controller.Controller, cli client.Client) error {
+	if err := c.Watch(&source.Kind{Type: &gamekruiseiov1alpha1.GameServerSet{}}, &handler.Funcs{
+		UpdateFunc: func(updateEvent event.UpdateEvent, limitingInterface workqueue.RateLimitingInterface) {
+			gssNew := updateEvent.ObjectNew.(*gamekruiseiov1alpha1.GameServerSet)
+			gssOld := updateEvent.ObjectOld.(*gamekruiseiov1alpha1.GameServerSet)
+			if gssNetworkDisabled(gssNew) == gssNetworkDisabled(gssOld) {
+				return
+			}
+			podList := &corev1.PodList{}
+			ownerGss, _ := labels.NewRequirement(gamekruiseiov1alpha1.GameServerOwnerGssKey, selection.Equals, []string{gssNew.GetName()})
+			err := cli.List(context.Background(), podList, &client.ListOptions{
+				Namespace:     gssNew.GetNamespace(),
+				LabelSelector: labels.NewSelector().Add(*ownerGss),
+			})
+			if err != nil {
+				klog.Errorf("List Pods of GameServerSet %s/%s failed: %s", gssNew.GetNamespace(), gssNew.GetName(), err.Error())
+				return
+			}
+			for _, pod := range podList.Items {
+				limitingInterface.Add(reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Namespace: pod.GetNamespace(),
+						Name:      pod.GetName(),
+					},
+				})
+			}
+		},
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func gssNetworkDisabled(gss *gamekruiseiov1alpha1.GameServerSet) bool {
+	return gss.Spec.Network != nil && gss.Spec.Network.NetworkDisabled
+}
+
 //+kubebuilder:rbac:groups=game.kruise.io,resources=gameservers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=game.kruise.io,resources=gameservers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=game.kruise.io,resources=gameservers/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=endpoints,verbs=create;update
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -216,6 +270,17 @@ func (r *GameServerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 
 	if podFound && !gsFound {
+		if pod.GetDeletionTimestamp() != nil {
+			// the GameServer of the deleting pod is gone, and there is nothing to wait for but the network cleanup
+			gsm := NewGameServerManager(nil, pod, r.Client, r.recorder)
+			if controllerutil.ContainsFinalizer(pod, gamekruiseiov1alpha1.GameServerExternalFinalizersFinalizer) {
+				if _, err := gsm.SyncExternalFinalizers(nil); err != nil {
+					return reconcile.Result{}, err
+				}
+			}
+			cleanupAfter, err := gsm.SyncNetworkCleanup()
+			return reconcile.Result{RequeueAfter: cleanupAfter}, err
+		}
 		gss, err := r.getGameServerSet(pod)
 		if err != nil {
 			if errors.IsNotFound(err) {
@@ -244,16 +309,34 @@ func (r *GameServerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	gsm := NewGameServerManager(gs, pod, r.Client, r.recorder)
 
-	gss, err := r.getGameServerSet(pod)
+	// release the network of the deleting pod
+	cleanupAfter, err := gsm.SyncNetworkCleanup()
 	if err != nil {
-		if errors.IsNotFound(err) {
-			return ctrl.Result{}, nil
-		}
+		klog.Errorf("failed to sync network cleanup of pod %s in %s, because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+		return reconcile.Result{}, err
+	}
+
+	gss, err := r.getGameServerSet(pod)
+	if err != nil && !errors.IsNotFound(err) {
 		klog.Errorf("failed to get GameServerSet for GameServer %s in %s, because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
 		return reconcile.Result{}, err
 	}
+	gssFound := err == nil
+	if !gssFound {
+		gss = nil
+	}
+
+	// wait for the finalizers of GameServer before the pod deleted by scale-down is gone
+	finalizerAfter, err := gsm.SyncExternalFinalizers(gss)
+	if err != nil {
+		klog.Errorf("failed to sync external finalizers of GameServer %s in %s, because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+		return reconcile.Result{}, err
+	}
+	if !gssFound {
+		return ctrl.Result{RequeueAfter: finalizerAfter}, nil
+	}
 
-	err = gsm.SyncGsToPod()
+	err = gsm.SyncGsToPod(gss)
 	if err != nil {
 		return reconcile.Result{RequeueAfter: 3 * time.Second}, err
 	}
@@ -263,17 +346,41 @@ func (r *GameServerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return reconcile.Result{}, err
 	}
 
+	err = gsm.SyncOpsStateScheduling(gss)
+	if err != nil {
+		klog.Errorf("failed to sync opsState scheduling of GameServer %s in %s, because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+		return reconcile.Result{}, err
+	}
+
+	preAllocatedAfter, err := gsm.SyncPreAllocatedTimeout(gss)
+	if err != nil {
+		klog.Errorf("failed to sync PreAllocated timeout of GameServer %s in %s, because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+		return reconcile.Result{}, err
+	}
+
 	if gsm.WaitOrNot() {
-		return ctrl.Result{RequeueAfter: NetworkIntervalTime}, nil
+		return ctrl.Result{RequeueAfter: getNetworkIntervalTime(pod, gss)}, nil
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: minRequeueAfter(cleanupAfter, finalizerAfter, preAllocatedAfter)}, nil
+}
+
+// minRequeueAfter returns the shortest of the positive intervals, or 0 if there is none.
+func minRequeueAfter(intervals ...time.Duration) time.Duration {
+	var result time.Duration
+	for _, interval := range intervals {
+		if interval > 0 && (result == 0 || interval < result) {
+			result = interval
+		}
+	}
+	return result
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *GameServerReconciler) SetupWithManager(mgr ctrl.Manager) (c controller.Controller, err error) {
 	c, err = ctrl.NewControllerManagedBy(mgr).
-		For(&gamekruiseiov1alpha1.GameServer{}).Build(r)
+		For(&gamekruiseiov1alpha1.GameServer{}).
+		WithOptions(newControllerOptions(r)).Build(r)
 	return c, err
 }
 
@@ -290,6 +397,7 @@ func (r *GameServerReconciler) getGameServerSet(pod *corev1.Pod) (*gamekruiseiov
 func (r *GameServerReconciler) initGameServerByPod(gss *gamekruiseiov1alpha1.GameServerSet, pod *corev1.Pod) error {
 	// default fields
 	gs := util.InitGameServer(gss, pod.Name)
+	gs.Finalizers = append(gs.Finalizers, gss.Spec.GameServerFinalizers...)
 
 	if gss.Spec.GameServerTemplate.ReclaimPolicy == gamekruiseiov1alpha1.CascadeGameServerReclaimPolicy || gss.Spec.GameServerTemplate.ReclaimPolicy == "" {
 		// rewrite ownerReferences
@@ -18,6 +18,7 @@ package gameserver
 
 import (
 	"context"
+	"flag"
 	"reflect"
 	"testing"
 
@@ -160,6 +161,34 @@ func TestGameServerReconcile(t *testing.T) {
 				return nil
 			},
 		},
+		// the deleting pod without GameServer gets its network cleaned up, and no GameServer is created for it
+		{
+			req: ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "xxx-0",
+					Namespace: "xxx",
+				},
+			},
+			getGss: func() *gameKruiseV1alpha1.GameServerSet {
+				return gssTemplate.DeepCopy()
+			},
+			getPod: func() *corev1.Pod {
+				pod := podTemplate.DeepCopy()
+				pod.DeletionTimestamp = ptr.To(metav1.Now())
+				pod.Finalizers = []string{gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer}
+				pod.Annotations = map[string]string{gameKruiseV1alpha1.GameServerNetworkCleanedKey: "true"}
+				return pod
+			},
+			getGs: func() *gameKruiseV1alpha1.GameServer {
+				return nil
+			},
+			getNode: func() *corev1.Node {
+				return nodeTemplate.DeepCopy()
+			},
+			getExpectGs: func() *gameKruiseV1alpha1.GameServer {
+				return nil
+			},
+		},
 	}
 
 	for i, test := range tests {
@@ -216,3 +245,21 @@ func TestGameServerReconcile(t *testing.T) {
 		}
 	}
 }
+
+func TestNewControllerOptions(t *testing.T) {
+	workers := flag.CommandLine.Lookup("gameserver-workers")
+	if workers == nil {
+		t.Fatalf("expect flag gameserver-workers registered")
+	}
+	if workers.DefValue != "10" {
+		t.Errorf("expect default workers 10 but actually got %s", workers.DefValue)
+	}
+	defer workers.Value.Set(workers.DefValue)
+
+	if err := workers.Value.Set("3"); err != nil {
+		t.Fatal(err)
+	}
+	if actual := newControllerOptions(nil).MaxConcurrentReconciles; actual != 3 {
+		t.Errorf("expect MaxConcurrentReconciles 3 but actually got %d", actual)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gameserver
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+)
+
+const (
+	// DNSServiceNamePrefix is the name prefix of the Service created for each GameServer by DNSDiscovery.
+	DNSServiceNamePrefix = "server-"
+	// dnsTargetAnnotationKey records the endpoint or IP the DNS Service resolves to.
+	dnsTargetAnnotationKey = "game.kruise.io/dns-target"
+)
+
+func isDNSDiscoveryEnabled(gss *gamekruiseiov1alpha1.GameServerSet) bool {
+	return gss != nil && gss.Spec.Network != nil && gss.Spec.Network.DNSDiscovery
+}
+
+// syncDNSService creates or updates the Service resolving the DNS name of the GameServer to its first external address.
+// It returns the DNS name, or "" if the network is not Ready yet. A Service of the same name not controlled by
+// the GameServer is never modified.
+func syncDNSService(ctx context.Context, c client.Client, gs *gamekruiseiov1alpha1.GameServer, networkStatus gamekruiseiov1alpha1.NetworkStatus) (string, error) {
+	if networkStatus.CurrentNetworkState != gamekruiseiov1alpha1.NetworkReady || len(networkStatus.ExternalAddresses) == 0 {
+		return "", nil
+	}
+	address := networkStatus.ExternalAddresses[0]
+	target := address.EndPoint
+	if target == "" {
+		target = address.IP
+	}
+	if target == "" {
+		return "", nil
+	}
+	name := DNSServiceNamePrefix + gs.GetName()
+	dnsName := name + "." + gs.GetNamespace() + ".svc"
+
+	svc := &corev1.Service{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: gs.GetNamespace(), Name: name}, svc)
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	found := err == nil
+	if found && !metav1.IsControlledBy(svc, gs) {
+		return "", fmt.Errorf("Service %s already exists and is not controlled by GameServer %s", name, gs.GetName())
+	}
+	if found && svc.GetAnnotations()[dnsTargetAnnotationKey] == target {
+		return dnsName, nil
+	}
+
+	desired := consDNSService(gs, name, address, target)
+	switch {
+	case !found:
+		err = c.Create(ctx, desired)
+	case svc.Spec.Type != desired.Spec.Type:
+		// the cluster IP is immutable, so the Service is recreated when switching between ExternalName and headless
+		err = c.Delete(ctx, svc)
+		if err == nil || errors.IsNotFound(err) {
+			err = c.Create(ctx, desired)
+		}
+	default:
+		svc.SetAnnotations(desired.GetAnnotations())
+		svc.SetOwnerReferences(desired.GetOwnerReferences())
+		svc.Spec.ExternalName = desired.Spec.ExternalName
+		svc.Spec.Ports = desired.Spec.Ports
+		err = c.Update(ctx, svc)
+	}
+	if err != nil {
+		return "", err
+	}
+	if desired.Spec.Type == corev1.ServiceTypeExternalName {
+		return dnsName, nil
+	}
+	return dnsName, syncDNSEndpoints(ctx, c, desired, address)
+}
+
+// setDNSEndpoint sets the DNS name as the endpoint of the external addresses having none.
+func setDNSEndpoint(networkStatus *gamekruiseiov1alpha1.NetworkStatus, dnsName string) {
+	if dnsName == "" {
+		return
+	}
+	addresses := make([]gamekruiseiov1alpha1.NetworkAddress, len(networkStatus.ExternalAddresses))
+	copy(addresses, networkStatus.ExternalAddresses)
+	for i := range addresses {
+		if addresses[i].EndPoint == "" {
+			addresses[i].EndPoint = dnsName
+		}
+	}
+	networkStatus.ExternalAddresses = addresses
+}
+
+// consDNSService constructs an ExternalName Service for the endpoint of the address,
+// or a headless Service without selector for the IP of the address.
+func consDNSService(gs *gamekruiseiov1alpha1.GameServer, name string, address gamekruiseiov1alpha1.NetworkAddress, target string) *corev1.Service {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: gs.GetNamespace(),
+			Annotations: map[string]string{
+				dnsTargetAnnotationKey: target,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(gs, controllerKind),
+			},
+		},
+	}
+	if address.EndPoint != "" {
+		svc.Spec.Type = corev1.ServiceTypeExternalName
+		svc.Spec.ExternalName = address.EndPoint
+		return svc
+	}
+	svc.Spec.Type = corev1.ServiceTypeClusterIP
+	svc.Spec.ClusterIP = corev1.ClusterIPNone
+	for _, port := range dnsEndpointPorts(address) {
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name:     port.Name,
+			Port:     port.Port,
+			Protocol: port.Protocol,
+		})
+	}
+	return svc
+}
+
+// syncDNSEndpoints sets the IP of the address as the endpoints of the headless Service.
+func syncDNSEndpoints(ctx context.Context, c client.Client, svc *corev1.Service, address gamekruiseiov1alpha1.NetworkAddress) error {
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            svc.GetName(),
+			Namespace:       svc.GetNamespace(),
+			OwnerReferences: svc.GetOwnerReferences(),
+		},
+		Subsets: []corev1.EndpointSubset{
+			{
+				Addresses: []corev1.EndpointAddress{{IP: address.IP}},
+				Ports:     dnsEndpointPorts(address),
+			},
+		},
+	}
+	// endpoints are written only when the target changes, so they are not read from the cache
+	err := c.Create(ctx, endpoints)
+	if errors.IsAlreadyExists(err) {
+		err = c.Update(ctx, endpoints)
+	}
+	return err
+}
+
+// dnsEndpointPorts returns the numeric ports of the address, named port-{index} as the port names of
+// the address are not always valid for Services.
+func dnsEndpointPorts(address gamekruiseiov1alpha1.NetworkAddress) []corev1.EndpointPort {
+	var ports []corev1.EndpointPort
+	for _, port := range address.Ports {
+		if port.Port == nil || port.Port.IntValue() <= 0 {
+			continue
+		}
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+		ports = append(ports, corev1.EndpointPort{
+			Name:     "port-" + strconv.Itoa(len(ports)),
+			Port:     int32(port.Port.IntValue()),
+			Protocol: protocol,
+		})
+	}
+	return ports
+}
@@ -0,0 +1,232 @@
+package gameserver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	gameKruiseV1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSyncDNSService(t *testing.T) {
+	port := intstr.FromInt(30001)
+	tests := []struct {
+		networkStatus gameKruiseV1alpha1.NetworkStatus
+		existing      *corev1.Service
+		dnsName       string
+		svcType       corev1.ServiceType
+		externalName  string
+		endpoints     *corev1.EndpointSubset
+		isErr         bool
+	}{
+		// case 0: network not ready
+		{
+			networkStatus: gameKruiseV1alpha1.NetworkStatus{
+				CurrentNetworkState: gameKruiseV1alpha1.NetworkWaiting,
+				ExternalAddresses: []gameKruiseV1alpha1.NetworkAddress{
+					{EndPoint: "lb.example.com"},
+				},
+			},
+		},
+		// case 1: ExternalName service for endpoint
+		{
+			networkStatus: gameKruiseV1alpha1.NetworkStatus{
+				CurrentNetworkState: gameKruiseV1alpha1.NetworkReady,
+				ExternalAddresses: []gameKruiseV1alpha1.NetworkAddress{
+					{IP: "1.2.3.4", EndPoint: "lb.example.com"},
+				},
+			},
+			dnsName:      "server-xxx-0.xxx.svc",
+			svcType:      corev1.ServiceTypeExternalName,
+			externalName: "lb.example.com",
+		},
+		// case 2: headless service and endpoints for ip
+		{
+			networkStatus: gameKruiseV1alpha1.NetworkStatus{
+				CurrentNetworkState: gameKruiseV1alpha1.NetworkReady,
+				ExternalAddresses: []gameKruiseV1alpha1.NetworkAddress{
+					{
+						IP: "1.2.3.4",
+						Ports: []gameKruiseV1alpha1.NetworkPort{
+							{Name: "80", Port: &port},
+						},
+					},
+				},
+			},
+			dnsName: "server-xxx-0.xxx.svc",
+			svcType: corev1.ServiceTypeClusterIP,
+			endpoints: &corev1.EndpointSubset{
+				Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}},
+				Ports:     []corev1.EndpointPort{{Name: "port-0", Port: 30001, Protocol: corev1.ProtocolTCP}},
+			},
+		},
+		// case 3: endpoint changed
+		{
+			networkStatus: gameKruiseV1alpha1.NetworkStatus{
+				CurrentNetworkState: gameKruiseV1alpha1.NetworkReady,
+				ExternalAddresses: []gameKruiseV1alpha1.NetworkAddress{
+					{EndPoint: "lb-new.example.com"},
+				},
+			},
+			existing: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "xxx",
+					Name:        "server-xxx-0",
+					Annotations: map[string]string{dnsTargetAnnotationKey: "lb-old.example.com"},
+					OwnerReferences: []metav1.OwnerReference{
+						{APIVersion: "game.kruise.io/v1alpha1", Kind: "GameServer", Name: "xxx-0", UID: "xxx-0-uid", Controller: ptr.To(true)},
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:         corev1.ServiceTypeExternalName,
+					ExternalName: "lb-old.example.com",
+				},
+			},
+			dnsName:      "server-xxx-0.xxx.svc",
+			svcType:      corev1.ServiceTypeExternalName,
+			externalName: "lb-new.example.com",
+		},
+		// case 4: switched from ip to endpoint
+		{
+			networkStatus: gameKruiseV1alpha1.NetworkStatus{
+				CurrentNetworkState: gameKruiseV1alpha1.NetworkReady,
+				ExternalAddresses: []gameKruiseV1alpha1.NetworkAddress{
+					{EndPoint: "lb.example.com"},
+				},
+			},
+			existing: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "xxx",
+					Name:        "server-xxx-0",
+					Annotations: map[string]string{dnsTargetAnnotationKey: "1.2.3.4"},
+					OwnerReferences: []metav1.OwnerReference{
+						{APIVersion: "game.kruise.io/v1alpha1", Kind: "GameServer", Name: "xxx-0", UID: "xxx-0-uid", Controller: ptr.To(true)},
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:      corev1.ServiceTypeClusterIP,
+					ClusterIP: corev1.ClusterIPNone,
+				},
+			},
+			dnsName:      "server-xxx-0.xxx.svc",
+			svcType:      corev1.ServiceTypeExternalName,
+			externalName: "lb.example.com",
+		},
+		// case 5: service of the same name not controlled by gs
+		{
+			networkStatus: gameKruiseV1alpha1.NetworkStatus{
+				CurrentNetworkState: gameKruiseV1alpha1.NetworkReady,
+				ExternalAddresses: []gameKruiseV1alpha1.NetworkAddress{
+					{EndPoint: "lb.example.com"},
+				},
+			},
+			existing: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "xxx",
+					Name:      "server-xxx-0",
+				},
+				Spec: corev1.ServiceSpec{
+					Type:         corev1.ServiceTypeExternalName,
+					ExternalName: "user.example.com",
+				},
+			},
+			svcType:      corev1.ServiceTypeExternalName,
+			externalName: "user.example.com",
+			isErr:        true,
+		},
+	}
+
+	for i, test := range tests {
+		gs := &gameKruiseV1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+				UID:       "xxx-0-uid",
+			},
+		}
+		builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs)
+		if test.existing != nil {
+			builder = builder.WithObjects(test.existing)
+		}
+		c := builder.Build()
+
+		dnsName, err := syncDNSService(context.TODO(), c, gs, test.networkStatus)
+		if (err != nil) != test.isErr {
+			t.Errorf("case %d: expect error %v but actually got %v", i, test.isErr, err)
+			continue
+		}
+		if dnsName != test.dnsName {
+			t.Errorf("case %d: expect dns name %s but actually got %s", i, test.dnsName, dnsName)
+		}
+
+		svc := &corev1.Service{}
+		err = c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "server-xxx-0"}, svc)
+		if test.svcType == "" {
+			if !errors.IsNotFound(err) {
+				t.Errorf("case %d: expect no service but actually got err %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		if svc.Spec.Type != test.svcType || svc.Spec.ExternalName != test.externalName {
+			t.Errorf("case %d: expect service %s %s but actually got %s %s", i, test.svcType, test.externalName, svc.Spec.Type, svc.Spec.ExternalName)
+		}
+		if !test.isErr && !metav1.IsControlledBy(svc, gs) {
+			t.Errorf("case %d: expect service owned by gs but actually got %v", i, svc.GetOwnerReferences())
+		}
+
+		if test.endpoints != nil {
+			endpoints := &corev1.Endpoints{}
+			if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "server-xxx-0"}, endpoints); err != nil {
+				t.Errorf("case %d: unexpected error %v", i, err)
+				continue
+			}
+			if len(endpoints.Subsets) != 1 || !reflect.DeepEqual(endpoints.Subsets[0], *test.endpoints) {
+				t.Errorf("case %d: expect endpoints %v but actually got %v", i, *test.endpoints, endpoints.Subsets)
+			}
+		}
+	}
+}
+
+func TestSetDNSEndpoint(t *testing.T) {
+	tests := []struct {
+		addresses []gameKruiseV1alpha1.NetworkAddress
+		dnsName   string
+		expect    []gameKruiseV1alpha1.NetworkAddress
+	}{
+		// case 0: no dns name
+		{
+			addresses: []gameKruiseV1alpha1.NetworkAddress{{IP: "1.2.3.4"}},
+			expect:    []gameKruiseV1alpha1.NetworkAddress{{IP: "1.2.3.4"}},
+		},
+		// case 1: fill empty endpoints only
+		{
+			addresses: []gameKruiseV1alpha1.NetworkAddress{{IP: "1.2.3.4"}, {EndPoint: "lb.example.com"}},
+			dnsName:   "server-xxx-0.xxx.svc",
+			expect:    []gameKruiseV1alpha1.NetworkAddress{{IP: "1.2.3.4", EndPoint: "server-xxx-0.xxx.svc"}, {EndPoint: "lb.example.com"}},
+		},
+	}
+
+	for i, test := range tests {
+		origin := make([]gameKruiseV1alpha1.NetworkAddress, len(test.addresses))
+		copy(origin, test.addresses)
+		networkStatus := gameKruiseV1alpha1.NetworkStatus{ExternalAddresses: test.addresses}
+		setDNSEndpoint(&networkStatus, test.dnsName)
+		if !reflect.DeepEqual(networkStatus.ExternalAddresses, test.expect) {
+			t.Errorf("case %d: expect addresses %v but actually got %v", i, test.expect, networkStatus.ExternalAddresses)
+		}
+		if !reflect.DeepEqual(test.addresses, origin) {
+			t.Errorf("case %d: expect origin addresses unchanged but actually got %v", i, test.addresses)
+		}
+	}
+}
@@ -21,6 +21,7 @@ import (
 	kruisePub "github.com/openkruise/kruise-api/apps/pub"
 	gameKruiseV1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	"github.com/openkruise/kruise-game/cloudprovider/utils"
+	"github.com/openkruise/kruise-game/pkg/metrics"
 	"github.com/openkruise/kruise-game/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -29,17 +30,20 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"strconv"
 	"strings"
 	"time"
 )
 
 var (
-	NetworkTotalWaitTime = util.GetNetworkTotalWaitTime()
-	NetworkIntervalTime  = util.GetNetworkIntervalTime()
+	NetworkTotalWaitTime   = util.GetNetworkTotalWaitTime()
+	NetworkIntervalTime    = util.GetNetworkIntervalTime()
+	PodDeletionCostWeights = util.GetPodDeletionCostWeights()
 )
 
 const (
@@ -47,17 +51,43 @@ const (
 )
 
 const (
-	StateReason = "GsStateChanged"
+	StateReason       = "GsStateChanged"
+	RescheduledReason = "Rescheduled"
+	// PreAllocatedTimeoutReason is the reason of the event when GameServer in opsState PreAllocated turns back to None.
+	PreAllocatedTimeoutReason = "PreAllocatedTimeout"
+	// DefaultPreAllocatedTimeoutSeconds is the timeout of opsState PreAllocated when PreAllocatedTimeoutSeconds is not set.
+	DefaultPreAllocatedTimeoutSeconds = 60
+	// GameServerFinalizerTimeoutReason is the reason of the event when the pod stops waiting for the finalizers of GameServer.
+	GameServerFinalizerTimeoutReason = "GameServerFinalizerTimeout"
+	// DefaultGameServerFinalizerTimeoutSeconds is the timeout of waiting for the finalizers of GameServer when
+	// GameServerFinalizerTimeoutSeconds is not set.
+	DefaultGameServerFinalizerTimeoutSeconds = 300
+	// DNSServiceSyncFailedReason is the reason of the event when the DNS Service of GameServer fails to sync.
+	DNSServiceSyncFailedReason = "DNSServiceSyncFailed"
+)
+
+const (
+	statusWritePatched = "patched"
+	statusWriteSkipped = "skipped"
 )
 
 type Control interface {
 	// SyncGsToPod compares the pod with GameServer, and decide whether to update the pod based on the results.
 	// When the fields of the pod is different from that of GameServer, pod will be updated.
-	SyncGsToPod() error
+	SyncGsToPod(gss *gameKruiseV1alpha1.GameServerSet) error
 	// SyncPodToGs compares the GameServer with pod, and update the GameServer.
 	SyncPodToGs(*gameKruiseV1alpha1.GameServerSet) error
 	// WaitOrNot compare the current game server network status to decide whether to re-queue.
 	WaitOrNot() bool
+	// SyncNetworkCleanup triggers the network cleanup of the deleting pod, and returns the interval to check again.
+	SyncNetworkCleanup() (time.Duration, error)
+	// SyncOpsStateScheduling reschedules the pod when GameServer enters an opsState of OpsStateScheduling.
+	SyncOpsStateScheduling(gss *gameKruiseV1alpha1.GameServerSet) error
+	// SyncPreAllocatedTimeout turns GameServer PreAllocated for too long back to None, and returns the interval to check again.
+	SyncPreAllocatedTimeout(gss *gameKruiseV1alpha1.GameServerSet) (time.Duration, error)
+	// SyncExternalFinalizers keeps the pod deleted by scale-down until the finalizers of GameServer are removed,
+	// and returns the interval to check again.
+	SyncExternalFinalizers(gss *gameKruiseV1alpha1.GameServerSet) (time.Duration, error)
 }
 
 type GameServerManager struct {
@@ -84,7 +114,7 @@ func syncMetadataFromGss(gss *gameKruiseV1alpha1.GameServerSet) metav1.ObjectMet
 	}
 }
 
-func (manager GameServerManager) SyncGsToPod() error {
+func (manager GameServerManager) SyncGsToPod(gss *gameKruiseV1alpha1.GameServerSet) error {
 	pod := manager.pod
 	gs := manager.gameServer
 	podLabels := pod.GetLabels()
@@ -118,10 +148,12 @@ func (manager GameServerManager) SyncGsToPod() error {
 			manager.eventRecorder.Eventf(gs, eventType, StateReason, "OpsState turn from %s to %s ", podGsOpsState, string(gs.Spec.OpsState))
 		}
 	}
-	if podNetworkDisabled != strconv.FormatBool(gs.Spec.NetworkDisabled) {
-		newLabels[gameKruiseV1alpha1.GameServerNetworkDisabled] = strconv.FormatBool(gs.Spec.NetworkDisabled)
+	// the network of pod is disabled if it is disabled by either GameServer or GameServerSet
+	networkDisabled := gs.Spec.NetworkDisabled || (gss != nil && gssNetworkDisabled(gss))
+	if podNetworkDisabled != strconv.FormatBool(networkDisabled) {
+		newLabels[gameKruiseV1alpha1.GameServerNetworkDisabled] = strconv.FormatBool(networkDisabled)
 		if podNetworkDisabled != "" {
-			manager.eventRecorder.Eventf(gs, corev1.EventTypeNormal, StateReason, "NetworkDisabled turn from %s to %s ", podNetworkDisabled, strconv.FormatBool(gs.Spec.NetworkDisabled))
+			manager.eventRecorder.Eventf(gs, corev1.EventTypeNormal, StateReason, "NetworkDisabled turn from %s to %s ", podNetworkDisabled, strconv.FormatBool(networkDisabled))
 		}
 	}
 
@@ -153,7 +185,7 @@ func (manager GameServerManager) SyncGsToPod() error {
 		// GameServer Ready / NotReady
 		_, condition := util.GetPodConditionFromList(pod.Status.Conditions, corev1.PodReady)
 		if condition != nil {
-			if condition.Status == corev1.ConditionTrue {
+			if condition.Status == corev1.ConditionTrue && (gss == nil || !gss.Spec.RequireNetworkReady || isNetworkReady(pod)) {
 				gsState = gameKruiseV1alpha1.Ready
 			} else {
 				gsState = gameKruiseV1alpha1.NotReady
@@ -178,13 +210,36 @@ func (manager GameServerManager) SyncGsToPod() error {
 		}
 	}
 
+	// the pod being updated in place is protected from evictions by the PodDisruptionBudget selecting the label,
+	// until the update completes
+	inPlaceUpdating := pod.GetLabels()[gameKruiseV1alpha1.GameServerInPlaceUpdatingKey]
+	if gsState == gameKruiseV1alpha1.PreUpdate || gsState == gameKruiseV1alpha1.Updating {
+		if inPlaceUpdating != "true" {
+			newLabels[gameKruiseV1alpha1.GameServerInPlaceUpdatingKey] = "true"
+		}
+	} else if inPlaceUpdating == "true" {
+		newLabels[gameKruiseV1alpha1.GameServerInPlaceUpdatingKey] = "false"
+	}
+
+	deletionCost := util.GetPodDeletionCost(string(gs.Spec.OpsState), gs.Spec.DeletionPriority, PodDeletionCostWeights)
+	if pod.GetAnnotations()[gameKruiseV1alpha1.PodDeletionCostKey] != deletionCost {
+		newAnnotations[gameKruiseV1alpha1.PodDeletionCostKey] = deletionCost
+	}
+
 	if pod.Annotations[gameKruiseV1alpha1.GameServerNetworkType] != "" {
 		oldTime, err := time.Parse(TimeFormat, pod.Annotations[gameKruiseV1alpha1.GameServerNetworkTriggerTime])
-		if (err == nil && time.Since(oldTime) > NetworkIntervalTime && time.Since(gs.Status.NetworkStatus.LastTransitionTime.Time) < NetworkTotalWaitTime) || (pod.Annotations[gameKruiseV1alpha1.GameServerNetworkTriggerTime] == "") {
+		if (err == nil && time.Since(oldTime) > getNetworkIntervalTime(pod, gss) && time.Since(gs.Status.NetworkStatus.LastTransitionTime.Time) < NetworkTotalWaitTime) || (pod.Annotations[gameKruiseV1alpha1.GameServerNetworkTriggerTime] == "") {
 			newAnnotations[gameKruiseV1alpha1.GameServerNetworkTriggerTime] = time.Now().Format(TimeFormat)
 		}
 	}
 
+	if override := gs.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkConfOverride]; override != pod.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkConfOverride] {
+		newAnnotations[gameKruiseV1alpha1.GameServerNetworkConfOverride] = override
+	}
+	if reprovision := gs.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkReprovision]; reprovision != pod.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkReprovision] {
+		newAnnotations[gameKruiseV1alpha1.GameServerNetworkReprovision] = reprovision
+	}
+
 	// sync annotations from gs to pod
 	for gsKey, gsValue := range gs.GetAnnotations() {
 		if util.IsHasPrefixGsSyncToPod(gsKey) {
@@ -256,7 +311,7 @@ func (manager GameServerManager) SyncPodToGs(gss *gameKruiseV1alpha1.GameServerS
 		gs.SetAnnotations(util.MergeMapString(gs.GetAnnotations(), gsMetadata.GetAnnotations()))
 	}
 
-	if !reflect.DeepEqual(oldGsSpec, gs.Spec) || !reflect.DeepEqual(oldGsLabels, gs.GetLabels()) || !reflect.DeepEqual(oldGsAnnotations, gs.GetAnnotations()) {
+	if !reflect.DeepEqual(*oldGsSpec, gs.Spec) || !reflect.DeepEqual(oldGsLabels, gs.GetLabels()) || !reflect.DeepEqual(oldGsAnnotations, gs.GetAnnotations()) {
 		// patch gs spec & metadata
 		patchSpec := map[string]interface{}{"spec": gs.Spec, "metadata": map[string]interface{}{"labels": gs.GetLabels(), "annotations": gs.GetAnnotations()}}
 		jsonPatchSpec, err := json.Marshal(patchSpec)
@@ -276,6 +331,21 @@ func (manager GameServerManager) SyncPodToGs(gss *gameKruiseV1alpha1.GameServerS
 		klog.Errorf("failed to get GameServer %s Conditions in %s, because of %s.", gs.GetName(), gs.GetNamespace(), err.Error())
 		return err
 	}
+	conditions = append(conditions, getServiceQualityConditions(gss.Spec.ServiceQualities, pod, oldGsStatus.Conditions, metav1.Now())...)
+	if gss.Spec.RequireNetworkReady {
+		conditions = append(conditions, getReadyCondition(pod, oldGsStatus.Conditions, metav1.Now()))
+	}
+
+	// sync the DNS Service of gs, a failure of which does not block the status of gs
+	networkStatus := manager.syncNetworkStatus()
+	if isDNSDiscoveryEnabled(gss) {
+		dnsName, err := syncDNSService(context.TODO(), manager.client, gs, networkStatus)
+		if err != nil {
+			klog.Errorf("failed to sync DNS Service of GameServer %s in %s, because of %s.", gs.GetName(), gs.GetNamespace(), err.Error())
+			manager.eventRecorder.Eventf(gs, corev1.EventTypeWarning, DNSServiceSyncFailedReason, "failed to sync DNS Service: %s", err.Error())
+		}
+		setDNSEndpoint(&networkStatus, dnsName)
+	}
 
 	// patch gs status
 	newStatus := gameKruiseV1alpha1.GameServerStatus{
@@ -285,27 +355,307 @@ func (manager GameServerManager) SyncPodToGs(gss *gameKruiseV1alpha1.GameServerS
 		UpdatePriority:            &podUpdatePriority,
 		DeletionPriority:          &podDeletePriority,
 		ServiceQualitiesCondition: sqConditions,
-		NetworkStatus:             manager.syncNetworkStatus(),
+		NetworkStatus:             networkStatus,
 		LastTransitionTime:        oldGsStatus.LastTransitionTime,
 		Conditions:                conditions,
+		// the user state is written by the game server itself, never by OKG
+		Extra: oldGsStatus.Extra,
 	}
-	if !reflect.DeepEqual(oldGsStatus, newStatus) {
-		newStatus.LastTransitionTime = metav1.Now()
-		patchStatus := map[string]interface{}{"status": newStatus}
-		jsonPatchStatus, err := json.Marshal(patchStatus)
-		if err != nil {
-			return err
+	statusDiff, err := diffGsStatus(oldGsStatus, newStatus)
+	if err != nil {
+		return err
+	}
+	if len(statusDiff) == 0 {
+		metrics.GameServerStatusWritesTotal.WithLabelValues(statusWriteSkipped).Inc()
+		return nil
+	}
+	// all changed fields are coalesced into one patch
+	statusDiff["lastTransitionTime"] = metav1.Now()
+	patchStatus := map[string]interface{}{"status": statusDiff}
+	jsonPatchStatus, err := json.Marshal(patchStatus)
+	if err != nil {
+		return err
+	}
+	err = manager.client.Status().Patch(context.TODO(), gs, client.RawPatch(types.MergePatchType, jsonPatchStatus))
+	if err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("failed to patch GameServer Status %s in %s,because of %s.", gs.GetName(), gs.GetNamespace(), err.Error())
+		return err
+	}
+	metrics.GameServerStatusWritesTotal.WithLabelValues(statusWritePatched).Inc()
+	return nil
+}
+
+// SyncOpsStateScheduling deletes the pod to reschedule it, once GameServer enters an opsState of OpsStateScheduling
+// whose nodeSelector or tolerations the pod does not have. The recreated pod gets them from the pod webhook.
+// The pod is rescheduled at most once each time GameServer enters the opsState, and it is left alone while
+// being updated in place or if GameServer would be deleted along with it.
+func (manager GameServerManager) SyncOpsStateScheduling(gss *gameKruiseV1alpha1.GameServerSet) error {
+	gs := manager.gameServer
+	pod := manager.pod
+	opsState := string(gs.Spec.OpsState)
+	scheduled := gs.GetAnnotations()[gameKruiseV1alpha1.GameServerOpsStateScheduledKey]
+	scheduling := util.GetOpsStateScheduling(gss, gs.Spec.OpsState)
+	if scheduling == nil || scheduled == opsState {
+		if scheduled != "" && scheduled != opsState {
+			return manager.patchOpsStateScheduled(nil)
 		}
-		err = manager.client.Status().Patch(context.TODO(), gs, client.RawPatch(types.MergePatchType, jsonPatchStatus))
-		if err != nil && !errors.IsNotFound(err) {
-			klog.Errorf("failed to patch GameServer Status %s in %s,because of %s.", gs.GetName(), gs.GetNamespace(), err.Error())
+		return nil
+	}
+	if util.IsPodSpecSatisfyingOpsStateScheduling(&pod.Spec, scheduling) {
+		return manager.patchOpsStateScheduled(opsState)
+	}
+	if pod.GetDeletionTimestamp() != nil || isOwnedByPod(gs, pod) {
+		return nil
+	}
+	if lifecycleState := pod.GetLabels()[kruisePub.LifecycleStateKey]; lifecycleState == string(kruisePub.LifecycleStateUpdating) || lifecycleState == string(kruisePub.LifecycleStatePreparingUpdate) {
+		return nil
+	}
+
+	err := manager.client.Delete(context.TODO(), pod)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	manager.eventRecorder.Eventf(gs, corev1.EventTypeNormal, RescheduledReason, "pod is deleted to be rescheduled for opsState %s", opsState)
+	return manager.patchOpsStateScheduled(opsState)
+}
+
+func (manager GameServerManager) patchOpsStateScheduled(value interface{}) error {
+	patchGs := map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]interface{}{gameKruiseV1alpha1.GameServerOpsStateScheduledKey: value}}}
+	patchBytes, err := json.Marshal(patchGs)
+	if err != nil {
+		return err
+	}
+	err = manager.client.Patch(context.TODO(), manager.gameServer, client.RawPatch(types.MergePatchType, patchBytes))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// SyncPreAllocatedTimeout records the time GameServer is found in opsState PreAllocated, and turns it back to None
+// once it is not confirmed as Allocated within PreAllocatedTimeoutSeconds. The record is removed when GameServer
+// leaves PreAllocated, so that each pre-allocation gets a full timeout.
+func (manager GameServerManager) SyncPreAllocatedTimeout(gss *gameKruiseV1alpha1.GameServerSet) (time.Duration, error) {
+	gs := manager.gameServer
+	preAllocatedTime, found := gs.GetAnnotations()[gameKruiseV1alpha1.GameServerPreAllocatedTimeKey]
+	if gs.Spec.OpsState != gameKruiseV1alpha1.PreAllocated {
+		if found {
+			return 0, manager.patchGsPreAllocated(nil)
+		}
+		return 0, nil
+	}
+
+	timeout := getPreAllocatedTimeout(gss)
+	now := time.Now()
+	start, err := time.Parse(time.RFC3339, preAllocatedTime)
+	if err != nil {
+		return timeout, manager.patchGsPreAllocated(now.Format(time.RFC3339))
+	}
+	if remaining := timeout - now.Sub(start); remaining > 0 {
+		return remaining, nil
+	}
+
+	reverted, err := manager.revertPreAllocated(preAllocatedTime)
+	if err != nil || !reverted {
+		return 0, err
+	}
+	manager.eventRecorder.Eventf(gs, corev1.EventTypeNormal, PreAllocatedTimeoutReason, "opsState turns back to None, since it is not Allocated within %v", timeout)
+	return 0, nil
+}
+
+// revertPreAllocated turns GameServer back to None with an optimistic lock, so that an allocation made since
+// the GameServer was cached is not overwritten. On conflict, it is retried only if the GameServer is still
+// PreAllocated by the same pre-allocation.
+func (manager GameServerManager) revertPreAllocated(preAllocatedTime string) (bool, error) {
+	gs := manager.gameServer.DeepCopy()
+	reverted := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if gs.Spec.OpsState != gameKruiseV1alpha1.PreAllocated || gs.GetAnnotations()[gameKruiseV1alpha1.GameServerPreAllocatedTimeKey] != preAllocatedTime {
+			return nil
+		}
+		newGs := gs.DeepCopy()
+		newGs.Spec.OpsState = gameKruiseV1alpha1.None
+		delete(newGs.Annotations, gameKruiseV1alpha1.GameServerPreAllocatedTimeKey)
+		err := manager.client.Patch(context.TODO(), newGs, client.MergeFromWithOptions(gs, client.MergeFromWithOptimisticLock{}))
+		if errors.IsConflict(err) {
+			if getErr := manager.client.Get(context.TODO(), types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}, gs); getErr != nil {
+				return getErr
+			}
 			return err
 		}
+		reverted = err == nil
+		return err
+	})
+	if errors.IsNotFound(err) {
+		return false, nil
 	}
+	return reverted, err
+}
 
+func (manager GameServerManager) patchGsPreAllocated(preAllocatedTime interface{}) error {
+	patchGs := map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]interface{}{gameKruiseV1alpha1.GameServerPreAllocatedTimeKey: preAllocatedTime}}}
+	patchBytes, err := json.Marshal(patchGs)
+	if err != nil {
+		return err
+	}
+	err = manager.client.Patch(context.TODO(), manager.gameServer, client.RawPatch(types.MergePatchType, patchBytes))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
 	return nil
 }
 
+func getPreAllocatedTimeout(gss *gameKruiseV1alpha1.GameServerSet) time.Duration {
+	if gss.Spec.PreAllocatedTimeoutSeconds != nil && *gss.Spec.PreAllocatedTimeoutSeconds > 0 {
+		return time.Duration(*gss.Spec.PreAllocatedTimeoutSeconds) * time.Second
+	}
+	return DefaultPreAllocatedTimeoutSeconds * time.Second
+}
+
+// SyncExternalFinalizers waits for the finalizers of external integrations on GameServer before the deleting pod
+// with GameServerExternalFinalizersFinalizer is gone. The GameServer deleted by scale-down is deleted first, so that
+// the integrations are notified to clean up and remove their finalizers. The pod is released once the GameServer has
+// no finalizers, or with an event once GameServerFinalizerTimeoutSeconds is over since the pod is deleted, leaving
+// the finalizers to the integrations. The GameServer of the pod deleted for other reasons is not waited for.
+func (manager GameServerManager) SyncExternalFinalizers(gss *gameKruiseV1alpha1.GameServerSet) (time.Duration, error) {
+	pod := manager.pod
+	gs := manager.gameServer
+	if pod.GetDeletionTimestamp() == nil || !controllerutil.ContainsFinalizer(pod, gameKruiseV1alpha1.GameServerExternalFinalizersFinalizer) {
+		return 0, nil
+	}
+
+	if gs != nil && len(gs.GetFinalizers()) > 0 && (gs.GetLabels()[gameKruiseV1alpha1.GameServerDeletingKey] == "true" || gs.GetDeletionTimestamp() != nil) {
+		if gs.GetDeletionTimestamp() == nil {
+			if err := manager.client.Delete(context.TODO(), gs); err != nil && !errors.IsNotFound(err) {
+				return 0, err
+			}
+		}
+		timeout := getGameServerFinalizerTimeout(gss)
+		if remaining := timeout - time.Since(pod.GetDeletionTimestamp().Time); remaining > 0 {
+			return remaining, nil
+		}
+		manager.eventRecorder.Eventf(gs, corev1.EventTypeWarning, GameServerFinalizerTimeoutReason, "finalizers %v are not removed within %v, stop waiting for them", gs.GetFinalizers(), timeout)
+	}
+
+	patch := client.MergeFrom(pod.DeepCopy())
+	controllerutil.RemoveFinalizer(pod, gameKruiseV1alpha1.GameServerExternalFinalizersFinalizer)
+	if err := manager.client.Patch(context.TODO(), pod, patch); err != nil && !errors.IsNotFound(err) {
+		return 0, err
+	}
+	return 0, nil
+}
+
+func getGameServerFinalizerTimeout(gss *gameKruiseV1alpha1.GameServerSet) time.Duration {
+	if gss != nil && gss.Spec.GameServerFinalizerTimeoutSeconds != nil && *gss.Spec.GameServerFinalizerTimeoutSeconds > 0 {
+		return time.Duration(*gss.Spec.GameServerFinalizerTimeoutSeconds) * time.Second
+	}
+	return DefaultGameServerFinalizerTimeoutSeconds * time.Second
+}
+
+// isOwnedByPod returns whether the GameServer is owned by the pod, i.e. it is deleted along with the pod.
+func isOwnedByPod(gs *gameKruiseV1alpha1.GameServer, pod *corev1.Pod) bool {
+	for _, or := range gs.GetOwnerReferences() {
+		if or.UID == pod.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+// diffGsStatus returns the status fields that differ between oldStatus and newStatus, in the form of merge patch.
+// The statuses are compared in serialized form, so that the precision lost in round trip will not be treated as a change.
+// Fields removed in newStatus are set to nil to be deleted by the merge patch.
+func diffGsStatus(oldStatus, newStatus gameKruiseV1alpha1.GameServerStatus) (map[string]interface{}, error) {
+	oldFields, err := toFieldMap(oldStatus)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := toFieldMap(newStatus)
+	if err != nil {
+		return nil, err
+	}
+	diff := make(map[string]interface{})
+	for key, newValue := range newFields {
+		if !reflect.DeepEqual(oldFields[key], newValue) {
+			diff[key] = newValue
+		}
+	}
+	for key := range oldFields {
+		if _, exist := newFields[key]; !exist {
+			diff[key] = nil
+		}
+	}
+	return diff, nil
+}
+
+func toFieldMap(obj interface{}) (map[string]interface{}, error) {
+	bytes, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]interface{})
+	err = json.Unmarshal(bytes, &fields)
+	return fields, err
+}
+
+// getNetworkIntervalTime returns the interval requested by the network plugin for the pod,
+// or the network poll interval of gss if none, or the global network interval.
+func getNetworkIntervalTime(pod *corev1.Pod, gss *gameKruiseV1alpha1.GameServerSet) time.Duration {
+	if pod != nil {
+		interval, err := time.ParseDuration(pod.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkRequeueAfter])
+		if err == nil && interval > 0 {
+			return interval
+		}
+	}
+	if gss != nil {
+		if interval, err := util.GetNetworkPollInterval(gss); err == nil && interval > 0 {
+			return interval
+		}
+	}
+	return NetworkIntervalTime
+}
+
+// SyncNetworkCleanup triggers the network plugin to release the network of the deleting pod, and removes
+// GameServerNetworkCleanupFinalizer once the pod is marked cleaned by the webhook, or the cleanup expires
+// in case the webhook is not available. It returns the interval to check again while the cleanup is in progress.
+func (manager GameServerManager) SyncNetworkCleanup() (time.Duration, error) {
+	pod := manager.pod
+	if pod.GetDeletionTimestamp() == nil || !controllerutil.ContainsFinalizer(pod, gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer) {
+		return 0, nil
+	}
+
+	now := time.Now()
+	cleaned := pod.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkCleanedKey] == "true"
+	if cleaned || util.IsNetworkCleanupExpired(pod, now) {
+		result := "completed"
+		if !cleaned {
+			klog.Warningf("Pod %s/%s network cleanup expired, removing finalizer %s", pod.GetNamespace(), pod.GetName(), gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer)
+			result = "expired"
+		}
+		metrics.NetworkCleanupDurationSeconds.WithLabelValues(result).Observe(util.GetNetworkCleanupDuration(pod, now))
+		patch := client.MergeFrom(pod.DeepCopy())
+		controllerutil.RemoveFinalizer(pod, gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer)
+		if err := manager.client.Patch(context.TODO(), pod, patch); err != nil && !errors.IsNotFound(err) {
+			return 0, err
+		}
+		return 0, nil
+	}
+
+	interval := getNetworkIntervalTime(pod, nil)
+	if oldTime, err := time.Parse(TimeFormat, pod.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkTriggerTime]); err == nil && now.Sub(oldTime) < interval {
+		return interval - now.Sub(oldTime), nil
+	}
+	patchPod := map[string]interface{}{"metadata": map[string]map[string]string{"annotations": {gameKruiseV1alpha1.GameServerNetworkTriggerTime: now.Format(TimeFormat)}}}
+	patchPodBytes, err := json.Marshal(patchPod)
+	if err != nil {
+		return 0, err
+	}
+	if err := manager.client.Patch(context.TODO(), pod, client.RawPatch(types.MergePatchType, patchPodBytes)); err != nil && !errors.IsNotFound(err) {
+		return 0, err
+	}
+	return interval, nil
+}
+
 func (manager GameServerManager) WaitOrNot() bool {
 	networkStatus := manager.gameServer.Status.NetworkStatus
 	alreadyWait := time.Since(networkStatus.LastTransitionTime.Time)
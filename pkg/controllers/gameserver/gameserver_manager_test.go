@@ -2,22 +2,31 @@ package gameserver
 
 import (
 	"context"
+	kruisePub "github.com/openkruise/kruise-api/apps/pub"
 	kruiseV1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	kruiseV1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	gameKruiseV1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	"github.com/openkruise/kruise-game/pkg/util"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -560,7 +569,7 @@ func TestSyncGsToPod(t *testing.T) {
 			pod:        test.pod,
 		}
 
-		if err := manager.SyncGsToPod(); err != nil {
+		if err := manager.SyncGsToPod(nil); err != nil {
 			t.Error(err)
 		}
 
@@ -596,6 +605,267 @@ func TestSyncGsToPod(t *testing.T) {
 	}
 }
 
+func TestSyncGsToPodDeletionCost(t *testing.T) {
+	up := intstr.FromInt(0)
+	tests := []struct {
+		opsStates         []gameKruiseV1alpha1.OpsState
+		deletionPriorites []intstr.IntOrString
+		costs             []string
+	}{
+		// case 0
+		{
+			opsStates:         []gameKruiseV1alpha1.OpsState{gameKruiseV1alpha1.None, gameKruiseV1alpha1.None},
+			deletionPriorites: []intstr.IntOrString{intstr.FromInt(0), intstr.FromInt(10)},
+			costs:             []string{"0", "-10"},
+		},
+		// case 1
+		{
+			opsStates:         []gameKruiseV1alpha1.OpsState{gameKruiseV1alpha1.Allocated, gameKruiseV1alpha1.WaitToDelete},
+			deletionPriorites: []intstr.IntOrString{intstr.FromInt(5), intstr.FromInt(5)},
+			costs:             []string{"995", "-1005"},
+		},
+		// case 2
+		{
+			opsStates:         []gameKruiseV1alpha1.OpsState{gameKruiseV1alpha1.Kill, gameKruiseV1alpha1.Kill},
+			deletionPriorites: []intstr.IntOrString{intstr.FromInt(0), intstr.FromInt(0)},
+			costs:             []string{"-10000", "-10000"},
+		},
+	}
+
+	for i, test := range tests {
+		gs := &gameKruiseV1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+			},
+			Spec: gameKruiseV1alpha1.GameServerSpec{
+				UpdatePriority: &up,
+			},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs, pod).Build()
+		for j := range test.costs {
+			gs.Spec.OpsState = test.opsStates[j]
+			gs.Spec.DeletionPriority = &test.deletionPriorites[j]
+			manager := &GameServerManager{
+				client:        c,
+				gameServer:    gs,
+				pod:           pod,
+				eventRecorder: record.NewFakeRecorder(10),
+			}
+			if err := manager.SyncGsToPod(nil); err != nil {
+				t.Error(err)
+			}
+			if err := c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, pod); err != nil {
+				t.Error(err)
+			}
+			if pod.Annotations[gameKruiseV1alpha1.PodDeletionCostKey] != test.costs[j] {
+				t.Errorf("case %d: expect pod deletion cost %s after sync %d, but actually got %s", i, test.costs[j], j, pod.Annotations[gameKruiseV1alpha1.PodDeletionCostKey])
+			}
+		}
+	}
+}
+
+func TestSyncGsToPodInPlaceUpdateEvictionProtection(t *testing.T) {
+	up := intstr.FromInt(0)
+	dp := intstr.FromInt(5)
+	gs := &gameKruiseV1alpha1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx-0",
+		},
+		Spec: gameKruiseV1alpha1.GameServerSpec{
+			OpsState:         gameKruiseV1alpha1.Allocated,
+			UpdatePriority:   &up,
+			DeletionPriority: &dp,
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx-0",
+			Labels:    map[string]string{},
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	// the PodDisruptionBudget of pods updated in place created by GameServerSet
+	maxUnavailable := intstr.FromInt(0)
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx-in-place-updating",
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					gameKruiseV1alpha1.GameServerOwnerGssKey:        "xxx",
+					gameKruiseV1alpha1.GameServerInPlaceUpdatingKey: "true",
+				},
+			},
+		},
+	}
+	pod.Labels[gameKruiseV1alpha1.GameServerOwnerGssKey] = "xxx"
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs, pod, pdb).Build()
+
+	tests := []struct {
+		lifecycleState kruisePub.LifecycleStateType
+		blocked        bool
+	}{
+		// case 0: before update
+		{
+			lifecycleState: kruisePub.LifecycleStateNormal,
+			blocked:        false,
+		},
+		// case 1: preparing update
+		{
+			lifecycleState: kruisePub.LifecycleStatePreparingUpdate,
+			blocked:        true,
+		},
+		// case 2: updating in place
+		{
+			lifecycleState: kruisePub.LifecycleStateUpdating,
+			blocked:        true,
+		},
+		// case 3: update completed
+		{
+			lifecycleState: kruisePub.LifecycleStateNormal,
+			blocked:        false,
+		},
+	}
+
+	for i, test := range tests {
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, pod); err != nil {
+			t.Fatal(err)
+		}
+		pod.SetLabels(util.MergeMapString(pod.GetLabels(), map[string]string{kruisePub.LifecycleStateKey: string(test.lifecycleState)}))
+		if err := c.Update(context.TODO(), pod); err != nil {
+			t.Fatal(err)
+		}
+		manager := &GameServerManager{
+			client:        c,
+			gameServer:    gs,
+			pod:           pod,
+			eventRecorder: record.NewFakeRecorder(10),
+		}
+		if err := manager.SyncGsToPod(nil); err != nil {
+			t.Error(err)
+		}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, pod); err != nil {
+			t.Fatal(err)
+		}
+		if actual := isEvictionBlocked(t, c, pod); actual != test.blocked {
+			t.Errorf("case %d: expect eviction blocked %v, but actually got %v", i, test.blocked, actual)
+		}
+	}
+}
+
+// isEvictionBlocked returns whether evicting pod is refused by a PodDisruptionBudget selecting it that allows
+// no disruption, as the eviction API does.
+func isEvictionBlocked(t *testing.T, c client.Client, pod *corev1.Pod) bool {
+	pdbList := &policyv1.PodDisruptionBudgetList{}
+	if err := c.List(context.TODO(), pdbList, client.InNamespace(pod.Namespace)); err != nil {
+		t.Fatal(err)
+	}
+	for _, pdb := range pdbList.Items {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !selector.Matches(labels.Set(pod.GetLabels())) {
+			continue
+		}
+		if pdb.Spec.MaxUnavailable != nil && pdb.Spec.MaxUnavailable.IntValue() == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSyncGsToPodNetworkDisabled(t *testing.T) {
+	up := intstr.FromInt(0)
+	dp := intstr.FromInt(0)
+	tests := []struct {
+		gsDisabled  []bool
+		gssDisabled []bool
+		expects     []string
+	}{
+		// case 0: disabled by GameServerSet, then re-enabled
+		{
+			gsDisabled:  []bool{false, false, false},
+			gssDisabled: []bool{false, true, false},
+			expects:     []string{"false", "true", "false"},
+		},
+		// case 1: GameServer stays disabled when GameServerSet is re-enabled
+		{
+			gsDisabled:  []bool{true, true, true},
+			gssDisabled: []bool{false, true, false},
+			expects:     []string{"true", "true", "true"},
+		},
+	}
+
+	for i, test := range tests {
+		gs := &gameKruiseV1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+			},
+			Spec: gameKruiseV1alpha1.GameServerSpec{
+				UpdatePriority:   &up,
+				DeletionPriority: &dp,
+				OpsState:         gameKruiseV1alpha1.None,
+			},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs, pod).Build()
+		for j := range test.expects {
+			gs.Spec.NetworkDisabled = test.gsDisabled[j]
+			gss := &gameKruiseV1alpha1.GameServerSet{
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					Network: &gameKruiseV1alpha1.Network{
+						NetworkType:     "Kubernetes-HostPort",
+						NetworkDisabled: test.gssDisabled[j],
+					},
+				},
+			}
+			manager := &GameServerManager{
+				client:        c,
+				gameServer:    gs,
+				pod:           pod,
+				eventRecorder: record.NewFakeRecorder(10),
+			}
+			if err := manager.SyncGsToPod(gss); err != nil {
+				t.Error(err)
+			}
+			if err := c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, pod); err != nil {
+				t.Error(err)
+			}
+			if pod.Labels[gameKruiseV1alpha1.GameServerNetworkDisabled] != test.expects[j] {
+				t.Errorf("case %d: expect pod network disabled %s after sync %d, but actually got %s", i, test.expects[j], j, pod.Labels[gameKruiseV1alpha1.GameServerNetworkDisabled])
+			}
+		}
+	}
+}
+
 func TestSyncNetworkStatus(t *testing.T) {
 	fakeTime := metav1.Now()
 	portInternal := intstr.FromInt(80)
@@ -960,5 +1230,951 @@ func TestSyncPodToGs(t *testing.T) {
 		if !isConditionsEqual(test.gsStatus.Conditions, gs.Status.Conditions) {
 			t.Errorf("case %d: expect conditions is %v, but actually %v", i, test.gsStatus.Conditions, gs.Status.Conditions)
 		}
+
+		// sync again, no write occurs when nothing changed
+		resourceVersion := gs.GetResourceVersion()
+		manager = &GameServerManager{
+			client:     c,
+			gameServer: gs,
+			pod:        test.pod,
+		}
+		if err := manager.SyncPodToGs(test.gss); err != nil {
+			t.Error(err)
+		}
+		if err := manager.client.Get(context.TODO(), types.NamespacedName{
+			Namespace: test.gs.Namespace,
+			Name:      test.gs.Name,
+		}, gs); err != nil {
+			t.Error(err)
+		}
+		if gs.GetResourceVersion() != resourceVersion {
+			t.Errorf("case %d: expect no write when nothing changed, but resourceVersion changed from %s to %s", i, resourceVersion, gs.GetResourceVersion())
+		}
+	}
+}
+
+func TestSyncPodToGsPreserveExtra(t *testing.T) {
+	gss := &gameKruiseV1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx",
+		},
+	}
+	gs := &gameKruiseV1alpha1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx-0",
+			Labels: map[string]string{
+				gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
+			},
+		},
+		Status: gameKruiseV1alpha1.GameServerStatus{
+			CurrentState: gameKruiseV1alpha1.Creating,
+			Extra: map[string]string{
+				"map": "de_dust",
+			},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx-0",
+			Labels: map[string]string{
+				gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.None),
+				gameKruiseV1alpha1.GameServerStateKey:    string(gameKruiseV1alpha1.Ready),
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs, pod, gss).Build()
+
+	tests := []struct {
+		extra map[string]string
+	}{
+		// case 0: extra set before OKG reconciles
+		{
+			extra: map[string]string{
+				"map": "de_dust",
+			},
+		},
+		// case 1: extra written by the game server between reconciles
+		{
+			extra: map[string]string{
+				"map":         "de_dust2",
+				"playerCount": "8",
+			},
+		},
+	}
+
+	for i, test := range tests {
+		current := &gameKruiseV1alpha1.GameServer{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}, current); err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 {
+			patch := client.MergeFrom(current.DeepCopy())
+			current.Status.Extra = test.extra
+			if err := c.Status().Patch(context.TODO(), current, patch); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		manager := &GameServerManager{
+			client:     c,
+			gameServer: current,
+			pod:        pod,
+		}
+		if err := manager.SyncPodToGs(gss); err != nil {
+			t.Error(err)
+		}
+
+		got := &gameKruiseV1alpha1.GameServer{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}, got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.Status.Extra, test.extra) {
+			t.Errorf("case %d: expect extra %v but actually got %v", i, test.extra, got.Status.Extra)
+		}
+		if got.Status.CurrentState != gameKruiseV1alpha1.Ready {
+			t.Errorf("case %d: expect current state %s but actually got %s", i, gameKruiseV1alpha1.Ready, got.Status.CurrentState)
+		}
+	}
+}
+
+func TestSyncPodToGsDNSServiceConflict(t *testing.T) {
+	gss := &gameKruiseV1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx",
+		},
+		Spec: gameKruiseV1alpha1.GameServerSetSpec{
+			Network: &gameKruiseV1alpha1.Network{
+				NetworkType:  "xxx-type",
+				DNSDiscovery: true,
+			},
+		},
+	}
+	gs := &gameKruiseV1alpha1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx-0",
+			UID:       "xxx-0-uid",
+			Labels: map[string]string{
+				gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
+			},
+		},
+		Status: gameKruiseV1alpha1.GameServerStatus{
+			CurrentState: gameKruiseV1alpha1.Creating,
+			NetworkStatus: gameKruiseV1alpha1.NetworkStatus{
+				NetworkType:         "xxx-type",
+				DesiredNetworkState: gameKruiseV1alpha1.NetworkReady,
+			},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx-0",
+			Labels: map[string]string{
+				gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.None),
+				gameKruiseV1alpha1.GameServerStateKey:    string(gameKruiseV1alpha1.Ready),
+			},
+			Annotations: map[string]string{
+				gameKruiseV1alpha1.GameServerNetworkType:   "xxx-type",
+				gameKruiseV1alpha1.GameServerNetworkStatus: "{\"externalAddresses\":[{\"ip\":\"47.99.47.99\"}],\"currentNetworkState\":\"Ready\",\"createTime\":null,\"lastTransitionTime\":null}",
+			},
+		},
+	}
+	// a Service of the same name created by the user
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "server-xxx-0",
+		},
+		Spec: corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: "user.example.com",
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs, pod, gss, svc).Build()
+	recorder := record.NewFakeRecorder(10)
+
+	manager := &GameServerManager{
+		client:        c,
+		gameServer:    gs,
+		pod:           pod,
+		eventRecorder: recorder,
+	}
+	if err := manager.SyncPodToGs(gss); err != nil {
+		t.Error(err)
+	}
+
+	got := &gameKruiseV1alpha1.GameServer{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.CurrentState != gameKruiseV1alpha1.Ready {
+		t.Errorf("expect current state %s but actually got %s", gameKruiseV1alpha1.Ready, got.Status.CurrentState)
+	}
+	if got.Status.NetworkStatus.CurrentNetworkState != gameKruiseV1alpha1.NetworkReady {
+		t.Errorf("expect network state %s but actually got %s", gameKruiseV1alpha1.NetworkReady, got.Status.NetworkStatus.CurrentNetworkState)
+	}
+	if addresses := got.Status.NetworkStatus.ExternalAddresses; len(addresses) != 1 || addresses[0].EndPoint != "" {
+		t.Errorf("expect no dns endpoint but actually got %v", addresses)
+	}
+
+	gotSvc := &corev1.Service{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "server-xxx-0"}, gotSvc); err != nil {
+		t.Fatal(err)
+	}
+	if gotSvc.Spec.ExternalName != "user.example.com" || len(gotSvc.GetOwnerReferences()) != 0 {
+		t.Errorf("expect service untouched but actually got %v", gotSvc)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, DNSServiceSyncFailedReason) {
+			t.Errorf("expect event %s but actually got %s", DNSServiceSyncFailedReason, event)
+		}
+	default:
+		t.Errorf("expect event %s but actually got none", DNSServiceSyncFailedReason)
+	}
+}
+
+func TestSyncPodToGsServiceQualityConditions(t *testing.T) {
+	gss := &gameKruiseV1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx",
+		},
+		Spec: gameKruiseV1alpha1.GameServerSetSpec{
+			ServiceQualities: []gameKruiseV1alpha1.ServiceQuality{
+				{Name: "healthy"},
+				{Name: "idle"},
+			},
+		},
+	}
+	tests := []struct {
+		status  corev1.ConditionStatus
+		message string
+	}{
+		// case 0: healthy probed true
+		{
+			status:  corev1.ConditionTrue,
+			message: "ok",
+		},
+		// case 1: healthy probed false
+		{
+			status:  corev1.ConditionFalse,
+			message: "timeout",
+		},
+	}
+
+	for i, test := range tests {
+		gs := &gameKruiseV1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+				Labels: map[string]string{
+					gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
+				},
+			},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+				Labels: map[string]string{
+					gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.None),
+					gameKruiseV1alpha1.GameServerStateKey:    string(gameKruiseV1alpha1.Ready),
+				},
+			},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{
+					{
+						Type:    "game.kruise.io/healthy",
+						Status:  test.status,
+						Message: test.message,
+					},
+				},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs, pod, gss).Build()
+		manager := &GameServerManager{
+			client:        c,
+			gameServer:    gs,
+			pod:           pod,
+			eventRecorder: record.NewFakeRecorder(10),
+		}
+		if err := manager.SyncPodToGs(gss); err != nil {
+			t.Error(err)
+		}
+
+		got := &gameKruiseV1alpha1.GameServer{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}, got); err != nil {
+			t.Fatal(err)
+		}
+		healthy := getGsCondition(got.Status.Conditions, "game.kruise.io/healthy")
+		if healthy.Status != test.status || healthy.Message != test.message {
+			t.Errorf("case %d: expect condition healthy %s with message %s but actually got %v", i, test.status, test.message, healthy)
+		}
+		if healthy.LastTransitionTime.IsZero() {
+			t.Errorf("case %d: expect lastTransitionTime of condition healthy set", i)
+		}
+		if idle := getGsCondition(got.Status.Conditions, "game.kruise.io/idle"); idle.Type != "" {
+			t.Errorf("case %d: expect no condition idle but actually got %v", i, idle)
+		}
+	}
+}
+
+func TestDiffGsStatus(t *testing.T) {
+	now := metav1.Now()
+	tests := []struct {
+		oldStatus gameKruiseV1alpha1.GameServerStatus
+		newStatus gameKruiseV1alpha1.GameServerStatus
+		diffKeys  []string
+	}{
+		// case 0
+		{
+			oldStatus: gameKruiseV1alpha1.GameServerStatus{
+				CurrentState:       gameKruiseV1alpha1.Ready,
+				DesiredState:       gameKruiseV1alpha1.Ready,
+				LastTransitionTime: metav1.NewTime(now.Rfc3339Copy().Time),
+			},
+			newStatus: gameKruiseV1alpha1.GameServerStatus{
+				CurrentState:       gameKruiseV1alpha1.Ready,
+				DesiredState:       gameKruiseV1alpha1.Ready,
+				LastTransitionTime: now,
+			},
+			diffKeys: []string{},
+		},
+		// case 1
+		{
+			oldStatus: gameKruiseV1alpha1.GameServerStatus{
+				CurrentState: gameKruiseV1alpha1.Creating,
+				DesiredState: gameKruiseV1alpha1.Ready,
+			},
+			newStatus: gameKruiseV1alpha1.GameServerStatus{
+				CurrentState: gameKruiseV1alpha1.Ready,
+				DesiredState: gameKruiseV1alpha1.Ready,
+			},
+			diffKeys: []string{"currentState"},
+		},
+		// case 2
+		{
+			oldStatus: gameKruiseV1alpha1.GameServerStatus{
+				CurrentState:  gameKruiseV1alpha1.Ready,
+				NetworkStatus: gameKruiseV1alpha1.NetworkStatus{NetworkType: "xxx"},
+				Conditions: []gameKruiseV1alpha1.GameServerCondition{
+					{
+						Type:   gameKruiseV1alpha1.PodNormal,
+						Status: corev1.ConditionTrue,
+					},
+				},
+			},
+			newStatus: gameKruiseV1alpha1.GameServerStatus{
+				CurrentState:  gameKruiseV1alpha1.Ready,
+				NetworkStatus: gameKruiseV1alpha1.NetworkStatus{NetworkType: "xxx"},
+			},
+			diffKeys: []string{"conditions"},
+		},
+	}
+
+	for i, test := range tests {
+		diff, err := diffGsStatus(test.oldStatus, test.newStatus)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(diff) != len(test.diffKeys) {
+			t.Errorf("case %d: expect diff keys %v, but actually %v", i, test.diffKeys, diff)
+		}
+		for _, key := range test.diffKeys {
+			if _, exist := diff[key]; !exist {
+				t.Errorf("case %d: expect diff key %s, but actually %v", i, key, diff)
+			}
+		}
+	}
+}
+
+func TestGetNetworkIntervalTime(t *testing.T) {
+	tests := []struct {
+		annotations    map[string]string
+		gssAnnotations map[string]string
+		interval       time.Duration
+	}{
+		// case 0
+		{
+			annotations: nil,
+			interval:    NetworkIntervalTime,
+		},
+		// case 1
+		{
+			annotations: map[string]string{gameKruiseV1alpha1.GameServerNetworkRequeueAfter: "2s"},
+			interval:    2 * time.Second,
+		},
+		// case 2
+		{
+			annotations: map[string]string{gameKruiseV1alpha1.GameServerNetworkRequeueAfter: "xxx"},
+			interval:    NetworkIntervalTime,
+		},
+		// case 3: poll interval of gss
+		{
+			gssAnnotations: map[string]string{gameKruiseV1alpha1.GameServerSetNetworkPollIntervalKey: "500ms"},
+			interval:       500 * time.Millisecond,
+		},
+		// case 4: interval requested by plugin takes precedence
+		{
+			annotations:    map[string]string{gameKruiseV1alpha1.GameServerNetworkRequeueAfter: "2s"},
+			gssAnnotations: map[string]string{gameKruiseV1alpha1.GameServerSetNetworkPollIntervalKey: "30s"},
+			interval:       2 * time.Second,
+		},
+		// case 5: invalid poll interval of gss
+		{
+			gssAnnotations: map[string]string{gameKruiseV1alpha1.GameServerSetNetworkPollIntervalKey: "-1s"},
+			interval:       NetworkIntervalTime,
+		},
+	}
+
+	for i, test := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: test.annotations,
+			},
+		}
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: test.gssAnnotations,
+			},
+		}
+		if actual := getNetworkIntervalTime(pod, gss); actual != test.interval {
+			t.Errorf("case %d: expect interval %v, but actually got %v", i, test.interval, actual)
+		}
+	}
+}
+
+func TestSyncNetworkCleanup(t *testing.T) {
+	tests := []struct {
+		deletionTimestamp *metav1.Time
+		triggerTime       string
+		cleaned           bool
+		cleaning          bool
+		triggered         bool
+		finalizer         bool
+	}{
+		// case 0: not deleting
+		{
+			deletionTimestamp: nil,
+			finalizer:         true,
+		},
+		// case 1: deleting, network cleanup triggered
+		{
+			deletionTimestamp: ptr.To(metav1.NewTime(time.Now().Add(10 * time.Second))),
+			cleaning:          true,
+			triggered:         true,
+			finalizer:         true,
+		},
+		// case 2: deleting, network cleanup triggered recently
+		{
+			deletionTimestamp: ptr.To(metav1.NewTime(time.Now().Add(10 * time.Second))),
+			triggerTime:       time.Now().Format(TimeFormat),
+			cleaning:          true,
+			finalizer:         true,
+		},
+		// case 3: deleting, network cleanup expired
+		{
+			deletionTimestamp: ptr.To(metav1.NewTime(time.Now().Add(-util.NetworkCleanupMaxExtension - time.Second))),
+			finalizer:         false,
+		},
+		// case 4: deleting, network cleaned by the webhook
+		{
+			deletionTimestamp: ptr.To(metav1.NewTime(time.Now().Add(10 * time.Second))),
+			cleaned:           true,
+			finalizer:         false,
+		},
+	}
+
+	for i, test := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "xxx",
+				Name:              "xxx-0",
+				DeletionTimestamp: test.deletionTimestamp,
+				Finalizers:        []string{gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer},
+				Annotations:       map[string]string{},
+			},
+		}
+		if test.triggerTime != "" {
+			pod.Annotations[gameKruiseV1alpha1.GameServerNetworkTriggerTime] = test.triggerTime
+		}
+		if test.cleaned {
+			pod.Annotations[gameKruiseV1alpha1.GameServerNetworkCleanedKey] = "true"
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+		manager := &GameServerManager{
+			client:        c,
+			pod:           pod,
+			eventRecorder: record.NewFakeRecorder(10),
+		}
+
+		after, err := manager.SyncNetworkCleanup()
+		if err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		if (after > 0) != test.cleaning {
+			t.Errorf("case %d: expect cleaning %v but actually got requeue after %v", i, test.cleaning, after)
+		}
+		newPod := &corev1.Pod{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx-0"}, newPod); err != nil {
+			// the deleting pod is gone once its finalizer is removed
+			if !errors.IsNotFound(err) || test.finalizer {
+				t.Errorf("case %d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if triggered := newPod.Annotations[gameKruiseV1alpha1.GameServerNetworkTriggerTime] != test.triggerTime; triggered != test.triggered {
+			t.Errorf("case %d: expect network triggered %v but actually got %v", i, test.triggered, triggered)
+		}
+		if finalizer := controllerutil.ContainsFinalizer(newPod, gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer); finalizer != test.finalizer {
+			t.Errorf("case %d: expect finalizer %v but actually got %v", i, test.finalizer, finalizer)
+		}
+	}
+}
+
+func TestSyncOpsStateScheduling(t *testing.T) {
+	gss := &gameKruiseV1alpha1.GameServerSet{
+		Spec: gameKruiseV1alpha1.GameServerSetSpec{
+			OpsStateScheduling: []gameKruiseV1alpha1.OpsStateScheduling{
+				{
+					OpsState:     gameKruiseV1alpha1.Maintaining,
+					NodeSelector: map[string]string{"pool": "cheap"},
+					Tolerations:  []corev1.Toleration{{Key: "cheap", Operator: corev1.TolerationOpExists}},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		opsState     gameKruiseV1alpha1.OpsState
+		scheduled    string
+		nodeSelector map[string]string
+		ownedByPod   bool
+		podDeleted   bool
+		expected     string
+	}{
+		// case 0: no scheduling for opsState None
+		{
+			opsState:  gameKruiseV1alpha1.None,
+			scheduled: "",
+			expected:  "",
+		},
+		// case 1: entering Maintaining reschedules the pod
+		{
+			opsState:   gameKruiseV1alpha1.Maintaining,
+			scheduled:  "",
+			podDeleted: true,
+			expected:   string(gameKruiseV1alpha1.Maintaining),
+		},
+		// case 2: the pod is rescheduled only once for Maintaining, even if it does not satisfy the scheduling
+		{
+			opsState:  gameKruiseV1alpha1.Maintaining,
+			scheduled: string(gameKruiseV1alpha1.Maintaining),
+			expected:  string(gameKruiseV1alpha1.Maintaining),
+		},
+		// case 3: the pod already satisfying the scheduling is not rescheduled
+		{
+			opsState:     gameKruiseV1alpha1.Maintaining,
+			scheduled:    "",
+			nodeSelector: map[string]string{"pool": "cheap"},
+			expected:     string(gameKruiseV1alpha1.Maintaining),
+		},
+		// case 4: the pod is not rescheduled if GameServer is deleted along with it
+		{
+			opsState:   gameKruiseV1alpha1.Maintaining,
+			scheduled:  "",
+			ownedByPod: true,
+			expected:   "",
+		},
+		// case 5: leaving Maintaining clears the record without rescheduling
+		{
+			opsState:  gameKruiseV1alpha1.None,
+			scheduled: string(gameKruiseV1alpha1.Maintaining),
+			expected:  "",
+		},
+	}
+
+	for i, test := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+				UID:       "pod-uid",
+			},
+			Spec: corev1.PodSpec{
+				NodeSelector: test.nodeSelector,
+				Tolerations:  []corev1.Toleration{{Key: "cheap", Operator: corev1.TolerationOpExists}},
+			},
+		}
+		gs := &gameKruiseV1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        "xxx-0",
+				Annotations: map[string]string{},
+			},
+			Spec: gameKruiseV1alpha1.GameServerSpec{
+				OpsState: test.opsState,
+			},
+		}
+		if test.scheduled != "" {
+			gs.Annotations[gameKruiseV1alpha1.GameServerOpsStateScheduledKey] = test.scheduled
+		}
+		if test.ownedByPod {
+			gs.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: pod.Name, UID: pod.UID}}
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs, pod).Build()
+		manager := &GameServerManager{
+			client:        c,
+			gameServer:    gs,
+			pod:           pod,
+			eventRecorder: record.NewFakeRecorder(10),
+		}
+		if err := manager.SyncOpsStateScheduling(gss); err != nil {
+			t.Error(err)
+		}
+
+		err := c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &corev1.Pod{})
+		if deleted := errors.IsNotFound(err); deleted != test.podDeleted {
+			t.Errorf("case %d: expect pod deleted %v but actually got %v", i, test.podDeleted, deleted)
+		}
+		newGs := &gameKruiseV1alpha1.GameServer{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}, newGs); err != nil {
+			t.Error(err)
+		}
+		if scheduled := newGs.Annotations[gameKruiseV1alpha1.GameServerOpsStateScheduledKey]; scheduled != test.expected {
+			t.Errorf("case %d: expect opsState scheduled %s but actually got %s", i, test.expected, scheduled)
+		}
+	}
+}
+
+func TestSyncPreAllocatedTimeout(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		opsState         gameKruiseV1alpha1.OpsState
+		preAllocatedTime string
+		timeoutSeconds   *int32
+		// allocatedMeanwhile turns GameServer Allocated after it is cached
+		allocatedMeanwhile bool
+		expectOpsState     gameKruiseV1alpha1.OpsState
+		expectRecorded     bool
+		expectAfter        time.Duration
+	}{
+		// case 0: PreAllocated is recorded when found
+		{
+			opsState:       gameKruiseV1alpha1.PreAllocated,
+			expectOpsState: gameKruiseV1alpha1.PreAllocated,
+			expectRecorded: true,
+			expectAfter:    DefaultPreAllocatedTimeoutSeconds * time.Second,
+		},
+		// case 1: PreAllocated within timeout
+		{
+			opsState:         gameKruiseV1alpha1.PreAllocated,
+			preAllocatedTime: now.Add(-10 * time.Second).Format(time.RFC3339),
+			timeoutSeconds:   ptr.To[int32](30),
+			expectOpsState:   gameKruiseV1alpha1.PreAllocated,
+			expectRecorded:   true,
+			expectAfter:      20 * time.Second,
+		},
+		// case 2: PreAllocated reverts to None after timeout
+		{
+			opsState:         gameKruiseV1alpha1.PreAllocated,
+			preAllocatedTime: now.Add(-2 * time.Minute).Format(time.RFC3339),
+			expectOpsState:   gameKruiseV1alpha1.None,
+			expectRecorded:   false,
+		},
+		// case 3: confirmed as Allocated, the record is removed
+		{
+			opsState:         gameKruiseV1alpha1.Allocated,
+			preAllocatedTime: now.Add(-2 * time.Minute).Format(time.RFC3339),
+			expectOpsState:   gameKruiseV1alpha1.Allocated,
+			expectRecorded:   false,
+		},
+		// case 4: allocated after cached, not reverted
+		{
+			opsState:           gameKruiseV1alpha1.PreAllocated,
+			preAllocatedTime:   now.Add(-2 * time.Minute).Format(time.RFC3339),
+			allocatedMeanwhile: true,
+			expectOpsState:     gameKruiseV1alpha1.Allocated,
+			expectRecorded:     true,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				PreAllocatedTimeoutSeconds: test.timeoutSeconds,
+			},
+		}
+		gs := &gameKruiseV1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        "xxx-0",
+				Annotations: map[string]string{},
+			},
+			Spec: gameKruiseV1alpha1.GameServerSpec{
+				OpsState: test.opsState,
+			},
+		}
+		if test.preAllocatedTime != "" {
+			gs.Annotations[gameKruiseV1alpha1.GameServerPreAllocatedTimeKey] = test.preAllocatedTime
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs).Build()
+		cachedGs := &gameKruiseV1alpha1.GameServer{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}, cachedGs); err != nil {
+			t.Fatal(err)
+		}
+		if test.allocatedMeanwhile {
+			allocatedGs := cachedGs.DeepCopy()
+			allocatedGs.Spec.OpsState = gameKruiseV1alpha1.Allocated
+			if err := c.Update(context.TODO(), allocatedGs); err != nil {
+				t.Fatal(err)
+			}
+		}
+		manager := &GameServerManager{
+			client:        c,
+			gameServer:    cachedGs,
+			eventRecorder: record.NewFakeRecorder(10),
+		}
+		after, err := manager.SyncPreAllocatedTimeout(gss)
+		if err != nil {
+			t.Error(err)
+		}
+		if after > test.expectAfter || after < test.expectAfter-5*time.Second {
+			t.Errorf("case %d: expect requeue after %v but actually got %v", i, test.expectAfter, after)
+		}
+
+		newGs := &gameKruiseV1alpha1.GameServer{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}, newGs); err != nil {
+			t.Error(err)
+		}
+		if newGs.Spec.OpsState != test.expectOpsState {
+			t.Errorf("case %d: expect opsState %s but actually got %s", i, test.expectOpsState, newGs.Spec.OpsState)
+		}
+		if _, recorded := newGs.Annotations[gameKruiseV1alpha1.GameServerPreAllocatedTimeKey]; recorded != test.expectRecorded {
+			t.Errorf("case %d: expect pre-allocated time recorded %v but actually got %v", i, test.expectRecorded, recorded)
+		}
+	}
+}
+
+func TestSyncGsToPodRequireNetworkReady(t *testing.T) {
+	up := intstr.FromInt(0)
+	dp := intstr.FromInt(0)
+	tests := []struct {
+		podReady     corev1.ConditionStatus
+		networkState gameKruiseV1alpha1.NetworkState
+		expect       gameKruiseV1alpha1.GameServerState
+	}{
+		// case 0: pod ready and network ready
+		{
+			podReady:     corev1.ConditionTrue,
+			networkState: gameKruiseV1alpha1.NetworkReady,
+			expect:       gameKruiseV1alpha1.Ready,
+		},
+		// case 1: pod ready but network not ready
+		{
+			podReady:     corev1.ConditionTrue,
+			networkState: gameKruiseV1alpha1.NetworkWaiting,
+			expect:       gameKruiseV1alpha1.NotReady,
+		},
+		// case 2: pod not ready but network ready
+		{
+			podReady:     corev1.ConditionFalse,
+			networkState: gameKruiseV1alpha1.NetworkReady,
+			expect:       gameKruiseV1alpha1.NotReady,
+		},
+		// case 3: neither pod nor network ready
+		{
+			podReady:     corev1.ConditionFalse,
+			networkState: gameKruiseV1alpha1.NetworkNotReady,
+			expect:       gameKruiseV1alpha1.NotReady,
+		},
+	}
+
+	for i, test := range tests {
+		gs := &gameKruiseV1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+			},
+			Spec: gameKruiseV1alpha1.GameServerSpec{
+				UpdatePriority:   &up,
+				DeletionPriority: &dp,
+				OpsState:         gameKruiseV1alpha1.None,
+			},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+				Annotations: map[string]string{
+					gameKruiseV1alpha1.GameServerNetworkType:   "Kubernetes-HostPort",
+					gameKruiseV1alpha1.GameServerNetworkStatus: `{"networkType":"Kubernetes-HostPort","currentNetworkState":"` + string(test.networkState) + `"}`,
+				},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{
+						Type:   corev1.PodReady,
+						Status: test.podReady,
+					},
+				},
+			},
+		}
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				RequireNetworkReady: true,
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs, pod).Build()
+		manager := &GameServerManager{
+			client:        c,
+			gameServer:    gs,
+			pod:           pod,
+			eventRecorder: record.NewFakeRecorder(10),
+		}
+		if err := manager.SyncGsToPod(gss); err != nil {
+			t.Error(err)
+		}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, pod); err != nil {
+			t.Error(err)
+		}
+		if pod.Labels[gameKruiseV1alpha1.GameServerStateKey] != string(test.expect) {
+			t.Errorf("case %d: expect pod state %s but actually got %s", i, test.expect, pod.Labels[gameKruiseV1alpha1.GameServerStateKey])
+		}
+	}
+}
+
+func TestSyncExternalFinalizers(t *testing.T) {
+	tests := []struct {
+		deletionTimestamp *metav1.Time
+		gsFound           bool
+		gsDeleting        string
+		gsFinalizers      []string
+		waiting           bool
+		gsDeleted         bool
+		finalizer         bool
+		event             bool
+	}{
+		// case 0: pod not deleting
+		{
+			deletionTimestamp: nil,
+			gsFound:           true,
+			gsDeleting:        "true",
+			gsFinalizers:      []string{"matchmaker.io/cleanup"},
+			finalizer:         true,
+		},
+		// case 1: pod deleted by scale-down, waiting for the finalizers of GameServer
+		{
+			deletionTimestamp: ptr.To(metav1.NewTime(time.Now().Add(-10 * time.Second))),
+			gsFound:           true,
+			gsDeleting:        "true",
+			gsFinalizers:      []string{"matchmaker.io/cleanup"},
+			waiting:           true,
+			gsDeleted:         true,
+			finalizer:         true,
+		},
+		// case 2: pod deleted by scale-down, waiting timed out and the finalizers of GameServer are kept
+		{
+			deletionTimestamp: ptr.To(metav1.NewTime(time.Now().Add(-DefaultGameServerFinalizerTimeoutSeconds*time.Second - time.Second))),
+			gsFound:           true,
+			gsDeleting:        "true",
+			gsFinalizers:      []string{"matchmaker.io/cleanup"},
+			gsDeleted:         true,
+			finalizer:         false,
+			event:             true,
+		},
+		// case 3: pod deleted by scale-down, the finalizers of GameServer are removed
+		{
+			deletionTimestamp: ptr.To(metav1.NewTime(time.Now().Add(-10 * time.Second))),
+			gsFound:           true,
+			gsDeleting:        "true",
+			finalizer:         false,
+		},
+		// case 4: pod deleted not by scale-down, GameServer is not waited for
+		{
+			deletionTimestamp: ptr.To(metav1.NewTime(time.Now().Add(-10 * time.Second))),
+			gsFound:           true,
+			gsDeleting:        "false",
+			gsFinalizers:      []string{"matchmaker.io/cleanup"},
+			finalizer:         false,
+		},
+		// case 5: GameServer is gone
+		{
+			deletionTimestamp: ptr.To(metav1.NewTime(time.Now().Add(-10 * time.Second))),
+			gsFound:           false,
+			finalizer:         false,
+		},
+	}
+
+	for i, test := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "xxx",
+				Name:              "xxx-0",
+				DeletionTimestamp: test.deletionTimestamp,
+				Finalizers:        []string{gameKruiseV1alpha1.GameServerExternalFinalizersFinalizer},
+			},
+		}
+		objs := []client.Object{pod}
+		var gs *gameKruiseV1alpha1.GameServer
+		if test.gsFound {
+			gs = &gameKruiseV1alpha1.GameServer{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:  "xxx",
+					Name:       "xxx-0",
+					Labels:     map[string]string{gameKruiseV1alpha1.GameServerDeletingKey: test.gsDeleting},
+					Finalizers: test.gsFinalizers,
+				},
+			}
+			objs = append(objs, gs)
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		recorder := record.NewFakeRecorder(10)
+		manager := &GameServerManager{
+			client:        c,
+			gameServer:    gs,
+			pod:           pod,
+			eventRecorder: recorder,
+		}
+
+		after, err := manager.SyncExternalFinalizers(nil)
+		if err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		if (after > 0) != test.waiting {
+			t.Errorf("case %d: expect waiting %v but actually got requeue after %v", i, test.waiting, after)
+		}
+		if event := len(recorder.Events) > 0; event != test.event {
+			t.Errorf("case %d: expect event %v but actually got %v", i, test.event, event)
+		}
+		if test.gsFound {
+			newGs := &gameKruiseV1alpha1.GameServer{}
+			if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx-0"}, newGs); err != nil {
+				t.Errorf("case %d: unexpected error %v", i, err)
+			} else if gsDeleted := newGs.GetDeletionTimestamp() != nil; gsDeleted != test.gsDeleted {
+				t.Errorf("case %d: expect GameServer deleted %v but actually got %v", i, test.gsDeleted, gsDeleted)
+			} else if !reflect.DeepEqual(test.gsFinalizers, newGs.GetFinalizers()) {
+				t.Errorf("case %d: expect GameServer finalizers %v but actually got %v", i, test.gsFinalizers, newGs.GetFinalizers())
+			}
+		}
+		newPod := &corev1.Pod{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx-0"}, newPod); err != nil {
+			// the deleting pod is gone once its finalizer is removed
+			if !errors.IsNotFound(err) || test.finalizer {
+				t.Errorf("case %d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if finalizer := controllerutil.ContainsFinalizer(newPod, gameKruiseV1alpha1.GameServerExternalFinalizersFinalizer); finalizer != test.finalizer {
+			t.Errorf("case %d: expect finalizer %v but actually got %v", i, test.finalizer, finalizer)
+		}
 	}
 }
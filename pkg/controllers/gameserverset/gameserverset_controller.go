@@ -18,9 +18,12 @@ package gameserverset
 
 import (
 	"context"
+	"flag"
+	"time"
 
 	kruiseV1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -49,8 +52,14 @@ var (
 	controllerKind = gamekruiseiov1alpha1.SchemeGroupVersion.WithKind("GameServerSet")
 	// leave it to batch size
 	concurrentReconciles = 10
+	// requeue to continue killing the GameServers deferred by KillMaxUnavailable
+	killDeferredRequeueDuration = 5 * time.Second
 )
 
+func init() {
+	flag.IntVar(&concurrentReconciles, "gameserverset-workers", concurrentReconciles, "The number of GameServerSets reconciled concurrently.")
+}
+
 func Add(mgr manager.Manager) error {
 	if !utildiscovery.DiscoverGVK(controllerKind) {
 		return nil
@@ -61,16 +70,22 @@ func Add(mgr manager.Manager) error {
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 	recorder := mgr.GetEventRecorderFor("gameserverset-controller")
 	return &GameServerSetReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		recorder: recorder,
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		recorder:  recorder,
+		apiReader: mgr.GetAPIReader(),
 	}
 }
 
+// newControllerOptions returns the options of the controller, with the workers set by flag.
+func newControllerOptions(r reconcile.Reconciler) controller.Options {
+	return controller.Options{Reconciler: r, MaxConcurrentReconciles: concurrentReconciles}
+}
+
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
 	klog.Info("Starting GameServerSet Controller")
-	c, err := controller.New("gameserverset-controller", mgr, controller.Options{Reconciler: r, MaxConcurrentReconciles: concurrentReconciles})
+	c, err := controller.New("gameserverset-controller", mgr, newControllerOptions(r))
 	if err != nil {
 		klog.Error(err)
 		return err
@@ -139,6 +154,12 @@ func watchWorkloads(c controller.Controller) (err error) {
 	}); err != nil {
 		return err
 	}
+	if err := c.Watch(&source.Kind{Type: &policyv1.PodDisruptionBudget{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &gamekruiseiov1alpha1.GameServerSet{},
+	}); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -147,11 +168,16 @@ type GameServerSetReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	recorder record.EventRecorder
+	// apiReader reads the objects not cached by the manager, such as Secrets.
+	apiReader client.Reader
 }
 
 //+kubebuilder:rbac:groups=game.kruise.io,resources=gameserversets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=game.kruise.io,resources=gameserversets/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=game.kruise.io,resources=gameserversets/finalizers,verbs=update
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;create;update
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -177,11 +203,33 @@ func (r *GameServerSetReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return reconcile.Result{}, err
 	}
 
+	// run pre-delete hook
+	if gss.GetDeletionTimestamp() != nil {
+		requeueAfter, err := runPreDeleteHook(ctx, r.Client, gss, r.recorder)
+		if err != nil {
+			klog.Errorf("GameServerSet %s failed to run pre-delete hook in %s,because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
+	}
+	updated, err := syncPreDeleteHookFinalizer(ctx, r.Client, gss)
+	if err != nil {
+		klog.Errorf("GameServerSet %s failed to sync pre-delete hook finalizer in %s,because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+		return reconcile.Result{}, err
+	}
+	if updated {
+		return reconcile.Result{}, nil
+	}
+
 	// get advanced statefulset
 	asts := &kruiseV1beta1.StatefulSet{}
 	err = r.Get(ctx, namespacedName, asts)
 	if err != nil {
 		if errors.IsNotFound(err) {
+			if util.IsGameServerSetPaused(gss) {
+				klog.Infof("GameServerSet %s in %s is paused, skip creating advanced statefulset.", namespacedName.Name, namespacedName.Namespace)
+				return reconcile.Result{}, nil
+			}
 			err = r.initAsts(gss)
 			if err != nil {
 				klog.Errorf("failed to create advanced statefulset %s in %s,because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
@@ -206,7 +254,21 @@ func (r *GameServerSetReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return reconcile.Result{}, err
 	}
 
-	gsm := NewGameServerSetManager(gss, asts, podList.Items, r.Client, r.recorder)
+	gsm := NewGameServerSetManager(gss, asts, podList.Items, r.Client, r.apiReader, r.recorder)
+
+	// only report status while paused
+	if util.IsGameServerSetPaused(gss) {
+		err = gsm.SyncStatus()
+		if err != nil {
+			klog.Errorf("GameServerSet %s failed to synchronize its status in %s,because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	// raise the workload replicas to the floor of scaling schedule
+	now := time.Now()
+	scheduleRequeueAfter := gsm.ApplyScalingSchedule(now)
 
 	// kill game servers
 	newReplicas := gsm.GetReplicasAfterKilling()
@@ -217,6 +279,9 @@ func (r *GameServerSetReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			klog.Errorf("failed to kill GameServers of GameServerSet %s in %s.", gss.GetName(), gss.GetNamespace())
 			return reconcile.Result{}, err
 		}
+		if gsm.IsKillDeferred() {
+			return reconcile.Result{RequeueAfter: killDeferredRequeueDuration}, nil
+		}
 		return reconcile.Result{}, nil
 	}
 
@@ -231,8 +296,8 @@ func (r *GameServerSetReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	}
 
 	// update workload
-	if gsm.IsNeedToUpdateWorkload() {
-		err = gsm.UpdateWorkload()
+	if gsm.IsNeedToUpdateWorkload(now) {
+		err = gsm.UpdateWorkload(now)
 		if err != nil {
 			klog.Errorf("GameServerSet %s failed to synchronize workload in %s,because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
 			return reconcile.Result{}, err
@@ -241,12 +306,42 @@ func (r *GameServerSetReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return reconcile.Result{}, nil
 	}
 
+	err = gsm.SyncImageOverrides()
+	if err != nil {
+		klog.Errorf("GameServerSet %s failed to synchronize image overrides in %s,because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+		return reconcile.Result{}, err
+	}
+
+	err = gsm.SyncMaintainIds()
+	if err != nil {
+		klog.Errorf("GameServerSet %s failed to synchronize maintain ids in %s,because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+		return reconcile.Result{}, err
+	}
+
 	err = gsm.SyncPodProbeMarker()
 	if err != nil {
 		klog.Errorf("GameServerSet %s failed to synchronize PodProbeMarker in %s,because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
 		return reconcile.Result{}, err
 	}
 
+	err = gsm.SyncPodDisruptionBudget()
+	if err != nil {
+		klog.Errorf("GameServerSet %s failed to synchronize PodDisruptionBudget in %s,because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+		return reconcile.Result{}, err
+	}
+
+	err = gsm.SyncInPlaceUpdatingPodDisruptionBudget()
+	if err != nil {
+		klog.Errorf("GameServerSet %s failed to synchronize PodDisruptionBudget of pods updated in place in %s,because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+		return reconcile.Result{}, err
+	}
+
+	err = gsm.SyncGameServerTokens()
+	if err != nil {
+		klog.Errorf("GameServerSet %s failed to synchronize GameServer tokens in %s,because of %s.", namespacedName.Name, namespacedName.Namespace, err.Error())
+		return reconcile.Result{}, err
+	}
+
 	// sync GameServerSet Status
 	err = gsm.SyncStatus()
 	if err != nil {
@@ -254,13 +349,19 @@ func (r *GameServerSetReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return reconcile.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	// requeue when the scaling schedule or the maintenance windows may change
+	requeueAfter := scheduleRequeueAfter
+	if _, holdRequeueAfter := gsm.IsUpdateHeld(now); holdRequeueAfter != 0 && (requeueAfter == 0 || holdRequeueAfter < requeueAfter) {
+		requeueAfter = holdRequeueAfter
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *GameServerSetReconciler) SetupWithManager(mgr ctrl.Manager) (c controller.Controller, err error) {
 	c, err = ctrl.NewControllerManagedBy(mgr).
-		For(&gamekruiseiov1alpha1.GameServerSet{}).Build(r)
+		For(&gamekruiseiov1alpha1.GameServerSet{}).
+		WithOptions(newControllerOptions(r)).Build(r)
 	return c, err
 }
 
@@ -294,7 +395,7 @@ func (r *GameServerSetReconciler) initAsts(gss *gamekruiseiov1alpha1.GameServerS
 
 	// set replicas
 	asts.Spec.Replicas = gss.Spec.Replicas
-	asts.Spec.ReserveOrdinals = gss.Spec.ReserveGameServerIds
+	asts.Spec.ReserveOrdinals = append(append([]int{}, gss.Spec.ReserveGameServerIds...), util.GetSliceInANotInB(gss.Spec.ExcludedGameServerIds, gss.Spec.ReserveGameServerIds)...)
 
 	// set ServiceName
 	asts.Spec.ServiceName = gss.Spec.ServiceName
@@ -304,6 +405,7 @@ func (r *GameServerSetReconciler) initAsts(gss *gamekruiseiov1alpha1.GameServerS
 	}
 
 	asts = util.GetNewAstsFromGss(gss.DeepCopy(), asts)
+	pinImageDigests(context.Background(), util.NewNodeImageDigestResolver(r.Client), gss, asts, nil)
 
 	return r.Client.Create(context.Background(), asts)
 }
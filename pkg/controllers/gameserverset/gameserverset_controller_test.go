@@ -2,16 +2,21 @@ package gameserverset
 
 import (
 	"context"
+	"flag"
 	appspub "github.com/openkruise/kruise-api/apps/pub"
+	kruiseV1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	kruiseV1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	gameKruiseV1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	"github.com/openkruise/kruise-game/pkg/util"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	"reflect"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"testing"
@@ -209,3 +214,237 @@ func TestInitAsts(t *testing.T) {
 		}
 	}
 }
+
+func TestReconcilePaused(t *testing.T) {
+	gss := &gameKruiseV1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "xxx",
+			Name:        "xxx",
+			Annotations: map[string]string{gameKruiseV1alpha1.GameServerSetPausedKey: "true"},
+		},
+		Spec: gameKruiseV1alpha1.GameServerSetSpec{
+			Replicas: ptr.To[int32](3),
+		},
+	}
+	asts := &kruiseV1beta1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "xxx",
+			Name:        "xxx",
+			Annotations: map[string]string{gameKruiseV1alpha1.AstsHashKey: "xx"},
+		},
+		Spec: kruiseV1beta1.StatefulSetSpec{
+			Replicas: ptr.To[int32](1),
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx-0",
+			Labels:    map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss, asts, pod).Build()
+	r := &GameServerSetReconciler{
+		Client:   c,
+		Scheme:   scheme,
+		recorder: record.NewFakeRecorder(100),
+	}
+
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "xxx", Name: "xxx"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	newAsts := &kruiseV1beta1.StatefulSet{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx"}, newAsts); err != nil {
+		t.Fatal(err)
+	}
+	if *newAsts.Spec.Replicas != 1 || newAsts.GetAnnotations()[gameKruiseV1alpha1.AstsHashKey] != "xx" {
+		t.Errorf("expect asts not mutated while paused, but actually got replicas %d and hash %s", *newAsts.Spec.Replicas, newAsts.GetAnnotations()[gameKruiseV1alpha1.AstsHashKey])
+	}
+	ppmList := &kruiseV1alpha1.PodProbeMarkerList{}
+	if err := c.List(context.TODO(), ppmList); err != nil {
+		t.Fatal(err)
+	}
+	if len(ppmList.Items) != 0 {
+		t.Errorf("expect no PodProbeMarker created while paused, but actually got %d", len(ppmList.Items))
+	}
+	newGss := &gameKruiseV1alpha1.GameServerSet{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx"}, newGss); err != nil {
+		t.Fatal(err)
+	}
+	if newGss.Status.Replicas != 3 || newGss.Status.CurrentReplicas != 1 {
+		t.Errorf("expect status reported while paused, but actually got %v", newGss.Status)
+	}
+
+	// the advanced statefulset is not created while paused
+	c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build()
+	r.Client = c
+	if _, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "xxx", Name: "xxx"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx"}, &kruiseV1beta1.StatefulSet{}); !errors.IsNotFound(err) {
+		t.Errorf("expect asts not created while paused, but actually got %v", err)
+	}
+}
+
+func TestReconcileScaleDownKeepsAllocated(t *testing.T) {
+	gss := &gameKruiseV1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx",
+		},
+		Spec: gameKruiseV1alpha1.GameServerSetSpec{
+			Replicas: ptr.To[int32](1),
+		},
+	}
+	asts := &kruiseV1beta1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "xxx",
+			Name:        "xxx",
+			Annotations: map[string]string{gameKruiseV1alpha1.AstsHashKey: util.GetAstsHash(gss)},
+		},
+		Spec: kruiseV1beta1.StatefulSetSpec{
+			Replicas: ptr.To[int32](3),
+		},
+	}
+	newPod := func(name string, opsState gameKruiseV1alpha1.OpsState) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      name,
+				Labels: map[string]string{
+					gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
+					gameKruiseV1alpha1.GameServerOpsStateKey: string(opsState),
+				},
+			},
+		}
+	}
+	pods := []*corev1.Pod{
+		newPod("xxx-0", gameKruiseV1alpha1.Allocated),
+		newPod("xxx-1", gameKruiseV1alpha1.None),
+		newPod("xxx-2", gameKruiseV1alpha1.Allocated),
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss, asts, pods[0], pods[1], pods[2]).Build()
+	r := &GameServerSetReconciler{
+		Client:   c,
+		Scheme:   scheme,
+		recorder: record.NewFakeRecorder(100),
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "xxx", Name: "xxx"}}
+
+	// the first reconcile scales down the None one only
+	if _, err := r.Reconcile(context.TODO(), req); err != nil {
+		t.Fatal(err)
+	}
+	scaledAsts := &kruiseV1beta1.StatefulSet{}
+	if err := c.Get(context.TODO(), req.NamespacedName, scaledAsts); err != nil {
+		t.Fatal(err)
+	}
+	if *scaledAsts.Spec.Replicas != 2 || !reflect.DeepEqual(scaledAsts.Spec.ReserveOrdinals, []int{1}) {
+		t.Fatalf("expect asts scaled to 2 replicas reserving 1, but actually got %d replicas reserving %v", *scaledAsts.Spec.Replicas, scaledAsts.Spec.ReserveOrdinals)
+	}
+
+	// the second reconcile, after the pod is deleted by asts, does not scale again and goes on to sync status
+	if err := c.Delete(context.TODO(), pods[1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.TODO(), req); err != nil {
+		t.Fatal(err)
+	}
+	newAsts := &kruiseV1beta1.StatefulSet{}
+	if err := c.Get(context.TODO(), req.NamespacedName, newAsts); err != nil {
+		t.Fatal(err)
+	}
+	if newAsts.GetResourceVersion() != scaledAsts.GetResourceVersion() {
+		t.Errorf("expect asts untouched by the second reconcile, but actually got %v", newAsts.Spec)
+	}
+	newGss := &gameKruiseV1alpha1.GameServerSet{}
+	if err := c.Get(context.TODO(), req.NamespacedName, newGss); err != nil {
+		t.Fatal(err)
+	}
+	if newGss.Status.CurrentReplicas != 2 {
+		t.Errorf("expect status synced with 2 current replicas, but actually got %v", newGss.Status)
+	}
+}
+
+func TestReconcileScalingScheduleKeepsReplicas(t *testing.T) {
+	gss := &gameKruiseV1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx",
+		},
+		Spec: gameKruiseV1alpha1.GameServerSetSpec{
+			Replicas: ptr.To[int32](1),
+			ScalingSchedule: []gameKruiseV1alpha1.ScalingScheduleWindow{
+				{
+					Schedule:        "* * * * *",
+					DurationSeconds: 3600,
+					MinReplicas:     3,
+				},
+			},
+		},
+	}
+	asts := &kruiseV1beta1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "xxx",
+			Name:        "xxx",
+			Annotations: map[string]string{gameKruiseV1alpha1.AstsHashKey: util.GetAstsHash(gss)},
+		},
+		Spec: kruiseV1beta1.StatefulSetSpec{
+			Replicas: ptr.To[int32](1),
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx-0",
+			Labels: map[string]string{
+				gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
+				gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.None),
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss, asts, pod).Build()
+	r := &GameServerSetReconciler{
+		Client:   c,
+		Scheme:   scheme,
+		recorder: record.NewFakeRecorder(100),
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "xxx", Name: "xxx"}}
+
+	if _, err := r.Reconcile(context.TODO(), req); err != nil {
+		t.Fatal(err)
+	}
+	newAsts := &kruiseV1beta1.StatefulSet{}
+	if err := c.Get(context.TODO(), req.NamespacedName, newAsts); err != nil {
+		t.Fatal(err)
+	}
+	if *newAsts.Spec.Replicas != 3 {
+		t.Errorf("expect asts raised to 3 replicas by scaling schedule, but actually got %d", *newAsts.Spec.Replicas)
+	}
+	newGss := &gameKruiseV1alpha1.GameServerSet{}
+	if err := c.Get(context.TODO(), req.NamespacedName, newGss); err != nil {
+		t.Fatal(err)
+	}
+	if *newGss.Spec.Replicas != 1 {
+		t.Errorf("expect gss replicas kept 1 for external scalers, but actually got %d", *newGss.Spec.Replicas)
+	}
+}
+
+func TestNewControllerOptions(t *testing.T) {
+	workers := flag.CommandLine.Lookup("gameserverset-workers")
+	if workers == nil {
+		t.Fatalf("expect flag gameserverset-workers registered")
+	}
+	if workers.DefValue != "10" {
+		t.Errorf("expect default workers 10 but actually got %s", workers.DefValue)
+	}
+	defer workers.Value.Set(workers.DefValue)
+
+	if err := workers.Value.Set("3"); err != nil {
+		t.Fatal(err)
+	}
+	if actual := newControllerOptions(nil).MaxConcurrentReconciles; actual != 3 {
+		t.Errorf("expect MaxConcurrentReconciles 3 but actually got %d", actual)
+	}
+}
@@ -18,13 +18,18 @@ package gameserverset
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	kruiseV1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	kruiseV1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
@@ -33,7 +38,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	gameKruiseV1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	"github.com/openkruise/kruise-game/pkg/util"
@@ -41,18 +48,29 @@ import (
 
 type Control interface {
 	GameServerScale() error
-	UpdateWorkload() error
+	UpdateWorkload(now time.Time) error
 	SyncStatus() error
 	IsNeedToScale() bool
-	IsNeedToUpdateWorkload() bool
+	IsNeedToUpdateWorkload(now time.Time) bool
+	IsUpdateHeld(now time.Time) (bool, time.Duration)
 	SyncPodProbeMarker() error
+	SyncPodDisruptionBudget() error
+	SyncInPlaceUpdatingPodDisruptionBudget() error
+	SyncGameServerTokens() error
+	SyncImageOverrides() error
+	SyncMaintainIds() error
 	GetReplicasAfterKilling() *int32
+	IsKillDeferred() bool
+	ApplyScalingSchedule(now time.Time) time.Duration
 }
 
 const (
 	ScaleReason          = "Scale"
 	CreatePPMReason      = "CreatePpm"
 	UpdatePPMReason      = "UpdatePpm"
+	CreatePDBReason      = "CreatePdb"
+	UpdatePDBReason      = "UpdatePdb"
+	CreateTokenReason    = "CreateTokenSecret"
 	CreateWorkloadReason = "CreateWorkload"
 	UpdateWorkloadReason = "UpdateWorkload"
 )
@@ -62,24 +80,63 @@ type GameServerSetManager struct {
 	asts          *kruiseV1beta1.StatefulSet
 	podList       []corev1.Pod
 	client        client.Client
+	// apiReader reads the Secret of GameServer tokens from the API server, so that Secrets are not cached.
+	// The client is used if it is nil.
+	apiReader     client.Reader
 	eventRecorder record.EventRecorder
+	// killDeferred is the number of GameServers to kill but deferred by KillMaxUnavailable or the scaling schedule.
+	killDeferred int
+	// scheduleFloor is the floor of replicas given by the active ScalingSchedule windows, set by ApplyScalingSchedule.
+	scheduleFloor *int32
+	// imageDigestResolver resolves the images of template for ResolveImageDigest.
+	imageDigestResolver util.ImageDigestResolver
 }
 
-func NewGameServerSetManager(gss *gameKruiseV1alpha1.GameServerSet, asts *kruiseV1beta1.StatefulSet, gsList []corev1.Pod, c client.Client, recorder record.EventRecorder) Control {
+func NewGameServerSetManager(gss *gameKruiseV1alpha1.GameServerSet, asts *kruiseV1beta1.StatefulSet, gsList []corev1.Pod, c client.Client, apiReader client.Reader, recorder record.EventRecorder) Control {
 	return &GameServerSetManager{
-		gameServerSet: gss,
-		asts:          asts,
-		podList:       gsList,
-		client:        c,
-		eventRecorder: recorder,
+		gameServerSet:       gss,
+		asts:                asts,
+		podList:             gsList,
+		client:              c,
+		apiReader:           apiReader,
+		eventRecorder:       recorder,
+		imageDigestResolver: util.NewNodeImageDigestResolver(c),
 	}
 }
 
+// getWorkloadReplicas returns the replicas of workload, which includes the warm pool spares beyond gss replicas
+// raised to the floor of scaling schedule.
+func (manager *GameServerSetManager) getWorkloadReplicas() int32 {
+	replicas := *manager.gameServerSet.Spec.Replicas
+	if manager.scheduleFloor != nil && *manager.scheduleFloor > replicas {
+		replicas = *manager.scheduleFloor
+	}
+	return replicas + int32(util.GetWarmPoolSpares(manager.gameServerSet, manager.podList))
+}
+
+// getScaleTargetReplicas returns the replicas the workload is scaled to. It is more than getWorkloadReplicas when
+// the Allocated and PreAllocated GameServers, which are never scaled down, outnumber it.
+func (manager *GameServerSetManager) getScaleTargetReplicas() int32 {
+	gss := manager.gameServerSet
+	var podList []corev1.Pod
+	for _, pod := range manager.podList {
+		if pod.GetDeletionTimestamp() == nil {
+			podList = append(podList, pod)
+		}
+	}
+	workloadReplicas := manager.getWorkloadReplicas()
+	if retained := int32(util.GetRetainedReplicas(gss.Spec.ReserveGameServerIds, gss.Spec.ExcludedGameServerIds, podList)); retained > workloadReplicas {
+		return retained
+	}
+	return workloadReplicas
+}
+
 func (manager *GameServerSetManager) GetReplicasAfterKilling() *int32 {
 	gss := manager.gameServerSet
 	asts := manager.asts
 	podList := manager.podList
-	if *gss.Spec.Replicas != *asts.Spec.Replicas || *gss.Spec.Replicas != int32(len(podList)) {
+	workloadReplicas := manager.getScaleTargetReplicas()
+	if workloadReplicas != *asts.Spec.Replicas || workloadReplicas != int32(len(podList)) {
 		return manager.gameServerSet.Spec.Replicas
 	}
 	toKill := 0
@@ -92,17 +149,54 @@ func (manager *GameServerSetManager) GetReplicasAfterKilling() *int32 {
 		}
 	}
 
-	klog.Infof("GameServerSet %s/%s will kill %d GameServers", gss.GetNamespace(), gss.GetName(), toKill)
+	if gss.Spec.KillMaxUnavailable != nil {
+		maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(gss.Spec.KillMaxUnavailable, int(*gss.Spec.Replicas), true)
+		if err != nil {
+			klog.Errorf("GameServerSet %s/%s has invalid killMaxUnavailable, because of %s", gss.GetNamespace(), gss.GetName(), err.Error())
+		} else if toKill > maxUnavailable {
+			manager.killDeferred = toKill - maxUnavailable
+			toKill = maxUnavailable
+		}
+	}
+
+	// the GameServers below the floor of scaling schedule are killed after the window ends
+	if floor := manager.scheduleFloor; floor != nil && *gss.Spec.Replicas-int32(toKill) < *floor {
+		held := int(*floor - (*gss.Spec.Replicas - int32(toKill)))
+		if held > toKill {
+			held = toKill
+		}
+		manager.killDeferred += held
+		toKill -= held
+	}
+
+	klog.Infof("GameServerSet %s/%s will kill %d GameServers, %d deferred", gss.GetNamespace(), gss.GetName(), toKill, manager.killDeferred)
 	return ptr.To[int32](*gss.Spec.Replicas - int32(toKill))
 }
 
+// IsKillDeferred returns whether some GameServers to kill are deferred by KillMaxUnavailable.
+// It is valid after GetReplicasAfterKilling called.
+func (manager *GameServerSetManager) IsKillDeferred() bool {
+	return manager.killDeferred > 0
+}
+
+// ApplyScalingSchedule raises the workload replicas to the floor of the active ScalingSchedule windows at now.
+// The replicas of gss are left to users and external scalers. It returns the duration until the floor may change,
+// which is zero if there is no window.
+func (manager *GameServerSetManager) ApplyScalingSchedule(now time.Time) time.Duration {
+	gss := manager.gameServerSet
+	floor, requeueAfter := util.GetScalingScheduleFloor(gss, now)
+	manager.scheduleFloor = floor
+	return requeueAfter
+}
+
 func (manager *GameServerSetManager) IsNeedToScale() bool {
 	gss := manager.gameServerSet
 	asts := manager.asts
 
 	// no need to scale
-	return !(*gss.Spec.Replicas == *asts.Spec.Replicas &&
-		util.IsSliceEqual(util.StringToIntSlice(gss.GetAnnotations()[gameKruiseV1alpha1.GameServerSetReserveIdsKey], ","), gss.Spec.ReserveGameServerIds))
+	return !(manager.getScaleTargetReplicas() == *asts.Spec.Replicas &&
+		util.IsSliceEqual(util.StringToIntSlice(gss.GetAnnotations()[gameKruiseV1alpha1.GameServerSetReserveIdsKey], ","), gss.Spec.ReserveGameServerIds) &&
+		len(util.GetSliceInANotInB(gss.Spec.ExcludedGameServerIds, asts.Spec.ReserveOrdinals)) == 0)
 }
 
 func (manager *GameServerSetManager) GameServerScale() error {
@@ -118,17 +212,18 @@ func (manager *GameServerSetManager) GameServerScale() error {
 	}
 
 	currentReplicas := len(podList)
-	expectedReplicas := int(*gss.Spec.Replicas)
+	expectedReplicas := int(manager.getWorkloadReplicas())
 	as := gss.GetAnnotations()
 	reserveIds := util.StringToIntSlice(as[gameKruiseV1alpha1.GameServerSetReserveIdsKey], ",")
 	notExistIds := util.GetSliceInANotInB(asts.Spec.ReserveOrdinals, reserveIds)
 	gssReserveIds := gss.Spec.ReserveGameServerIds
+	excludedIds := gss.Spec.ExcludedGameServerIds
 
-	klog.Infof("GameServers %s/%s already has %d replicas, expect to have %d replicas; With newExplicit: %v; oldExplicit: %v; oldImplicit: %v",
-		gss.GetNamespace(), gss.GetName(), currentReplicas, expectedReplicas, gssReserveIds, reserveIds, notExistIds)
+	klog.Infof("GameServers %s/%s already has %d replicas, expect to have %d replicas; With newExplicit: %v; oldExplicit: %v; oldImplicit: %v; excluded: %v",
+		gss.GetNamespace(), gss.GetName(), currentReplicas, expectedReplicas, gssReserveIds, reserveIds, notExistIds, excludedIds)
 	manager.eventRecorder.Eventf(gss, corev1.EventTypeNormal, ScaleReason, "scale from %d to %d", currentReplicas, expectedReplicas)
 
-	newManageIds, newReserveIds := computeToScaleGs(gssReserveIds, reserveIds, notExistIds, expectedReplicas, podList)
+	newManageIds, newReserveIds := util.ComputeToScaleGs(gssReserveIds, reserveIds, notExistIds, excludedIds, expectedReplicas, podList)
 
 	if gss.Spec.GameServerTemplate.ReclaimPolicy == gameKruiseV1alpha1.DeleteGameServerReclaimPolicy {
 		err := SyncGameServer(gss, c, newManageIds, util.GetIndexListFromPodList(podList))
@@ -137,8 +232,15 @@ func (manager *GameServerSetManager) GameServerScale() error {
 		}
 	}
 
+	// the tokens of new GameServers are generated before their pods are created, which fail to start without them
+	if err := manager.syncGameServerTokens(newManageIds); err != nil {
+		klog.Errorf("failed to generate GameServer tokens of %s in %s,because of %s.", gss.GetName(), gss.GetNamespace(), err.Error())
+		return err
+	}
+
 	asts.Spec.ReserveOrdinals = newReserveIds
-	asts.Spec.Replicas = gss.Spec.Replicas
+	// it is more than expected when Allocated GameServers are kept from scaling down, same as getScaleTargetReplicas
+	asts.Spec.Replicas = ptr.To[int32](int32(len(newManageIds)))
 	asts.Spec.ScaleStrategy = &kruiseV1beta1.StatefulSetScaleStrategy{
 		MaxUnavailable: gss.Spec.ScaleStrategy.MaxUnavailable,
 	}
@@ -149,7 +251,8 @@ func (manager *GameServerSetManager) GameServerScale() error {
 	}
 
 	if gss.Spec.ScaleStrategy.ScaleDownStrategyType == gameKruiseV1alpha1.ReserveIdsScaleDownStrategyType {
-		gssReserveIds = newReserveIds
+		// excluded ids are not backfilled, unless they are also reserved by users
+		gssReserveIds = util.GetSliceInANotInB(newReserveIds, util.GetSliceInANotInB(excludedIds, gssReserveIds))
 	}
 	gssAnnotations := make(map[string]string)
 	gssAnnotations[gameKruiseV1alpha1.GameServerSetReserveIdsKey] = util.IntSliceToString(gssReserveIds, ",")
@@ -164,61 +267,6 @@ func (manager *GameServerSetManager) GameServerScale() error {
 	return nil
 }
 
-// computeToScaleGs is to compute what the id list the pods should be existed in cluster, and what the asts reserve id list should be.
-// reserveIds is the explicit id list.
-// notExistIds is the implicit id list.
-// gssReserveIds is the newest explicit id list.
-// pods is the pods that managed by gss now.
-func computeToScaleGs(gssReserveIds, reserveIds, notExistIds []int, expectedReplicas int, pods []corev1.Pod) ([]int, []int) {
-	// 1. Get newest implicit list & explicit.
-	newAddExplicit := util.GetSliceInANotInB(gssReserveIds, reserveIds)
-	newDeleteExplicit := util.GetSliceInANotInB(reserveIds, gssReserveIds)
-	newImplicit := util.GetSliceInANotInB(notExistIds, newAddExplicit)
-	newImplicit = append(newImplicit, newDeleteExplicit...)
-	newExplicit := gssReserveIds
-
-	// 2. Remove the pods ids is in newExplicit.
-	var workloadManageIds []int
-	var newPods []corev1.Pod
-	for _, pod := range pods {
-		index := util.GetIndexFromGsName(pod.Name)
-		if util.IsNumInList(index, newExplicit) {
-			continue
-		}
-		workloadManageIds = append(workloadManageIds, index)
-		newPods = append(newPods, pod)
-	}
-
-	// 3. Continue to delete or add pods based on the current and expected number of pods.
-	existReplicas := len(workloadManageIds)
-
-	if existReplicas < expectedReplicas {
-		// Add pods.
-		num := 0
-		var toAdd []int
-		for i := 0; num < expectedReplicas-existReplicas; i++ {
-			if util.IsNumInList(i, workloadManageIds) || util.IsNumInList(i, newExplicit) {
-				continue
-			}
-			if util.IsNumInList(i, newImplicit) {
-				newImplicit = util.GetSliceInANotInB(newImplicit, []int{i})
-			}
-			toAdd = append(toAdd, i)
-			num++
-		}
-		workloadManageIds = append(workloadManageIds, toAdd...)
-	} else if existReplicas > expectedReplicas {
-		// Delete pods.
-		sortedGs := util.DeleteSequenceGs(newPods)
-		sort.Sort(sortedGs)
-		toDelete := util.GetIndexListFromPodList(sortedGs[:existReplicas-expectedReplicas])
-		workloadManageIds = util.GetSliceInANotInB(workloadManageIds, toDelete)
-		newImplicit = append(newImplicit, toDelete...)
-	}
-
-	return workloadManageIds, append(newImplicit, newExplicit...)
-}
-
 func SyncGameServer(gss *gameKruiseV1alpha1.GameServerSet, c client.Client, newManageIds, oldManageIds []int) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -236,7 +284,7 @@ func SyncGameServer(gss *gameKruiseV1alpha1.GameServerSet, c client.Client, newM
 			defer ctx.Done()
 
 			gs := &gameKruiseV1alpha1.GameServer{}
-			gsName := gss.Name + "-" + strconv.Itoa(id)
+			gsName := util.GetGsName(gss.Name, id)
 			err := c.Get(ctx, types.NamespacedName{
 				Name:      gsName,
 				Namespace: gss.Namespace,
@@ -296,13 +344,36 @@ func SyncGameServer(gss *gameKruiseV1alpha1.GameServerSet, c client.Client, newM
 	return nil
 }
 
-func (manager *GameServerSetManager) IsNeedToUpdateWorkload() bool {
-	return manager.asts.GetAnnotations()[gameKruiseV1alpha1.AstsHashKey] != util.GetAstsHash(manager.gameServerSet)
+func (manager *GameServerSetManager) IsNeedToUpdateWorkload(now time.Time) bool {
+	gss := manager.gameServerSet
+	asts := manager.asts
+	if asts.GetAnnotations()[gameKruiseV1alpha1.AstsHashKey] != util.GetAstsHash(gss) || hasPendingImageDigests(gss, asts) {
+		return true
+	}
+	if len(gss.Spec.UpdateStrategy.MaintenanceWindows) == 0 || asts.Spec.UpdateStrategy.Type == apps.OnDeleteStatefulSetStrategyType {
+		return false
+	}
+	// hold the rolling update when the maintenance window ends, and resume it when the next one starts
+	held, _ := manager.IsUpdateHeld(now)
+	paused := held || (gss.Spec.UpdateStrategy.RollingUpdate != nil && gss.Spec.UpdateStrategy.RollingUpdate.Paused)
+	return isRollingUpdatePaused(asts) != paused
+}
+
+// IsUpdateHeld returns whether rolling updates are held by MaintenanceWindows at now,
+// and the duration until the next window starts or the current one ends.
+func (manager *GameServerSetManager) IsUpdateHeld(now time.Time) (bool, time.Duration) {
+	inWindows, requeueAfter := util.IsInMaintenanceWindows(manager.gameServerSet.Spec.UpdateStrategy.MaintenanceWindows, now)
+	return !inWindows, requeueAfter
 }
 
-func (manager *GameServerSetManager) UpdateWorkload() error {
+func (manager *GameServerSetManager) UpdateWorkload(now time.Time) error {
 	gss := manager.gameServerSet
 	asts := manager.asts
+	held, _ := manager.IsUpdateHeld(now)
+	var oldPartition *int32
+	if asts.Spec.UpdateStrategy.RollingUpdate != nil {
+		oldPartition = asts.Spec.UpdateStrategy.RollingUpdate.Partition
+	}
 
 	// sync with Advanced StatefulSet
 	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -310,6 +381,11 @@ func (manager *GameServerSetManager) UpdateWorkload() error {
 		astsAns := asts.GetAnnotations()
 		astsAns[gameKruiseV1alpha1.AstsHashKey] = util.GetAstsHash(manager.gameServerSet)
 		asts.SetAnnotations(astsAns)
+		pinImageDigests(context.TODO(), manager.imageDigestResolver, gss, asts, manager.podList)
+		if held {
+			klog.Infof("rolling update of GameServerSet %s/%s is held until the next maintenance window", gss.GetNamespace(), gss.GetName())
+			holdRollingUpdate(asts, oldPartition)
+		}
 
 		return manager.client.Update(context.TODO(), asts)
 	})
@@ -317,6 +393,297 @@ func (manager *GameServerSetManager) UpdateWorkload() error {
 	return retryErr
 }
 
+// holdRollingUpdate pauses the rolling update of asts and keeps its partition from being lowered,
+// so that no more pods are updated until the next maintenance window starts.
+func holdRollingUpdate(asts *kruiseV1beta1.StatefulSet, oldPartition *int32) {
+	if asts.Spec.UpdateStrategy.Type == apps.OnDeleteStatefulSetStrategyType {
+		return
+	}
+	if asts.Spec.UpdateStrategy.RollingUpdate == nil {
+		asts.Spec.UpdateStrategy.RollingUpdate = &kruiseV1beta1.RollingUpdateStatefulSetStrategy{}
+	}
+	rollingUpdate := asts.Spec.UpdateStrategy.RollingUpdate
+	rollingUpdate.Paused = true
+	if oldPartition != nil && (rollingUpdate.Partition == nil || *rollingUpdate.Partition < *oldPartition) {
+		rollingUpdate.Partition = ptr.To(*oldPartition)
+	}
+}
+
+func isRollingUpdatePaused(asts *kruiseV1beta1.StatefulSet) bool {
+	return asts.Spec.UpdateStrategy.RollingUpdate != nil && asts.Spec.UpdateStrategy.RollingUpdate.Paused
+}
+
+// pinImageDigests replaces the images of asts template with their digests if ResolveImageDigest of gss is set.
+// The images are resolved once and recorded in annotation image-digests of asts, so that they are pinned to
+// the same digests in the following updates. The images failed to be resolved are left with their tags and
+// resolved again in the following updates, until the pods run them, after which they are recorded as is,
+// since pinning them would restart the running containers.
+func pinImageDigests(ctx context.Context, resolver util.ImageDigestResolver, gss *gameKruiseV1alpha1.GameServerSet, asts *kruiseV1beta1.StatefulSet, pods []corev1.Pod) {
+	astsAns := asts.GetAnnotations()
+	if !gss.Spec.ResolveImageDigest || resolver == nil {
+		delete(astsAns, gameKruiseV1alpha1.AstsImageDigestsKey)
+		return
+	}
+	recorded := getRecordedImageDigests(gss, asts)
+
+	pinned := make(map[string]string)
+	pin := func(containers []corev1.Container) {
+		for i := range containers {
+			image := containers[i].Image
+			if util.HasImageDigest(image) {
+				continue
+			}
+			p, ok := pinned[image]
+			if !ok {
+				p, ok = recorded[image]
+			}
+			if !ok {
+				resolved, err := resolver.ResolveImageDigest(ctx, image)
+				switch {
+				case err == nil:
+					p = resolved
+				case isImageRun(pods, image):
+					klog.Warningf("GameServerSet %s/%s failed to resolve the digest of image %s, which is left with its tag since pods run it: %s", gss.GetNamespace(), gss.GetName(), image, err.Error())
+					p = image
+				default:
+					klog.Warningf("GameServerSet %s/%s failed to resolve the digest of image %s, which will be resolved again: %s", gss.GetNamespace(), gss.GetName(), image, err.Error())
+					continue
+				}
+			}
+			pinned[image] = p
+			containers[i].Image = p
+		}
+	}
+	pin(asts.Spec.Template.Spec.InitContainers)
+	pin(asts.Spec.Template.Spec.Containers)
+
+	if len(pinned) == 0 {
+		delete(astsAns, gameKruiseV1alpha1.AstsImageDigestsKey)
+		return
+	}
+	pinnedBytes, _ := json.Marshal(pinned)
+	astsAns[gameKruiseV1alpha1.AstsImageDigestsKey] = string(pinnedBytes)
+	asts.SetAnnotations(astsAns)
+}
+
+// getRecordedImageDigests returns the images recorded in annotation image-digests of asts.
+func getRecordedImageDigests(gss *gameKruiseV1alpha1.GameServerSet, asts *kruiseV1beta1.StatefulSet) map[string]string {
+	recorded := make(map[string]string)
+	if value := asts.GetAnnotations()[gameKruiseV1alpha1.AstsImageDigestsKey]; value != "" {
+		if err := json.Unmarshal([]byte(value), &recorded); err != nil {
+			klog.Warningf("GameServerSet %s/%s has invalid annotation %s of workload: %s", gss.GetNamespace(), gss.GetName(), gameKruiseV1alpha1.AstsImageDigestsKey, err.Error())
+		}
+	}
+	return recorded
+}
+
+// hasPendingImageDigests returns whether some images of asts template failed to be resolved by pinImageDigests,
+// which are neither pinned nor recorded, and are to be resolved again.
+func hasPendingImageDigests(gss *gameKruiseV1alpha1.GameServerSet, asts *kruiseV1beta1.StatefulSet) bool {
+	if !gss.Spec.ResolveImageDigest {
+		return false
+	}
+	recorded := getRecordedImageDigests(gss, asts)
+	templateSpec := asts.Spec.Template.Spec
+	for _, container := range append(append([]corev1.Container{}, templateSpec.InitContainers...), templateSpec.Containers...) {
+		if _, ok := recorded[container.Image]; !ok && !util.HasImageDigest(container.Image) {
+			return true
+		}
+	}
+	return false
+}
+
+// isImageRun returns whether any of the pods runs the image.
+func isImageRun(pods []corev1.Pod, image string) bool {
+	for _, pod := range pods {
+		for _, container := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+			if container.Image == image {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SyncImageOverrides pins the images of GameServers according to ImageOverrides.
+// Once a container is no longer overridden, its image is set back to the template one until the pod runs it,
+// and then the container is released from GameServer.Spec.Containers.
+func (manager *GameServerSetManager) SyncImageOverrides() error {
+	gss := manager.gameServerSet
+	gsList := &gameKruiseV1alpha1.GameServerList{}
+	err := manager.client.List(context.TODO(), gsList, client.InNamespace(gss.GetNamespace()),
+		client.MatchingLabels{gameKruiseV1alpha1.GameServerOwnerGssKey: gss.GetName()})
+	if err != nil {
+		return err
+	}
+	gameServers := make(map[string]*gameKruiseV1alpha1.GameServer, len(gsList.Items))
+	for i := range gsList.Items {
+		gs := &gsList.Items[i]
+		// nothing to pin or roll back
+		if len(gss.Spec.ImageOverrides) == 0 && gs.GetAnnotations()[gameKruiseV1alpha1.GameServerImageOverrideKey] == "" {
+			continue
+		}
+		gameServers[gs.GetName()] = gs
+	}
+	if len(gameServers) == 0 {
+		return nil
+	}
+
+	for _, pod := range manager.podList {
+		gs, ok := gameServers[pod.GetName()]
+		if !ok {
+			continue
+		}
+
+		containers, managed, changed := computeImageOverrides(gss, gs, &pod)
+		if !changed {
+			continue
+		}
+		var managedValue interface{}
+		if len(managed) != 0 {
+			managedValue = strings.Join(managed, ",")
+		}
+		patchGs := map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": map[string]interface{}{gameKruiseV1alpha1.GameServerImageOverrideKey: managedValue}},
+			"spec":     map[string]interface{}{"containers": containers},
+		}
+		patchBytes, err := json.Marshal(patchGs)
+		if err != nil {
+			return err
+		}
+		err = manager.client.Patch(context.TODO(), gs, client.RawPatch(types.MergePatchType, patchBytes))
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		klog.Infof("GameServer %s/%s containers are synced with ImageOverrides", gs.GetNamespace(), gs.GetName())
+	}
+	return nil
+}
+
+// SyncMaintainIds puts the GameServers listed in annotation maintain-ids into opsState Maintaining,
+// and sets them back to None once they are removed from the list.
+// GameServers already in Maintaining are left to users, and so are the ones whose opsState is changed by others.
+func (manager *GameServerSetManager) SyncMaintainIds() error {
+	gss := manager.gameServerSet
+	ids := gss.GetAnnotations()[gameKruiseV1alpha1.GameServerSetMaintainIdsKey]
+	// without ids, only the GameServers in Maintaining may have to be set back
+	if ids == "" && !hasMaintainingPod(manager.podList) {
+		return nil
+	}
+	maintainIds, err := util.ParseIdRanges(ids)
+	if err != nil {
+		klog.Warningf("GameServerSet %s/%s has invalid annotation %s: %s", gss.GetNamespace(), gss.GetName(), gameKruiseV1alpha1.GameServerSetMaintainIdsKey, err.Error())
+		return nil
+	}
+	gsList := &gameKruiseV1alpha1.GameServerList{}
+	err = manager.client.List(context.TODO(), gsList, client.InNamespace(gss.GetNamespace()),
+		client.MatchingLabels{gameKruiseV1alpha1.GameServerOwnerGssKey: gss.GetName()})
+	if err != nil {
+		return err
+	}
+	for i := range gsList.Items {
+		gs := &gsList.Items[i]
+		maintain := util.IsNumInList(util.GetIndexFromGsName(gs.GetName()), maintainIds)
+		maintained := gs.GetAnnotations()[gameKruiseV1alpha1.GameServerMaintainedByIdsKey] == "true"
+		var patchGs map[string]interface{}
+		switch {
+		case maintain && !maintained && gs.Spec.OpsState != gameKruiseV1alpha1.Maintaining:
+			patchGs = map[string]interface{}{
+				"metadata": map[string]interface{}{"annotations": map[string]interface{}{gameKruiseV1alpha1.GameServerMaintainedByIdsKey: "true"}},
+				"spec":     map[string]interface{}{"opsState": gameKruiseV1alpha1.Maintaining},
+			}
+		case !maintain && maintained:
+			patchGs = map[string]interface{}{
+				"metadata": map[string]interface{}{"annotations": map[string]interface{}{gameKruiseV1alpha1.GameServerMaintainedByIdsKey: nil}},
+			}
+			if gs.Spec.OpsState == gameKruiseV1alpha1.Maintaining {
+				patchGs["spec"] = map[string]interface{}{"opsState": gameKruiseV1alpha1.None}
+			}
+		default:
+			continue
+		}
+		patchBytes, err := json.Marshal(patchGs)
+		if err != nil {
+			return err
+		}
+		err = manager.client.Patch(context.TODO(), gs, client.RawPatch(types.MergePatchType, patchBytes))
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		klog.Infof("GameServer %s/%s opsState is synced with %s, maintaining: %v", gs.GetNamespace(), gs.GetName(), gameKruiseV1alpha1.GameServerSetMaintainIdsKey, maintain)
+	}
+	return nil
+}
+
+// hasMaintainingPod returns whether any of the pods is in opsState Maintaining.
+func hasMaintainingPod(pods []corev1.Pod) bool {
+	for _, pod := range pods {
+		if pod.GetLabels()[gameKruiseV1alpha1.GameServerOpsStateKey] == string(gameKruiseV1alpha1.Maintaining) {
+			return true
+		}
+	}
+	return false
+}
+
+// computeImageOverrides returns the containers of GameServer after applying ImageOverrides,
+// the names of the containers still managed by ImageOverrides, and whether anything changed.
+func computeImageOverrides(gss *gameKruiseV1alpha1.GameServerSet, gs *gameKruiseV1alpha1.GameServer, pod *corev1.Pod) ([]gameKruiseV1alpha1.GameServerContainer, []string, bool) {
+	id := util.GetIndexFromGsName(gs.GetName())
+	oldManaged := gs.GetAnnotations()[gameKruiseV1alpha1.GameServerImageOverrideKey]
+	var managed []string
+	containers := make([]gameKruiseV1alpha1.GameServerContainer, 0, len(gs.Spec.Containers))
+	handled := make(map[string]bool)
+
+	apply := func(name string, c *gameKruiseV1alpha1.GameServerContainer) bool {
+		if image, ok := util.GetImageOverride(gss.Spec.ImageOverrides, id, name); ok {
+			c.Image = image
+			managed = append(managed, name)
+			return true
+		}
+		if !util.IsStringInList(name, strings.Split(oldManaged, ",")) {
+			return true
+		}
+		// no longer overridden, roll back to the template image
+		templateImage := ""
+		for _, tc := range gss.Spec.GameServerTemplate.Spec.Containers {
+			if tc.Name == name {
+				templateImage = tc.Image
+			}
+		}
+		for _, pc := range pod.Spec.Containers {
+			if pc.Name == name && pc.Image != templateImage {
+				c.Image = templateImage
+				managed = append(managed, name)
+				return true
+			}
+		}
+		c.Image = ""
+		return !equality.Semantic.DeepEqual(c.Resources, corev1.ResourceRequirements{})
+	}
+
+	for _, c := range gs.Spec.Containers {
+		handled[c.Name] = true
+		newContainer := c
+		if apply(c.Name, &newContainer) {
+			containers = append(containers, newContainer)
+		}
+	}
+	for _, o := range gss.Spec.ImageOverrides {
+		if handled[o.ContainerName] {
+			continue
+		}
+		handled[o.ContainerName] = true
+		newContainer := gameKruiseV1alpha1.GameServerContainer{Name: o.ContainerName}
+		if apply(o.ContainerName, &newContainer) && newContainer.Image != "" {
+			containers = append(containers, newContainer)
+		}
+	}
+
+	sort.Strings(managed)
+	changed := strings.Join(managed, ",") != oldManaged || !equality.Semantic.DeepEqual(containers, gs.Spec.Containers)
+	return containers, managed, changed
+}
+
 func (manager *GameServerSetManager) SyncPodProbeMarker() error {
 	gss := manager.gameServerSet
 	sqs := gss.Spec.ServiceQualities
@@ -331,7 +698,7 @@ func (manager *GameServerSetManager) SyncPodProbeMarker() error {
 	}, ppm)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			if sqs == nil {
+			if len(sqs) == 0 {
 				return nil
 			}
 			// create ppm
@@ -341,13 +708,20 @@ func (manager *GameServerSetManager) SyncPodProbeMarker() error {
 		return err
 	}
 
-	// delete ppm
-	if sqs == nil {
+	// delete ppm when all service qualities are removed
+	if len(sqs) == 0 {
 		return c.Delete(ctx, ppm)
 	}
 
-	// update ppm
-	if util.GetHash(gss.Spec.ServiceQualities) != ppm.GetAnnotations()[gameKruiseV1alpha1.PpmHashKey] {
+	// update ppm, probes are rebuilt from the whole service qualities so that removed ones are not left behind
+	ppmHash := util.GetHash(gss.Spec.ServiceQualities)
+	if ppmHash != ppm.GetAnnotations()[gameKruiseV1alpha1.PpmHashKey] {
+		ppmAns := ppm.GetAnnotations()
+		if ppmAns == nil {
+			ppmAns = make(map[string]string)
+		}
+		ppmAns[gameKruiseV1alpha1.PpmHashKey] = ppmHash
+		ppm.SetAnnotations(ppmAns)
 		ppm.Spec.Probes = constructProbes(gss)
 		manager.eventRecorder.Event(gss, corev1.EventTypeNormal, UpdatePPMReason, "update PodProbeMarker")
 		return c.Update(ctx, ppm)
@@ -355,6 +729,222 @@ func (manager *GameServerSetManager) SyncPodProbeMarker() error {
 	return nil
 }
 
+// SyncPodDisruptionBudget creates, updates or deletes the PodDisruptionBudget of GameServerSet according to
+// its PodDisruptionBudget. The PodDisruptionBudgets not controlled by GameServerSet are left as is.
+func (manager *GameServerSetManager) SyncPodDisruptionBudget() error {
+	gss := manager.gameServerSet
+	c := manager.client
+	ctx := context.Background()
+
+	pdb := &policyv1.PodDisruptionBudget{}
+	err := c.Get(ctx, types.NamespacedName{
+		Namespace: gss.GetNamespace(),
+		Name:      gss.GetName(),
+	}, pdb)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			if gss.Spec.PodDisruptionBudget == nil {
+				return nil
+			}
+			manager.eventRecorder.Event(gss, corev1.EventTypeNormal, CreatePDBReason, "create PodDisruptionBudget")
+			return c.Create(ctx, createPdb(gss))
+		}
+		return err
+	}
+	if !metav1.IsControlledBy(pdb, gss) {
+		return nil
+	}
+
+	// delete pdb when it is unset
+	if gss.Spec.PodDisruptionBudget == nil {
+		err = c.Delete(ctx, pdb)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	newPdb := createPdb(gss)
+	if equality.Semantic.DeepEqual(pdb.Spec, newPdb.Spec) {
+		return nil
+	}
+	pdb.Spec = newPdb.Spec
+	manager.eventRecorder.Event(gss, corev1.EventTypeNormal, UpdatePDBReason, "update PodDisruptionBudget")
+	return c.Update(ctx, pdb)
+}
+
+// SyncInPlaceUpdatingPodDisruptionBudget creates the PodDisruptionBudget with maxUnavailable 0 selecting the pods
+// of GameServerSet being updated in place, so that they are not evicted during the update. It is deleted once pods
+// are no longer updated in place. The PodDisruptionBudget not controlled by GameServerSet is left as is.
+func (manager *GameServerSetManager) SyncInPlaceUpdatingPodDisruptionBudget() error {
+	gss := manager.gameServerSet
+	c := manager.client
+	ctx := context.Background()
+
+	rollingUpdate := gss.Spec.UpdateStrategy.RollingUpdate
+	inPlace := rollingUpdate != nil && (rollingUpdate.PodUpdatePolicy == kruiseV1beta1.InPlaceIfPossiblePodUpdateStrategyType ||
+		rollingUpdate.PodUpdatePolicy == kruiseV1beta1.InPlaceOnlyPodUpdateStrategyType)
+
+	pdb := &policyv1.PodDisruptionBudget{}
+	err := c.Get(ctx, types.NamespacedName{
+		Namespace: gss.GetNamespace(),
+		Name:      inPlaceUpdatingPdbName(gss),
+	}, pdb)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			if !inPlace {
+				return nil
+			}
+			manager.eventRecorder.Event(gss, corev1.EventTypeNormal, CreatePDBReason, "create PodDisruptionBudget of pods updated in place")
+			return c.Create(ctx, createInPlaceUpdatingPdb(gss))
+		}
+		return err
+	}
+	if !metav1.IsControlledBy(pdb, gss) {
+		return nil
+	}
+
+	if !inPlace {
+		err = c.Delete(ctx, pdb)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	newPdb := createInPlaceUpdatingPdb(gss)
+	if equality.Semantic.DeepEqual(pdb.Spec, newPdb.Spec) {
+		return nil
+	}
+	pdb.Spec = newPdb.Spec
+	manager.eventRecorder.Event(gss, corev1.EventTypeNormal, UpdatePDBReason, "update PodDisruptionBudget of pods updated in place")
+	return c.Update(ctx, pdb)
+}
+
+// SyncGameServerTokens generates the tokens of the GameServers missing in the Secret of InjectGameServerToken.
+// The existing tokens are never changed, so that a GameServer keeps its token across recreation.
+// The Secret not controlled by GameServerSet is left as is.
+func (manager *GameServerSetManager) SyncGameServerTokens() error {
+	return manager.syncGameServerTokens(util.GetIndexListFromPodList(manager.podList))
+}
+
+// syncGameServerTokens generates the tokens of ordinals missing in the Secret of InjectGameServerToken. It is also
+// called with the ordinals to scale to before the workload is scaled, so that new pods find their tokens.
+func (manager *GameServerSetManager) syncGameServerTokens(ordinals []int) error {
+	gss := manager.gameServerSet
+	if !gss.Spec.InjectGameServerToken {
+		return nil
+	}
+	c := manager.client
+	ctx := context.Background()
+	var reader client.Reader = c
+	if manager.apiReader != nil {
+		reader = manager.apiReader
+	}
+
+	secret := &corev1.Secret{}
+	err := reader.Get(ctx, types.NamespacedName{
+		Namespace: gss.GetNamespace(),
+		Name:      util.GetGameServerTokenSecretName(gss.GetName()),
+	}, secret)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      util.GetGameServerTokenSecretName(gss.GetName()),
+				Namespace: gss.GetNamespace(),
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(gss, controllerKind),
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+		}
+		if _, err := addGameServerTokens(secret, ordinals); err != nil {
+			return err
+		}
+		manager.eventRecorder.Event(gss, corev1.EventTypeNormal, CreateTokenReason, "create Secret of GameServer tokens")
+		return c.Create(ctx, secret)
+	}
+	if !metav1.IsControlledBy(secret, gss) {
+		return nil
+	}
+
+	added, err := addGameServerTokens(secret, ordinals)
+	if err != nil || !added {
+		return err
+	}
+	return c.Update(ctx, secret)
+}
+
+// addGameServerTokens generates random tokens for the ordinals not in secret yet,
+// and returns whether any token is added.
+func addGameServerTokens(secret *corev1.Secret, ordinals []int) (bool, error) {
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	added := false
+	for _, id := range ordinals {
+		ordinal := strconv.Itoa(id)
+		if _, ok := secret.Data[ordinal]; ok {
+			continue
+		}
+		token := make([]byte, 32)
+		if _, err := rand.Read(token); err != nil {
+			return added, err
+		}
+		secret.Data[ordinal] = []byte(hex.EncodeToString(token))
+		added = true
+	}
+	return added, nil
+}
+
+func createPdb(gss *gameKruiseV1alpha1.GameServerSet) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      gss.GetName(),
+			Namespace: gss.GetNamespace(),
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(gss, controllerKind),
+			},
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable:   gss.Spec.PodDisruptionBudget.MinAvailable,
+			MaxUnavailable: gss.Spec.PodDisruptionBudget.MaxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: gss.GetName()},
+			},
+		},
+	}
+}
+
+func inPlaceUpdatingPdbName(gss *gameKruiseV1alpha1.GameServerSet) string {
+	return gss.GetName() + "-in-place-updating"
+}
+
+func createInPlaceUpdatingPdb(gss *gameKruiseV1alpha1.GameServerSet) *policyv1.PodDisruptionBudget {
+	maxUnavailable := intstr.FromInt(0)
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      inPlaceUpdatingPdbName(gss),
+			Namespace: gss.GetNamespace(),
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(gss, controllerKind),
+			},
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					gameKruiseV1alpha1.GameServerOwnerGssKey:        gss.GetName(),
+					gameKruiseV1alpha1.GameServerInPlaceUpdatingKey: "true",
+				},
+			},
+		},
+	}
+}
+
 func constructProbes(gss *gameKruiseV1alpha1.GameServerSet) []kruiseV1alpha1.PodContainerProbe {
 	var probes []kruiseV1alpha1.PodContainerProbe
 	for _, sq := range gss.Spec.ServiceQualities {
@@ -401,6 +991,40 @@ func createPpm(gss *gameKruiseV1alpha1.GameServerSet) *kruiseV1alpha1.PodProbeMa
 	}
 }
 
+// getNetworkSummary returns the external addresses of the Ready GameServers whose network is Ready, in the order of
+// their ids, at most MaxNetworkSummaryLength of them. The network status is read from the pods, which carry it
+// before the GameServers do.
+func getNetworkSummary(podList []corev1.Pod) []gameKruiseV1alpha1.GameServerNetworkSummary {
+	var summary []gameKruiseV1alpha1.GameServerNetworkSummary
+	for _, pod := range podList {
+		if pod.GetDeletionTimestamp() != nil || pod.GetLabels()[gameKruiseV1alpha1.GameServerStateKey] != string(gameKruiseV1alpha1.Ready) {
+			continue
+		}
+		networkStatusStr := pod.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkStatus]
+		if networkStatusStr == "" {
+			continue
+		}
+		networkStatus := gameKruiseV1alpha1.NetworkStatus{}
+		if err := json.Unmarshal([]byte(networkStatusStr), &networkStatus); err != nil {
+			continue
+		}
+		if networkStatus.CurrentNetworkState != gameKruiseV1alpha1.NetworkReady || len(networkStatus.ExternalAddresses) == 0 {
+			continue
+		}
+		summary = append(summary, gameKruiseV1alpha1.GameServerNetworkSummary{
+			Name:              pod.GetName(),
+			ExternalAddresses: networkStatus.ExternalAddresses,
+		})
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		return util.GetIndexFromGsName(summary[i].Name) < util.GetIndexFromGsName(summary[j].Name)
+	})
+	if len(summary) > gameKruiseV1alpha1.MaxNetworkSummaryLength {
+		summary = summary[:gameKruiseV1alpha1.MaxNetworkSummaryLength]
+	}
+	return summary
+}
+
 func (manager *GameServerSetManager) SyncStatus() error {
 	gss := manager.gameServerSet
 	asts := manager.asts
@@ -436,11 +1060,27 @@ func (manager *GameServerSetManager) SyncStatus() error {
 		WaitToBeDeletedReplicas: ptr.To[int32](int32(waitToBeDeletedGs)),
 		LabelSelector:           asts.Status.LabelSelector,
 		ObservedGeneration:      gss.GetGeneration(),
+		// NetworkConfigError is owned by the pod webhook, and left out of the patch below.
+		NetworkConfigError: gss.Status.NetworkConfigError,
+		NetworkSummary:     getNetworkSummary(podList),
 	}
 	if equality.Semantic.DeepEqual(gss.Status, status) {
 		return nil
 	}
-	patchStatus := map[string]interface{}{"status": status}
+	statusBytes, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	statusFields := make(map[string]interface{})
+	if err := json.Unmarshal(statusBytes, &statusFields); err != nil {
+		return err
+	}
+	// the omitted summary must be removed explicitly once no GameServer is ready
+	if len(status.NetworkSummary) == 0 {
+		statusFields["networkSummary"] = nil
+	}
+	delete(statusFields, "networkConfigError")
+	patchStatus := map[string]interface{}{"status": statusFields}
 	jsonPatch, err := json.Marshal(patchStatus)
 	if err != nil {
 		return err
@@ -2,18 +2,26 @@ package gameserverset
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	appspub "github.com/openkruise/kruise-api/apps/pub"
 	kruiseV1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	kruiseV1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	apps "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
@@ -33,28 +41,140 @@ func init() {
 	utilruntime.Must(kruiseV1beta1.AddToScheme(scheme))
 	utilruntime.Must(kruiseV1alpha1.AddToScheme(scheme))
 	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(batchv1.AddToScheme(scheme))
+	utilruntime.Must(policyv1.AddToScheme(scheme))
 }
 
-func TestComputeToScaleGs(t *testing.T) {
+func TestIsNeedToScale(t *testing.T) {
 	tests := []struct {
-		newGssReserveIds []int
-		oldGssreserveIds []int
-		notExistIds      []int
-		expectedReplicas int
-		pods             []corev1.Pod
-		newReserveIds    []int
-		newManageIds     []int
+		gss    *gameKruiseV1alpha1.GameServerSet
+		asts   *kruiseV1beta1.StatefulSet
+		result bool
 	}{
-		// case 0
 		{
-			newGssReserveIds: []int{2, 3, 4},
-			oldGssreserveIds: []int{2, 3},
-			notExistIds:      []int{5},
-			expectedReplicas: 3,
-			pods: []corev1.Pod{
+			gss: &gameKruiseV1alpha1.GameServerSet{
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					Replicas: ptr.To[int32](5),
+				},
+			},
+			asts: &kruiseV1beta1.StatefulSet{
+				Spec: kruiseV1beta1.StatefulSetSpec{
+					Replicas: ptr.To[int32](5),
+				},
+				Status: kruiseV1beta1.StatefulSetStatus{
+					Replicas: int32(5),
+				},
+			},
+			result: false,
+		},
+		{
+			gss: &gameKruiseV1alpha1.GameServerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{gameKruiseV1alpha1.GameServerSetReserveIdsKey: "1,5"},
+				},
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					Replicas:             ptr.To[int32](5),
+					ReserveGameServerIds: []int{1, 5},
+				},
+			},
+			asts: &kruiseV1beta1.StatefulSet{
+				Spec: kruiseV1beta1.StatefulSetSpec{
+					Replicas: ptr.To[int32](5),
+				},
+				Status: kruiseV1beta1.StatefulSetStatus{
+					Replicas: int32(5),
+				},
+			},
+			result: false,
+		},
+		{
+			gss: &gameKruiseV1alpha1.GameServerSet{
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					Replicas:              ptr.To[int32](5),
+					ExcludedGameServerIds: []int{2},
+				},
+			},
+			asts: &kruiseV1beta1.StatefulSet{
+				Spec: kruiseV1beta1.StatefulSetSpec{
+					Replicas: ptr.To[int32](5),
+				},
+				Status: kruiseV1beta1.StatefulSetStatus{
+					Replicas: int32(5),
+				},
+			},
+			result: true,
+		},
+		{
+			gss: &gameKruiseV1alpha1.GameServerSet{
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					Replicas:              ptr.To[int32](5),
+					ExcludedGameServerIds: []int{2},
+				},
+			},
+			asts: &kruiseV1beta1.StatefulSet{
+				Spec: kruiseV1beta1.StatefulSetSpec{
+					Replicas:        ptr.To[int32](5),
+					ReserveOrdinals: []int{2},
+				},
+				Status: kruiseV1beta1.StatefulSetStatus{
+					Replicas: int32(5),
+				},
+			},
+			result: false,
+		},
+	}
+	for _, test := range tests {
+		manager := &GameServerSetManager{
+			gameServerSet: test.gss,
+			asts:          test.asts,
+		}
+		actual := manager.IsNeedToScale()
+		if actual != test.result {
+			t.Errorf("expect spec %v but got %v", test.result, actual)
+		}
+	}
+}
+
+func TestGameServerScale(t *testing.T) {
+	recorder := record.NewFakeRecorder(100)
+
+	tests := []struct {
+		gss            *gameKruiseV1alpha1.GameServerSet
+		asts           *kruiseV1beta1.StatefulSet
+		podList        []corev1.Pod
+		astsReserveIds []int
+		gssReserveIds  string
+	}{
+		// case0: scale down without reserveIds
+		{
+			gss: &gameKruiseV1alpha1.GameServerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "xxx",
+					Name:        "case0",
+					Annotations: map[string]string{gameKruiseV1alpha1.GameServerSetReserveIdsKey: "1"},
+				},
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					Replicas:             ptr.To[int32](3),
+					ReserveGameServerIds: []int{1},
+				},
+			},
+			asts: &kruiseV1beta1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "xxx",
+					Name:      "case0",
+				},
+				Spec: kruiseV1beta1.StatefulSetSpec{
+					Replicas:        ptr.To[int32](4),
+					ReserveOrdinals: []int{1},
+				},
+				Status: kruiseV1beta1.StatefulSetStatus{
+					Replicas: int32(4),
+				},
+			},
+			podList: []corev1.Pod{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-0",
+						Name: "case0-0",
 						Labels: map[string]string{
 							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "10",
@@ -63,72 +183,83 @@ func TestComputeToScaleGs(t *testing.T) {
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-1",
+						Name: "case0-2",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.Maintaining),
+							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.WaitToDelete),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-4",
+						Name: "case0-3",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.WaitToDelete),
+							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-6",
+						Name: "case0-4",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
+							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.Maintaining),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
 						},
 					},
 				},
 			},
-			newReserveIds: []int{2, 3, 4, 5},
-			newManageIds:  []int{0, 1, 6},
+			astsReserveIds: []int{1, 2},
+			gssReserveIds:  "1",
 		},
-		// case 1
+		// case1: scale down with reserveIds
 		{
-			newGssReserveIds: []int{0, 2, 3},
-			oldGssreserveIds: []int{0, 4, 5},
-			notExistIds:      []int{},
-			expectedReplicas: 3,
-			pods: []corev1.Pod{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-1",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
-						},
-					},
+			gss: &gameKruiseV1alpha1.GameServerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "xxx",
+					Name:        "case1",
+					Annotations: map[string]string{gameKruiseV1alpha1.GameServerSetReserveIdsKey: "1"},
+				},
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					Replicas:             ptr.To[int32](3),
+					ReserveGameServerIds: []int{1, 0},
+				},
+			},
+			asts: &kruiseV1beta1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "xxx",
+					Name:      "case1",
+				},
+				Spec: kruiseV1beta1.StatefulSetSpec{
+					Replicas:        ptr.To[int32](4),
+					ReserveOrdinals: []int{1},
+				},
+				Status: kruiseV1beta1.StatefulSetStatus{
+					Replicas: int32(4),
 				},
+			},
+			podList: []corev1.Pod{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-2",
+						Name: "case1-0",
 						Labels: map[string]string{
 							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerDeletePriorityKey: "10",
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-3",
+						Name: "case1-2",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
+							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.WaitToDelete),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-6",
+						Name: "case1-3",
 						Labels: map[string]string{
 							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
@@ -137,54 +268,65 @@ func TestComputeToScaleGs(t *testing.T) {
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-7",
+						Name: "case1-4",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
+							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.Maintaining),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
 						},
 					},
 				},
 			},
-			newReserveIds: []int{0, 2, 3, 4, 5},
-			newManageIds:  []int{1, 6, 7},
+			astsReserveIds: []int{1, 0},
+			gssReserveIds:  "1,0",
 		},
-		// case 2
+		// case2: scale up with reserveIds
 		{
-			newGssReserveIds: []int{0},
-			oldGssreserveIds: []int{0, 4, 5},
-			notExistIds:      []int{},
-			expectedReplicas: 1,
-			pods: []corev1.Pod{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-1",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
-						},
-					},
+			gss: &gameKruiseV1alpha1.GameServerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "xxx",
+					Name:        "case2",
+					Annotations: map[string]string{gameKruiseV1alpha1.GameServerSetReserveIdsKey: "1"},
+				},
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					Replicas:             ptr.To[int32](5),
+					ReserveGameServerIds: []int{},
+				},
+			},
+			asts: &kruiseV1beta1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "xxx",
+					Name:      "case2",
+				},
+				Spec: kruiseV1beta1.StatefulSetSpec{
+					Replicas:        ptr.To[int32](4),
+					ReserveOrdinals: []int{1},
+				},
+				Status: kruiseV1beta1.StatefulSetStatus{
+					Replicas: int32(4),
 				},
+			},
+			podList: []corev1.Pod{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-2",
+						Name: "case2-0",
 						Labels: map[string]string{
 							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerDeletePriorityKey: "10",
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-3",
+						Name: "case2-2",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
+							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.WaitToDelete),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-6",
+						Name: "case2-3",
 						Labels: map[string]string{
 							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
@@ -193,92 +335,106 @@ func TestComputeToScaleGs(t *testing.T) {
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-7",
+						Name: "case2-4",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
+							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.Maintaining),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
 						},
 					},
 				},
 			},
-			newReserveIds: []int{0, 2, 3, 4, 5, 6, 7},
-			newManageIds:  []int{1},
+			astsReserveIds: nil,
+			gssReserveIds:  "",
 		},
-		// case 3
+		// case3: scale up with both reserveIds and others
 		{
-			newGssReserveIds: []int{0, 2, 3},
-			oldGssreserveIds: []int{0, 4, 5},
-			notExistIds:      []int{},
-			expectedReplicas: 4,
-			pods: []corev1.Pod{
+			gss: &gameKruiseV1alpha1.GameServerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "xxx",
+					Name:        "case3",
+					Annotations: map[string]string{gameKruiseV1alpha1.GameServerSetReserveIdsKey: "1"},
+				},
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					Replicas:             ptr.To[int32](5),
+					ReserveGameServerIds: []int{},
+				},
+			},
+			asts: &kruiseV1beta1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "xxx",
+					Name:      "case3",
+				},
+				Spec: kruiseV1beta1.StatefulSetSpec{
+					Replicas:        ptr.To[int32](3),
+					ReserveOrdinals: []int{1, 3},
+				},
+				Status: kruiseV1beta1.StatefulSetStatus{
+					Replicas: int32(3),
+				},
+			},
+			podList: []corev1.Pod{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-1",
+						Name: "case3-0",
 						Labels: map[string]string{
 							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerDeletePriorityKey: "10",
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-2",
+						Name: "case3-2",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
+							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.WaitToDelete),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-3",
+						Name: "case3-4",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-6",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-7",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
+							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.Maintaining),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
 						},
 					},
 				},
 			},
-			newReserveIds: []int{0, 2, 3, 5},
-			newManageIds:  []int{1, 4, 6, 7},
+			astsReserveIds: nil,
+			gssReserveIds:  "",
 		},
-		// case 4
+		// case4: scale down with excludedIds, which are not backfilled into reserveIds
 		{
-			newGssReserveIds: []int{0, 3, 5},
-			oldGssreserveIds: []int{0, 3, 5},
-			notExistIds:      []int{},
-			expectedReplicas: 1,
-			pods: []corev1.Pod{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-1",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
-						},
+			gss: &gameKruiseV1alpha1.GameServerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "xxx",
+					Name:      "case4",
+				},
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					Replicas:              ptr.To[int32](1),
+					ExcludedGameServerIds: []int{1},
+					ScaleStrategy: gameKruiseV1alpha1.ScaleStrategy{
+						ScaleDownStrategyType: gameKruiseV1alpha1.ReserveIdsScaleDownStrategyType,
 					},
 				},
+			},
+			asts: &kruiseV1beta1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "xxx",
+					Name:      "case4",
+				},
+				Spec: kruiseV1beta1.StatefulSetSpec{
+					Replicas: ptr.To[int32](3),
+				},
+				Status: kruiseV1beta1.StatefulSetStatus{
+					Replicas: int32(3),
+				},
+			},
+			podList: []corev1.Pod{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-2",
+						Name: "case4-0",
 						Labels: map[string]string{
 							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
@@ -287,7 +443,7 @@ func TestComputeToScaleGs(t *testing.T) {
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-4",
+						Name: "case4-1",
 						Labels: map[string]string{
 							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
 							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
@@ -296,936 +452,2138 @@ func TestComputeToScaleGs(t *testing.T) {
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-6",
+						Name: "case4-2",
 						Labels: map[string]string{
 							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerDeletePriorityKey: "10",
 						},
 					},
 				},
 			},
-			newReserveIds: []int{0, 3, 5, 2, 4, 6},
-			newManageIds:  []int{1},
+			astsReserveIds: []int{1, 2},
+			gssReserveIds:  "2",
 		},
-		// case 5
+	}
+
+	for _, test := range tests {
+		objs := []client.Object{test.asts, test.gss}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		manager := &GameServerSetManager{
+			gameServerSet: test.gss,
+			asts:          test.asts,
+			podList:       test.podList,
+			eventRecorder: recorder,
+			client:        c,
+		}
+
+		if err := manager.GameServerScale(); err != nil {
+			t.Error(err)
+		}
+
+		updateAsts := &kruiseV1beta1.StatefulSet{}
+		if err := manager.client.Get(context.TODO(), types.NamespacedName{
+			Namespace: test.asts.Namespace,
+			Name:      test.asts.Name,
+		}, updateAsts); err != nil {
+			t.Error(err)
+		}
+		if !util.IsSliceEqual(updateAsts.Spec.ReserveOrdinals, test.astsReserveIds) {
+			t.Errorf("expect asts ReserveOrdinals %v but got %v", test.astsReserveIds, updateAsts.Spec.ReserveOrdinals)
+		}
+
+		updateGss := &gameKruiseV1alpha1.GameServerSet{}
+		if err := manager.client.Get(context.TODO(), types.NamespacedName{
+			Namespace: test.gss.Namespace,
+			Name:      test.gss.Name,
+		}, updateGss); err != nil {
+			t.Error(err)
+		}
+		if updateGss.GetAnnotations()[gameKruiseV1alpha1.GameServerSetReserveIdsKey] != test.gssReserveIds {
+			t.Errorf("expect asts ReserveOrdinals %v but got %v", test.gssReserveIds, updateGss.GetAnnotations()[gameKruiseV1alpha1.GameServerSetReserveIdsKey])
+		}
+	}
+}
+
+func TestSyncGameServer(t *testing.T) {
+	tests := []struct {
+		gss           *gameKruiseV1alpha1.GameServerSet
+		gsList        []*gameKruiseV1alpha1.GameServer
+		newManageIds  []int
+		oldManageIds  []int
+		IdsLabelTure  []int
+		IdsLabelFalse []int
+	}{
+		// case 0
 		{
-			newGssReserveIds: []int{1, 2},
-			oldGssreserveIds: []int{},
-			notExistIds:      []int{1, 2},
-			expectedReplicas: 2,
-			pods: []corev1.Pod{
+			gss: &gameKruiseV1alpha1.GameServerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "xxx",
+					Name:      "xxx",
+				},
+			},
+			gsList: []*gameKruiseV1alpha1.GameServer{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-0",
+						Namespace: "xxx",
+						Name:      "xxx-0",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-3",
+						Namespace: "xxx",
+						Name:      "xxx-2",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
 						},
 					},
 				},
-			},
-			newReserveIds: []int{1, 2},
-			newManageIds:  []int{0, 3},
-		},
-		// case 6
-		{
-			newGssReserveIds: []int{},
-			oldGssreserveIds: []int{},
-			notExistIds:      []int{},
-			expectedReplicas: 3,
-			pods:             []corev1.Pod{},
-			newReserveIds:    []int{},
-			newManageIds:     []int{0, 1, 2},
-		},
-		// case 7
-		{
-			newGssReserveIds: []int{1, 2},
-			oldGssreserveIds: []int{},
-			notExistIds:      []int{},
-			expectedReplicas: 3,
-			pods:             []corev1.Pod{},
-			newReserveIds:    []int{1, 2},
-			newManageIds:     []int{0, 3, 4},
-		},
-		// case 8
-		{
-			newGssReserveIds: []int{0},
-			oldGssreserveIds: []int{},
-			notExistIds:      []int{0},
-			expectedReplicas: 1,
-			pods:             []corev1.Pod{},
-			newReserveIds:    []int{0},
-			newManageIds:     []int{1},
-		},
-		// case 9
-		{
-			newGssReserveIds: []int{},
-			oldGssreserveIds: []int{1},
-			notExistIds:      []int{},
-			expectedReplicas: 2,
-			pods: []corev1.Pod{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-0",
+						Namespace: "xxx",
+						Name:      "xxx-3",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-2",
+						Namespace: "xxx",
+						Name:      "xxx-4",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
 						},
 					},
 				},
 			},
-			newReserveIds: []int{1},
-			newManageIds:  []int{0, 2},
+			oldManageIds:  []int{0, 2, 3, 4},
+			newManageIds:  []int{0, 1},
+			IdsLabelTure:  []int{2, 3, 4},
+			IdsLabelFalse: []int{},
+		},
+
+		// case 1
+		{
+			gss: &gameKruiseV1alpha1.GameServerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "xxx",
+					Name:      "xxx",
+				},
+			},
+			gsList:        []*gameKruiseV1alpha1.GameServer{},
+			oldManageIds:  []int{},
+			newManageIds:  []int{0, 1, 3},
+			IdsLabelTure:  []int{},
+			IdsLabelFalse: []int{},
 		},
-		// case 10
+
+		// case 2
 		{
-			newGssReserveIds: []int{0},
-			oldGssreserveIds: []int{},
-			notExistIds:      []int{2, 3, 4},
-			expectedReplicas: 4,
-			pods: []corev1.Pod{
+			gss: &gameKruiseV1alpha1.GameServerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "xxx",
+					Name:      "xxx",
+				},
+			},
+			gsList: []*gameKruiseV1alpha1.GameServer{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "xxx-1",
+						Namespace: "xxx",
+						Name:      "xxx-0",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
+							gameKruiseV1alpha1.GameServerDeletingKey: "true",
 						},
 					},
 				},
 			},
-			newReserveIds: []int{0},
-			newManageIds:  []int{1, 2, 3, 4},
+			oldManageIds:  []int{},
+			newManageIds:  []int{0},
+			IdsLabelTure:  []int{},
+			IdsLabelFalse: []int{0},
 		},
 	}
 
 	for i, test := range tests {
-		t.Logf("case %d : newGssReserveIds: %v ; oldGssreserveIds: %v ; notExistIds: %v ; expectedReplicas: %d; pods: %v", i, test.newGssReserveIds, test.oldGssreserveIds, test.notExistIds, test.expectedReplicas, test.pods)
-		newManageIds, newReserveIds := computeToScaleGs(test.newGssReserveIds, test.oldGssreserveIds, test.notExistIds, test.expectedReplicas, test.pods)
-		if !util.IsSliceEqual(newReserveIds, test.newReserveIds) {
-			t.Errorf("case %d: expect newNotExistIds %v but got %v", i, test.newReserveIds, newReserveIds)
+		objs := []client.Object{test.gss}
+		for _, gs := range test.gsList {
+			objs = append(objs, gs)
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		if err := SyncGameServer(test.gss, c, test.newManageIds, test.oldManageIds); err != nil {
+			t.Error(err)
+		}
+
+		for _, id := range test.IdsLabelTure {
+			gs := &gameKruiseV1alpha1.GameServer{}
+			if err := c.Get(context.Background(), types.NamespacedName{
+				Namespace: test.gss.GetNamespace(),
+				Name:      test.gss.GetName() + "-" + strconv.Itoa(id),
+			}, gs); err != nil {
+				t.Errorf("case %d: err: %s", i, err.Error())
+			}
+			if gs.GetLabels()[gameKruiseV1alpha1.GameServerDeletingKey] != "true" {
+				t.Errorf("case %d: gs %d GameServerDeletingKey is not true", i, id)
+			}
 		}
-		if !util.IsSliceEqual(newManageIds, test.newManageIds) {
-			t.Errorf("case %d: expect newManageIds %v but got %v", i, test.newManageIds, newManageIds)
+
+		for _, id := range test.IdsLabelFalse {
+			gs := &gameKruiseV1alpha1.GameServer{}
+			if err := c.Get(context.Background(), types.NamespacedName{
+				Namespace: test.gss.GetNamespace(),
+				Name:      test.gss.GetName() + "-" + strconv.Itoa(id),
+			}, gs); err != nil {
+				t.Error(err)
+			}
+			if gs.GetLabels()[gameKruiseV1alpha1.GameServerDeletingKey] != "false" {
+				t.Errorf("case %d: gs %d GameServerDeletingKey is not false", i, id)
+			}
 		}
-		t.Logf("case %d : newManageIds: %v ; newReserveIds: %v", i, newManageIds, newReserveIds)
 	}
 }
 
-func TestIsNeedToScale(t *testing.T) {
+func TestNumberToKill(t *testing.T) {
+	now := metav1.Now()
 	tests := []struct {
-		gss    *gameKruiseV1alpha1.GameServerSet
-		asts   *kruiseV1beta1.StatefulSet
-		result bool
+		gss           *gameKruiseV1alpha1.GameServerSet
+		asts          *kruiseV1beta1.StatefulSet
+		podList       []corev1.Pod
+		scheduleFloor *int32
+		number        int32
+		deferred      bool
 	}{
+		// case 0
 		{
 			gss: &gameKruiseV1alpha1.GameServerSet{
 				Spec: gameKruiseV1alpha1.GameServerSetSpec{
-					Replicas: ptr.To[int32](5),
+					Replicas: ptr.To[int32](3),
 				},
 			},
 			asts: &kruiseV1beta1.StatefulSet{
 				Spec: kruiseV1beta1.StatefulSetSpec{
-					Replicas: ptr.To[int32](5),
+					Replicas: ptr.To[int32](3),
 				},
-				Status: kruiseV1beta1.StatefulSetStatus{
-					Replicas: int32(5),
+			},
+			podList: []corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "xxx-0",
+						Namespace: "xxx",
+						Labels: map[string]string{
+							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "xxx-2",
+						Namespace: "xxx",
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "xxx-4",
+						Namespace: "xxx",
+					},
 				},
 			},
-			result: false,
+			number: 2,
 		},
+		// case 1
 		{
 			gss: &gameKruiseV1alpha1.GameServerSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Annotations: map[string]string{gameKruiseV1alpha1.GameServerSetReserveIdsKey: "1,5"},
-				},
 				Spec: gameKruiseV1alpha1.GameServerSetSpec{
-					Replicas:             ptr.To[int32](5),
-					ReserveGameServerIds: []int{1, 5},
+					Replicas: ptr.To[int32](3),
+				},
+				Status: gameKruiseV1alpha1.GameServerSetStatus{
+					Replicas: int32(3),
 				},
 			},
 			asts: &kruiseV1beta1.StatefulSet{
 				Spec: kruiseV1beta1.StatefulSetSpec{
-					Replicas: ptr.To[int32](5),
+					Replicas: ptr.To[int32](3),
 				},
-				Status: kruiseV1beta1.StatefulSetStatus{
-					Replicas: int32(5),
+			},
+			podList: []corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "xxx-0",
+						Namespace: "xxx",
+						Labels: map[string]string{
+							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
+						},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "xxx-2",
+						Namespace:         "xxx",
+						DeletionTimestamp: &now,
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "xxx-4",
+						Namespace: "xxx",
+					},
 				},
 			},
-			result: false,
+			number: 3,
 		},
-	}
-	for _, test := range tests {
-		manager := &GameServerSetManager{
-			gameServerSet: test.gss,
-			asts:          test.asts,
-		}
-		actual := manager.IsNeedToScale()
-		if actual != test.result {
-			t.Errorf("expect spec %v but got %v", test.result, actual)
-		}
-	}
-}
-
-func TestGameServerScale(t *testing.T) {
-	recorder := record.NewFakeRecorder(100)
-
-	tests := []struct {
-		gss            *gameKruiseV1alpha1.GameServerSet
-		asts           *kruiseV1beta1.StatefulSet
-		podList        []corev1.Pod
-		astsReserveIds []int
-		gssReserveIds  string
-	}{
-		// case0: scale down without reserveIds
+		// case 2
 		{
 			gss: &gameKruiseV1alpha1.GameServerSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace:   "xxx",
-					Name:        "case0",
-					Annotations: map[string]string{gameKruiseV1alpha1.GameServerSetReserveIdsKey: "1"},
-				},
 				Spec: gameKruiseV1alpha1.GameServerSetSpec{
-					Replicas:             ptr.To[int32](3),
-					ReserveGameServerIds: []int{1},
+					Replicas: ptr.To[int32](2),
+				},
+				Status: gameKruiseV1alpha1.GameServerSetStatus{
+					Replicas: int32(2),
 				},
 			},
 			asts: &kruiseV1beta1.StatefulSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "xxx",
-					Name:      "case0",
-				},
 				Spec: kruiseV1beta1.StatefulSetSpec{
-					Replicas:        ptr.To[int32](4),
-					ReserveOrdinals: []int{1},
-				},
-				Status: kruiseV1beta1.StatefulSetStatus{
-					Replicas: int32(4),
+					Replicas: ptr.To[int32](2),
 				},
 			},
 			podList: []corev1.Pod{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case0-0",
+						Name:      "xxx-0",
+						Namespace: "xxx",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "10",
+							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case0-2",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.WaitToDelete),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
-						},
+						Name:              "xxx-2",
+						Namespace:         "xxx",
+						DeletionTimestamp: &now,
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case0-3",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
-						},
+						Name:      "xxx-4",
+						Namespace: "xxx",
 					},
 				},
+			},
+			number: 2,
+		},
+		// case 3
+		{
+			gss: &gameKruiseV1alpha1.GameServerSet{
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					Replicas: ptr.To[int32](4),
+				},
+				Status: gameKruiseV1alpha1.GameServerSetStatus{
+					Replicas: int32(3),
+				},
+			},
+			asts: &kruiseV1beta1.StatefulSet{
+				Spec: kruiseV1beta1.StatefulSetSpec{
+					Replicas: ptr.To[int32](3),
+				},
+			},
+			podList: []corev1.Pod{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case0-4",
+						Name:      "xxx-0",
+						Namespace: "xxx",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.Maintaining),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
 						},
 					},
 				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "xxx-2",
+						Namespace:         "xxx",
+						DeletionTimestamp: &now,
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "xxx-4",
+						Namespace: "xxx",
+					},
+				},
 			},
-			astsReserveIds: []int{1, 2},
-			gssReserveIds:  "1",
+			number: 4,
 		},
-		// case1: scale down with reserveIds
+		// case 4
 		{
 			gss: &gameKruiseV1alpha1.GameServerSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace:   "xxx",
-					Name:        "case1",
-					Annotations: map[string]string{gameKruiseV1alpha1.GameServerSetReserveIdsKey: "1"},
-				},
 				Spec: gameKruiseV1alpha1.GameServerSetSpec{
-					Replicas:             ptr.To[int32](3),
-					ReserveGameServerIds: []int{1, 0},
+					Replicas:           ptr.To[int32](4),
+					KillMaxUnavailable: ptr.To(intstr.FromInt(1)),
 				},
 			},
 			asts: &kruiseV1beta1.StatefulSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "xxx",
-					Name:      "case1",
-				},
 				Spec: kruiseV1beta1.StatefulSetSpec{
-					Replicas:        ptr.To[int32](4),
-					ReserveOrdinals: []int{1},
-				},
-				Status: kruiseV1beta1.StatefulSetStatus{
-					Replicas: int32(4),
+					Replicas: ptr.To[int32](4),
 				},
 			},
 			podList: []corev1.Pod{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case1-0",
+						Name:      "xxx-0",
+						Namespace: "xxx",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "10",
+							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case1-2",
+						Name:      "xxx-1",
+						Namespace: "xxx",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.WaitToDelete),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case1-3",
+						Name:      "xxx-2",
+						Namespace: "xxx",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case1-4",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.Maintaining),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
-						},
+						Name:      "xxx-3",
+						Namespace: "xxx",
 					},
 				},
 			},
-			astsReserveIds: []int{1, 0},
-			gssReserveIds:  "1,0",
+			number:   3,
+			deferred: true,
 		},
-		// case2: scale up with reserveIds
+		// case 5
 		{
 			gss: &gameKruiseV1alpha1.GameServerSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace:   "xxx",
-					Name:        "case2",
-					Annotations: map[string]string{gameKruiseV1alpha1.GameServerSetReserveIdsKey: "1"},
-				},
 				Spec: gameKruiseV1alpha1.GameServerSetSpec{
-					Replicas:             ptr.To[int32](5),
-					ReserveGameServerIds: []int{},
+					Replicas:           ptr.To[int32](4),
+					KillMaxUnavailable: ptr.To(intstr.FromString("50%")),
 				},
 			},
 			asts: &kruiseV1beta1.StatefulSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "xxx",
-					Name:      "case2",
-				},
 				Spec: kruiseV1beta1.StatefulSetSpec{
-					Replicas:        ptr.To[int32](4),
-					ReserveOrdinals: []int{1},
-				},
-				Status: kruiseV1beta1.StatefulSetStatus{
-					Replicas: int32(4),
+					Replicas: ptr.To[int32](4),
 				},
 			},
 			podList: []corev1.Pod{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case2-0",
+						Name:      "xxx-0",
+						Namespace: "xxx",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "10",
+							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case2-2",
+						Name:      "xxx-1",
+						Namespace: "xxx",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.WaitToDelete),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case2-3",
+						Name:      "xxx-2",
+						Namespace: "xxx",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case2-4",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.Maintaining),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
-						},
+						Name:      "xxx-3",
+						Namespace: "xxx",
 					},
 				},
 			},
-			astsReserveIds: nil,
-			gssReserveIds:  "",
+			number:   2,
+			deferred: true,
 		},
-		// case3: scale up with both reserveIds and others
+		// case 6: the GameServers below the floor of scaling schedule are deferred
 		{
 			gss: &gameKruiseV1alpha1.GameServerSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace:   "xxx",
-					Name:        "case3",
-					Annotations: map[string]string{gameKruiseV1alpha1.GameServerSetReserveIdsKey: "1"},
-				},
 				Spec: gameKruiseV1alpha1.GameServerSetSpec{
-					Replicas:             ptr.To[int32](5),
-					ReserveGameServerIds: []int{},
+					Replicas: ptr.To[int32](4),
 				},
 			},
 			asts: &kruiseV1beta1.StatefulSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "xxx",
-					Name:      "case3",
-				},
 				Spec: kruiseV1beta1.StatefulSetSpec{
-					Replicas:        ptr.To[int32](3),
-					ReserveOrdinals: []int{1, 3},
-				},
-				Status: kruiseV1beta1.StatefulSetStatus{
-					Replicas: int32(3),
+					Replicas: ptr.To[int32](4),
 				},
 			},
 			podList: []corev1.Pod{
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case3-0",
+						Name:      "xxx-0",
+						Namespace: "xxx",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.None),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "10",
+							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case3-2",
+						Name:      "xxx-1",
+						Namespace: "xxx",
 						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.WaitToDelete),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
+							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
 						},
 					},
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name: "case3-4",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey:       string(gameKruiseV1alpha1.Maintaining),
-							gameKruiseV1alpha1.GameServerDeletePriorityKey: "0",
-						},
+						Name:      "xxx-2",
+						Namespace: "xxx",
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "xxx-3",
+						Namespace: "xxx",
 					},
 				},
 			},
-			astsReserveIds: nil,
-			gssReserveIds:  "",
+			scheduleFloor: ptr.To[int32](3),
+			number:        3,
+			deferred:      true,
 		},
 	}
 
-	for _, test := range tests {
-		objs := []client.Object{test.asts, test.gss}
+	for i, test := range tests {
+		objs := []client.Object{test.gss}
+		for _, pod := range test.podList {
+			objs = append(objs, pod.DeepCopy())
+		}
 		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
 		manager := &GameServerSetManager{
-			gameServerSet: test.gss,
-			asts:          test.asts,
 			podList:       test.podList,
-			eventRecorder: recorder,
+			asts:          test.asts,
+			gameServerSet: test.gss,
 			client:        c,
+			scheduleFloor: test.scheduleFloor,
 		}
-
-		if err := manager.GameServerScale(); err != nil {
-			t.Error(err)
+		actual := manager.GetReplicasAfterKilling()
+		expect := test.number
+		if *actual != expect {
+			t.Errorf("case %d: expect gs replicas %v but actually %v", i, expect, *actual)
+		}
+		if manager.IsKillDeferred() != test.deferred {
+			t.Errorf("case %d: expect kill deferred %v but actually %v", i, test.deferred, manager.IsKillDeferred())
 		}
+	}
+}
 
-		updateAsts := &kruiseV1beta1.StatefulSet{}
-		if err := manager.client.Get(context.TODO(), types.NamespacedName{
-			Namespace: test.asts.Namespace,
-			Name:      test.asts.Name,
-		}, updateAsts); err != nil {
-			t.Error(err)
+func TestGameServerSetManager_ApplyScalingSchedule(t *testing.T) {
+	// Monday 19:00 UTC
+	monday := time.Date(2024, 5, 6, 19, 0, 0, 0, time.UTC)
+	peak := gameKruiseV1alpha1.ScalingScheduleWindow{
+		Name:            "peak",
+		Schedule:        "0 18 * * 1-5",
+		DurationSeconds: 4 * 3600,
+		MinReplicas:     10,
+	}
+	tests := []struct {
+		schedule     []gameKruiseV1alpha1.ScalingScheduleWindow
+		replicas     int32
+		now          time.Time
+		expect       int32
+		requeueAfter time.Duration
+	}{
+		// case 0: inside the window, workload replicas are raised to the floor until the window ends
+		{
+			schedule:     []gameKruiseV1alpha1.ScalingScheduleWindow{peak},
+			replicas:     3,
+			now:          monday,
+			expect:       10,
+			requeueAfter: 3 * time.Hour,
+		},
+		// case 1: outside the window, replicas are kept until the next window starts
+		{
+			schedule:     []gameKruiseV1alpha1.ScalingScheduleWindow{peak},
+			replicas:     3,
+			now:          monday.Add(4 * time.Hour),
+			expect:       3,
+			requeueAfter: 19 * time.Hour,
+		},
+		// case 2: inside the window, workload replicas above the floor are kept
+		{
+			schedule:     []gameKruiseV1alpha1.ScalingScheduleWindow{peak},
+			replicas:     15,
+			now:          monday,
+			expect:       15,
+			requeueAfter: 3 * time.Hour,
+		},
+		// case 3: no scaling schedule
+		{
+			replicas:     3,
+			now:          monday,
+			expect:       3,
+			requeueAfter: 0,
+		},
+		// case 4: the highest floor of overlapping windows wins, and the earliest boundary is requeued
+		{
+			schedule: []gameKruiseV1alpha1.ScalingScheduleWindow{peak, {
+				Schedule:        "30 18 * * *",
+				DurationSeconds: 3600,
+				MinReplicas:     20,
+			}},
+			replicas:     3,
+			now:          monday,
+			expect:       20,
+			requeueAfter: 30 * time.Minute,
+		},
+		// case 5: weekend is out of the window
+		{
+			schedule:     []gameKruiseV1alpha1.ScalingScheduleWindow{peak},
+			replicas:     3,
+			now:          monday.Add(-48 * time.Hour),
+			expect:       3,
+			requeueAfter: 47 * time.Hour,
+		},
+	}
+
+	for i, test := range tests {
+		manager := &GameServerSetManager{
+			gameServerSet: &gameKruiseV1alpha1.GameServerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "xxx",
+					Name:      "xxx",
+				},
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					Replicas:        ptr.To(test.replicas),
+					ScalingSchedule: test.schedule,
+				},
+			},
 		}
-		if !util.IsSliceEqual(updateAsts.Spec.ReserveOrdinals, test.astsReserveIds) {
-			t.Errorf("expect asts ReserveOrdinals %v but got %v", test.astsReserveIds, updateAsts.Spec.ReserveOrdinals)
+		requeueAfter := manager.ApplyScalingSchedule(test.now)
+		if actual := manager.getWorkloadReplicas(); actual != test.expect {
+			t.Errorf("case %d: expect workload replicas %d but actually got %d", i, test.expect, actual)
 		}
-
-		updateGss := &gameKruiseV1alpha1.GameServerSet{}
-		if err := manager.client.Get(context.TODO(), types.NamespacedName{
-			Namespace: test.gss.Namespace,
-			Name:      test.gss.Name,
-		}, updateGss); err != nil {
-			t.Error(err)
+		if *manager.gameServerSet.Spec.Replicas != test.replicas {
+			t.Errorf("case %d: expect gss replicas %d kept but actually got %d", i, test.replicas, *manager.gameServerSet.Spec.Replicas)
 		}
-		if updateGss.GetAnnotations()[gameKruiseV1alpha1.GameServerSetReserveIdsKey] != test.gssReserveIds {
-			t.Errorf("expect asts ReserveOrdinals %v but got %v", test.gssReserveIds, updateGss.GetAnnotations()[gameKruiseV1alpha1.GameServerSetReserveIdsKey])
+		if requeueAfter != test.requeueAfter {
+			t.Errorf("case %d: expect requeue after %v but actually got %v", i, test.requeueAfter, requeueAfter)
 		}
 	}
 }
 
-func TestSyncGameServer(t *testing.T) {
+func TestGameServerSetManager_UpdateWorkload(t *testing.T) {
 	tests := []struct {
-		gss           *gameKruiseV1alpha1.GameServerSet
-		gsList        []*gameKruiseV1alpha1.GameServer
-		newManageIds  []int
-		oldManageIds  []int
-		IdsLabelTure  []int
-		IdsLabelFalse []int
+		gss     *gameKruiseV1alpha1.GameServerSet
+		asts    *kruiseV1beta1.StatefulSet
+		newAsts *kruiseV1beta1.StatefulSet
 	}{
-		// case 0
 		{
 			gss: &gameKruiseV1alpha1.GameServerSet{
 				ObjectMeta: metav1.ObjectMeta{
 					Namespace: "xxx",
-					Name:      "xxx",
+					Name:      "case0",
+				},
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					GameServerTemplate: gameKruiseV1alpha1.GameServerTemplate{},
 				},
 			},
-			gsList: []*gameKruiseV1alpha1.GameServer{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Namespace: "xxx",
-						Name:      "xxx-0",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
-						},
-					},
+			asts: &kruiseV1beta1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "xxx",
+					Name:        "case0",
+					Annotations: map[string]string{gameKruiseV1alpha1.AstsHashKey: "xx"},
 				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Namespace: "xxx",
-						Name:      "xxx-2",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
+				Spec: kruiseV1beta1.StatefulSetSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Affinity: &corev1.Affinity{
+								NodeAffinity: &corev1.NodeAffinity{
+									PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
+										{
+											Weight: 1,
+											Preference: corev1.NodeSelectorTerm{
+												MatchFields: []corev1.NodeSelectorRequirement{
+													{
+														Key:      "role",
+														Operator: corev1.NodeSelectorOpIn,
+														Values:   []string{"test"},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
 						},
 					},
 				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Namespace: "xxx",
-						Name:      "xxx-3",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
-						},
-					},
+			},
+			newAsts: &kruiseV1beta1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "xxx",
+					Name:        "case0",
+					Annotations: map[string]string{gameKruiseV1alpha1.AstsHashKey: "xxx"},
 				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Namespace: "xxx",
-						Name:      "xxx-4",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
+				Spec: kruiseV1beta1.StatefulSetSpec{
+					ScaleStrategy: &kruiseV1beta1.StatefulSetScaleStrategy{
+						MaxUnavailable: nil,
+					},
+					PodManagementPolicy: apps.ParallelPodManagement,
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "case0"},
+						},
+						Spec: corev1.PodSpec{
+							ReadinessGates: []corev1.PodReadinessGate{
+								{
+									ConditionType: appspub.InPlaceUpdateReady,
+								},
+							},
 						},
 					},
 				},
 			},
-			oldManageIds:  []int{0, 2, 3, 4},
-			newManageIds:  []int{0, 1},
-			IdsLabelTure:  []int{2, 3, 4},
-			IdsLabelFalse: []int{},
 		},
+	}
+	recorder := record.NewFakeRecorder(100)
 
-		// case 1
+	for _, test := range tests {
+		objs := []client.Object{test.asts, test.gss}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		manager := &GameServerSetManager{
+			gameServerSet: test.gss,
+			asts:          test.asts,
+			eventRecorder: recorder,
+			client:        c,
+		}
+
+		if err := manager.UpdateWorkload(time.Now()); err != nil {
+			t.Error(err)
+		}
+
+		updateAsts := &kruiseV1beta1.StatefulSet{}
+		if err := manager.client.Get(context.TODO(), types.NamespacedName{
+			Namespace: test.asts.Namespace,
+			Name:      test.asts.Name,
+		}, updateAsts); err != nil {
+			t.Error(err)
+		}
+
+		if !reflect.DeepEqual(updateAsts.Spec, test.newAsts.Spec) {
+			t.Errorf("expect new asts spec %v but got %v", test.newAsts.Spec, updateAsts.Spec)
+		}
+	}
+}
+
+func TestGameServerSetManager_SyncPodProbeMarker(t *testing.T) {
+	sqs := []gameKruiseV1alpha1.ServiceQuality{
 		{
-			gss: &gameKruiseV1alpha1.GameServerSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "xxx",
-					Name:      "xxx",
+			Name:          "healthy",
+			ContainerName: "main",
+			Probe: corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					Exec: &corev1.ExecAction{Command: []string{"/bin/sh", "-c", "/healthy.sh"}},
 				},
+				InitialDelaySeconds: 5,
 			},
-			gsList:        []*gameKruiseV1alpha1.GameServer{},
-			oldManageIds:  []int{},
-			newManageIds:  []int{0, 1, 3},
-			IdsLabelTure:  []int{},
-			IdsLabelFalse: []int{},
+			Permanent: true,
 		},
-
-		// case 2
+		{
+			Name:          "idle",
+			ContainerName: "main",
+			Probe: corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					Exec: &corev1.ExecAction{Command: []string{"/bin/sh", "-c", "/idle.sh"}},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		gss  *gameKruiseV1alpha1.GameServerSet
+		hash string
+	}{
+		// case 0
 		{
 			gss: &gameKruiseV1alpha1.GameServerSet{
 				ObjectMeta: metav1.ObjectMeta{
 					Namespace: "xxx",
-					Name:      "xxx",
+					Name:      "case0",
 				},
-			},
-			gsList: []*gameKruiseV1alpha1.GameServer{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Namespace: "xxx",
-						Name:      "xxx-0",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
-							gameKruiseV1alpha1.GameServerDeletingKey: "true",
-						},
-					},
+				Spec: gameKruiseV1alpha1.GameServerSetSpec{
+					ServiceQualities: sqs,
 				},
 			},
-			oldManageIds:  []int{},
-			newManageIds:  []int{0},
-			IdsLabelTure:  []int{},
-			IdsLabelFalse: []int{0},
+			hash: util.GetHash(sqs),
 		},
 	}
+	recorder := record.NewFakeRecorder(100)
 
 	for i, test := range tests {
-		objs := []client.Object{test.gss}
-		for _, gs := range test.gsList {
-			objs = append(objs, gs)
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(test.gss).Build()
+		manager := &GameServerSetManager{
+			gameServerSet: test.gss,
+			eventRecorder: recorder,
+			client:        c,
 		}
-		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
-		if err := SyncGameServer(test.gss, c, test.newManageIds, test.oldManageIds); err != nil {
+
+		if err := manager.SyncPodProbeMarker(); err != nil {
 			t.Error(err)
 		}
-
-		for _, id := range test.IdsLabelTure {
-			gs := &gameKruiseV1alpha1.GameServer{}
-			if err := c.Get(context.Background(), types.NamespacedName{
-				Namespace: test.gss.GetNamespace(),
-				Name:      test.gss.GetName() + "-" + strconv.Itoa(id),
-			}, gs); err != nil {
-				t.Errorf("case %d: err: %s", i, err.Error())
-			}
-			if gs.GetLabels()[gameKruiseV1alpha1.GameServerDeletingKey] != "true" {
-				t.Errorf("case %d: gs %d GameServerDeletingKey is not true", i, id)
+		ppm := &kruiseV1alpha1.PodProbeMarker{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: test.gss.Namespace, Name: test.gss.Name}, ppm); err != nil {
+			t.Fatal(err)
+		}
+		if ppm.GetAnnotations()[gameKruiseV1alpha1.PpmHashKey] != test.hash {
+			t.Errorf("case %d: expect ppm hash %s but got %s", i, test.hash, ppm.GetAnnotations()[gameKruiseV1alpha1.PpmHashKey])
+		}
+		if len(ppm.Spec.Probes) != len(sqs) {
+			t.Fatalf("case %d: expect %d probes but got %d", i, len(sqs), len(ppm.Spec.Probes))
+		}
+		for j, probe := range ppm.Spec.Probes {
+			if !reflect.DeepEqual(probe.Probe.Probe, sqs[j].Probe) {
+				t.Errorf("case %d: expect probe %s %v but got %v", i, probe.Name, sqs[j].Probe, probe.Probe.Probe)
 			}
 		}
 
-		for _, id := range test.IdsLabelFalse {
-			gs := &gameKruiseV1alpha1.GameServer{}
-			if err := c.Get(context.Background(), types.NamespacedName{
-				Namespace: test.gss.GetNamespace(),
-				Name:      test.gss.GetName() + "-" + strconv.Itoa(id),
-			}, gs); err != nil {
-				t.Error(err)
-			}
-			if gs.GetLabels()[gameKruiseV1alpha1.GameServerDeletingKey] != "false" {
-				t.Errorf("case %d: gs %d GameServerDeletingKey is not false", i, id)
-			}
+		// reconcile again, ppm should not be updated when config is unchanged
+		resourceVersion := ppm.GetResourceVersion()
+		if err := manager.SyncPodProbeMarker(); err != nil {
+			t.Error(err)
+		}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: test.gss.Namespace, Name: test.gss.Name}, ppm); err != nil {
+			t.Fatal(err)
+		}
+		if ppm.GetResourceVersion() != resourceVersion {
+			t.Errorf("case %d: expect ppm not updated but resourceVersion changed from %s to %s", i, resourceVersion, ppm.GetResourceVersion())
 		}
 	}
 }
 
-func TestNumberToKill(t *testing.T) {
-	now := metav1.Now()
-	tests := []struct {
-		gss     *gameKruiseV1alpha1.GameServerSet
-		asts    *kruiseV1beta1.StatefulSet
-		podList []corev1.Pod
-		number  int32
-	}{
-		// case 0
+func TestGameServerSetManager_SyncPodProbeMarkerShrink(t *testing.T) {
+	sqs := []gameKruiseV1alpha1.ServiceQuality{
 		{
-			gss: &gameKruiseV1alpha1.GameServerSet{
-				Spec: gameKruiseV1alpha1.GameServerSetSpec{
-					Replicas: ptr.To[int32](3),
-				},
-			},
-			asts: &kruiseV1beta1.StatefulSet{
-				Spec: kruiseV1beta1.StatefulSetSpec{
-					Replicas: ptr.To[int32](3),
+			Name:          "healthy",
+			ContainerName: "main",
+			Probe: corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					Exec: &corev1.ExecAction{Command: []string{"/bin/sh", "-c", "/healthy.sh"}},
 				},
 			},
-			podList: []corev1.Pod{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "xxx-0",
-						Namespace: "xxx",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "xxx-2",
-						Namespace: "xxx",
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "xxx-4",
-						Namespace: "xxx",
-					},
+			Permanent: true,
+		},
+		{
+			Name:          "idle",
+			ContainerName: "main",
+			Probe: corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{
+					Exec: &corev1.ExecAction{Command: []string{"/bin/sh", "-c", "/idle.sh"}},
 				},
 			},
-			number: 2,
+		},
+	}
+	tests := []struct {
+		newSqs      []gameKruiseV1alpha1.ServiceQuality
+		probeNames  []string
+		ppmDeleted  bool
+		hashChanged bool
+	}{
+		// case 0
+		{
+			newSqs:      sqs[:1],
+			probeNames:  []string{"healthy"},
+			hashChanged: true,
 		},
 		// case 1
 		{
-			gss: &gameKruiseV1alpha1.GameServerSet{
-				Spec: gameKruiseV1alpha1.GameServerSetSpec{
-					Replicas: ptr.To[int32](3),
-				},
-				Status: gameKruiseV1alpha1.GameServerSetStatus{
-					Replicas: int32(3),
-				},
+			newSqs:      sqs[1:],
+			probeNames:  []string{"idle"},
+			hashChanged: true,
+		},
+		// case 2
+		{
+			newSqs:     []gameKruiseV1alpha1.ServiceQuality{},
+			ppmDeleted: true,
+		},
+	}
+	recorder := record.NewFakeRecorder(100)
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "case" + strconv.Itoa(i),
 			},
-			asts: &kruiseV1beta1.StatefulSet{
-				Spec: kruiseV1beta1.StatefulSetSpec{
-					Replicas: ptr.To[int32](3),
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				ServiceQualities: sqs,
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build()
+		manager := &GameServerSetManager{
+			gameServerSet: gss,
+			eventRecorder: recorder,
+			client:        c,
+		}
+		if err := manager.SyncPodProbeMarker(); err != nil {
+			t.Error(err)
+		}
+		ppm := &kruiseV1alpha1.PodProbeMarker{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: gss.Namespace, Name: gss.Name}, ppm); err != nil {
+			t.Fatal(err)
+		}
+		oldHash := ppm.GetAnnotations()[gameKruiseV1alpha1.PpmHashKey]
+
+		// remove service qualities
+		gss.Spec.ServiceQualities = test.newSqs
+		if err := manager.SyncPodProbeMarker(); err != nil {
+			t.Error(err)
+		}
+		ppm = &kruiseV1alpha1.PodProbeMarker{}
+		err := c.Get(context.TODO(), types.NamespacedName{Namespace: gss.Namespace, Name: gss.Name}, ppm)
+		if test.ppmDeleted {
+			if !errors.IsNotFound(err) {
+				t.Errorf("case %d: expect ppm deleted but got err %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var probeNames []string
+		for _, probe := range ppm.Spec.Probes {
+			probeNames = append(probeNames, probe.Name)
+		}
+		if !reflect.DeepEqual(probeNames, test.probeNames) {
+			t.Errorf("case %d: expect probes %v but got %v", i, test.probeNames, probeNames)
+		}
+		newHash := ppm.GetAnnotations()[gameKruiseV1alpha1.PpmHashKey]
+		if (newHash != oldHash) != test.hashChanged {
+			t.Errorf("case %d: expect hash changed %v, old hash %s, new hash %s", i, test.hashChanged, oldHash, newHash)
+		}
+		if newHash != util.GetHash(test.newSqs) {
+			t.Errorf("case %d: expect hash %s but got %s", i, util.GetHash(test.newSqs), newHash)
+		}
+	}
+}
+
+func TestGameServerSetManager_WarmPool(t *testing.T) {
+	newPod := func(name string, opsState gameKruiseV1alpha1.OpsState) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      name,
+				Labels: map[string]string{
+					gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
+					gameKruiseV1alpha1.GameServerOpsStateKey: string(opsState),
 				},
 			},
+		}
+	}
+	tests := []struct {
+		warmPoolSize  *int32
+		podList       []corev1.Pod
+		astsReplicas  int32
+		needToScale   bool
+		expectReplica int32
+	}{
+		// case 0: no warm pool
+		{
+			warmPoolSize: nil,
 			podList: []corev1.Pod{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "xxx-0",
-						Namespace: "xxx",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:              "xxx-2",
-						Namespace:         "xxx",
-						DeletionTimestamp: &now,
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "xxx-4",
-						Namespace: "xxx",
-					},
-				},
+				newPod("xxx-0", gameKruiseV1alpha1.None),
+				newPod("xxx-1", gameKruiseV1alpha1.None),
 			},
-			number: 3,
+			astsReplicas:  2,
+			needToScale:   false,
+			expectReplica: 2,
 		},
-		// case 2
+		// case 1: fill the warm pool
 		{
-			gss: &gameKruiseV1alpha1.GameServerSet{
-				Spec: gameKruiseV1alpha1.GameServerSetSpec{
-					Replicas: ptr.To[int32](2),
-				},
-				Status: gameKruiseV1alpha1.GameServerSetStatus{
-					Replicas: int32(2),
-				},
-			},
-			asts: &kruiseV1beta1.StatefulSet{
-				Spec: kruiseV1beta1.StatefulSetSpec{
-					Replicas: ptr.To[int32](2),
-				},
-			},
+			warmPoolSize: ptr.To[int32](2),
 			podList: []corev1.Pod{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "xxx-0",
-						Namespace: "xxx",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:              "xxx-2",
-						Namespace:         "xxx",
-						DeletionTimestamp: &now,
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "xxx-4",
-						Namespace: "xxx",
-					},
-				},
+				newPod("xxx-0", gameKruiseV1alpha1.None),
+				newPod("xxx-1", gameKruiseV1alpha1.None),
 			},
-			number: 2,
+			astsReplicas:  2,
+			needToScale:   true,
+			expectReplica: 4,
 		},
-		// case 3
+		// case 2: one spare is allocated, the warm pool is drained by one
 		{
-			gss: &gameKruiseV1alpha1.GameServerSet{
-				Spec: gameKruiseV1alpha1.GameServerSetSpec{
-					Replicas: ptr.To[int32](4),
-				},
-				Status: gameKruiseV1alpha1.GameServerSetStatus{
-					Replicas: int32(3),
-				},
+			warmPoolSize: ptr.To[int32](2),
+			podList: []corev1.Pod{
+				newPod("xxx-0", gameKruiseV1alpha1.Allocated),
+				newPod("xxx-1", gameKruiseV1alpha1.None),
+				newPod("xxx-2", gameKruiseV1alpha1.None),
+				newPod("xxx-3", gameKruiseV1alpha1.None),
 			},
-			asts: &kruiseV1beta1.StatefulSet{
-				Spec: kruiseV1beta1.StatefulSetSpec{
-					Replicas: ptr.To[int32](3),
-				},
+			astsReplicas:  4,
+			needToScale:   true,
+			expectReplica: 3,
+		},
+		// case 3: all spares are allocated, the warm pool is empty, but Allocated GameServers are not scaled down
+		{
+			warmPoolSize: ptr.To[int32](2),
+			podList: []corev1.Pod{
+				newPod("xxx-0", gameKruiseV1alpha1.Allocated),
+				newPod("xxx-1", gameKruiseV1alpha1.Allocated),
+				newPod("xxx-2", gameKruiseV1alpha1.Allocated),
 			},
+			astsReplicas:  3,
+			needToScale:   false,
+			expectReplica: 3,
+		},
+		// case 4: all spares are allocated, the None one is scaled down
+		{
+			warmPoolSize: ptr.To[int32](2),
 			podList: []corev1.Pod{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "xxx-0",
-						Namespace: "xxx",
-						Labels: map[string]string{
-							gameKruiseV1alpha1.GameServerOpsStateKey: string(gameKruiseV1alpha1.Kill),
-						},
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:              "xxx-2",
-						Namespace:         "xxx",
-						DeletionTimestamp: &now,
-					},
-				},
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      "xxx-4",
-						Namespace: "xxx",
-					},
-				},
+				newPod("xxx-0", gameKruiseV1alpha1.Allocated),
+				newPod("xxx-1", gameKruiseV1alpha1.None),
+				newPod("xxx-2", gameKruiseV1alpha1.Allocated),
+				newPod("xxx-3", gameKruiseV1alpha1.Allocated),
 			},
-			number: 4,
+			astsReplicas:  4,
+			needToScale:   true,
+			expectReplica: 3,
 		},
 	}
+	recorder := record.NewFakeRecorder(100)
 
 	for i, test := range tests {
-		objs := []client.Object{test.gss}
-		for _, pod := range test.podList {
-			objs = append(objs, pod.DeepCopy())
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx",
+			},
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				Replicas:     ptr.To[int32](2),
+				WarmPoolSize: test.warmPoolSize,
+			},
 		}
-		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		asts := &kruiseV1beta1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx",
+			},
+			Spec: kruiseV1beta1.StatefulSetSpec{
+				Replicas: ptr.To[int32](test.astsReplicas),
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss, asts).Build()
 		manager := &GameServerSetManager{
+			gameServerSet: gss,
+			asts:          asts,
 			podList:       test.podList,
-			asts:          test.asts,
-			gameServerSet: test.gss,
+			eventRecorder: recorder,
 			client:        c,
 		}
-		actual := manager.GetReplicasAfterKilling()
-		expect := test.number
-		if *actual != expect {
-			t.Errorf("case %d: expect gs replicas %v but actually %v", i, expect, *actual)
+
+		if manager.IsNeedToScale() != test.needToScale {
+			t.Errorf("case %d: expect need to scale %v but got %v", i, test.needToScale, !test.needToScale)
+		}
+		if !test.needToScale {
+			continue
+		}
+		if err := manager.GameServerScale(); err != nil {
+			t.Error(err)
+		}
+		updateAsts := &kruiseV1beta1.StatefulSet{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: asts.Namespace, Name: asts.Name}, updateAsts); err != nil {
+			t.Error(err)
+		}
+		if *updateAsts.Spec.Replicas != test.expectReplica {
+			t.Errorf("case %d: expect asts replicas %d but got %d", i, test.expectReplica, *updateAsts.Spec.Replicas)
 		}
 	}
 }
 
-func TestGameServerSetManager_UpdateWorkload(t *testing.T) {
+func TestGameServerSetManager_SyncImageOverrides(t *testing.T) {
+	newGss := func(overrides []gameKruiseV1alpha1.ImageOverride) *gameKruiseV1alpha1.GameServerSet {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx",
+			},
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				Replicas:       ptr.To[int32](1),
+				ImageOverrides: overrides,
+			},
+		}
+		gss.Spec.GameServerTemplate.Spec.Containers = []corev1.Container{{Name: "gameserver", Image: "gameserver:v1"}}
+		return gss
+	}
 	tests := []struct {
-		gss     *gameKruiseV1alpha1.GameServerSet
-		asts    *kruiseV1beta1.StatefulSet
-		newAsts *kruiseV1beta1.StatefulSet
+		gss             *gameKruiseV1alpha1.GameServerSet
+		gsAnnotations   map[string]string
+		gsContainers    []gameKruiseV1alpha1.GameServerContainer
+		podImage        string
+		expectManaged   string
+		expectContainer []gameKruiseV1alpha1.GameServerContainer
 	}{
+		// case 0: the id is overridden
 		{
-			gss: &gameKruiseV1alpha1.GameServerSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "xxx",
-					Name:      "case0",
-				},
-				Spec: gameKruiseV1alpha1.GameServerSetSpec{
-					GameServerTemplate: gameKruiseV1alpha1.GameServerTemplate{},
-				},
+			gss: newGss([]gameKruiseV1alpha1.ImageOverride{
+				{StartId: 0, EndId: 4, ContainerName: "gameserver", Image: "gameserver:v2"},
+			}),
+			podImage:        "gameserver:v1",
+			expectManaged:   "gameserver",
+			expectContainer: []gameKruiseV1alpha1.GameServerContainer{{Name: "gameserver", Image: "gameserver:v2"}},
+		},
+		// case 1: the id is out of range
+		{
+			gss: newGss([]gameKruiseV1alpha1.ImageOverride{
+				{StartId: 1, EndId: 4, ContainerName: "gameserver", Image: "gameserver:v2"},
+			}),
+			podImage:        "gameserver:v1",
+			expectManaged:   "",
+			expectContainer: nil,
+		},
+		// case 2: the override is removed, and the pod still runs the override image
+		{
+			gss:             newGss(nil),
+			gsAnnotations:   map[string]string{gameKruiseV1alpha1.GameServerImageOverrideKey: "gameserver"},
+			gsContainers:    []gameKruiseV1alpha1.GameServerContainer{{Name: "gameserver", Image: "gameserver:v2"}},
+			podImage:        "gameserver:v2",
+			expectManaged:   "gameserver",
+			expectContainer: []gameKruiseV1alpha1.GameServerContainer{{Name: "gameserver", Image: "gameserver:v1"}},
+		},
+		// case 3: the override is removed, and the pod runs the template image
+		{
+			gss:             newGss(nil),
+			gsAnnotations:   map[string]string{gameKruiseV1alpha1.GameServerImageOverrideKey: "gameserver"},
+			gsContainers:    []gameKruiseV1alpha1.GameServerContainer{{Name: "gameserver", Image: "gameserver:v1"}},
+			podImage:        "gameserver:v1",
+			expectManaged:   "",
+			expectContainer: nil,
+		},
+		// case 4: no override at all
+		{
+			gss:             newGss(nil),
+			podImage:        "gameserver:v1",
+			expectManaged:   "",
+			expectContainer: nil,
+		},
+	}
+
+	for i, test := range tests {
+		gs := &gameKruiseV1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        "xxx-0",
+				Labels:      map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"},
+				Annotations: test.gsAnnotations,
 			},
-			asts: &kruiseV1beta1.StatefulSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace:   "xxx",
-					Name:        "case0",
-					Annotations: map[string]string{gameKruiseV1alpha1.AstsHashKey: "xx"},
-				},
-				Spec: kruiseV1beta1.StatefulSetSpec{
-					Template: corev1.PodTemplateSpec{
-						Spec: corev1.PodSpec{
-							Affinity: &corev1.Affinity{
-								NodeAffinity: &corev1.NodeAffinity{
-									PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
-										{
-											Weight: 1,
-											Preference: corev1.NodeSelectorTerm{
-												MatchFields: []corev1.NodeSelectorRequirement{
-													{
-														Key:      "role",
-														Operator: corev1.NodeSelectorOpIn,
-														Values:   []string{"test"},
-													},
-												},
-											},
-										},
-									},
-								},
-							},
-						},
+			Spec: gameKruiseV1alpha1.GameServerSpec{
+				Containers: test.gsContainers,
+			},
+		}
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "gameserver", Image: test.podImage}},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs).Build()
+		manager := &GameServerSetManager{
+			gameServerSet: test.gss,
+			podList:       []corev1.Pod{pod},
+			client:        c,
+		}
+
+		if err := manager.SyncImageOverrides(); err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		newGs := &gameKruiseV1alpha1.GameServer{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx-0"}, newGs); err != nil {
+			t.Error(err)
+			continue
+		}
+		if managed := newGs.GetAnnotations()[gameKruiseV1alpha1.GameServerImageOverrideKey]; managed != test.expectManaged {
+			t.Errorf("case %d: expect managed containers %s but actually got %s", i, test.expectManaged, managed)
+		}
+		if !reflect.DeepEqual(newGs.Spec.Containers, test.expectContainer) {
+			t.Errorf("case %d: expect containers %v but actually got %v", i, test.expectContainer, newGs.Spec.Containers)
+		}
+	}
+}
+
+func TestGameServerSetManager_UpdateWorkloadTopologySpread(t *testing.T) {
+	zoneConstraint := corev1.TopologySpreadConstraint{
+		MaxSkew:           2,
+		TopologyKey:       corev1.LabelTopologyZone,
+		WhenUnsatisfiable: corev1.DoNotSchedule,
+	}
+	tests := []struct {
+		topologySpread    *gameKruiseV1alpha1.TopologySpread
+		constraints       []corev1.TopologySpreadConstraint
+		expectConstraints []corev1.TopologySpreadConstraint
+	}{
+		// case 0: no topology spread
+		{
+			topologySpread:    nil,
+			constraints:       []corev1.TopologySpreadConstraint{zoneConstraint},
+			expectConstraints: []corev1.TopologySpreadConstraint{zoneConstraint},
+		},
+		// case 1: topology spread with defaults
+		{
+			topologySpread: &gameKruiseV1alpha1.TopologySpread{
+				TopologyKey: corev1.LabelHostname,
+			},
+			expectConstraints: []corev1.TopologySpreadConstraint{
+				{
+					MaxSkew:           1,
+					TopologyKey:       corev1.LabelHostname,
+					WhenUnsatisfiable: corev1.ScheduleAnyway,
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"},
 					},
 				},
 			},
-			newAsts: &kruiseV1beta1.StatefulSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace:   "xxx",
-					Name:        "case0",
-					Annotations: map[string]string{gameKruiseV1alpha1.AstsHashKey: "xxx"},
-				},
-				Spec: kruiseV1beta1.StatefulSetSpec{
-					ScaleStrategy: &kruiseV1beta1.StatefulSetScaleStrategy{
-						MaxUnavailable: nil,
-					},
-					PodManagementPolicy: apps.ParallelPodManagement,
-					Template: corev1.PodTemplateSpec{
-						ObjectMeta: metav1.ObjectMeta{
-							Labels: map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "case0"},
-						},
-						Spec: corev1.PodSpec{
-							ReadinessGates: []corev1.PodReadinessGate{
-								{
-									ConditionType: appspub.InPlaceUpdateReady,
-								},
-							},
-						},
+		},
+		// case 2: merged with the existing constraints
+		{
+			topologySpread: &gameKruiseV1alpha1.TopologySpread{
+				TopologyKey:       corev1.LabelHostname,
+				MaxSkew:           ptr.To[int32](3),
+				WhenUnsatisfiable: corev1.DoNotSchedule,
+			},
+			constraints: []corev1.TopologySpreadConstraint{zoneConstraint},
+			expectConstraints: []corev1.TopologySpreadConstraint{
+				zoneConstraint,
+				{
+					MaxSkew:           3,
+					TopologyKey:       corev1.LabelHostname,
+					WhenUnsatisfiable: corev1.DoNotSchedule,
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"},
 					},
 				},
 			},
 		},
+		// case 3: the existing constraint with the same topologyKey takes precedence
+		{
+			topologySpread: &gameKruiseV1alpha1.TopologySpread{
+				TopologyKey: corev1.LabelTopologyZone,
+			},
+			constraints:       []corev1.TopologySpreadConstraint{zoneConstraint},
+			expectConstraints: []corev1.TopologySpreadConstraint{zoneConstraint},
+		},
 	}
 	recorder := record.NewFakeRecorder(100)
 
-	for _, test := range tests {
-		objs := []client.Object{test.asts, test.gss}
-		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx",
+			},
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				TopologySpread: test.topologySpread,
+			},
+		}
+		gss.Spec.GameServerTemplate.Spec.TopologySpreadConstraints = test.constraints
+		asts := &kruiseV1beta1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        "xxx",
+				Annotations: map[string]string{gameKruiseV1alpha1.AstsHashKey: "xx"},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(asts, gss).Build()
 		manager := &GameServerSetManager{
-			gameServerSet: test.gss,
-			asts:          test.asts,
+			gameServerSet: gss,
+			asts:          asts,
 			eventRecorder: recorder,
 			client:        c,
 		}
 
-		if err := manager.UpdateWorkload(); err != nil {
+		if err := manager.UpdateWorkload(time.Now()); err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+
+		updateAsts := &kruiseV1beta1.StatefulSet{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx"}, updateAsts); err != nil {
+			t.Error(err)
+			continue
+		}
+		if !reflect.DeepEqual(updateAsts.Spec.Template.Spec.TopologySpreadConstraints, test.expectConstraints) {
+			t.Errorf("case %d: expect topologySpreadConstraints %v but actually got %v", i, test.expectConstraints, updateAsts.Spec.Template.Spec.TopologySpreadConstraints)
+		}
+	}
+}
+
+func TestGameServerSetManager_UpdateWorkloadDefaultContainerResources(t *testing.T) {
+	defaults := &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("1"),
+			corev1.ResourceMemory: resource.MustParse("1Gi"),
+		},
+	}
+	gss := &gameKruiseV1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx",
+		},
+		Spec: gameKruiseV1alpha1.GameServerSetSpec{
+			DefaultContainerResources: defaults,
+		},
+	}
+	gss.Spec.GameServerTemplate.Spec.Containers = []corev1.Container{
+		// unset
+		{Name: "gameserver"},
+		// explicit requests and limits
+		{
+			Name: "sidecar",
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")},
+			},
+		},
+		// request higher than the default limit
+		{
+			Name: "proxy",
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			},
+		},
+	}
+	expectResources := []corev1.ResourceRequirements{
+		*defaults,
+		{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("200m"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+		{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+				corev1.ResourceMemory: resource.MustParse("2Gi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("1"),
+			},
+		},
+	}
+	asts := &kruiseV1beta1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "xxx",
+			Name:        "xxx",
+			Annotations: map[string]string{gameKruiseV1alpha1.AstsHashKey: "xx"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(asts, gss).Build()
+	manager := &GameServerSetManager{
+		gameServerSet: gss,
+		asts:          asts,
+		eventRecorder: record.NewFakeRecorder(100),
+		client:        c,
+	}
+
+	if err := manager.UpdateWorkload(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	updateAsts := &kruiseV1beta1.StatefulSet{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx"}, updateAsts); err != nil {
+		t.Fatal(err)
+	}
+	for i, container := range updateAsts.Spec.Template.Spec.Containers {
+		if !equality.Semantic.DeepEqual(container.Resources, expectResources[i]) {
+			t.Errorf("container %s: expect resources %v but actually got %v", container.Name, expectResources[i], container.Resources)
+		}
+	}
+	if len(gss.Spec.GameServerTemplate.Spec.Containers[0].Resources.Requests) != 0 {
+		t.Errorf("expect GameServerTemplate not modified, but actually got %v", gss.Spec.GameServerTemplate.Spec.Containers[0].Resources)
+	}
+}
+
+func TestGameServerSetManager_SyncMaintainIds(t *testing.T) {
+	tests := []struct {
+		maintainIds   string
+		opsState      gameKruiseV1alpha1.OpsState
+		gsAnnotations map[string]string
+		expectState   gameKruiseV1alpha1.OpsState
+		expectManaged bool
+	}{
+		// case 0: the id is added
+		{
+			maintainIds:   "0,3-5",
+			opsState:      gameKruiseV1alpha1.None,
+			expectState:   gameKruiseV1alpha1.Maintaining,
+			expectManaged: true,
+		},
+		// case 1: the id is not listed
+		{
+			maintainIds:   "1-2",
+			opsState:      gameKruiseV1alpha1.None,
+			expectState:   gameKruiseV1alpha1.None,
+			expectManaged: false,
+		},
+		// case 2: the id is removed
+		{
+			maintainIds:   "1",
+			opsState:      gameKruiseV1alpha1.Maintaining,
+			gsAnnotations: map[string]string{gameKruiseV1alpha1.GameServerMaintainedByIdsKey: "true"},
+			expectState:   gameKruiseV1alpha1.None,
+			expectManaged: false,
+		},
+		// case 3: the id is removed, while the opsState has been changed by others
+		{
+			maintainIds:   "1",
+			opsState:      gameKruiseV1alpha1.WaitToDelete,
+			gsAnnotations: map[string]string{gameKruiseV1alpha1.GameServerMaintainedByIdsKey: "true"},
+			expectState:   gameKruiseV1alpha1.WaitToDelete,
+			expectManaged: false,
+		},
+		// case 4: the gs put into Maintaining by users is left as is
+		{
+			maintainIds:   "0",
+			opsState:      gameKruiseV1alpha1.Maintaining,
+			expectState:   gameKruiseV1alpha1.Maintaining,
+			expectManaged: false,
+		},
+		// case 5: the annotation is cleared
+		{
+			maintainIds:   "",
+			opsState:      gameKruiseV1alpha1.Maintaining,
+			gsAnnotations: map[string]string{gameKruiseV1alpha1.GameServerMaintainedByIdsKey: "true"},
+			expectState:   gameKruiseV1alpha1.None,
+			expectManaged: false,
+		},
+		// case 6: neither ids nor gs in Maintaining
+		{
+			maintainIds:   "",
+			opsState:      gameKruiseV1alpha1.None,
+			expectState:   gameKruiseV1alpha1.None,
+			expectManaged: false,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        "xxx",
+				Annotations: map[string]string{gameKruiseV1alpha1.GameServerSetMaintainIdsKey: test.maintainIds},
+			},
+		}
+		gs := &gameKruiseV1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        "xxx-0",
+				Labels:      map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"},
+				Annotations: test.gsAnnotations,
+			},
+			Spec: gameKruiseV1alpha1.GameServerSpec{
+				OpsState: test.opsState,
+			},
+		}
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+				Labels:    map[string]string{gameKruiseV1alpha1.GameServerOpsStateKey: string(test.opsState)},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs).Build()
+		manager := &GameServerSetManager{
+			gameServerSet: gss,
+			podList:       []corev1.Pod{pod},
+			client:        c,
+		}
+
+		if err := manager.SyncMaintainIds(); err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		newGs := &gameKruiseV1alpha1.GameServer{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx-0"}, newGs); err != nil {
+			t.Error(err)
+			continue
+		}
+		if newGs.Spec.OpsState != test.expectState {
+			t.Errorf("case %d: expect opsState %s but actually got %s", i, test.expectState, newGs.Spec.OpsState)
+		}
+		if managed := newGs.GetAnnotations()[gameKruiseV1alpha1.GameServerMaintainedByIdsKey] == "true"; managed != test.expectManaged {
+			t.Errorf("case %d: expect maintained by ids %v but actually got %v", i, test.expectManaged, managed)
+		}
+	}
+}
+
+func TestGameServerSetManager_SyncPodDisruptionBudget(t *testing.T) {
+	gss := &gameKruiseV1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx",
+			UID:       "xxx-uid",
+		},
+		Spec: gameKruiseV1alpha1.GameServerSetSpec{
+			Replicas: ptr.To[int32](5),
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build()
+	minAvailable := intstr.FromString("80%")
+	maxUnavailable := intstr.FromInt(1)
+	tests := []struct {
+		pdb    *gameKruiseV1alpha1.GameServerSetPodDisruptionBudget
+		exist  bool
+		expect policyv1.PodDisruptionBudgetSpec
+	}{
+		// case 0: not set
+		{
+			pdb:   nil,
+			exist: false,
+		},
+		// case 1: created
+		{
+			pdb:   &gameKruiseV1alpha1.GameServerSetPodDisruptionBudget{MinAvailable: &minAvailable},
+			exist: true,
+			expect: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"}},
+			},
+		},
+		// case 2: updated
+		{
+			pdb:   &gameKruiseV1alpha1.GameServerSetPodDisruptionBudget{MaxUnavailable: &maxUnavailable},
+			exist: true,
+			expect: policyv1.PodDisruptionBudgetSpec{
+				MaxUnavailable: &maxUnavailable,
+				Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"}},
+			},
+		},
+		// case 3: deleted
+		{
+			pdb:   nil,
+			exist: false,
+		},
+	}
+
+	for i, test := range tests {
+		gss.Spec.PodDisruptionBudget = test.pdb
+		manager := &GameServerSetManager{
+			gameServerSet: gss,
+			client:        c,
+			eventRecorder: record.NewFakeRecorder(10),
+		}
+		if err := manager.SyncPodDisruptionBudget(); err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		pdb := &policyv1.PodDisruptionBudget{}
+		err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx"}, pdb)
+		if !test.exist {
+			if !errors.IsNotFound(err) {
+				t.Errorf("case %d: expect no PodDisruptionBudget but actually got err %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(pdb.Spec, test.expect) {
+			t.Errorf("case %d: expect PodDisruptionBudget spec %v but actually got %v", i, test.expect, pdb.Spec)
+		}
+		if !metav1.IsControlledBy(pdb, gss) {
+			t.Errorf("case %d: expect PodDisruptionBudget controlled by GameServerSet but actually got %v", i, pdb.GetOwnerReferences())
+		}
+	}
+
+	// the PodDisruptionBudget created by users is left as is
+	userPdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx",
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{MaxUnavailable: &maxUnavailable},
+	}
+	if err := c.Create(context.TODO(), userPdb); err != nil {
+		t.Fatal(err)
+	}
+	gss.Spec.PodDisruptionBudget = &gameKruiseV1alpha1.GameServerSetPodDisruptionBudget{MinAvailable: &minAvailable}
+	manager := &GameServerSetManager{
+		gameServerSet: gss,
+		client:        c,
+		eventRecorder: record.NewFakeRecorder(10),
+	}
+	if err := manager.SyncPodDisruptionBudget(); err != nil {
+		t.Fatal(err)
+	}
+	pdb := &policyv1.PodDisruptionBudget{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx"}, pdb); err != nil {
+		t.Fatal(err)
+	}
+	if pdb.Spec.MinAvailable != nil {
+		t.Errorf("expect PodDisruptionBudget of users not updated but actually got %v", pdb.Spec)
+	}
+}
+
+func TestGameServerSetManager_SyncInPlaceUpdatingPodDisruptionBudget(t *testing.T) {
+	gss := &gameKruiseV1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx",
+			UID:       "xxx-uid",
+		},
+		Spec: gameKruiseV1alpha1.GameServerSetSpec{
+			Replicas: ptr.To[int32](5),
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build()
+	tests := []struct {
+		podUpdatePolicy kruiseV1beta1.PodUpdateStrategyType
+		exist           bool
+	}{
+		// case 0: recreate
+		{
+			podUpdatePolicy: kruiseV1beta1.RecreatePodUpdateStrategyType,
+			exist:           false,
+		},
+		// case 1: created for in-place update
+		{
+			podUpdatePolicy: kruiseV1beta1.InPlaceIfPossiblePodUpdateStrategyType,
+			exist:           true,
+		},
+		// case 2: deleted when turned back to recreate
+		{
+			podUpdatePolicy: kruiseV1beta1.RecreatePodUpdateStrategyType,
+			exist:           false,
+		},
+	}
+
+	for i, test := range tests {
+		gss.Spec.UpdateStrategy.RollingUpdate = &gameKruiseV1alpha1.RollingUpdateStatefulSetStrategy{PodUpdatePolicy: test.podUpdatePolicy}
+		manager := &GameServerSetManager{
+			gameServerSet: gss,
+			client:        c,
+			eventRecorder: record.NewFakeRecorder(10),
+		}
+		if err := manager.SyncInPlaceUpdatingPodDisruptionBudget(); err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		pdb := &policyv1.PodDisruptionBudget{}
+		err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx-in-place-updating"}, pdb)
+		if !test.exist {
+			if !errors.IsNotFound(err) {
+				t.Errorf("case %d: expect no PodDisruptionBudget but actually got err %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		if pdb.Spec.MaxUnavailable == nil || pdb.Spec.MaxUnavailable.IntValue() != 0 || pdb.Spec.MinAvailable != nil {
+			t.Errorf("case %d: expect maxUnavailable 0 but actually got %v", i, pdb.Spec)
+		}
+		expectLabels := map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx", gameKruiseV1alpha1.GameServerInPlaceUpdatingKey: "true"}
+		if !reflect.DeepEqual(pdb.Spec.Selector.MatchLabels, expectLabels) {
+			t.Errorf("case %d: expect selector %v but actually got %v", i, expectLabels, pdb.Spec.Selector.MatchLabels)
+		}
+	}
+}
+
+type fakeImageDigestResolver map[string]string
+
+func (r fakeImageDigestResolver) ResolveImageDigest(ctx context.Context, image string) (string, error) {
+	if pinned, ok := r[image]; ok {
+		return pinned, nil
+	}
+	return "", fmt.Errorf("image %s not found", image)
+}
+
+func TestGameServerSetManager_UpdateWorkloadResolveImageDigest(t *testing.T) {
+	resolver := fakeImageDigestResolver{
+		"game:v1":  "game@sha256:111",
+		"game:v2":  "game@sha256:222",
+		"init:v1":  "init@sha256:333",
+		"proxy:v1": "proxy@sha256:444",
+	}
+	tests := []struct {
+		resolve         bool
+		images          []string
+		recorded        string
+		podImage        string
+		expectImages    []string
+		expectInitImage string
+		expectRecorded  string
+		expectPending   bool
+	}{
+		// case 0: not enabled
+		{
+			resolve:         false,
+			images:          []string{"game:v1"},
+			recorded:        `{"game:v1":"game@sha256:111"}`,
+			expectImages:    []string{"game:v1"},
+			expectInitImage: "init:v1",
+			expectRecorded:  "",
+		},
+		// case 1: resolved, and the images with digests are left as is
+		{
+			resolve:         true,
+			images:          []string{"game:v1", "sidecar@sha256:555"},
+			expectImages:    []string{"game@sha256:111", "sidecar@sha256:555"},
+			expectInitImage: "init@sha256:333",
+			expectRecorded:  `{"game:v1":"game@sha256:111","init:v1":"init@sha256:333"}`,
+		},
+		// case 2: the recorded digest is kept even if the tag is moved, and the stale ones are dropped
+		{
+			resolve:         true,
+			images:          []string{"game:v2", "proxy:v1"},
+			recorded:        `{"game:v1":"game@sha256:111","game:v2":"game@sha256:000","init:v1":"init@sha256:333"}`,
+			expectImages:    []string{"game@sha256:000", "proxy@sha256:444"},
+			expectInitImage: "init@sha256:333",
+			expectRecorded:  `{"game:v2":"game@sha256:000","init:v1":"init@sha256:333","proxy:v1":"proxy@sha256:444"}`,
+		},
+		// case 3: failed to resolve, the tag is kept and resolved again later
+		{
+			resolve:         true,
+			images:          []string{"game:v3"},
+			expectImages:    []string{"game:v3"},
+			expectInitImage: "init@sha256:333",
+			expectRecorded:  `{"init:v1":"init@sha256:333"}`,
+			expectPending:   true,
+		},
+		// case 4: failed to resolve while pods run the tag, the tag is kept and recorded
+		{
+			resolve:         true,
+			images:          []string{"game:v3"},
+			podImage:        "game:v3",
+			expectImages:    []string{"game:v3"},
+			expectInitImage: "init@sha256:333",
+			expectRecorded:  `{"game:v3":"game:v3","init:v1":"init@sha256:333"}`,
+		},
+	}
+	recorder := record.NewFakeRecorder(100)
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx",
+			},
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				ResolveImageDigest: test.resolve,
+			},
+		}
+		for j, image := range test.images {
+			gss.Spec.GameServerTemplate.Spec.Containers = append(gss.Spec.GameServerTemplate.Spec.Containers, corev1.Container{Name: "c" + strconv.Itoa(j), Image: image})
+		}
+		gss.Spec.GameServerTemplate.Spec.InitContainers = []corev1.Container{{Name: "init", Image: "init:v1"}}
+		asts := &kruiseV1beta1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        "xxx",
+				Annotations: map[string]string{gameKruiseV1alpha1.AstsHashKey: "xx"},
+			},
+		}
+		if test.recorded != "" {
+			asts.Annotations[gameKruiseV1alpha1.AstsImageDigestsKey] = test.recorded
+		}
+		var pods []corev1.Pod
+		if test.podImage != "" {
+			pods = append(pods, corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx-0"},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "c0", Image: test.podImage}}},
+			})
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(asts, gss).Build()
+		manager := &GameServerSetManager{
+			gameServerSet:       gss,
+			asts:                asts,
+			podList:             pods,
+			eventRecorder:       recorder,
+			client:              c,
+			imageDigestResolver: resolver,
+		}
+		if err := manager.UpdateWorkload(time.Now()); err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		updateAsts := &kruiseV1beta1.StatefulSet{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx"}, updateAsts); err != nil {
 			t.Error(err)
+			continue
+		}
+		var images []string
+		for _, container := range updateAsts.Spec.Template.Spec.Containers {
+			images = append(images, container.Image)
+		}
+		if !reflect.DeepEqual(images, test.expectImages) {
+			t.Errorf("case %d: expect images %v but actually got %v", i, test.expectImages, images)
+		}
+		if image := updateAsts.Spec.Template.Spec.InitContainers[0].Image; image != test.expectInitImage {
+			t.Errorf("case %d: expect init image %s but actually got %s", i, test.expectInitImage, image)
+		}
+		if recorded := updateAsts.GetAnnotations()[gameKruiseV1alpha1.AstsImageDigestsKey]; recorded != test.expectRecorded {
+			t.Errorf("case %d: expect recorded images %s but actually got %s", i, test.expectRecorded, recorded)
+		}
+		if pending := hasPendingImageDigests(gss, updateAsts); pending != test.expectPending {
+			t.Errorf("case %d: expect pending image digests %v but actually got %v", i, test.expectPending, pending)
+		}
+	}
+}
+
+func TestUpdateWorkloadMaintenanceWindows(t *testing.T) {
+	windows := []gameKruiseV1alpha1.MaintenanceWindow{{Schedule: "0 2 * * *", DurationSeconds: 3600}}
+	inWindow := time.Date(2024, 5, 1, 2, 30, 0, 0, time.UTC)
+	outOfWindow := time.Date(2024, 5, 1, 5, 0, 0, 0, time.UTC)
+	tests := []struct {
+		now              time.Time
+		gssPartition     int32
+		astsPartition    int32
+		partition        int32
+		paused           bool
+		held             bool
+		heldRequeueAfter time.Duration
+	}{
+		// case 0: the partition is lowered in the window
+		{
+			now:              inWindow,
+			gssPartition:     2,
+			astsPartition:    5,
+			partition:        2,
+			paused:           false,
+			held:             false,
+			heldRequeueAfter: 30 * time.Minute,
+		},
+		// case 1: the partition is held out of the window
+		{
+			now:              outOfWindow,
+			gssPartition:     2,
+			astsPartition:    5,
+			partition:        5,
+			paused:           true,
+			held:             true,
+			heldRequeueAfter: 21 * time.Hour,
+		},
+		// case 2: the partition can be raised out of the window
+		{
+			now:              outOfWindow,
+			gssPartition:     8,
+			astsPartition:    5,
+			partition:        8,
+			paused:           true,
+			held:             true,
+			heldRequeueAfter: 21 * time.Hour,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "case"},
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				Replicas: ptr.To[int32](10),
+				UpdateStrategy: gameKruiseV1alpha1.UpdateStrategy{
+					Type: apps.RollingUpdateStatefulSetStrategyType,
+					RollingUpdate: &gameKruiseV1alpha1.RollingUpdateStatefulSetStrategy{
+						Partition: ptr.To(test.gssPartition),
+					},
+					MaintenanceWindows: windows,
+				},
+			},
+		}
+		asts := &kruiseV1beta1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "case", Annotations: map[string]string{gameKruiseV1alpha1.AstsHashKey: "xxx"}},
+			Spec: kruiseV1beta1.StatefulSetSpec{
+				Replicas: ptr.To[int32](10),
+				UpdateStrategy: kruiseV1beta1.StatefulSetUpdateStrategy{
+					Type: apps.RollingUpdateStatefulSetStrategyType,
+					RollingUpdate: &kruiseV1beta1.RollingUpdateStatefulSetStrategy{
+						Partition: ptr.To(test.astsPartition),
+					},
+				},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss, asts).Build()
+		manager := &GameServerSetManager{
+			gameServerSet: gss,
+			asts:          asts,
+			eventRecorder: record.NewFakeRecorder(10),
+			client:        c,
 		}
 
+		held, requeueAfter := manager.IsUpdateHeld(test.now)
+		if held != test.held || requeueAfter != test.heldRequeueAfter {
+			t.Errorf("case %d: expect held %v and requeue after %v but actually got %v and %v", i, test.held, test.heldRequeueAfter, held, requeueAfter)
+		}
+		if !manager.IsNeedToUpdateWorkload(test.now) {
+			t.Errorf("case %d: expect workload to be updated but actually not", i)
+		}
+		if err := manager.UpdateWorkload(test.now); err != nil {
+			t.Fatal(err)
+		}
 		updateAsts := &kruiseV1beta1.StatefulSet{}
-		if err := manager.client.Get(context.TODO(), types.NamespacedName{
-			Namespace: test.asts.Namespace,
-			Name:      test.asts.Name,
-		}, updateAsts); err != nil {
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "case"}, updateAsts); err != nil {
+			t.Fatal(err)
+		}
+		rollingUpdate := updateAsts.Spec.UpdateStrategy.RollingUpdate
+		if *rollingUpdate.Partition != test.partition || rollingUpdate.Paused != test.paused {
+			t.Errorf("case %d: expect partition %d and paused %v but actually got %d and %v", i, test.partition, test.paused, *rollingUpdate.Partition, rollingUpdate.Paused)
+		}
+
+		// the held rolling update is resumed once the window starts
+		manager.asts = updateAsts
+		if manager.IsNeedToUpdateWorkload(test.now) {
+			t.Errorf("case %d: expect workload not to be updated again at the same time but actually not", i)
+		}
+		if test.held && !manager.IsNeedToUpdateWorkload(inWindow.Add(24*time.Hour)) {
+			t.Errorf("case %d: expect workload to be updated when the next window starts but actually not", i)
+		}
+	}
+}
+
+func TestGameServerSetManager_SyncStatusNetworkSummary(t *testing.T) {
+	readyNetwork := `{"currentNetworkState":"Ready","externalAddresses":[{"ip":"1.2.3.4","ports":[{"name":"80","port":8080,"protocol":"TCP"}]}]}`
+	notReadyNetwork := `{"currentNetworkState":"NotReady"}`
+	newPod := func(name string, state gameKruiseV1alpha1.GameServerState, networkStatus string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        name,
+				Labels:      map[string]string{gameKruiseV1alpha1.GameServerStateKey: string(state)},
+				Annotations: map[string]string{gameKruiseV1alpha1.GameServerNetworkStatus: networkStatus},
+			},
+		}
+	}
+	tests := []struct {
+		oldSummary []gameKruiseV1alpha1.GameServerNetworkSummary
+		podList    []corev1.Pod
+		expect     []string
+	}{
+		// case 0: only the ready servers with ready network are listed, in the order of ids
+		{
+			podList: []corev1.Pod{
+				newPod("xxx-10", gameKruiseV1alpha1.Ready, readyNetwork),
+				newPod("xxx-1", gameKruiseV1alpha1.Ready, notReadyNetwork),
+				newPod("xxx-2", gameKruiseV1alpha1.NotReady, readyNetwork),
+				newPod("xxx-3", gameKruiseV1alpha1.Ready, ""),
+				newPod("xxx-0", gameKruiseV1alpha1.Ready, readyNetwork),
+			},
+			expect: []string{"xxx-0", "xxx-10"},
+		},
+		// case 1: the summary follows the servers becoming ready or not
+		{
+			oldSummary: []gameKruiseV1alpha1.GameServerNetworkSummary{{Name: "xxx-0"}, {Name: "xxx-10"}},
+			podList: []corev1.Pod{
+				newPod("xxx-0", gameKruiseV1alpha1.NotReady, readyNetwork),
+				newPod("xxx-1", gameKruiseV1alpha1.Ready, readyNetwork),
+			},
+			expect: []string{"xxx-1"},
+		},
+		// case 2: the summary is removed once no server is ready
+		{
+			oldSummary: []gameKruiseV1alpha1.GameServerNetworkSummary{{Name: "xxx-0"}},
+			podList: []corev1.Pod{
+				newPod("xxx-0", gameKruiseV1alpha1.NotReady, readyNetwork),
+			},
+			expect: nil,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx",
+			},
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				Replicas: ptr.To[int32](int32(len(test.podList))),
+			},
+			Status: gameKruiseV1alpha1.GameServerSetStatus{
+				NetworkSummary: test.oldSummary,
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build()
+		manager := &GameServerSetManager{
+			gameServerSet: gss,
+			asts:          &kruiseV1beta1.StatefulSet{},
+			podList:       test.podList,
+			client:        c,
+		}
+		if err := manager.SyncStatus(); err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		newGss := &gameKruiseV1alpha1.GameServerSet{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx"}, newGss); err != nil {
 			t.Error(err)
+			continue
+		}
+		var actual []string
+		for _, s := range newGss.Status.NetworkSummary {
+			actual = append(actual, s.Name)
+			if len(s.ExternalAddresses) != 1 || s.ExternalAddresses[0].IP != "1.2.3.4" {
+				t.Errorf("case %d: expect external address 1.2.3.4 of %s but actually got %v", i, s.Name, s.ExternalAddresses)
+			}
+		}
+		if !reflect.DeepEqual(actual, test.expect) {
+			t.Errorf("case %d: expect network summary %v but actually got %v", i, test.expect, actual)
 		}
+	}
+}
 
-		if !reflect.DeepEqual(updateAsts.Spec, test.newAsts.Spec) {
-			t.Errorf("expect new asts spec %v but got %v", test.newAsts.Spec, updateAsts.Spec)
+func TestGameServerSetManager_SyncStatusNetworkConfigError(t *testing.T) {
+	gss := &gameKruiseV1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "xxx",
+		},
+		Spec: gameKruiseV1alpha1.GameServerSetSpec{
+			Replicas: ptr.To[int32](1),
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build()
+
+	// the cached GameServerSet still has the error cleared by the pod webhook
+	cached := gss.DeepCopy()
+	cached.Status.NetworkConfigError = "invalid port"
+	manager := &GameServerSetManager{
+		gameServerSet: cached,
+		asts:          &kruiseV1beta1.StatefulSet{},
+		podList:       []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx-0"}}},
+		client:        c,
+	}
+	if err := manager.SyncStatus(); err != nil {
+		t.Fatal(err)
+	}
+	newGss := &gameKruiseV1alpha1.GameServerSet{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "xxx"}, newGss); err != nil {
+		t.Fatal(err)
+	}
+	if newGss.Status.CurrentReplicas != 1 {
+		t.Errorf("expect current replicas 1 but actually got %d", newGss.Status.CurrentReplicas)
+	}
+	if newGss.Status.NetworkConfigError != "" {
+		t.Errorf("expect network config error not written back but actually got %q", newGss.Status.NetworkConfigError)
+	}
+}
+
+func TestSyncGameServerTokens(t *testing.T) {
+	gss := &gameKruiseV1alpha1.GameServerSet{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "GameServerSet",
+			APIVersion: "game.kruise.io/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "case0",
+			UID:       "xxx0",
+		},
+		Spec: gameKruiseV1alpha1.GameServerSetSpec{
+			InjectGameServerToken: true,
+		},
+	}
+	newPods := func(ids ...int) []corev1.Pod {
+		pods := make([]corev1.Pod, 0)
+		for _, id := range ids {
+			pods = append(pods, corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "xxx",
+					Name:      "case0-" + strconv.Itoa(id),
+				},
+			})
+		}
+		return pods
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build()
+	getTokens := func() map[string][]byte {
+		secret := &corev1.Secret{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "case0-gameserver-tokens"}, secret); err != nil {
+			t.Fatal(err)
+		}
+		if !metav1.IsControlledBy(secret, gss) {
+			t.Errorf("expect Secret controlled by GameServerSet but actually got %v", secret.GetOwnerReferences())
 		}
+		return secret.Data
+	}
+
+	// the tokens of pods 0 and 1 are generated, and they are unique
+	manager := &GameServerSetManager{
+		gameServerSet: gss,
+		podList:       newPods(0, 1),
+		client:        c,
+		eventRecorder: record.NewFakeRecorder(10),
+	}
+	if err := manager.SyncGameServerTokens(); err != nil {
+		t.Fatal(err)
+	}
+	tokens := getTokens()
+	if len(tokens) != 2 || len(tokens["0"]) == 0 || len(tokens["1"]) == 0 {
+		t.Fatalf("expect tokens of ordinals 0 and 1 but actually got %v", tokens)
+	}
+	if string(tokens["0"]) == string(tokens["1"]) {
+		t.Errorf("expect unique tokens but actually got the same token %s", tokens["0"])
+	}
+
+	// pod 1 is scaled down, pod 2 is created, and pod 0 is recreated: the existing tokens stay the same
+	manager.podList = newPods(0, 2)
+	if err := manager.SyncGameServerTokens(); err != nil {
+		t.Fatal(err)
+	}
+	newTokens := getTokens()
+	if len(newTokens) != 3 {
+		t.Fatalf("expect tokens of ordinals 0, 1 and 2 but actually got %v", newTokens)
+	}
+	for _, ordinal := range []string{"0", "1"} {
+		if string(newTokens[ordinal]) != string(tokens[ordinal]) {
+			t.Errorf("expect token of ordinal %s unchanged as %s but actually got %s", ordinal, tokens[ordinal], newTokens[ordinal])
+		}
+	}
+	if string(newTokens["2"]) == string(newTokens["0"]) || string(newTokens["2"]) == string(newTokens["1"]) {
+		t.Errorf("expect unique token of ordinal 2 but actually got %s", newTokens["2"])
+	}
+
+	// scaled up to 4: the tokens of the pods to create are generated before the workload is scaled
+	asts := &kruiseV1beta1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "xxx",
+			Name:      "case0",
+		},
+		Spec: kruiseV1beta1.StatefulSetSpec{
+			Replicas:        ptr.To[int32](2),
+			ReserveOrdinals: []int{1},
+		},
+	}
+	if err := c.Create(context.TODO(), asts); err != nil {
+		t.Fatal(err)
+	}
+	gss.Spec.Replicas = ptr.To[int32](4)
+	manager.asts = asts
+	if err := manager.GameServerScale(); err != nil {
+		t.Fatal(err)
+	}
+	scaledTokens := getTokens()
+	if len(scaledTokens) != 4 || len(scaledTokens["3"]) == 0 {
+		t.Errorf("expect token of ordinal 3 generated before scaling but actually got %v", scaledTokens)
 	}
 }
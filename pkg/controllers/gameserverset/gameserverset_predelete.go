@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gameserverset
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+)
+
+const (
+	PreDeleteHookReason = "PreDeleteHook"
+
+	preDeleteHookJobSuffix      = "-pre-delete"
+	defaultPreDeleteHookTimeout = 300 * time.Second
+	preDeleteHookRequeueTime    = 5 * time.Second
+)
+
+// syncPreDeleteHookFinalizer keeps the PreDeleteHook finalizer consistent with the spec of a living GameServerSet.
+// It returns true if the GameServerSet is updated.
+func syncPreDeleteHookFinalizer(ctx context.Context, c client.Client, gss *gamekruiseiov1alpha1.GameServerSet) (bool, error) {
+	hasFinalizer := controllerutil.ContainsFinalizer(gss, gamekruiseiov1alpha1.PreDeleteHookFinalizer)
+	if gss.Spec.PreDeleteHook != nil && !hasFinalizer {
+		controllerutil.AddFinalizer(gss, gamekruiseiov1alpha1.PreDeleteHookFinalizer)
+		return true, c.Update(ctx, gss)
+	}
+	if gss.Spec.PreDeleteHook == nil && hasFinalizer {
+		controllerutil.RemoveFinalizer(gss, gamekruiseiov1alpha1.PreDeleteHookFinalizer)
+		return true, c.Update(ctx, gss)
+	}
+	return false, nil
+}
+
+// runPreDeleteHook runs the PreDeleteHook Job of a deleting GameServerSet, and releases the finalizer
+// once the Job completes or the timeout expires. It returns the duration to requeue if still waiting.
+func runPreDeleteHook(ctx context.Context, c client.Client, gss *gamekruiseiov1alpha1.GameServerSet, recorder record.EventRecorder) (time.Duration, error) {
+	if !controllerutil.ContainsFinalizer(gss, gamekruiseiov1alpha1.PreDeleteHookFinalizer) {
+		return 0, nil
+	}
+	if gss.Spec.PreDeleteHook == nil {
+		return 0, releasePreDeleteHook(ctx, c, gss)
+	}
+
+	timeout := defaultPreDeleteHookTimeout
+	if gss.Spec.PreDeleteHook.TimeoutSeconds != nil {
+		timeout = time.Duration(*gss.Spec.PreDeleteHook.TimeoutSeconds) * time.Second
+	}
+	remaining := timeout - time.Since(gss.GetDeletionTimestamp().Time)
+
+	job := &batchv1.Job{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: gss.GetNamespace(), Name: gss.GetName() + preDeleteHookJobSuffix}, job)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return 0, err
+		}
+		if remaining <= 0 {
+			return 0, finishPreDeleteHook(ctx, c, gss, recorder, gamekruiseiov1alpha1.PreDeleteHookTimeout)
+		}
+		job = newPreDeleteHookJob(gss)
+		if err := c.Create(ctx, job); err != nil && !errors.IsAlreadyExists(err) {
+			return 0, err
+		}
+		recorder.Event(gss, corev1.EventTypeNormal, PreDeleteHookReason, "created pre-delete Job "+job.GetName())
+		return preDeleteHookRequeueTime, updatePreDeleteHookPhase(ctx, c, gss, gamekruiseiov1alpha1.PreDeleteHookRunning)
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return 0, finishPreDeleteHook(ctx, c, gss, recorder, gamekruiseiov1alpha1.PreDeleteHookSucceeded)
+		case batchv1.JobFailed:
+			return 0, finishPreDeleteHook(ctx, c, gss, recorder, gamekruiseiov1alpha1.PreDeleteHookFailed)
+		}
+	}
+	if remaining <= 0 {
+		return 0, finishPreDeleteHook(ctx, c, gss, recorder, gamekruiseiov1alpha1.PreDeleteHookTimeout)
+	}
+
+	klog.Infof("GameServerSet %s/%s is waiting for pre-delete Job %s, %v remaining", gss.GetNamespace(), gss.GetName(), job.GetName(), remaining)
+	if remaining < preDeleteHookRequeueTime {
+		return remaining, nil
+	}
+	return preDeleteHookRequeueTime, nil
+}
+
+func newPreDeleteHookJob(gss *gamekruiseiov1alpha1.GameServerSet) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: gss.GetNamespace(),
+			Name:      gss.GetName() + preDeleteHookJobSuffix,
+			Labels: map[string]string{
+				gamekruiseiov1alpha1.GameServerOwnerGssKey: gss.GetName(),
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(gss, controllerKind),
+			},
+		},
+		Spec: *gss.Spec.PreDeleteHook.JobTemplate.DeepCopy(),
+	}
+}
+
+func finishPreDeleteHook(ctx context.Context, c client.Client, gss *gamekruiseiov1alpha1.GameServerSet, recorder record.EventRecorder, phase gamekruiseiov1alpha1.PreDeleteHookPhase) error {
+	eventType := corev1.EventTypeNormal
+	if phase != gamekruiseiov1alpha1.PreDeleteHookSucceeded {
+		eventType = corev1.EventTypeWarning
+	}
+	recorder.Eventf(gss, eventType, PreDeleteHookReason, "pre-delete hook finished with %s, deletion continues", phase)
+	if err := updatePreDeleteHookPhase(ctx, c, gss, phase); err != nil {
+		return err
+	}
+	return releasePreDeleteHook(ctx, c, gss)
+}
+
+func releasePreDeleteHook(ctx context.Context, c client.Client, gss *gamekruiseiov1alpha1.GameServerSet) error {
+	controllerutil.RemoveFinalizer(gss, gamekruiseiov1alpha1.PreDeleteHookFinalizer)
+	err := c.Update(ctx, gss)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func updatePreDeleteHookPhase(ctx context.Context, c client.Client, gss *gamekruiseiov1alpha1.GameServerSet, phase gamekruiseiov1alpha1.PreDeleteHookPhase) error {
+	if gss.Status.PreDeleteHookPhase == phase {
+		return nil
+	}
+	patchStatus := map[string]interface{}{"status": map[string]interface{}{"preDeleteHookPhase": phase}}
+	jsonPatch, err := json.Marshal(patchStatus)
+	if err != nil {
+		return err
+	}
+	if err := c.Status().Patch(ctx, gss, client.RawPatch(types.MergePatchType, jsonPatch)); err != nil {
+		return err
+	}
+	gss.Status.PreDeleteHookPhase = phase
+	return nil
+}
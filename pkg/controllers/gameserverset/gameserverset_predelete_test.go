@@ -0,0 +1,191 @@
+package gameserverset
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	gameKruiseV1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+)
+
+func TestRunPreDeleteHook(t *testing.T) {
+	newGss := func(deletedAgo time.Duration) *gameKruiseV1alpha1.GameServerSet {
+		return &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "xxx",
+				Name:              "case",
+				UID:               "xxx",
+				Finalizers:        []string{gameKruiseV1alpha1.PreDeleteHookFinalizer},
+				DeletionTimestamp: ptr.To(metav1.NewTime(time.Now().Add(-deletedAgo))),
+			},
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				Replicas: ptr.To[int32](1),
+				PreDeleteHook: &gameKruiseV1alpha1.PreDeleteHook{
+					TimeoutSeconds: ptr.To[int32](60),
+				},
+			},
+		}
+	}
+	newJob := func(conditionType batchv1.JobConditionType) *batchv1.Job {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "case" + preDeleteHookJobSuffix,
+			},
+		}
+		if conditionType != "" {
+			job.Status.Conditions = []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue}}
+		}
+		return job
+	}
+
+	tests := []struct {
+		gss             *gameKruiseV1alpha1.GameServerSet
+		job             *batchv1.Job
+		expectRequeue   bool
+		expectFinalizer bool
+		expectPhase     gameKruiseV1alpha1.PreDeleteHookPhase
+	}{
+		// case 0: job is created
+		{
+			gss:             newGss(time.Second),
+			expectRequeue:   true,
+			expectFinalizer: true,
+			expectPhase:     gameKruiseV1alpha1.PreDeleteHookRunning,
+		},
+		// case 1: job is running
+		{
+			gss:             newGss(time.Second),
+			job:             newJob(""),
+			expectRequeue:   true,
+			expectFinalizer: true,
+		},
+		// case 2: job completes
+		{
+			gss:             newGss(time.Second),
+			job:             newJob(batchv1.JobComplete),
+			expectRequeue:   false,
+			expectFinalizer: false,
+			expectPhase:     gameKruiseV1alpha1.PreDeleteHookSucceeded,
+		},
+		// case 3: job fails
+		{
+			gss:             newGss(time.Second),
+			job:             newJob(batchv1.JobFailed),
+			expectRequeue:   false,
+			expectFinalizer: false,
+			expectPhase:     gameKruiseV1alpha1.PreDeleteHookFailed,
+		},
+		// case 4: job times out
+		{
+			gss:             newGss(2 * time.Minute),
+			job:             newJob(""),
+			expectRequeue:   false,
+			expectFinalizer: false,
+			expectPhase:     gameKruiseV1alpha1.PreDeleteHookTimeout,
+		},
+	}
+
+	for i, test := range tests {
+		objs := []client.Object{test.gss}
+		if test.job != nil {
+			objs = append(objs, test.job)
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		requeueAfter, err := runPreDeleteHook(context.TODO(), c, test.gss, record.NewFakeRecorder(10))
+		if err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+			continue
+		}
+		if (requeueAfter > 0) != test.expectRequeue {
+			t.Errorf("case %d: expect requeue %v, but actually requeue after %v", i, test.expectRequeue, requeueAfter)
+		}
+
+		if test.gss.Status.PreDeleteHookPhase != test.expectPhase {
+			t.Errorf("case %d: expect phase %s, but actually got %s", i, test.expectPhase, test.gss.Status.PreDeleteHookPhase)
+		}
+		// the deleting GameServerSet is gone once the finalizer is released
+		gss := &gameKruiseV1alpha1.GameServerSet{}
+		err = c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "case"}, gss)
+		if test.expectFinalizer {
+			if err != nil || !controllerutil.ContainsFinalizer(gss, gameKruiseV1alpha1.PreDeleteHookFinalizer) {
+				t.Errorf("case %d: expect GameServerSet blocked by finalizer, but got %v", i, err)
+			}
+		} else if !errors.IsNotFound(err) {
+			t.Errorf("case %d: expect GameServerSet deleted, but got %v", i, err)
+		}
+		job := &batchv1.Job{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "case" + preDeleteHookJobSuffix}, job); err != nil {
+			t.Errorf("case %d: expect pre-delete job exists, but got %v", i, err)
+		}
+	}
+}
+
+func TestSyncPreDeleteHookFinalizer(t *testing.T) {
+	tests := []struct {
+		hook            *gameKruiseV1alpha1.PreDeleteHook
+		finalizers      []string
+		expectUpdated   bool
+		expectFinalizer bool
+	}{
+		// case 0
+		{
+			hook:            &gameKruiseV1alpha1.PreDeleteHook{},
+			expectUpdated:   true,
+			expectFinalizer: true,
+		},
+		// case 1
+		{
+			hook:            nil,
+			finalizers:      []string{gameKruiseV1alpha1.PreDeleteHookFinalizer},
+			expectUpdated:   true,
+			expectFinalizer: false,
+		},
+		// case 2
+		{
+			hook:            nil,
+			expectUpdated:   false,
+			expectFinalizer: false,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:  "xxx",
+				Name:       "case",
+				Finalizers: test.finalizers,
+			},
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				PreDeleteHook: test.hook,
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build()
+		updated, err := syncPreDeleteHookFinalizer(context.TODO(), c, gss)
+		if err != nil {
+			t.Errorf("case %d: unexpected error %v", i, err)
+		}
+		if updated != test.expectUpdated {
+			t.Errorf("case %d: expect updated %v, but actually got %v", i, test.expectUpdated, updated)
+		}
+		newGss := &gameKruiseV1alpha1.GameServerSet{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "xxx", Name: "case"}, newGss); err != nil {
+			t.Error(err)
+			continue
+		}
+		if controllerutil.ContainsFinalizer(newGss, gameKruiseV1alpha1.PreDeleteHookFinalizer) != test.expectFinalizer {
+			t.Errorf("case %d: expect finalizer %v, but actually got %v", i, test.expectFinalizer, newGss.GetFinalizers())
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
@@ -11,11 +12,23 @@ import (
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strconv"
+	"time"
 )
 
 const (
 	NoneGameServerMinNumberKey = "minAvailable"
 	NoneGameServerMaxNumberKey = "maxAvailable"
+	// GameServerLabelSelectorKey limits the GameServers counted by the scaler to those matching the label selector,
+	// such as "region=us-west". If no GameServer matches it, the subset is considered to have no None GameServers.
+	GameServerLabelSelectorKey = "gameServerLabelSelector"
+	// ReadinessLatencyThresholdKey enables scaling up ahead of slow provisioning, in seconds. When the time since creation
+	// of the None GameServers not ready yet, aggregated by ReadinessLatencyAggregationKey, exceeds it,
+	// these GameServers are not counted as None GameServers, so that more are created to keep minAvailable.
+	ReadinessLatencyThresholdKey = "readinessLatencyThreshold"
+	// ReadinessLatencyAggregationKey is how the readiness latency is aggregated, max (default) or average.
+	ReadinessLatencyAggregationKey     = "readinessLatencyAggregation"
+	ReadinessLatencyAggregationMax     = "max"
+	ReadinessLatencyAggregationAverage = "average"
 )
 
 type ExternalScaler struct {
@@ -68,6 +81,12 @@ func (e *ExternalScaler) GetMetrics(ctx context.Context, metricRequest *GetMetri
 		return nil, err
 	}
 
+	subsetRequirements, err := parseGameServerLabelSelector(metricRequest.ScaledObjectRef.GetScalerMetadata()[GameServerLabelSelectorKey])
+	if err != nil {
+		klog.Error(err)
+		return nil, err
+	}
+
 	// scale up when number of GameServers with None opsState less than minAvailable defined by user
 	isGssOwner, _ := labels.NewRequirement(gamekruiseiov1alpha1.GameServerOwnerGssKey, selection.Equals, []string{name})
 	isNone, _ := labels.NewRequirement(gamekruiseiov1alpha1.GameServerOpsStateKey, selection.Equals, []string{string(gamekruiseiov1alpha1.None)})
@@ -77,7 +96,7 @@ func (e *ExternalScaler) GetMetrics(ctx context.Context, metricRequest *GetMetri
 		LabelSelector: labels.NewSelector().Add(
 			*isNone,
 			*isGssOwner,
-		),
+		).Add(subsetRequirements...),
 	})
 	if err != nil {
 		klog.Error(err)
@@ -85,12 +104,54 @@ func (e *ExternalScaler) GetMetrics(ctx context.Context, metricRequest *GetMetri
 	}
 
 	noneNum := len(podList.Items)
+
+	// None GameServers provisioned too slowly are not available in time
+	slowNum, err := e.getSlowProvisioningNum(ctx, gss, podList.Items, metricRequest.ScaledObjectRef.GetScalerMetadata())
+	if err != nil {
+		klog.Error(err)
+		return nil, err
+	}
+	noneNum = noneNum - slowNum
+
+	// warm pool spares are kept beyond replicas, so they are not counted as None GameServers
+	if gss.Spec.WarmPoolSize != nil {
+		gssPodList := &corev1.PodList{}
+		err = e.client.List(ctx, gssPodList, &client.ListOptions{
+			Namespace:     ns,
+			LabelSelector: labels.NewSelector().Add(*isGssOwner),
+		})
+		if err != nil {
+			klog.Error(err)
+			return nil, err
+		}
+		noneNum = noneNum - util.GetWarmPoolSpares(gss, gssPodList.Items)
+		if noneNum < 0 {
+			noneNum = 0
+		}
+	}
+	// Allocated and PreAllocated GameServers are never scaled down, so replicas are at least the number of them.
+	// Only None GameServers are the idle surplus, which is kept between minAvailable and maxAvailable.
+	isAllocated, _ := labels.NewRequirement(gamekruiseiov1alpha1.GameServerOpsStateKey, selection.In, []string{string(gamekruiseiov1alpha1.Allocated), string(gamekruiseiov1alpha1.PreAllocated)})
+	allocatedPodList := &corev1.PodList{}
+	err = e.client.List(ctx, allocatedPodList, &client.ListOptions{
+		Namespace:     ns,
+		LabelSelector: labels.NewSelector().Add(*isAllocated, *isGssOwner),
+	})
+	if err != nil {
+		klog.Error(err)
+		return nil, err
+	}
+	baseReplicas := *gss.Spec.Replicas
+	if allocatedNum := int32(len(allocatedPodList.Items)); allocatedNum > baseReplicas {
+		baseReplicas = allocatedNum
+	}
+
 	minNum, err := strconv.ParseInt(metricRequest.ScaledObjectRef.GetScalerMetadata()[NoneGameServerMinNumberKey], 10, 32)
 	if err != nil {
 		klog.Errorf("minAvailable should be integer type, err: %s", err.Error())
 	}
 	if err == nil && noneNum < int(minNum) {
-		desireReplicas := *gss.Spec.Replicas + int32(minNum) - int32(noneNum)
+		desireReplicas := withScalingScheduleFloor(gss, baseReplicas+int32(minNum)-int32(noneNum))
 		klog.Infof("GameServerSet %s/%s desire replicas is %d", ns, name, desireReplicas)
 		return &GetMetricsResponse{
 			MetricValues: []*MetricValue{{
@@ -110,14 +171,14 @@ func (e *ExternalScaler) GetMetrics(ctx context.Context, metricRequest *GetMetri
 			*isWaitToDelete,
 			*notDeleting,
 			*isGssOwner,
-		),
+		).Add(subsetRequirements...),
 	})
 	if err != nil {
 		klog.Error(err)
 		return nil, err
 	}
 
-	desireReplicas := int(*gss.Spec.Replicas)
+	desireReplicas := int(baseReplicas)
 	numWaitToBeDeleted := len(podList.Items)
 	if numWaitToBeDeleted != 0 {
 		desireReplicas = desireReplicas - numWaitToBeDeleted
@@ -132,6 +193,7 @@ func (e *ExternalScaler) GetMetrics(ctx context.Context, metricRequest *GetMetri
 		}
 	}
 
+	desireReplicas = int(withScalingScheduleFloor(gss, int32(desireReplicas)))
 	klog.Infof("GameServerSet %s/%s desire replicas is %d", ns, name, desireReplicas)
 	return &GetMetricsResponse{
 		MetricValues: []*MetricValue{{
@@ -141,6 +203,103 @@ func (e *ExternalScaler) GetMetrics(ctx context.Context, metricRequest *GetMetri
 	}, nil
 }
 
+// getSlowProvisioningNum returns the number of the not-ready GameServers among pods
+// if their readiness latency exceeds the threshold set in scaler metadata, or 0 otherwise.
+func (e *ExternalScaler) getSlowProvisioningNum(ctx context.Context, gss *gamekruiseiov1alpha1.GameServerSet, pods []corev1.Pod, metadata map[string]string) (int, error) {
+	thresholdValue, ok := metadata[ReadinessLatencyThresholdKey]
+	if !ok || len(pods) == 0 {
+		return 0, nil
+	}
+	threshold, err := strconv.ParseInt(thresholdValue, 10, 32)
+	if err != nil || threshold <= 0 {
+		klog.Errorf("readinessLatencyThreshold should be positive integer, but got %s", thresholdValue)
+		return 0, nil
+	}
+
+	isGssOwner, _ := labels.NewRequirement(gamekruiseiov1alpha1.GameServerOwnerGssKey, selection.Equals, []string{gss.GetName()})
+	gsList := &gamekruiseiov1alpha1.GameServerList{}
+	err = e.client.List(ctx, gsList, &client.ListOptions{
+		Namespace:     gss.GetNamespace(),
+		LabelSelector: labels.NewSelector().Add(*isGssOwner),
+	})
+	if err != nil {
+		return 0, err
+	}
+	podNames := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		podNames[pod.GetName()] = true
+	}
+	gameServers := make([]gamekruiseiov1alpha1.GameServer, 0, len(pods))
+	for _, gs := range gsList.Items {
+		if podNames[gs.GetName()] {
+			gameServers = append(gameServers, gs)
+		}
+	}
+
+	average, max, notReady := getReadinessLatency(gameServers, time.Now())
+	latency := max
+	if metadata[ReadinessLatencyAggregationKey] == ReadinessLatencyAggregationAverage {
+		latency = average
+	}
+	if latency < time.Duration(threshold)*time.Second {
+		return 0, nil
+	}
+	klog.Infof("GameServerSet %s/%s has %d GameServers not ready for %v", gss.GetNamespace(), gss.GetName(), notReady, latency)
+	return notReady, nil
+}
+
+// getReadinessLatency returns the average and max time since creation of the GameServers not ready yet, and the number of them.
+// A GameServer is ready when its state is Ready and its network, if any, is Ready. Deleting GameServers are ignored.
+func getReadinessLatency(gameServers []gamekruiseiov1alpha1.GameServer, now time.Time) (time.Duration, time.Duration, int) {
+	var total, max time.Duration
+	notReady := 0
+	for _, gs := range gameServers {
+		if gs.GetDeletionTimestamp() != nil || gs.Status.CurrentState == gamekruiseiov1alpha1.Deleting {
+			continue
+		}
+		networkState := gs.Status.NetworkStatus.CurrentNetworkState
+		if gs.Status.CurrentState == gamekruiseiov1alpha1.Ready && (networkState == "" || networkState == gamekruiseiov1alpha1.NetworkReady) {
+			continue
+		}
+		latency := now.Sub(gs.GetCreationTimestamp().Time)
+		if latency < 0 {
+			latency = 0
+		}
+		total += latency
+		if latency > max {
+			max = latency
+		}
+		notReady++
+	}
+	if notReady == 0 {
+		return 0, 0, 0
+	}
+	return total / time.Duration(notReady), max, notReady
+}
+
+// withScalingScheduleFloor raises the desired replicas to the floor of the active ScalingSchedule windows,
+// so that the scaler never scales in below the floor enforced by the GameServerSet controller.
+func withScalingScheduleFloor(gss *gamekruiseiov1alpha1.GameServerSet, desireReplicas int32) int32 {
+	floor, _ := util.GetScalingScheduleFloor(gss, time.Now())
+	if floor != nil && *floor > desireReplicas {
+		return *floor
+	}
+	return desireReplicas
+}
+
+// parseGameServerLabelSelector returns the requirements of the label selector given in scaler metadata.
+func parseGameServerLabelSelector(selector string) ([]labels.Requirement, error) {
+	if selector == "" {
+		return nil, nil
+	}
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %s: %s", GameServerLabelSelectorKey, selector, err.Error())
+	}
+	requirements, _ := parsed.Requirements()
+	return requirements, nil
+}
+
 func NewExternalScaler(client client.Client) *ExternalScaler {
 	return &ExternalScaler{
 		client: client,
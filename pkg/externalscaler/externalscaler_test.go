@@ -0,0 +1,388 @@
+package externalscaler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var (
+	scheme = runtime.NewScheme()
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(gamekruiseiov1alpha1.AddToScheme(scheme))
+}
+
+func TestGetMetricsWithLabelSelector(t *testing.T) {
+	newPod := func(name, region string, opsState gamekruiseiov1alpha1.OpsState) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      name,
+				Labels: map[string]string{
+					gamekruiseiov1alpha1.GameServerOwnerGssKey: "xxx",
+					gamekruiseiov1alpha1.GameServerOpsStateKey: string(opsState),
+					gamekruiseiov1alpha1.GameServerStateKey:    string(gamekruiseiov1alpha1.Ready),
+					"region":                                   region,
+				},
+			},
+		}
+	}
+	objs := []client.Object{
+		&gamekruiseiov1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx",
+			},
+			Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+				Replicas: ptr.To[int32](4),
+			},
+		},
+		newPod("xxx-0", "a", gamekruiseiov1alpha1.None),
+		newPod("xxx-1", "a", gamekruiseiov1alpha1.WaitToDelete),
+		newPod("xxx-2", "b", gamekruiseiov1alpha1.None),
+		newPod("xxx-3", "b", gamekruiseiov1alpha1.None),
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	scaler := NewExternalScaler(c)
+
+	tests := []struct {
+		metadata map[string]string
+		replicas int64
+		hasErr   bool
+	}{
+		// case 0: all GameServers, scale down the WaitToBeDeleted one
+		{
+			metadata: map[string]string{
+				NoneGameServerMinNumberKey: "1",
+				NoneGameServerMaxNumberKey: "10",
+			},
+			replicas: 3,
+		},
+		// case 1: region b has 2 None GameServers, more than maxAvailable
+		{
+			metadata: map[string]string{
+				NoneGameServerMinNumberKey: "1",
+				NoneGameServerMaxNumberKey: "1",
+				GameServerLabelSelectorKey: "region=b",
+			},
+			replicas: 3,
+		},
+		// case 2: region a has 1 None GameServer, less than minAvailable
+		{
+			metadata: map[string]string{
+				NoneGameServerMinNumberKey: "2",
+				NoneGameServerMaxNumberKey: "10",
+				GameServerLabelSelectorKey: "region=a",
+			},
+			replicas: 5,
+		},
+		// case 3: selector matches nothing
+		{
+			metadata: map[string]string{
+				NoneGameServerMinNumberKey: "1",
+				NoneGameServerMaxNumberKey: "10",
+				GameServerLabelSelectorKey: "region=c",
+			},
+			replicas: 5,
+		},
+		// case 4: invalid selector
+		{
+			metadata: map[string]string{
+				NoneGameServerMinNumberKey: "1",
+				GameServerLabelSelectorKey: "region in (",
+			},
+			hasErr: true,
+		},
+	}
+
+	for i, test := range tests {
+		resp, err := scaler.GetMetrics(context.TODO(), &GetMetricsRequest{
+			ScaledObjectRef: &ScaledObjectRef{
+				Name:           "xxx",
+				Namespace:      "xxx",
+				ScalerMetadata: test.metadata,
+			},
+		})
+		if (err != nil) != test.hasErr {
+			t.Errorf("case %d: expect error %v, but actually got %v", i, test.hasErr, err)
+			continue
+		}
+		if test.hasErr {
+			continue
+		}
+		if actual := resp.MetricValues[0].MetricValue; actual != test.replicas {
+			t.Errorf("case %d: expect replicas %d, but actually got %d", i, test.replicas, actual)
+		}
+	}
+}
+
+func TestGetMetricsWithAllocated(t *testing.T) {
+	newPod := func(name string, opsState gamekruiseiov1alpha1.OpsState) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      name,
+				Labels: map[string]string{
+					gamekruiseiov1alpha1.GameServerOwnerGssKey: "xxx",
+					gamekruiseiov1alpha1.GameServerOpsStateKey: string(opsState),
+					gamekruiseiov1alpha1.GameServerStateKey:    string(gamekruiseiov1alpha1.Ready),
+				},
+			},
+		}
+	}
+	newGss := func(replicas int32) *gamekruiseiov1alpha1.GameServerSet {
+		return &gamekruiseiov1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx",
+			},
+			Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+				Replicas: ptr.To[int32](replicas),
+			},
+		}
+	}
+
+	tests := []struct {
+		objs     []client.Object
+		replicas int64
+	}{
+		// case 0: Allocated GameServers are not idle, scale up to keep 2 None GameServers
+		{
+			objs: []client.Object{
+				newGss(3),
+				newPod("xxx-0", gamekruiseiov1alpha1.Allocated),
+				newPod("xxx-1", gamekruiseiov1alpha1.Allocated),
+				newPod("xxx-2", gamekruiseiov1alpha1.None),
+			},
+			replicas: 4,
+		},
+		// case 1: only the idle surplus is scaled down
+		{
+			objs: []client.Object{
+				newGss(5),
+				newPod("xxx-0", gamekruiseiov1alpha1.Allocated),
+				newPod("xxx-1", gamekruiseiov1alpha1.None),
+				newPod("xxx-2", gamekruiseiov1alpha1.None),
+				newPod("xxx-3", gamekruiseiov1alpha1.None),
+				newPod("xxx-4", gamekruiseiov1alpha1.None),
+			},
+			replicas: 3,
+		},
+		// case 2: replicas lower than Allocated GameServers, which are kept
+		{
+			objs: []client.Object{
+				newGss(1),
+				newPod("xxx-0", gamekruiseiov1alpha1.Allocated),
+				newPod("xxx-1", gamekruiseiov1alpha1.Allocated),
+				newPod("xxx-2", gamekruiseiov1alpha1.Allocated),
+			},
+			replicas: 5,
+		},
+	}
+
+	for i, test := range tests {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(test.objs...).Build()
+		scaler := NewExternalScaler(c)
+		resp, err := scaler.GetMetrics(context.TODO(), &GetMetricsRequest{
+			ScaledObjectRef: &ScaledObjectRef{
+				Name:      "xxx",
+				Namespace: "xxx",
+				ScalerMetadata: map[string]string{
+					NoneGameServerMinNumberKey: "2",
+					NoneGameServerMaxNumberKey: "2",
+				},
+			},
+		})
+		if err != nil {
+			t.Errorf("case %d: expect no error, but actually got %v", i, err)
+			continue
+		}
+		if actual := resp.MetricValues[0].MetricValue; actual != test.replicas {
+			t.Errorf("case %d: expect replicas %d, but actually got %d", i, test.replicas, actual)
+		}
+	}
+}
+
+func TestGetReadinessLatency(t *testing.T) {
+	now := time.Now()
+	newGs := func(name string, age time.Duration, state gamekruiseiov1alpha1.GameServerState, networkState gamekruiseiov1alpha1.NetworkState) gamekruiseiov1alpha1.GameServer {
+		return gamekruiseiov1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+			Status: gamekruiseiov1alpha1.GameServerStatus{
+				CurrentState: state,
+				NetworkStatus: gamekruiseiov1alpha1.NetworkStatus{
+					CurrentNetworkState: networkState,
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		gameServers []gamekruiseiov1alpha1.GameServer
+		average     time.Duration
+		max         time.Duration
+		notReady    int
+	}{
+		// case 0: all ready
+		{
+			gameServers: []gamekruiseiov1alpha1.GameServer{
+				newGs("xxx-0", time.Hour, gamekruiseiov1alpha1.Ready, ""),
+				newGs("xxx-1", time.Hour, gamekruiseiov1alpha1.Ready, gamekruiseiov1alpha1.NetworkReady),
+			},
+		},
+		// case 1: creating and waiting for network
+		{
+			gameServers: []gamekruiseiov1alpha1.GameServer{
+				newGs("xxx-0", time.Hour, gamekruiseiov1alpha1.Ready, ""),
+				newGs("xxx-1", 30*time.Second, gamekruiseiov1alpha1.Creating, ""),
+				newGs("xxx-2", 90*time.Second, gamekruiseiov1alpha1.Ready, gamekruiseiov1alpha1.NetworkNotReady),
+			},
+			average:  time.Minute,
+			max:      90 * time.Second,
+			notReady: 2,
+		},
+		// case 2: deleting ones are ignored
+		{
+			gameServers: []gamekruiseiov1alpha1.GameServer{
+				newGs("xxx-0", 10*time.Second, gamekruiseiov1alpha1.NotReady, ""),
+				newGs("xxx-1", time.Hour, gamekruiseiov1alpha1.Deleting, ""),
+			},
+			average:  10 * time.Second,
+			max:      10 * time.Second,
+			notReady: 1,
+		},
+	}
+
+	for i, test := range tests {
+		average, max, notReady := getReadinessLatency(test.gameServers, now)
+		if average != test.average || max != test.max || notReady != test.notReady {
+			t.Errorf("case %d: expect average %v, max %v, notReady %d, but actually got %v, %v, %d", i, test.average, test.max, test.notReady, average, max, notReady)
+		}
+	}
+}
+
+func TestGetMetricsWithReadinessLatency(t *testing.T) {
+	now := time.Now()
+	newPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      name,
+				Labels: map[string]string{
+					gamekruiseiov1alpha1.GameServerOwnerGssKey: "xxx",
+					gamekruiseiov1alpha1.GameServerOpsStateKey: string(gamekruiseiov1alpha1.None),
+				},
+			},
+		}
+	}
+	newGs := func(name string, age time.Duration, state gamekruiseiov1alpha1.GameServerState) *gamekruiseiov1alpha1.GameServer {
+		return &gamekruiseiov1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "xxx",
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+				Labels: map[string]string{
+					gamekruiseiov1alpha1.GameServerOwnerGssKey: "xxx",
+				},
+			},
+			Status: gamekruiseiov1alpha1.GameServerStatus{
+				CurrentState: state,
+			},
+		}
+	}
+	objs := []client.Object{
+		&gamekruiseiov1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx",
+			},
+			Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+				Replicas: ptr.To[int32](3),
+			},
+		},
+		newPod("xxx-0"),
+		newPod("xxx-1"),
+		newPod("xxx-2"),
+		newGs("xxx-0", time.Hour, gamekruiseiov1alpha1.Ready),
+		newGs("xxx-1", 20*time.Second, gamekruiseiov1alpha1.Creating),
+		newGs("xxx-2", 120*time.Second, gamekruiseiov1alpha1.Creating),
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	scaler := NewExternalScaler(c)
+
+	tests := []struct {
+		metadata map[string]string
+		replicas int64
+	}{
+		// case 0: readiness latency not considered
+		{
+			metadata: map[string]string{
+				NoneGameServerMinNumberKey: "2",
+				NoneGameServerMaxNumberKey: "3",
+			},
+			replicas: 3,
+		},
+		// case 1: max latency exceeds the threshold, 2 not-ready GameServers are not counted
+		{
+			metadata: map[string]string{
+				NoneGameServerMinNumberKey:   "2",
+				NoneGameServerMaxNumberKey:   "3",
+				ReadinessLatencyThresholdKey: "60",
+			},
+			replicas: 4,
+		},
+		// case 2: average latency does not exceed the threshold
+		{
+			metadata: map[string]string{
+				NoneGameServerMinNumberKey:     "2",
+				NoneGameServerMaxNumberKey:     "3",
+				ReadinessLatencyThresholdKey:   "100",
+				ReadinessLatencyAggregationKey: ReadinessLatencyAggregationAverage,
+			},
+			replicas: 3,
+		},
+		// case 3: average latency exceeds the threshold
+		{
+			metadata: map[string]string{
+				NoneGameServerMinNumberKey:     "2",
+				NoneGameServerMaxNumberKey:     "3",
+				ReadinessLatencyThresholdKey:   "60",
+				ReadinessLatencyAggregationKey: ReadinessLatencyAggregationAverage,
+			},
+			replicas: 4,
+		},
+	}
+
+	for i, test := range tests {
+		resp, err := scaler.GetMetrics(context.TODO(), &GetMetricsRequest{
+			ScaledObjectRef: &ScaledObjectRef{
+				Name:           "xxx",
+				Namespace:      "xxx",
+				ScalerMetadata: test.metadata,
+			},
+		})
+		if err != nil {
+			t.Errorf("case %d: expect no error, but actually got %v", i, err)
+			continue
+		}
+		if actual := resp.MetricValues[0].MetricValue; actual != test.replicas {
+			t.Errorf("case %d: expect replicas %d, but actually got %d", i, test.replicas, actual)
+		}
+	}
+}
@@ -28,6 +28,13 @@ func init() {
 	metrics.Registry.MustRegister(GameServerSetsReplicasCount)
 	metrics.Registry.MustRegister(GameServerDeletionPriority)
 	metrics.Registry.MustRegister(GameServerUpdatePriority)
+	metrics.Registry.MustRegister(GameServerStatusWritesTotal)
+	metrics.Registry.MustRegister(NlbPortDriftTotal)
+	metrics.Registry.MustRegister(NlbPortUtilization)
+	metrics.Registry.MustRegister(EipAllocationDurationSeconds)
+	metrics.Registry.MustRegister(NetworkCleanupDurationSeconds)
+	metrics.Registry.MustRegister(APIServerClientThrottledTotal)
+	metrics.Registry.MustRegister(NetworkPluginErrorsTotal)
 }
 
 var (
@@ -73,4 +80,54 @@ var (
 		},
 		[]string{"gsName", "gsNs"},
 	)
+	GameServerStatusWritesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okg_gameserver_status_writes_total",
+			Help: "The total of gameserver status writes, labeled by whether the write is patched or skipped as no-op",
+		},
+		[]string{"result"},
+	)
+	NlbPortDriftTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "okg_nlb_port_drift_total",
+			Help: "The total of NLB port allocations found different from the live services and repaired",
+		},
+	)
+	NlbPortUtilization = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "okg_nlb_port_utilization",
+			Help: "The ratio of allocated ports to the total ports available per NLB",
+		},
+		[]string{"lbId"},
+	)
+	EipAllocationDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "okg_eip_allocation_duration_seconds",
+			Help:    "The duration from the EIP requested to its address allocated",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		},
+		[]string{"isp"},
+	)
+	NetworkCleanupDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "okg_network_cleanup_duration_seconds",
+			Help:    "The duration from the pod deletion started to its network resources released, labeled by whether the cleanup completed or expired",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		},
+		[]string{"result"},
+	)
+	APIServerClientThrottledTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okg_apiserver_client_throttled_total",
+			Help: "The total of requests to the API server delayed by the client-side rate limiter, which is set by --api-server-qps and --api-server-qps-burst",
+		},
+		[]string{},
+	)
+	NetworkPluginErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "okg_network_plugin_errors_total",
+			Help: "The total of errors returned by network plugins, labeled by plugin, operation and error type",
+		},
+		[]string{"plugin", "operation", "type"},
+	)
 )
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+
+	"github.com/openkruise/kruise-game/pkg/metrics"
+)
+
+// LongThrottleLatency is the wait of a request beyond which the client-side throttling is logged as a warning.
+const LongThrottleLatency = time.Second
+
+// throttleReportingRateLimiter counts the requests delayed by the rate limiter it wraps,
+// and warns about the long ones, so that operators know when to raise the QPS.
+type throttleReportingRateLimiter struct {
+	flowcontrol.RateLimiter
+}
+
+// NewThrottleReportingRateLimiter returns a token bucket rate limiter with the given qps and burst,
+// which reports client-side throttling by the metric okg_apiserver_client_throttled_total.
+func NewThrottleReportingRateLimiter(qps float32, burst int) flowcontrol.RateLimiter {
+	return WithThrottleReporting(flowcontrol.NewTokenBucketRateLimiter(qps, burst))
+}
+
+// WithThrottleReporting wraps the rate limiter to report client-side throttling.
+func WithThrottleReporting(limiter flowcontrol.RateLimiter) flowcontrol.RateLimiter {
+	return &throttleReportingRateLimiter{RateLimiter: limiter}
+}
+
+func (r *throttleReportingRateLimiter) Wait(ctx context.Context) error {
+	if r.RateLimiter.TryAccept() {
+		return nil
+	}
+	metrics.APIServerClientThrottledTotal.WithLabelValues().Inc()
+	start := time.Now()
+	err := r.RateLimiter.Wait(ctx)
+	if latency := time.Since(start); latency > LongThrottleLatency {
+		klog.Warningf("Waited for %s due to client-side throttling, consider raising --api-server-qps (now %v)", latency.String(), r.RateLimiter.QPS())
+	}
+	return err
+}
+
+func (r *throttleReportingRateLimiter) Accept() {
+	if r.RateLimiter.TryAccept() {
+		return
+	}
+	metrics.APIServerClientThrottledTotal.WithLabelValues().Inc()
+	r.RateLimiter.Accept()
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/openkruise/kruise-game/pkg/metrics"
+)
+
+type fakeRateLimiter struct {
+	tokens int
+	waits  int
+}
+
+func (f *fakeRateLimiter) TryAccept() bool {
+	if f.tokens > 0 {
+		f.tokens--
+		return true
+	}
+	return false
+}
+
+func (f *fakeRateLimiter) Accept() {
+	f.waits++
+}
+
+func (f *fakeRateLimiter) Stop() {}
+
+func (f *fakeRateLimiter) QPS() float32 {
+	return 1
+}
+
+func (f *fakeRateLimiter) Wait(ctx context.Context) error {
+	f.waits++
+	return ctx.Err()
+}
+
+func TestThrottleReportingRateLimiter(t *testing.T) {
+	tests := []struct {
+		tokens    int
+		requests  int
+		throttled float64
+	}{
+		// case 0: no request is throttled
+		{
+			tokens:    3,
+			requests:  3,
+			throttled: 0,
+		},
+		// case 1: the requests beyond the tokens are throttled
+		{
+			tokens:    3,
+			requests:  5,
+			throttled: 2,
+		},
+	}
+
+	for i, test := range tests {
+		before := testutil.ToFloat64(metrics.APIServerClientThrottledTotal.WithLabelValues())
+		fake := &fakeRateLimiter{tokens: test.tokens}
+		limiter := WithThrottleReporting(fake)
+		for j := 0; j < test.requests; j++ {
+			if j%2 == 0 {
+				if err := limiter.Wait(context.Background()); err != nil {
+					t.Errorf("case %d: unexpected error %s", i, err.Error())
+				}
+			} else {
+				limiter.Accept()
+			}
+		}
+		throttled := testutil.ToFloat64(metrics.APIServerClientThrottledTotal.WithLabelValues()) - before
+		if throttled != test.throttled {
+			t.Errorf("case %d: expect %v requests throttled, but actually got %v", i, test.throttled, throttled)
+		}
+		if float64(fake.waits) != test.throttled {
+			t.Errorf("case %d: expect %v waits on the wrapped limiter, but actually got %v", i, test.throttled, fake.waits)
+		}
+	}
+}
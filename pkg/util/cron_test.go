@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	tests := []struct {
+		expr   string
+		hasErr bool
+	}{
+		{expr: "0 18 * * 1-5"},
+		{expr: "*/15 0-6,20-23 1 */2 7"},
+		{expr: "30 9 * * *"},
+		{expr: "0 18 * *", hasErr: true},
+		{expr: "60 18 * * *", hasErr: true},
+		{expr: "0 18 * * 8", hasErr: true},
+		{expr: "0 18-12 * * *", hasErr: true},
+		{expr: "*/0 18 * * *", hasErr: true},
+		{expr: "a 18 * * *", hasErr: true},
+	}
+
+	for _, test := range tests {
+		_, err := ParseCronSchedule(test.expr)
+		if (err != nil) != test.hasErr {
+			t.Errorf("expr %q: expect error %v but actually got %v", test.expr, test.hasErr, err)
+		}
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	// Monday 19:00 UTC
+	monday := time.Date(2024, 5, 6, 19, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expr   string
+		from   time.Time
+		expect time.Time
+	}{
+		// case 0: next weekday
+		{
+			expr:   "0 18 * * 1-5",
+			from:   monday,
+			expect: time.Date(2024, 5, 7, 18, 0, 0, 0, time.UTC),
+		},
+		// case 1: skip the weekend
+		{
+			expr:   "0 18 * * 1-5",
+			from:   time.Date(2024, 5, 10, 18, 0, 0, 0, time.UTC),
+			expect: time.Date(2024, 5, 13, 18, 0, 0, 0, time.UTC),
+		},
+		// case 2: the time itself is excluded
+		{
+			expr:   "*/15 * * * *",
+			from:   monday,
+			expect: monday.Add(15 * time.Minute),
+		},
+		// case 3: 7 is Sunday
+		{
+			expr:   "0 0 * * 7",
+			from:   monday,
+			expect: time.Date(2024, 5, 12, 0, 0, 0, 0, time.UTC),
+		},
+		// case 4: either day of month or day of week matches when both are restricted
+		{
+			expr:   "0 0 10 * 3",
+			from:   monday,
+			expect: time.Date(2024, 5, 8, 0, 0, 0, 0, time.UTC),
+		},
+		// case 5: leap day
+		{
+			expr:   "0 0 29 2 *",
+			from:   monday,
+			expect: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		// case 6: never matches
+		{
+			expr:   "0 0 30 2 *",
+			from:   monday,
+			expect: time.Time{},
+		},
+	}
+
+	for i, test := range tests {
+		schedule, err := ParseCronSchedule(test.expr)
+		if err != nil {
+			t.Fatalf("case %d: failed to parse %q: %s", i, test.expr, err.Error())
+		}
+		actual := schedule.Next(test.from)
+		if !actual.Equal(test.expect) {
+			t.Errorf("case %d: expect next %v but actually got %v", i, test.expect, actual)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"k8s.io/klog/v2"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -30,3 +31,30 @@ func GetNetworkIntervalTime() time.Duration {
 	}
 	return networkIntervalTime
 }
+
+// GetPodDeletionCostWeights returns the base pod-deletion-cost of each opsState.
+// The defaults can be overridden by POD_DELETION_COST_OPSSTATE_WEIGHTS, formatted as "WaitToBeDeleted=-1000,Allocated=1000".
+func GetPodDeletionCostWeights() map[string]int {
+	weights := map[string]int{
+		"Kill":            -10000,
+		"WaitToBeDeleted": -1000,
+		"None":            0,
+		"Allocated":       1000,
+		"PreAllocated":    1000,
+		"Maintaining":     1000,
+	}
+	if conf := os.Getenv("POD_DELETION_COST_OPSSTATE_WEIGHTS"); len(conf) > 0 {
+		for _, kv := range strings.Split(conf, ",") {
+			pair := strings.SplitN(strings.TrimSpace(kv), "=", 2)
+			if len(pair) != 2 {
+				klog.Fatalf("failed to parse POD_DELETION_COST_OPSSTATE_WEIGHTS=%v in env: invalid item %s", conf, kv)
+			}
+			w, err := strconv.Atoi(strings.TrimSpace(pair[1]))
+			if err != nil {
+				klog.Fatalf("failed to convert POD_DELETION_COST_OPSSTATE_WEIGHTS=%v in env: %v", conf, err)
+			}
+			weights[strings.TrimSpace(pair[0])] = w
+		}
+	}
+	return weights
+}
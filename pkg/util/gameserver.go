@@ -19,8 +19,11 @@ package util
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -63,10 +66,25 @@ func (dg DeleteSequenceGs) Less(i, j int) bool {
 		jDeletionPriorityInt, _ := strconv.Atoi(jDeletionPriority)
 		return iDeletionPriorityInt > jDeletionPriorityInt
 	}
+	// Scale Down Weight
+	iWeight := scaleDownWeight(dg[i].GetAnnotations())
+	jWeight := scaleDownWeight(dg[j].GetAnnotations())
+	if iWeight != jWeight {
+		return iWeight > jWeight
+	}
 	// Index Number
 	return GetIndexFromGsName(dg[i].GetName()) > GetIndexFromGsName(dg[j].GetName())
 }
 
+// scaleDownWeight returns the weight from the scale-down-weight annotation, 0 if absent or invalid.
+func scaleDownWeight(annotations map[string]string) int {
+	weight, err := strconv.Atoi(annotations[gameKruiseV1alpha1.GameServerScaleDownWeightKey])
+	if err != nil {
+		return 0
+	}
+	return weight
+}
+
 func opsStateDeletePrority(opsState string) int {
 	switch opsState {
 	case string(gameKruiseV1alpha1.Kill):
@@ -75,7 +93,7 @@ func opsStateDeletePrority(opsState string) int {
 		return 1
 	case string(gameKruiseV1alpha1.None):
 		return 0
-	case string(gameKruiseV1alpha1.Allocated):
+	case string(gameKruiseV1alpha1.Allocated), string(gameKruiseV1alpha1.PreAllocated):
 		return -1
 	case string(gameKruiseV1alpha1.Maintaining):
 		return -2
@@ -83,12 +101,81 @@ func opsStateDeletePrority(opsState string) int {
 	return 0
 }
 
+// GetPodDeletionCost translates opsState and deletion priority into the value of
+// controller.kubernetes.io/pod-deletion-cost. Pods more likely to be deleted get a lower cost,
+// so that cluster-autoscaler and ReplicaSet-like controllers prefer removing them.
+func GetPodDeletionCost(opsState string, deletionPriority *intstr.IntOrString, weights map[string]int) string {
+	cost := int64(weights[opsState])
+	if deletionPriority != nil {
+		cost -= int64(deletionPriority.IntValue())
+	}
+	if cost > math.MaxInt32 {
+		cost = math.MaxInt32
+	}
+	if cost < math.MinInt32 {
+		cost = math.MinInt32
+	}
+	return strconv.FormatInt(cost, 10)
+}
+
 func GetIndexFromGsName(gsName string) int {
 	temp := strings.Split(gsName, "-")
 	index, _ := strconv.Atoi(temp[len(temp)-1])
 	return index
 }
 
+const (
+	// DefaultServerNameFormat is the format of the names of GameServers and pods.
+	DefaultServerNameFormat      = "{gss}-{ordinal}"
+	serverNameGssPlaceholder     = "{gss}"
+	serverNameOrdinalPlaceholder = "{ordinal}"
+)
+
+// GetGsName returns the name of the GameServer with the ordinal in gss, which is also the name of its pod.
+func GetGsName(gssName string, ordinal int) string {
+	return FormatServerName(DefaultServerNameFormat, gssName, ordinal)
+}
+
+// FormatServerName returns the server name of the GameServer with the ordinal in gss by format,
+// replacing the placeholders {gss} and {ordinal}.
+func FormatServerName(format, gssName string, ordinal int) string {
+	name := strings.ReplaceAll(format, serverNameGssPlaceholder, gssName)
+	return strings.ReplaceAll(name, serverNameOrdinalPlaceholder, strconv.Itoa(ordinal))
+}
+
+// GetIndexFromServerName parses the ordinal from the server name formatted by format for gss.
+// It returns -1 if the server name does not match format.
+func GetIndexFromServerName(format, gssName, serverName string) int {
+	parts := strings.Split(format, serverNameOrdinalPlaceholder)
+	if len(parts) != 2 {
+		return -1
+	}
+	prefix := strings.ReplaceAll(parts[0], serverNameGssPlaceholder, gssName)
+	suffix := strings.ReplaceAll(parts[1], serverNameGssPlaceholder, gssName)
+	if len(serverName) <= len(prefix)+len(suffix) || !strings.HasPrefix(serverName, prefix) || !strings.HasSuffix(serverName, suffix) {
+		return -1
+	}
+	ordinalStr := serverName[len(prefix) : len(serverName)-len(suffix)]
+	for _, c := range ordinalStr {
+		if c < '0' || c > '9' {
+			return -1
+		}
+	}
+	ordinal, err := strconv.Atoi(ordinalStr)
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
+
+// ValidateServerNameFormat checks that format has exactly one {ordinal}, so that server names are unique and parsable.
+func ValidateServerNameFormat(format string) error {
+	if strings.Count(format, serverNameOrdinalPlaceholder) != 1 {
+		return fmt.Errorf("serverNameFormat %s should contain %s exactly once", format, serverNameOrdinalPlaceholder)
+	}
+	return nil
+}
+
 func GetIndexListFromPodList(podList []corev1.Pod) []int {
 	var indexList []int
 	for i := 0; i < len(podList); i++ {
@@ -135,6 +222,10 @@ func GetNewAstsFromGss(gss *gameKruiseV1alpha1.GameServerSet, asts *kruiseV1beta
 	readinessGates := gss.Spec.GameServerTemplate.Spec.ReadinessGates
 	readinessGates = append(readinessGates, corev1.PodReadinessGate{ConditionType: appspub.InPlaceUpdateReady})
 	asts.Spec.Template.Spec.ReadinessGates = readinessGates
+	// TopologySpread
+	asts.Spec.Template.Spec.TopologySpreadConstraints = mergeTopologySpread(gss)
+	// DefaultContainerResources
+	asts.Spec.Template.Spec.Containers = applyDefaultContainerResources(gss.Spec.GameServerTemplate.Spec.Containers, gss.Spec.DefaultContainerResources)
 
 	// set Lifecycle
 	asts.Spec.Lifecycle = gss.Spec.Lifecycle
@@ -188,6 +279,78 @@ func GetNewAstsFromGss(gss *gameKruiseV1alpha1.GameServerSet, asts *kruiseV1beta
 	return asts
 }
 
+// mergeTopologySpread returns the topologySpreadConstraints of the pod template with the one translated from TopologySpread,
+// unless the template already has a constraint with the same topologyKey.
+func mergeTopologySpread(gss *gameKruiseV1alpha1.GameServerSet) []corev1.TopologySpreadConstraint {
+	constraints := gss.Spec.GameServerTemplate.Spec.TopologySpreadConstraints
+	ts := gss.Spec.TopologySpread
+	if ts == nil {
+		return constraints
+	}
+	for _, constraint := range constraints {
+		if constraint.TopologyKey == ts.TopologyKey {
+			return constraints
+		}
+	}
+
+	maxSkew := int32(1)
+	if ts.MaxSkew != nil {
+		maxSkew = *ts.MaxSkew
+	}
+	whenUnsatisfiable := ts.WhenUnsatisfiable
+	if whenUnsatisfiable == "" {
+		whenUnsatisfiable = corev1.ScheduleAnyway
+	}
+	merged := make([]corev1.TopologySpreadConstraint, 0, len(constraints)+1)
+	merged = append(merged, constraints...)
+	return append(merged, corev1.TopologySpreadConstraint{
+		MaxSkew:           maxSkew,
+		TopologyKey:       ts.TopologyKey,
+		WhenUnsatisfiable: whenUnsatisfiable,
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: gss.GetName()},
+		},
+	})
+}
+
+// applyDefaultContainerResources returns the containers with the default resources set where they are unset.
+// The given containers are not modified.
+func applyDefaultContainerResources(containers []corev1.Container, defaults *corev1.ResourceRequirements) []corev1.Container {
+	if defaults == nil {
+		return containers
+	}
+	newContainers := make([]corev1.Container, len(containers))
+	for i := range containers {
+		container := containers[i].DeepCopy()
+		resources := &container.Resources
+		for name, quantity := range defaults.Requests {
+			_, hasRequest := resources.Requests[name]
+			_, hasLimit := resources.Limits[name]
+			if hasRequest || hasLimit {
+				continue
+			}
+			if resources.Requests == nil {
+				resources.Requests = make(corev1.ResourceList)
+			}
+			resources.Requests[name] = quantity.DeepCopy()
+		}
+		for name, quantity := range defaults.Limits {
+			if _, hasLimit := resources.Limits[name]; hasLimit {
+				continue
+			}
+			if request, hasRequest := resources.Requests[name]; hasRequest && request.Cmp(quantity) > 0 {
+				continue
+			}
+			if resources.Limits == nil {
+				resources.Limits = make(corev1.ResourceList)
+			}
+			resources.Limits[name] = quantity.DeepCopy()
+		}
+		newContainers[i] = *container
+	}
+	return newContainers
+}
+
 type astsToUpdate struct {
 	UpdateStrategy gameKruiseV1alpha1.UpdateStrategy
 	Template       gameKruiseV1alpha1.GameServerTemplate
@@ -199,11 +362,26 @@ func GetAstsHash(gss *gameKruiseV1alpha1.GameServerSet) string {
 	if gss.Spec.Network != nil {
 		networkConfigs = gss.Spec.Network.NetworkConf
 	}
-	return GetHash(astsToUpdate{
+	hash := GetHash(astsToUpdate{
 		UpdateStrategy: gss.Spec.UpdateStrategy,
 		Template:       gss.Spec.GameServerTemplate,
 		NetworkConfigs: networkConfigs,
 	})
+	// the fields below are hashed only when set, so that the hash of existing GameServerSets is unchanged
+	if gss.Spec.TopologySpread != nil {
+		hash = GetHash(hash + GetHash(gss.Spec.TopologySpread))
+	}
+	if gss.Spec.DefaultContainerResources != nil {
+		hash = GetHash(hash + GetHash(gss.Spec.DefaultContainerResources))
+	}
+	if gss.Spec.ResolveImageDigest {
+		hash = GetHash(hash + "resolveImageDigest")
+	}
+	// the network type can only change when its migration is allowed, and is hashed only then
+	if gss.Spec.Network != nil && gss.GetAnnotations()[gameKruiseV1alpha1.GameServerSetAllowNetworkMigrationKey] == "true" {
+		hash = GetHash(hash + gss.Spec.Network.NetworkType)
+	}
+	return hash
 }
 
 func GetGsTemplateMetadataHash(gss *gameKruiseV1alpha1.GameServerSet) string {
@@ -239,6 +417,34 @@ func GetGameServerSetOfPod(pod *corev1.Pod, c client.Client, ctx context.Context
 	return gss, err
 }
 
+// IsGameServerSetPaused returns whether the reconciliation of gss is paused by annotation paused.
+func IsGameServerSetPaused(gss *gameKruiseV1alpha1.GameServerSet) bool {
+	return gss.GetAnnotations()[gameKruiseV1alpha1.GameServerSetPausedKey] == "true"
+}
+
+// IsNetworkCleanupFinalizerEnabled returns whether the pods of gss are kept by GameServerNetworkCleanupFinalizer
+// until their network resources are released.
+func IsNetworkCleanupFinalizerEnabled(gss *gameKruiseV1alpha1.GameServerSet) bool {
+	return gss.GetAnnotations()[gameKruiseV1alpha1.GameServerSetNetworkCleanupFinalizerKey] == "true"
+}
+
+// GetNetworkPollInterval returns the network poll interval set by annotation network-poll-interval of gss,
+// or 0 if it is not set.
+func GetNetworkPollInterval(gss *gameKruiseV1alpha1.GameServerSet) (time.Duration, error) {
+	value, ok := gss.GetAnnotations()[gameKruiseV1alpha1.GameServerSetNetworkPollIntervalKey]
+	if !ok {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("should be greater than 0")
+	}
+	return interval, nil
+}
+
 func IsAllowNotReadyContainers(networkConfParams []gameKruiseV1alpha1.NetworkConfParams) bool {
 	for _, networkConfParam := range networkConfParams {
 		if networkConfParam.Name == gameKruiseV1alpha1.AllowNotReadyContainersNetworkConfName {
@@ -248,6 +454,11 @@ func IsAllowNotReadyContainers(networkConfParams []gameKruiseV1alpha1.NetworkCon
 	return false
 }
 
+// GetGameServerTokenSecretName returns the name of the Secret keeping the tokens of GameServers of gss.
+func GetGameServerTokenSecretName(gssName string) string {
+	return gssName + "-gameserver-tokens"
+}
+
 func InitGameServer(gss *gameKruiseV1alpha1.GameServerSet, name string) *gameKruiseV1alpha1.GameServer {
 	gs := &gameKruiseV1alpha1.GameServer{}
 	gs.Name = name
@@ -279,6 +490,9 @@ func InitGameServer(gss *gameKruiseV1alpha1.GameServerSet, name string) *gameKru
 		gsAnnotations = make(map[string]string)
 	}
 	gsAnnotations[gameKruiseV1alpha1.GsTemplateMetadataHashKey] = GetGsTemplateMetadataHash(gss)
+	if gss.Spec.ServerNameFormat != "" {
+		gsAnnotations[gameKruiseV1alpha1.GameServerServerNameKey] = FormatServerName(gss.Spec.ServerNameFormat, gss.GetName(), GetIndexFromGsName(name))
+	}
 	gs.SetAnnotations(gsAnnotations)
 
 	// set NetWork
@@ -297,3 +511,153 @@ func InitGameServer(gss *gameKruiseV1alpha1.GameServerSet, name string) *gameKru
 
 	return gs
 }
+
+// GetWarmPoolSpares returns the number of spare GameServers that should be kept beyond gss replicas.
+// Every Allocated GameServer drains one spare from the warm pool.
+func GetWarmPoolSpares(gss *gameKruiseV1alpha1.GameServerSet, pods []corev1.Pod) int {
+	if gss.Spec.WarmPoolSize == nil || *gss.Spec.WarmPoolSize <= 0 {
+		return 0
+	}
+	allocated := 0
+	for _, pod := range pods {
+		if pod.GetDeletionTimestamp() != nil {
+			continue
+		}
+		if pod.GetLabels()[gameKruiseV1alpha1.GameServerOpsStateKey] == string(gameKruiseV1alpha1.Allocated) {
+			allocated++
+		}
+	}
+	spares := int(*gss.Spec.WarmPoolSize) - allocated
+	if spares < 0 {
+		return 0
+	}
+	return spares
+}
+
+// GetScalingScheduleFloor returns the floor of replicas given by the active ScalingSchedule windows at now,
+// and the duration until the next window starts or an active one ends, when the floor may change.
+// Windows with invalid schedules are ignored, as they are rejected by the webhook.
+func GetScalingScheduleFloor(gss *gameKruiseV1alpha1.GameServerSet, now time.Time) (*int32, time.Duration) {
+	var floor *int32
+	var requeueAfter time.Duration
+	now = now.UTC()
+	for _, window := range gss.Spec.ScalingSchedule {
+		active, boundary, ok := getWindowState(window.Schedule, window.DurationSeconds, now)
+		if !ok {
+			continue
+		}
+		if active && (floor == nil || window.MinReplicas > *floor) {
+			floor = ptr.To(window.MinReplicas)
+		}
+		if after := boundary.Sub(now); requeueAfter == 0 || after < requeueAfter {
+			requeueAfter = after
+		}
+	}
+	return floor, requeueAfter
+}
+
+// IsInMaintenanceWindows returns whether now is in any of the maintenance windows, and the duration until
+// the next window starts or an active one ends. It is always in the windows if there is none.
+// Windows with invalid schedules are ignored, as they are rejected by the webhook.
+func IsInMaintenanceWindows(windows []gameKruiseV1alpha1.MaintenanceWindow, now time.Time) (bool, time.Duration) {
+	if len(windows) == 0 {
+		return true, 0
+	}
+	inWindows := false
+	var requeueAfter time.Duration
+	now = now.UTC()
+	for _, window := range windows {
+		active, boundary, ok := getWindowState(window.Schedule, window.DurationSeconds, now)
+		if !ok {
+			continue
+		}
+		inWindows = inWindows || active
+		if after := boundary.Sub(now); requeueAfter == 0 || after < requeueAfter {
+			requeueAfter = after
+		}
+	}
+	return inWindows, requeueAfter
+}
+
+// getWindowState returns whether the window starting at the cron schedule and lasting durationSeconds is active
+// at now, and when it ends if active, or when it starts next otherwise. ok is false if the schedule is invalid
+// or never starts.
+func getWindowState(schedule string, durationSeconds int32, now time.Time) (active bool, boundary time.Time, ok bool) {
+	cron, err := ParseCronSchedule(schedule)
+	if err != nil {
+		return false, time.Time{}, false
+	}
+	duration := time.Duration(durationSeconds) * time.Second
+	// the window is active if it started within the last duration
+	start := cron.Next(now.Add(-duration))
+	if start.IsZero() {
+		return false, time.Time{}, false
+	}
+	if start.After(now) {
+		return false, start, true
+	}
+	return true, start.Add(duration), true
+}
+
+// GetOpsStateScheduling returns the OpsStateScheduling of the GameServerSet for the opsState, or nil if there is none.
+func GetOpsStateScheduling(gss *gameKruiseV1alpha1.GameServerSet, opsState gameKruiseV1alpha1.OpsState) *gameKruiseV1alpha1.OpsStateScheduling {
+	if gss == nil {
+		return nil
+	}
+	for i := range gss.Spec.OpsStateScheduling {
+		if gss.Spec.OpsStateScheduling[i].OpsState == opsState {
+			return &gss.Spec.OpsStateScheduling[i]
+		}
+	}
+	return nil
+}
+
+// IsPodSpecSatisfyingOpsStateScheduling returns whether the pod spec has the nodeSelector and the tolerations of the scheduling.
+func IsPodSpecSatisfyingOpsStateScheduling(spec *corev1.PodSpec, scheduling *gameKruiseV1alpha1.OpsStateScheduling) bool {
+	for key, value := range scheduling.NodeSelector {
+		if v, ok := spec.NodeSelector[key]; !ok || v != value {
+			return false
+		}
+	}
+	for i := range scheduling.Tolerations {
+		if !hasToleration(spec.Tolerations, &scheduling.Tolerations[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyOpsStateScheduling merges the nodeSelector and the tolerations of the scheduling into the pod spec.
+func ApplyOpsStateScheduling(spec *corev1.PodSpec, scheduling *gameKruiseV1alpha1.OpsStateScheduling) {
+	if len(scheduling.NodeSelector) != 0 && spec.NodeSelector == nil {
+		spec.NodeSelector = make(map[string]string)
+	}
+	for key, value := range scheduling.NodeSelector {
+		spec.NodeSelector[key] = value
+	}
+	for i := range scheduling.Tolerations {
+		if !hasToleration(spec.Tolerations, &scheduling.Tolerations[i]) {
+			spec.Tolerations = append(spec.Tolerations, scheduling.Tolerations[i])
+		}
+	}
+}
+
+func hasToleration(tolerations []corev1.Toleration, toleration *corev1.Toleration) bool {
+	for i := range tolerations {
+		if tolerations[i].MatchToleration(toleration) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetImageOverride returns the image that ImageOverrides pins for the container of the GameServer with the given id.
+// The first matching override wins.
+func GetImageOverride(overrides []gameKruiseV1alpha1.ImageOverride, id int, containerName string) (string, bool) {
+	for _, o := range overrides {
+		if o.ContainerName == containerName && id >= o.StartId && id <= o.EndId {
+			return o.Image, true
+		}
+	}
+	return "", false
+}
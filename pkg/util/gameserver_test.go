@@ -20,6 +20,7 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -53,6 +54,72 @@ func TestGetIndexFromGsName(t *testing.T) {
 	}
 }
 
+func TestServerName(t *testing.T) {
+	tests := []struct {
+		format     string
+		gssName    string
+		ordinal    int
+		serverName string
+	}{
+		// case 0
+		{
+			format:     DefaultServerNameFormat,
+			gssName:    "xxx-a",
+			ordinal:    12,
+			serverName: "xxx-a-12",
+		},
+		// case 1
+		{
+			format:     "{gss}.{ordinal}",
+			gssName:    "minecraft",
+			ordinal:    3,
+			serverName: "minecraft.3",
+		},
+		// case 2
+		{
+			format:     "srv{ordinal}-{gss}.game",
+			gssName:    "minecraft-2",
+			ordinal:    10,
+			serverName: "srv10-minecraft-2.game",
+		},
+		// case 3
+		{
+			format:     "{ordinal}",
+			gssName:    "minecraft",
+			ordinal:    0,
+			serverName: "0",
+		},
+	}
+
+	for i, test := range tests {
+		serverName := FormatServerName(test.format, test.gssName, test.ordinal)
+		if serverName != test.serverName {
+			t.Errorf("case %d: expect server name %s but actually got %s", i, test.serverName, serverName)
+		}
+		if ordinal := GetIndexFromServerName(test.format, test.gssName, serverName); ordinal != test.ordinal {
+			t.Errorf("case %d: expect ordinal %d but actually got %d", i, test.ordinal, ordinal)
+		}
+		if test.format == DefaultServerNameFormat {
+			if ordinal := GetIndexFromGsName(GetGsName(test.gssName, test.ordinal)); ordinal != test.ordinal {
+				t.Errorf("case %d: expect ordinal %d from GetIndexFromGsName but actually got %d", i, test.ordinal, ordinal)
+			}
+		}
+	}
+
+	// names not matching the format
+	for _, serverName := range []string{"minecraft.", "other.3", "minecraft.3x", "minecraft.-3"} {
+		if ordinal := GetIndexFromServerName("{gss}.{ordinal}", "minecraft", serverName); ordinal != -1 {
+			t.Errorf("expect server name %s not matched but actually got ordinal %d", serverName, ordinal)
+		}
+	}
+
+	for format, valid := range map[string]bool{"{gss}.{ordinal}": true, "{gss}": false, "{ordinal}-{ordinal}": false} {
+		if err := ValidateServerNameFormat(format); (err == nil) != valid {
+			t.Errorf("expect format %s valid %v but actually got error %v", format, valid, err)
+		}
+	}
+}
+
 func TestDeleteSequenceGs(t *testing.T) {
 	tests := []struct {
 		before []corev1.Pod
@@ -252,6 +319,29 @@ func TestRemovePrefixGameKruise(t *testing.T) {
 	}
 }
 
+func TestGetAstsHashNetworkType(t *testing.T) {
+	newGss := func(networkType string, allowMigration bool) *gameKruiseV1alpha1.GameServerSet {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				Network: &gameKruiseV1alpha1.Network{NetworkType: networkType},
+			},
+		}
+		if allowMigration {
+			gss.SetAnnotations(map[string]string{gameKruiseV1alpha1.GameServerSetAllowNetworkMigrationKey: "true"})
+		}
+		return gss
+	}
+
+	// the hash of existing GameServerSets is unchanged
+	if GetAstsHash(newGss("Kubernetes-HostPort", false)) != GetAstsHash(newGss("Kubernetes-NodePort", false)) {
+		t.Errorf("expect network type not hashed unless the migration is allowed")
+	}
+	// the workload is updated when migrating to another network type
+	if GetAstsHash(newGss("Kubernetes-HostPort", true)) == GetAstsHash(newGss("Kubernetes-NodePort", true)) {
+		t.Errorf("expect network type hashed when the migration is allowed")
+	}
+}
+
 func TestGetGsTemplateMetadataHash(t *testing.T) {
 	tests := []struct {
 		gssA   *gameKruiseV1alpha1.GameServerSet
@@ -331,6 +421,52 @@ func TestGetGsTemplateMetadataHash(t *testing.T) {
 	}
 }
 
+func TestGetNetworkPollInterval(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		interval    time.Duration
+		valid       bool
+	}{
+		// case 0: not set
+		{
+			annotations: nil,
+			interval:    0,
+			valid:       true,
+		},
+		// case 1
+		{
+			annotations: map[string]string{gameKruiseV1alpha1.GameServerSetNetworkPollIntervalKey: "2s"},
+			interval:    2 * time.Second,
+			valid:       true,
+		},
+		// case 2: not a duration
+		{
+			annotations: map[string]string{gameKruiseV1alpha1.GameServerSetNetworkPollIntervalKey: "2"},
+			valid:       false,
+		},
+		// case 3: not positive
+		{
+			annotations: map[string]string{gameKruiseV1alpha1.GameServerSetNetworkPollIntervalKey: "0s"},
+			valid:       false,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: test.annotations,
+			},
+		}
+		interval, err := GetNetworkPollInterval(gss)
+		if (err == nil) != test.valid {
+			t.Errorf("case %d: expect valid %v but actually got err %v", i, test.valid, err)
+		}
+		if interval != test.interval {
+			t.Errorf("case %d: expect interval %v but actually got %v", i, test.interval, interval)
+		}
+	}
+}
+
 func TestIsAllowNotReadyContainers(t *testing.T) {
 	tests := []struct {
 		networkConfParams         []gameKruiseV1alpha1.NetworkConfParams
@@ -365,6 +501,78 @@ func TestIsAllowNotReadyContainers(t *testing.T) {
 	}
 }
 
+func TestGetImageOverride(t *testing.T) {
+	overrides := []gameKruiseV1alpha1.ImageOverride{
+		{
+			StartId:       0,
+			EndId:         4,
+			ContainerName: "gameserver",
+			Image:         "gameserver:v2",
+		},
+		{
+			StartId:       3,
+			EndId:         6,
+			ContainerName: "gameserver",
+			Image:         "gameserver:v3",
+		},
+		{
+			StartId:       0,
+			EndId:         9,
+			ContainerName: "sidecar",
+			Image:         "sidecar:v2",
+		},
+	}
+	tests := []struct {
+		id            int
+		containerName string
+		image         string
+		found         bool
+	}{
+		// case 0
+		{
+			id:            0,
+			containerName: "gameserver",
+			image:         "gameserver:v2",
+			found:         true,
+		},
+		// case 1
+		{
+			id:            4,
+			containerName: "gameserver",
+			image:         "gameserver:v2",
+			found:         true,
+		},
+		// case 2
+		{
+			id:            5,
+			containerName: "gameserver",
+			image:         "gameserver:v3",
+			found:         true,
+		},
+		// case 3
+		{
+			id:            7,
+			containerName: "gameserver",
+			image:         "",
+			found:         false,
+		},
+		// case 4
+		{
+			id:            7,
+			containerName: "sidecar",
+			image:         "sidecar:v2",
+			found:         true,
+		},
+	}
+
+	for i, test := range tests {
+		image, found := GetImageOverride(overrides, test.id, test.containerName)
+		if image != test.image || found != test.found {
+			t.Errorf("case %d: expect image %s (found %v) but actually got %s (found %v)", i, test.image, test.found, image, found)
+		}
+	}
+}
+
 func TestInitGameServer(t *testing.T) {
 	updatePriority := intstr.FromInt(0)
 	deletionPriority := intstr.FromInt(0)
@@ -434,6 +642,17 @@ func TestInitGameServer(t *testing.T) {
 		},
 	}
 
+	// case 1: server name formatted
+	serverNameGss := tests[0].gss.DeepCopy()
+	serverNameGss.Spec.ServerNameFormat = "{gss}.{ordinal}"
+	serverNameGs := tests[0].gs.DeepCopy()
+	serverNameGs.Annotations[gameKruiseV1alpha1.GameServerServerNameKey] = "case0.1"
+	tests = append(tests, struct {
+		gss  *gameKruiseV1alpha1.GameServerSet
+		name string
+		gs   *gameKruiseV1alpha1.GameServer
+	}{gss: serverNameGss, name: "case0-1", gs: serverNameGs})
+
 	for i, test := range tests {
 		expect := test.gs
 		actual := InitGameServer(test.gss, test.name)
@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ImageDigestResolver resolves an image reference with tag into the reference pinned to its digest,
+// e.g. nginx:1.25 into nginx@sha256:{digest}.
+type ImageDigestResolver interface {
+	ResolveImageDigest(ctx context.Context, image string) (string, error)
+}
+
+// HasImageDigest returns whether the image reference is already pinned to a digest.
+func HasImageDigest(image string) bool {
+	return strings.Contains(image, "@")
+}
+
+// NewNodeImageDigestResolver returns an ImageDigestResolver looking up the images cached on nodes,
+// which are reported in the node status along with their digests.
+func NewNodeImageDigestResolver(c client.Client) ImageDigestResolver {
+	return &nodeImageDigestResolver{client: c}
+}
+
+type nodeImageDigestResolver struct {
+	client client.Client
+}
+
+func (r *nodeImageDigestResolver) ResolveImageDigest(ctx context.Context, image string) (string, error) {
+	nodeList := &corev1.NodeList{}
+	if err := r.client.List(ctx, nodeList); err != nil {
+		return "", err
+	}
+	name := normalizeImageName(image)
+	repository := imageRepository(name)
+	for _, node := range nodeList.Items {
+		for _, nodeImage := range node.Status.Images {
+			if !IsStringInList(name, normalizeImageNames(nodeImage.Names)) {
+				continue
+			}
+			for _, n := range nodeImage.Names {
+				if i := strings.Index(n, "@"); i >= 0 && imageRepository(normalizeImageName(n)) == repository {
+					return imageRepository(image) + n[i:], nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("image %s is not found on any node", image)
+}
+
+func normalizeImageNames(names []string) []string {
+	normalized := make([]string, 0, len(names))
+	for _, n := range names {
+		normalized = append(normalized, normalizeImageName(n))
+	}
+	return normalized
+}
+
+// normalizeImageName completes the image reference with the default registry, namespace and tag,
+// e.g. nginx into docker.io/library/nginx:latest.
+func normalizeImageName(image string) string {
+	name := image
+	if i := strings.Index(name, "/"); i < 0 || !strings.ContainsAny(name[:i], ".:") && name[:i] != "localhost" {
+		if !strings.Contains(name, "/") {
+			name = "library/" + name
+		}
+		name = "docker.io/" + name
+	}
+	if HasImageDigest(name) {
+		return name
+	}
+	if i := strings.LastIndex(name, "/"); !strings.Contains(name[i+1:], ":") {
+		name = name + ":latest"
+	}
+	return name
+}
+
+// imageRepository returns the image reference without tag or digest.
+func imageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, "/"); strings.Contains(image[i+1:], ":") {
+		image = image[:i+1+strings.Index(image[i+1:], ":")]
+	}
+	return image
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNodeImageDigestResolver(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-0"},
+		Status: corev1.NodeStatus{
+			Images: []corev1.ContainerImage{
+				{
+					Names: []string{
+						"docker.io/library/nginx@sha256:111",
+						"docker.io/library/nginx:1.25",
+					},
+				},
+				{
+					Names: []string{
+						"registry.example.com:5000/game/server@sha256:222",
+						"registry.example.com:5000/game/server:v1",
+					},
+				},
+				{
+					Names: []string{
+						"docker.io/library/busybox@sha256:333",
+						"docker.io/library/busybox:latest",
+					},
+				},
+			},
+		},
+	}
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewNodeImageDigestResolver(fake.NewClientBuilder().WithScheme(scheme).WithObjects(node).Build())
+
+	tests := []struct {
+		image  string
+		pinned string
+		found  bool
+	}{
+		// case 0: short name
+		{
+			image:  "nginx:1.25",
+			pinned: "nginx@sha256:111",
+			found:  true,
+		},
+		// case 1: registry with port
+		{
+			image:  "registry.example.com:5000/game/server:v1",
+			pinned: "registry.example.com:5000/game/server@sha256:222",
+			found:  true,
+		},
+		// case 2: default tag
+		{
+			image:  "busybox",
+			pinned: "busybox@sha256:333",
+			found:  true,
+		},
+		// case 3: tag not cached
+		{
+			image: "nginx:1.26",
+			found: false,
+		},
+	}
+
+	for i, test := range tests {
+		pinned, err := resolver.ResolveImageDigest(context.TODO(), test.image)
+		if (err == nil) != test.found {
+			t.Errorf("case %d: expect found %v but actually got err %v", i, test.found, err)
+			continue
+		}
+		if pinned != test.pinned {
+			t.Errorf("case %d: expect pinned image %s but actually got %s", i, test.pinned, pinned)
+		}
+	}
+}
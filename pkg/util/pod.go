@@ -19,8 +19,13 @@ package util
 import (
 	gameKruiseV1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	"time"
 )
 
+// NetworkCleanupMaxExtension is the longest time the network cleanup of a deleting pod is allowed to take
+// beyond its grace period, during which the pod is kept by GameServerNetworkCleanupFinalizer.
+const NetworkCleanupMaxExtension = 5 * time.Minute
+
 // GetPodConditionFromList extracts the provided condition from the given list of condition and
 // returns the index of the condition and the condition. Returns -1 and nil if the condition is not present.
 func GetPodConditionFromList(conditions []corev1.PodCondition, conditionType corev1.PodConditionType) (int, *corev1.PodCondition) {
@@ -51,3 +56,42 @@ func IsContainersPreInplaceUpdating(pod *corev1.Pod, gss *gameKruiseV1alpha1.Gam
 	}
 	return false
 }
+
+// GetNetworkCleanupDeadline returns the deadline of the network cleanup of pod. It is the end of the grace period,
+// derived from terminationGracePeriodSeconds if the pod is not deleting yet. Once the grace period is over,
+// the deadline is extended by NetworkCleanupMaxExtension.
+func GetNetworkCleanupDeadline(pod *corev1.Pod, now time.Time) time.Time {
+	if pod.DeletionTimestamp == nil {
+		gracePeriod := int64(corev1.DefaultTerminationGracePeriodSeconds)
+		if pod.Spec.TerminationGracePeriodSeconds != nil {
+			gracePeriod = *pod.Spec.TerminationGracePeriodSeconds
+		}
+		return now.Add(time.Duration(gracePeriod) * time.Second)
+	}
+	deadline := pod.DeletionTimestamp.Time
+	if now.After(deadline) {
+		deadline = deadline.Add(NetworkCleanupMaxExtension)
+	}
+	return deadline
+}
+
+// IsNetworkCleanupExpired returns whether the network cleanup of the deleting pod has exceeded NetworkCleanupMaxExtension.
+func IsNetworkCleanupExpired(pod *corev1.Pod, now time.Time) bool {
+	return pod.DeletionTimestamp != nil && now.After(pod.DeletionTimestamp.Add(NetworkCleanupMaxExtension))
+}
+
+// GetNetworkCleanupDuration returns the seconds since the deletion of pod started.
+func GetNetworkCleanupDuration(pod *corev1.Pod, now time.Time) float64 {
+	if pod.DeletionTimestamp == nil {
+		return 0
+	}
+	start := pod.DeletionTimestamp.Time
+	if pod.DeletionGracePeriodSeconds != nil {
+		start = start.Add(-time.Duration(*pod.DeletionGracePeriodSeconds) * time.Second)
+	}
+	duration := now.Sub(start).Seconds()
+	if duration < 0 {
+		duration = 0
+	}
+	return duration
+}
@@ -19,8 +19,11 @@ package util
 import (
 	gameKruiseV1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestGetPodConditionFromList(t *testing.T) {
@@ -192,3 +195,72 @@ func TestIsContainersPreInplaceUpdating(t *testing.T) {
 		}
 	}
 }
+
+func TestGetNetworkCleanupDeadline(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		pod      *corev1.Pod
+		deadline time.Time
+		expired  bool
+	}{
+		// case 0: not deleting, with the default grace period
+		{
+			pod:      &corev1.Pod{},
+			deadline: now.Add(30 * time.Second),
+		},
+		// case 1: not deleting, with terminationGracePeriodSeconds
+		{
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{TerminationGracePeriodSeconds: ptr.To[int64](10)},
+			},
+			deadline: now.Add(10 * time.Second),
+		},
+		// case 2: deleting, within the grace period
+		{
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: ptr.To(metav1.NewTime(now.Add(5 * time.Second)))},
+			},
+			deadline: now.Add(5 * time.Second),
+		},
+		// case 3: deleting, beyond the grace period
+		{
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: ptr.To(metav1.NewTime(now.Add(-time.Minute)))},
+			},
+			deadline: now.Add(-time.Minute).Add(NetworkCleanupMaxExtension),
+		},
+		// case 4: deleting, beyond the max extension
+		{
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: ptr.To(metav1.NewTime(now.Add(-NetworkCleanupMaxExtension - time.Second)))},
+			},
+			deadline: now.Add(-time.Second),
+			expired:  true,
+		},
+	}
+
+	for i, test := range tests {
+		if actual := GetNetworkCleanupDeadline(test.pod, now); !actual.Equal(test.deadline) {
+			t.Errorf("case %d: expect deadline %v but actually got %v", i, test.deadline, actual)
+		}
+		if actual := IsNetworkCleanupExpired(test.pod, now); actual != test.expired {
+			t.Errorf("case %d: expect expired %v but actually got %v", i, test.expired, actual)
+		}
+	}
+}
+
+func TestGetNetworkCleanupDuration(t *testing.T) {
+	now := time.Now()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			DeletionTimestamp:          ptr.To(metav1.NewTime(now.Add(20 * time.Second))),
+			DeletionGracePeriodSeconds: ptr.To[int64](30),
+		},
+	}
+	if actual := GetNetworkCleanupDuration(pod, now); actual != 10 {
+		t.Errorf("expect duration 10 but actually got %v", actual)
+	}
+	if actual := GetNetworkCleanupDuration(&corev1.Pod{}, now); actual != 0 {
+		t.Errorf("expect duration 0 for pod not deleting but actually got %v", actual)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	gameKruiseV1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sort"
+)
+
+// ComputeToScaleGs computes the ids of GameServers that should exist in the cluster (the manage ids), and the reserve ids
+// of the Advanced StatefulSet, when a GameServerSet is scaled to expectedReplicas. It has no side effects.
+//
+// There are two kinds of reserved ids. The explicit ones are set by users in ReserveGameServerIds of GameServerSet,
+// and the implicit ones are left by GameServers scaled down, which are reused first when scaling up.
+// Besides, the excluded ids are owned by external systems and are never used, taking precedence over both kinds.
+//   - gssReserveIds is the newest explicit id list, from the spec of GameServerSet.
+//   - reserveIds is the explicit id list handled last time, from the annotation of GameServerSet.
+//   - notExistIds is the implicit id list, i.e. the reserve ids of the Advanced StatefulSet not in reserveIds.
+//   - excludedIds is the id list in ExcludedGameServerIds of GameServerSet. Once an id is no longer excluded,
+//     it is left in notExistIds by the last computation and becomes an implicit one.
+//   - pods are the pods managed by GameServerSet now. Their opsState, deletion priority and scale down weight
+//     decide which ones are removed first when scaling down, while the Allocated and PreAllocated ones are never removed.
+//
+// The pods whose ids become explicitly reserved or excluded are removed regardless of expectedReplicas. The returned
+// reserve ids are the implicit ones followed by the explicit ones and then the excluded ones.
+func ComputeToScaleGs(gssReserveIds, reserveIds, notExistIds, excludedIds []int, expectedReplicas int, pods []corev1.Pod) ([]int, []int) {
+	// 1. Get newest implicit list & explicit.
+	newAddExplicit := GetSliceInANotInB(gssReserveIds, reserveIds)
+	newDeleteExplicit := GetSliceInANotInB(reserveIds, gssReserveIds)
+	newImplicit := GetSliceInANotInB(notExistIds, newAddExplicit)
+	newImplicit = append(newImplicit, newDeleteExplicit...)
+	newImplicit = GetSliceInANotInB(newImplicit, excludedIds)
+	newExplicit := gssReserveIds
+	newExcluded := GetSliceInANotInB(excludedIds, newExplicit)
+	skipIds := append(append([]int{}, newExplicit...), newExcluded...)
+
+	// 2. Remove the pods ids is in newExplicit or excludedIds.
+	var workloadManageIds []int
+	var newPods []corev1.Pod
+	for _, pod := range pods {
+		index := GetIndexFromGsName(pod.Name)
+		if IsNumInList(index, skipIds) {
+			continue
+		}
+		workloadManageIds = append(workloadManageIds, index)
+		newPods = append(newPods, pod)
+	}
+
+	// 3. Continue to delete or add pods based on the current and expected number of pods.
+	existReplicas := len(workloadManageIds)
+
+	if existReplicas < expectedReplicas {
+		// Add pods.
+		num := 0
+		var toAdd []int
+		for i := 0; num < expectedReplicas-existReplicas; i++ {
+			if IsNumInList(i, workloadManageIds) || IsNumInList(i, skipIds) {
+				continue
+			}
+			if IsNumInList(i, newImplicit) {
+				newImplicit = GetSliceInANotInB(newImplicit, []int{i})
+			}
+			toAdd = append(toAdd, i)
+			num++
+		}
+		workloadManageIds = append(workloadManageIds, toAdd...)
+	} else if existReplicas > expectedReplicas {
+		// Delete pods. Allocated GameServers are serving players, and PreAllocated ones are reserved for them,
+		// they are never scaled down.
+		var removablePods []corev1.Pod
+		for _, pod := range newPods {
+			if !isRetainedOnScaleDown(pod) {
+				removablePods = append(removablePods, pod)
+			}
+		}
+		toDeleteNum := existReplicas - expectedReplicas
+		if toDeleteNum > len(removablePods) {
+			klog.Infof("%d GameServers are expected to be scaled down, but only %d are not Allocated or PreAllocated", toDeleteNum, len(removablePods))
+			toDeleteNum = len(removablePods)
+		}
+		sortedGs := DeleteSequenceGs(removablePods)
+		sort.Sort(sortedGs)
+		toDelete := GetIndexListFromPodList(sortedGs[:toDeleteNum])
+		workloadManageIds = GetSliceInANotInB(workloadManageIds, toDelete)
+		newImplicit = append(newImplicit, toDelete...)
+	}
+
+	return workloadManageIds, append(newImplicit, skipIds...)
+}
+
+// GetRetainedReplicas returns the number of pods that ComputeToScaleGs never scales down, i.e. the Allocated and
+// PreAllocated ones whose ids are neither in gssReserveIds nor in excludedIds. The manage ids computed by ComputeToScaleGs
+// are at least as many as them, whatever expectedReplicas is.
+func GetRetainedReplicas(gssReserveIds, excludedIds []int, pods []corev1.Pod) int {
+	retained := 0
+	for _, pod := range pods {
+		index := GetIndexFromGsName(pod.Name)
+		if IsNumInList(index, gssReserveIds) || IsNumInList(index, excludedIds) {
+			continue
+		}
+		if isRetainedOnScaleDown(pod) {
+			retained++
+		}
+	}
+	return retained
+}
+
+// isRetainedOnScaleDown returns whether the pod is Allocated or PreAllocated, which is never scaled down.
+func isRetainedOnScaleDown(pod corev1.Pod) bool {
+	opsState := pod.GetLabels()[gameKruiseV1alpha1.GameServerOpsStateKey]
+	return opsState == string(gameKruiseV1alpha1.Allocated) || opsState == string(gameKruiseV1alpha1.PreAllocated)
+}
@@ -162,3 +162,33 @@ func IsHasNegativeNum(nums []int) bool {
 	}
 	return false
 }
+
+// ParseIdRanges parses ids separated by "," into a list, where a range of ids can be given as "{start}-{end}".
+// e.g. "1,3,5-7" is parsed into [1 3 5 6 7].
+func ParseIdRanges(str string) ([]int, error) {
+	var ids []int
+	for _, item := range strings.Split(str, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		bounds := strings.SplitN(item, "-", 2)
+		start, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid id %s", item)
+		}
+		end := start
+		if len(bounds) == 2 {
+			end, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid id range %s", item)
+			}
+		}
+		for id := start; id <= end; id++ {
+			if !IsNumInList(id, ids) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
@@ -16,7 +16,10 @@ limitations under the License.
 
 package util
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestIsNumInList(t *testing.T) {
 	tests := []struct {
@@ -183,6 +186,53 @@ func TestStringToIntSlice(t *testing.T) {
 	}
 }
 
+func TestParseIdRanges(t *testing.T) {
+	tests := []struct {
+		str    string
+		result []int
+		valid  bool
+	}{
+		// case 0
+		{
+			str:    "",
+			result: nil,
+			valid:  true,
+		},
+		// case 1
+		{
+			str:    "1,3,5-7",
+			result: []int{1, 3, 5, 6, 7},
+			valid:  true,
+		},
+		// case 2: overlapped
+		{
+			str:    "2-4, 3",
+			result: []int{2, 3, 4},
+			valid:  true,
+		},
+		// case 3: reversed range
+		{
+			str:   "7-5",
+			valid: false,
+		},
+		// case 4: not a number
+		{
+			str:   "1,a",
+			valid: false,
+		},
+	}
+
+	for i, test := range tests {
+		actual, err := ParseIdRanges(test.str)
+		if (err == nil) != test.valid {
+			t.Errorf("case %d: expect valid %v but actually got err %v", i, test.valid, err)
+		}
+		if !reflect.DeepEqual(actual, test.result) {
+			t.Errorf("case %d: expect %v but actually got %v", i, test.result, actual)
+		}
+	}
+}
+
 func TestStringToInt32Slice(t *testing.T) {
 	tests := []struct {
 		str       string
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
+	admissionv1 "k8s.io/api/admission/v1"
+	"net/http"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"strings"
+)
+
+type GssMutatingHandler struct {
+	Client  client.Client
+	decoder *admission.Decoder
+}
+
+func (gmh *GssMutatingHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	gss := &gamekruiseiov1alpha1.GameServerSet{}
+	err := gmh.decoder.Decode(req, gss)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if gss.Spec.Network == nil {
+		return admission.Allowed("no network to mutate")
+	}
+
+	// network conf of existing GameServerSets is left as it is unless the update changes it,
+	// so that GameServerSets created before the param schemas were registered can still be updated.
+	if req.Operation == admissionv1.Update {
+		oldGss := &gamekruiseiov1alpha1.GameServerSet{}
+		if err := gmh.decoder.DecodeRaw(req.OldObject, oldGss); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if reflect.DeepEqual(gss.Spec.Network, oldGss.Spec.Network) {
+			return admission.Allowed("network unchanged")
+		}
+	}
+
+	if err := canonicalizeNetworkConf(gss.Spec.Network); err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	marshaledGss, err := json.Marshal(gss)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledGss)
+}
+
+// canonicalizeNetworkConf rewrites the network conf param names that differ from the ones registered by the plugin
+// only in case, and rejects the names that are unknown for the network type.
+func canonicalizeNetworkConf(network *gamekruiseiov1alpha1.Network) error {
+	schemas, ok := cloudprovider.GetParamSchemas(network.NetworkType)
+	if !ok {
+		return nil
+	}
+	for i, conf := range network.NetworkConf {
+		canonicalName := ""
+		for _, schema := range schemas {
+			if strings.EqualFold(conf.Name, schema.Name) {
+				canonicalName = schema.Name
+				break
+			}
+		}
+		if canonicalName == "" {
+			names := make([]string, 0, len(schemas))
+			for _, schema := range schemas {
+				names = append(names, schema.Name)
+			}
+			return fmt.Errorf("network conf %s is unknown for network type %s, valid names are %v", conf.Name, network.NetworkType, names)
+		}
+		network.NetworkConf[i].Name = canonicalName
+	}
+	return nil
+}
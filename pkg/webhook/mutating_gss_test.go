@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider/alibabacloud"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"testing"
+)
+
+func TestCanonicalizeNetworkConf(t *testing.T) {
+	tests := []struct {
+		network *gamekruiseiov1alpha1.Network
+		expect  []gamekruiseiov1alpha1.NetworkConfParams
+		isErr   bool
+	}{
+		// case 0
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.NlbNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{
+						Name:  "nlbIds",
+						Value: "nlb-xxx",
+					},
+					{
+						Name:  "PORTPROTOCOLS",
+						Value: "80/TCP",
+					},
+					{
+						Name:  "lbHealthCheckFlag",
+						Value: "on",
+					},
+				},
+			},
+			expect: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  alibabacloud.NlbIdsConfigName,
+					Value: "nlb-xxx",
+				},
+				{
+					Name:  alibabacloud.PortProtocolsConfigName,
+					Value: "80/TCP",
+				},
+				{
+					Name:  alibabacloud.LBHealthCheckFlagConfigName,
+					Value: "on",
+				},
+			},
+			isErr: false,
+		},
+		// case 1
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.NlbNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{
+						Name:  alibabacloud.NlbIdsConfigName,
+						Value: "nlb-xxx",
+					},
+					{
+						Name:  "Zonemps",
+						Value: "cn-hangzhou-h@vpc-xxx@vsw-xxx",
+					},
+				},
+			},
+			isErr: true,
+		},
+		// case 2
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.MultiNlbsNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{
+						Name:  "NLBIdNames",
+						Value: "nlb-xxx/dx",
+					},
+					{
+						Name:  "allowNotReadyContainers",
+						Value: "sidecar",
+					},
+				},
+			},
+			expect: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  alibabacloud.NlbIdNamesConfigName,
+					Value: "nlb-xxx/dx",
+				},
+				{
+					Name:  gamekruiseiov1alpha1.AllowNotReadyContainersNetworkConfName,
+					Value: "sidecar",
+				},
+			},
+			isErr: false,
+		},
+		// case 3
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: "Kubernetes-HostPort",
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{
+						Name:  "whatever",
+						Value: "xxx",
+					},
+				},
+			},
+			expect: []gamekruiseiov1alpha1.NetworkConfParams{
+				{
+					Name:  "whatever",
+					Value: "xxx",
+				},
+			},
+			isErr: false,
+		},
+	}
+
+	for i, test := range tests {
+		err := canonicalizeNetworkConf(test.network)
+		if (err != nil) != test.isErr {
+			t.Errorf("case %d: expect err %v, but got %v", i, test.isErr, err)
+		}
+		if test.isErr {
+			continue
+		}
+		if !reflect.DeepEqual(test.network.NetworkConf, test.expect) {
+			t.Errorf("case %d: expect network conf %v, but got %v", i, test.expect, test.network.NetworkConf)
+		}
+	}
+}
+
+func TestGssMutatingHandle(t *testing.T) {
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownConf := []gamekruiseiov1alpha1.NetworkConfParams{
+		{Name: alibabacloud.NlbIdsConfigName, Value: "nlb-xxx"},
+		{Name: "Zonemaps", Value: "xxx"},
+	}
+	tests := []struct {
+		operation admissionv1.Operation
+		newConf   []gamekruiseiov1alpha1.NetworkConfParams
+		oldConf   []gamekruiseiov1alpha1.NetworkConfParams
+		allowed   bool
+	}{
+		// case 0: create with unknown conf
+		{
+			operation: admissionv1.Create,
+			newConf:   unknownConf,
+			allowed:   false,
+		},
+		// case 1: update with unknown conf unchanged
+		{
+			operation: admissionv1.Update,
+			newConf:   unknownConf,
+			oldConf:   unknownConf,
+			allowed:   true,
+		},
+		// case 2: update changing to unknown conf
+		{
+			operation: admissionv1.Update,
+			newConf:   unknownConf,
+			oldConf:   unknownConf[:1],
+			allowed:   false,
+		},
+	}
+
+	for i, test := range tests {
+		newGss := &gamekruiseiov1alpha1.GameServerSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "game.kruise.io/v1alpha1", Kind: "GameServerSet"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx"},
+			Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+				Network: &gamekruiseiov1alpha1.Network{NetworkType: alibabacloud.NlbNetwork, NetworkConf: test.newConf},
+			},
+		}
+		oldGss := newGss.DeepCopy()
+		oldGss.Spec.Network.NetworkConf = test.oldConf
+		newRaw, _ := json.Marshal(newGss)
+		oldRaw, _ := json.Marshal(oldGss)
+
+		gmh := &GssMutatingHandler{decoder: decoder}
+		resp := gmh.Handle(context.TODO(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: test.operation,
+			Object:    runtime.RawExtension{Raw: newRaw},
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+		}})
+		if resp.Allowed != test.allowed {
+			t.Errorf("case %d: expect allowed %v but actually got %v, because of %v", i, test.allowed, resp.Allowed, resp.Result)
+		}
+	}
+}
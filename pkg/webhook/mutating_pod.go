@@ -21,8 +21,11 @@ import (
 	"encoding/json"
 	"fmt"
 	gameKruiseV1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
 	"github.com/openkruise/kruise-game/cloudprovider/errors"
 	"github.com/openkruise/kruise-game/cloudprovider/manager"
+	"github.com/openkruise/kruise-game/pkg/metrics"
+	"github.com/openkruise/kruise-game/pkg/util"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -32,13 +35,31 @@ import (
 	"k8s.io/klog/v2"
 	"net/http"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"strconv"
+	"sync"
 	"time"
 )
 
 const (
 	podMutatingTimeout    = 8 * time.Second
 	mutatingTimeoutReason = "MutatingTimeout"
+	// reprovisionWaitInterval is the interval to check again whether the old Service has been deleted when reprovisioning.
+	reprovisionWaitInterval = 3 * time.Second
+	// pluginEventInterval is the minimal interval between events of the same reason on a pod,
+	// since plugins waiting for cloud resources fail the same way on every reconcile.
+	pluginEventInterval = time.Minute
+	// apiCallBackoffBase is the backoff of the first retry beyond the api call retry budget, doubled afterwards.
+	apiCallBackoffBase   = 10 * time.Second
+	apiCallBackoffReason = "ApiCallBackoff"
+)
+
+// The operations of plugins, as the label of metric okg_network_plugin_errors_total.
+const (
+	onPodAddedOperation   = "OnPodAdded"
+	onPodUpdatedOperation = "OnPodUpdated"
+	onPodDeletedOperation = "OnPodDeleted"
 )
 
 type patchResult struct {
@@ -51,6 +72,51 @@ type PodMutatingHandler struct {
 	decoder              *admission.Decoder
 	CloudProviderManager *manager.ProviderManager
 	eventRecorder        record.EventRecorder
+
+	// apiCallRetryBudget is the number of consecutive apiCallErrors of a pod before backing off, 0 means unlimited.
+	apiCallRetryBudget int
+	apiCallMaxBackoff  time.Duration
+	apiCallFailures    *apiCallFailureCounter
+}
+
+// apiCallFailureCounter counts the consecutive apiCallErrors of plugins by pod uid.
+// The pod recreated with the same name replaces the failures of the old one, so that they are kept
+// at most one per name even if the deletion of the old pod is never seen.
+type apiCallFailureCounter struct {
+	lock   sync.Mutex
+	counts map[types.UID]int
+	uids   map[types.NamespacedName]types.UID
+}
+
+func newApiCallFailureCounter() *apiCallFailureCounter {
+	return &apiCallFailureCounter{
+		counts: make(map[types.UID]int),
+		uids:   make(map[types.NamespacedName]types.UID),
+	}
+}
+
+// inc increases the failures of pod and returns the result.
+func (c *apiCallFailureCounter) inc(pod *corev1.Pod) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+	if uid, ok := c.uids[key]; ok && uid != pod.UID {
+		delete(c.counts, uid)
+	}
+	c.uids[key] = pod.UID
+	c.counts[pod.UID]++
+	return c.counts[pod.UID]
+}
+
+// reset forgets the failures of pod.
+func (c *apiCallFailureCounter) reset(pod *corev1.Pod) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+	if uid, ok := c.uids[key]; ok && uid == pod.UID {
+		delete(c.uids, key)
+	}
+	delete(c.counts, pod.UID)
 }
 
 func (pmh *PodMutatingHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
@@ -66,6 +132,21 @@ func (pmh *PodMutatingHandler) Handle(ctx context.Context, req admission.Request
 			msg := fmt.Sprintf("Pod %s/%s patchContainers failed, because of %s", pod.Namespace, pod.Name, err.Error())
 			return admission.Denied(msg)
 		}
+		pod, err = patchOpsStateScheduling(pmh.Client, pod, ctx)
+		if err != nil {
+			msg := fmt.Sprintf("Pod %s/%s patchOpsStateScheduling failed, because of %s", pod.Namespace, pod.Name, err.Error())
+			return admission.Denied(msg)
+		}
+		pod, err = patchGameServerToken(pmh.Client, pod, ctx)
+		if err != nil {
+			msg := fmt.Sprintf("Pod %s/%s patchGameServerToken failed, because of %s", pod.Namespace, pod.Name, err.Error())
+			return admission.Denied(msg)
+		}
+		pod, err = patchExternalFinalizers(pmh.Client, pod, ctx)
+		if err != nil {
+			msg := fmt.Sprintf("Pod %s/%s patchExternalFinalizers failed, because of %s", pod.Namespace, pod.Name, err.Error())
+			return admission.Denied(msg)
+		}
 	}
 
 	// get the plugin according to pod
@@ -73,6 +154,17 @@ func (pmh *PodMutatingHandler) Handle(ctx context.Context, req admission.Request
 	if !ok {
 		msg := fmt.Sprintf("Pod %s/%s has no available plugin", pod.Namespace, pod.Name)
 		klog.Infof(msg)
+		if pod.GetDeletionTimestamp() != nil && controllerutil.ContainsFinalizer(pod, gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer) {
+			// nothing to release, and the finalizer is removed by the controller
+			markNetworkCleaned(pod)
+		}
+		return getAdmissionResponse(req, patchResult{pod: pod, err: nil})
+	}
+
+	// the network of existing pods is not updated while GameServerSet is paused. The network of new pods is
+	// still allocated, and it is still released when pods are deleted
+	if req.Operation == admissionv1.Update && pod.GetDeletionTimestamp() == nil && isGameServerSetPaused(pmh.Client, pod, ctx) {
+		klog.Infof("GameServerSet of pod %s/%s is paused, skip plugin %s", pod.Namespace, pod.Name, plugin.Name())
 		return getAdmissionResponse(req, patchResult{pod: pod, err: nil})
 	}
 
@@ -88,10 +180,54 @@ func (pmh *PodMutatingHandler) Handle(ctx context.Context, req admission.Request
 		switch req.Operation {
 		case admissionv1.Create:
 			newPod, pluginError = plugin.OnPodAdded(pmh.Client, pod, ctx)
+			observePluginError(plugin.Name(), onPodAddedOperation, pluginError)
+			if pluginError == nil && newPod != nil && isNetworkCleanupFinalizerEnabled(pmh.Client, pod, ctx) {
+				controllerutil.AddFinalizer(newPod, gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer)
+			}
 		case admissionv1.Update:
+			if pod.GetDeletionTimestamp() != nil {
+				// the network of the deleting pod is only released, and the update is always admitted,
+				// so that the other finalizers of the pod can be removed even if the cleanup fails
+				pmh.apiCallFailures.reset(pod)
+				newPod = pod
+				if !controllerutil.ContainsFinalizer(pod, gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer) || pod.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkCleanedKey] == "true" {
+					break
+				}
+				if cleanupError := cleanupNetwork(pmh.Client, plugin, pod, ctx, time.Now()); cleanupError != nil {
+					msg := fmt.Sprintf("Failed to release network of pod %s/%s, retry later, because of %s", pod.Namespace, pod.Name, cleanupError.Error())
+					klog.Warningf(msg)
+					pmh.eventRecorder.Eventf(pod, corev1.EventTypeWarning, string(cleanupError.Type()), msg)
+				}
+				break
+			}
+			if oldPod := getNetworkMigratedPod(req, pmh.decoder, pod); oldPod != nil {
+				pluginError = pmh.releaseMigratedNetwork(oldPod, ctx)
+				if pluginError != nil {
+					break
+				}
+				delete(pod.Annotations, gameKruiseV1alpha1.GameServerNetworkStatus)
+			}
+			var waiting bool
+			waiting, pluginError = reprovisionNetwork(pmh.Client, plugin, pod, ctx)
+			if pluginError != nil {
+				break
+			}
+			if waiting {
+				newPod, pluginError = handleRequeue(pod, errors.NewRequeueError(reprovisionWaitInterval, "waiting for the old service of pod %s/%s deleted", pod.Namespace, pod.Name))
+				break
+			}
 			newPod, pluginError = plugin.OnPodUpdated(pmh.Client, pod, ctx)
+			observePluginError(plugin.Name(), onPodUpdatedOperation, pluginError)
+			reportNetworkConfigError(pmh.Client, plugin, pod, ctx)
+			newPod, pluginError = pmh.handleApiCallError(pod, newPod, pluginError)
+			newPod, pluginError = handleRequeue(newPod, pluginError)
 		case admissionv1.Delete:
-			pluginError = plugin.OnPodDeleted(pmh.Client, pod, ctx)
+			// the network of pod with the finalizer is released once it is deleting, triggered by the controller
+			pmh.apiCallFailures.reset(pod)
+			if controllerutil.ContainsFinalizer(pod, gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer) {
+				break
+			}
+			pluginError = observePluginError(plugin.Name(), onPodDeletedOperation, plugin.OnPodDeleted(pmh.Client, pod, ctx))
 		}
 		if pluginError != nil {
 			msg := fmt.Sprintf("Failed to %s pod %s/%s ,because of %s", req.Operation, pod.Namespace, pod.Name, pluginError.Error())
@@ -117,6 +253,322 @@ func (pmh *PodMutatingHandler) Handle(ctx context.Context, req admission.Request
 	}
 }
 
+// isGameServerSetPaused returns whether the GameServerSet owning pod is paused.
+func isGameServerSetPaused(c client.Client, pod *corev1.Pod, ctx context.Context) bool {
+	if _, ok := pod.GetLabels()[gameKruiseV1alpha1.GameServerOwnerGssKey]; !ok {
+		return false
+	}
+	gss, err := util.GetGameServerSetOfPod(pod, c, ctx)
+	if err != nil {
+		return false
+	}
+	return util.IsGameServerSetPaused(gss)
+}
+
+// isNetworkCleanupFinalizerEnabled returns whether the GameServerSet owning pod opts in GameServerNetworkCleanupFinalizer.
+func isNetworkCleanupFinalizerEnabled(c client.Client, pod *corev1.Pod, ctx context.Context) bool {
+	if _, ok := pod.GetLabels()[gameKruiseV1alpha1.GameServerOwnerGssKey]; !ok {
+		return false
+	}
+	gss, err := util.GetGameServerSetOfPod(pod, c, ctx)
+	if err != nil {
+		return false
+	}
+	return util.IsNetworkCleanupFinalizerEnabled(gss)
+}
+
+// patchOpsStateScheduling sets the nodeSelector and tolerations of OpsStateScheduling on the pod being created,
+// according to the opsState of its GameServer, which exists if the pod is recreated.
+func patchOpsStateScheduling(c client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, error) {
+	if _, ok := pod.GetLabels()[gameKruiseV1alpha1.GameServerOwnerGssKey]; !ok {
+		return pod, nil
+	}
+	gss, err := util.GetGameServerSetOfPod(pod, c, ctx)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return pod, nil
+		}
+		return pod, err
+	}
+	if len(gss.Spec.OpsStateScheduling) == 0 {
+		return pod, nil
+	}
+	gs := &gameKruiseV1alpha1.GameServer{}
+	err = c.Get(ctx, types.NamespacedName{
+		Namespace: pod.GetNamespace(),
+		Name:      pod.GetName(),
+	}, gs)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return pod, nil
+		}
+		return pod, err
+	}
+	if scheduling := util.GetOpsStateScheduling(gss, gs.Spec.OpsState); scheduling != nil {
+		util.ApplyOpsStateScheduling(&pod.Spec, scheduling)
+	}
+	return pod, nil
+}
+
+// patchGameServerToken injects env GameServerTokenEnvName into the containers of the pod being created, referring to
+// the token of its ordinal in the Secret of InjectGameServerToken. The containers wait for the token until
+// the GameServerSet controller generates it.
+func patchGameServerToken(c client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, error) {
+	if _, ok := pod.GetLabels()[gameKruiseV1alpha1.GameServerOwnerGssKey]; !ok {
+		return pod, nil
+	}
+	gss, err := util.GetGameServerSetOfPod(pod, c, ctx)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return pod, nil
+		}
+		return pod, err
+	}
+	if !gss.Spec.InjectGameServerToken {
+		return pod, nil
+	}
+	tokenEnv := corev1.EnvVar{
+		Name: gameKruiseV1alpha1.GameServerTokenEnvName,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: util.GetGameServerTokenSecretName(gss.GetName())},
+				Key:                  strconv.Itoa(util.GetIndexFromGsName(pod.GetName())),
+			},
+		},
+	}
+	for i := range pod.Spec.Containers {
+		found := false
+		for _, env := range pod.Spec.Containers[i].Env {
+			if env.Name == tokenEnv.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			pod.Spec.Containers[i].Env = append(pod.Spec.Containers[i].Env, tokenEnv)
+		}
+	}
+	return pod, nil
+}
+
+// patchExternalFinalizers adds GameServerExternalFinalizersFinalizer to the pod being created if its GameServerSet
+// declares GameServerFinalizers, so that the pod deleted by scale-down waits for the finalizers of its GameServer.
+func patchExternalFinalizers(c client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, error) {
+	if _, ok := pod.GetLabels()[gameKruiseV1alpha1.GameServerOwnerGssKey]; !ok {
+		return pod, nil
+	}
+	gss, err := util.GetGameServerSetOfPod(pod, c, ctx)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return pod, nil
+		}
+		return pod, err
+	}
+	if len(gss.Spec.GameServerFinalizers) > 0 {
+		controllerutil.AddFinalizer(pod, gameKruiseV1alpha1.GameServerExternalFinalizersFinalizer)
+	}
+	return pod, nil
+}
+
+// cleanupNetwork releases the network resources of the deleting pod by plugin before the deadline derived from
+// its grace period, and marks the pod by GameServerNetworkCleanedKey once done, so that the controller removes
+// GameServerNetworkCleanupFinalizer. If the cleanup fails, it is triggered again by the controller until
+// NetworkCleanupMaxExtension beyond the grace period, and then the finalizer is removed anyway.
+func cleanupNetwork(c client.Client, plugin cloudprovider.Plugin, pod *corev1.Pod, ctx context.Context, now time.Time) errors.PluginError {
+	ctx, cancel := context.WithDeadline(ctx, util.GetNetworkCleanupDeadline(pod, now))
+	defer cancel()
+
+	if pluginError := observePluginError(plugin.Name(), onPodDeletedOperation, plugin.OnPodDeleted(c, pod, ctx)); pluginError != nil {
+		return pluginError
+	}
+	markNetworkCleaned(pod)
+	return nil
+}
+
+// markNetworkCleaned marks the network resources of the deleting pod released.
+func markNetworkCleaned(pod *corev1.Pod) {
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[gameKruiseV1alpha1.GameServerNetworkCleanedKey] = "true"
+}
+
+// observePluginError counts the error returned by the operation of plugin by its type, and returns the error as is.
+func observePluginError(pluginName, operation string, pluginError errors.PluginError) errors.PluginError {
+	if pluginError != nil {
+		metrics.NetworkPluginErrorsTotal.WithLabelValues(pluginName, operation, string(pluginError.Type())).Inc()
+	}
+	return pluginError
+}
+
+// handleRequeue records the interval requested by the plugin on the pod, so that the network is triggered
+// again after it. A RequeueError is not a failure, and the pod patched by the plugin is admitted.
+func handleRequeue(pod *corev1.Pod, pluginError errors.PluginError) (*corev1.Pod, errors.PluginError) {
+	if pod == nil {
+		return pod, pluginError
+	}
+	if pluginError != nil && pluginError.Type() != errors.RequeueError {
+		return pod, pluginError
+	}
+	if after, ok := errors.GetRequeueAfter(pluginError); ok {
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		pod.Annotations[gameKruiseV1alpha1.GameServerNetworkRequeueAfter] = after.String()
+	} else {
+		delete(pod.Annotations, gameKruiseV1alpha1.GameServerNetworkRequeueAfter)
+	}
+	return pod, nil
+}
+
+// handleApiCallError counts the consecutive apiCallErrors of the pod. Once they exceed the retry budget,
+// the pod is admitted with a RequeueError of exponential backoff instead of being denied, so that the network
+// is no longer retried at the network interval against a degraded cloud API, and an event is emitted.
+func (pmh *PodMutatingHandler) handleApiCallError(pod, newPod *corev1.Pod, pluginError errors.PluginError) (*corev1.Pod, errors.PluginError) {
+	if pluginError == nil || pluginError.Type() != errors.ApiCallError {
+		pmh.apiCallFailures.reset(pod)
+		return newPod, pluginError
+	}
+	failures := pmh.apiCallFailures.inc(pod)
+	if pmh.apiCallRetryBudget <= 0 || failures <= pmh.apiCallRetryBudget {
+		return newPod, pluginError
+	}
+	backoff := getApiCallBackoff(failures-pmh.apiCallRetryBudget, pmh.apiCallMaxBackoff)
+	msg := fmt.Sprintf("pod %s/%s failed with apiCallError %d times in a row, retry after %v: %s", pod.Namespace, pod.Name, failures, backoff, pluginError.Error())
+	klog.Warningf(msg)
+	pmh.eventRecorder.Eventf(pod, corev1.EventTypeWarning, apiCallBackoffReason, msg)
+	return pod.DeepCopy(), errors.NewRequeueError(backoff, msg)
+}
+
+// getApiCallBackoff returns the backoff of the nth retry beyond the retry budget, capped by maxBackoff if positive.
+func getApiCallBackoff(n int, maxBackoff time.Duration) time.Duration {
+	backoff := apiCallBackoffBase
+	for i := 1; i < n; i++ {
+		backoff *= 2
+		if maxBackoff > 0 && backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	if maxBackoff > 0 && backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// getNetworkMigratedPod returns the old pod if the network type of pod has been changed by the update, or nil otherwise.
+func getNetworkMigratedPod(req admission.Request, decoder *admission.Decoder, pod *corev1.Pod) *corev1.Pod {
+	if req.Operation != admissionv1.Update || len(req.OldObject.Raw) == 0 {
+		return nil
+	}
+	oldPod := &corev1.Pod{}
+	if err := decoder.DecodeRaw(req.OldObject, oldPod); err != nil {
+		return nil
+	}
+	oldNetworkType := oldPod.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkType]
+	if oldNetworkType == "" || oldNetworkType == pod.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkType] {
+		return nil
+	}
+	return oldPod
+}
+
+// releaseMigratedNetwork runs OnPodDeleted of the plugin used before migration, so that its resources are not orphaned.
+func (pmh *PodMutatingHandler) releaseMigratedNetwork(oldPod *corev1.Pod, ctx context.Context) errors.PluginError {
+	oldPlugin, ok := pmh.CloudProviderManager.FindAvailablePlugins(oldPod)
+	if !ok {
+		return nil
+	}
+	klog.Infof("Pod %s/%s network type migrated from %s, releasing its network resources", oldPod.Namespace, oldPod.Name, oldPlugin.Name())
+	return observePluginError(oldPlugin.Name(), onPodDeletedOperation, oldPlugin.OnPodDeleted(pmh.Client, oldPod, ctx))
+}
+
+// reprovisionNetwork releases the network resources of pod once the value of annotation network-reprovision changes,
+// so that they are provisioned again by the plugin. The Service owned by the pod is deleted and recreated.
+// It returns true while the deleted Service still exists, when the plugin should not handle the pod.
+func reprovisionNetwork(c client.Client, plugin cloudprovider.Plugin, pod *corev1.Pod, ctx context.Context) (bool, errors.PluginError) {
+	svc := &corev1.Service{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: pod.GetNamespace(), Name: pod.GetName()}, svc)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return false, errors.ToPluginError(err, errors.ApiCallError)
+	}
+	svcOwned := err == nil && isOwnedByPod(svc, pod)
+	if svcOwned && svc.GetDeletionTimestamp() != nil {
+		return true, nil
+	}
+
+	reprovision := pod.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkReprovision]
+	if reprovision == "" || reprovision == pod.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkReprovisioned] {
+		return false, nil
+	}
+
+	klog.Infof("Pod %s/%s network reprovision %s triggered, releasing network resources by %s", pod.Namespace, pod.Name, reprovision, plugin.Name())
+	if pluginError := observePluginError(plugin.Name(), onPodDeletedOperation, plugin.OnPodDeleted(c, pod, ctx)); pluginError != nil {
+		return false, pluginError
+	}
+	if svcOwned {
+		if err := c.Delete(ctx, svc); err != nil && !k8serrors.IsNotFound(err) {
+			return false, errors.ToPluginError(err, errors.ApiCallError)
+		}
+	}
+	delete(pod.Annotations, gameKruiseV1alpha1.GameServerNetworkStatus)
+	pod.Annotations[gameKruiseV1alpha1.GameServerNetworkReprovisioned] = reprovision
+	return svcOwned, nil
+}
+
+func isOwnedByPod(obj client.Object, pod *corev1.Pod) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Kind == "Pod" && ref.UID == pod.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+// reportNetworkConfigError sets the error of parsing the network conf of GameServerSet owning the pod on its status,
+// and clears it once the conf is valid. Errors of the per-pod conf override or of cloud APIs are not reported there,
+// since they are not errors of GameServerSet. The field is written here only.
+func reportNetworkConfigError(c client.Client, plugin cloudprovider.Plugin, pod *corev1.Pod, ctx context.Context) {
+	if _, ok := pod.GetLabels()[gameKruiseV1alpha1.GameServerOwnerGssKey]; !ok {
+		return
+	}
+	gss, err := util.GetGameServerSetOfPod(pod, c, ctx)
+	if err != nil || gss.Spec.Network == nil {
+		return
+	}
+	var configError string
+	if err := validateNetworkConf(plugin, gss.Spec.Network); err != nil {
+		configError = err.Error()
+	}
+	if gss.Status.NetworkConfigError == configError {
+		return
+	}
+
+	var value interface{}
+	if configError != "" {
+		value = configError
+	}
+	patchBytes, err := json.Marshal(map[string]interface{}{"status": map[string]interface{}{"networkConfigError": value}})
+	if err != nil {
+		return
+	}
+	if err := c.Status().Patch(ctx, gss, client.RawPatch(types.MergePatchType, patchBytes)); err != nil {
+		klog.Warningf("failed to report network config error of GameServerSet %s/%s, because of %s", gss.Namespace, gss.Name, err.Error())
+	}
+}
+
+// validateNetworkConf checks network conf against the param schemas registered for its network type,
+// and by the plugin if it implements cloudprovider.ConfigValidator.
+func validateNetworkConf(plugin cloudprovider.Plugin, network *gameKruiseV1alpha1.Network) error {
+	if schemas, ok := cloudprovider.GetParamSchemas(network.NetworkType); ok {
+		if err := cloudprovider.ValidateNetworkConf(schemas, network.NetworkConf); err != nil {
+			return err
+		}
+	}
+	if validator, ok := plugin.(cloudprovider.ConfigValidator); ok {
+		return validator.ValidateConfig(network.NetworkConf)
+	}
+	return nil
+}
+
 func getPodFromRequest(req admission.Request, decoder *admission.Decoder) (*corev1.Pod, error) {
 	pod := &corev1.Pod{}
 	if req.Operation == admissionv1.Delete {
@@ -147,6 +599,9 @@ func NewPodMutatingHandler(client client.Client, decoder *admission.Decoder, cpm
 		decoder:              decoder,
 		CloudProviderManager: cpm,
 		eventRecorder:        recorder,
+		apiCallRetryBudget:   apiCallRetryBudget,
+		apiCallMaxBackoff:    apiCallMaxBackoff,
+		apiCallFailures:      newApiCallFailureCounter(),
 	}
 }
 
@@ -2,18 +2,32 @@ package webhook
 
 import (
 	"context"
+	"encoding/json"
 	gameKruiseV1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
+	"github.com/openkruise/kruise-game/cloudprovider/alibabacloud"
+	"github.com/openkruise/kruise-game/cloudprovider/errors"
+	"github.com/openkruise/kruise-game/cloudprovider/manager"
+	"github.com/openkruise/kruise-game/pkg/metrics"
+	"github.com/openkruise/kruise-game/pkg/util"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -325,3 +339,972 @@ func TestGetPodFromRequest(t *testing.T) {
 		}
 	}
 }
+
+func TestHandleRequeue(t *testing.T) {
+	tests := []struct {
+		annotations       map[string]string
+		pluginError       errors.PluginError
+		expectErr         bool
+		expectRequeueTime string
+	}{
+		// case 0
+		{
+			annotations:       map[string]string{},
+			pluginError:       errors.NewRequeueError(2*time.Second, "waiting for lb"),
+			expectErr:         false,
+			expectRequeueTime: "2s",
+		},
+		// case 1
+		{
+			annotations:       map[string]string{gameKruiseV1alpha1.GameServerNetworkRequeueAfter: "2s"},
+			pluginError:       nil,
+			expectErr:         false,
+			expectRequeueTime: "",
+		},
+		// case 2
+		{
+			annotations:       map[string]string{gameKruiseV1alpha1.GameServerNetworkRequeueAfter: "2s"},
+			pluginError:       errors.NewPluginError(errors.ApiCallError, "xxx"),
+			expectErr:         true,
+			expectRequeueTime: "2s",
+		},
+	}
+
+	for i, test := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: test.annotations,
+			},
+		}
+		newPod, err := handleRequeue(pod, test.pluginError)
+		if (err != nil) != test.expectErr {
+			t.Errorf("case %d: expect err %v, but actually got %v", i, test.expectErr, err)
+		}
+		if actual := newPod.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkRequeueAfter]; actual != test.expectRequeueTime {
+			t.Errorf("case %d: expect requeue after %s, but actually got %s", i, test.expectRequeueTime, actual)
+		}
+	}
+}
+
+func TestHandleApiCallError(t *testing.T) {
+	pmh := &PodMutatingHandler{
+		eventRecorder:      record.NewFakeRecorder(10),
+		apiCallRetryBudget: 2,
+		apiCallMaxBackoff:  15 * time.Second,
+		apiCallFailures:    newApiCallFailureCounter(),
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx-0", UID: "uid-0"},
+	}
+	apiCallError := errors.NewPluginError(errors.ApiCallError, "cloud api is degraded")
+	tests := []struct {
+		pluginError errors.PluginError
+		errorType   errors.PluginErrorType
+		after       time.Duration
+	}{
+		// case 0: within the budget
+		{
+			pluginError: apiCallError,
+			errorType:   errors.ApiCallError,
+		},
+		// case 1: within the budget
+		{
+			pluginError: apiCallError,
+			errorType:   errors.ApiCallError,
+		},
+		// case 2: beyond the budget
+		{
+			pluginError: apiCallError,
+			errorType:   errors.RequeueError,
+			after:       10 * time.Second,
+		},
+		// case 3: backoff capped by max backoff
+		{
+			pluginError: apiCallError,
+			errorType:   errors.RequeueError,
+			after:       15 * time.Second,
+		},
+		// case 4: success resets the failures
+		{
+			pluginError: nil,
+		},
+		// case 5: within the budget again
+		{
+			pluginError: apiCallError,
+			errorType:   errors.ApiCallError,
+		},
+	}
+
+	for i, test := range tests {
+		_, actual := pmh.handleApiCallError(pod, pod, test.pluginError)
+		if test.errorType == "" {
+			if actual != nil {
+				t.Errorf("case %d: expect no error but actually got %v", i, actual)
+			}
+			continue
+		}
+		if actual == nil || actual.Type() != test.errorType {
+			t.Errorf("case %d: expect error type %s but actually got %v", i, test.errorType, actual)
+			continue
+		}
+		if after, _ := errors.GetRequeueAfter(actual); after != test.after {
+			t.Errorf("case %d: expect requeue after %v but actually got %v", i, test.after, after)
+		}
+	}
+
+	events := pmh.eventRecorder.(*record.FakeRecorder).Events
+	if len(events) != 2 {
+		t.Errorf("expect 2 %s events but actually got %d", apiCallBackoffReason, len(events))
+	}
+}
+
+func TestApiCallFailureCounter(t *testing.T) {
+	c := newApiCallFailureCounter()
+	oldPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx-0", UID: "uid-0"}}
+	newPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx-0", UID: "uid-1"}}
+
+	c.inc(oldPod)
+	if failures := c.inc(oldPod); failures != 2 {
+		t.Errorf("expect 2 failures of the old pod but actually got %d", failures)
+	}
+	// the recreated pod starts over, and the failures of the old one are pruned
+	if failures := c.inc(newPod); failures != 1 {
+		t.Errorf("expect 1 failure of the new pod but actually got %d", failures)
+	}
+	if len(c.counts) != 1 || len(c.uids) != 1 {
+		t.Errorf("expect failures of the new pod only but actually got %v", c.counts)
+	}
+	// resetting the old pod does not affect the new one
+	c.reset(oldPod)
+	if len(c.counts) != 1 || len(c.uids) != 1 {
+		t.Errorf("expect failures of the new pod kept but actually got %v", c.counts)
+	}
+	c.reset(newPod)
+	if len(c.counts) != 0 || len(c.uids) != 0 {
+		t.Errorf("expect no failures but actually got %v %v", c.counts, c.uids)
+	}
+}
+
+func TestGetNetworkMigratedPod(t *testing.T) {
+	podRaw := func(networkType string) []byte {
+		return []byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"foo","namespace":"default","annotations":{"game.kruise.io/network-type":"` + networkType + `"}}}`)
+	}
+	tests := []struct {
+		req      admission.Request
+		pod      *corev1.Pod
+		migrated bool
+	}{
+		// case 0
+		{
+			req: admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: admissionv1.Update,
+					OldObject: runtime.RawExtension{Raw: podRaw("AlibabaCloud-SLB")},
+				},
+			},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{gameKruiseV1alpha1.GameServerNetworkType: "AlibabaCloud-NLB"},
+				},
+			},
+			migrated: true,
+		},
+		// case 1
+		{
+			req: admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: admissionv1.Update,
+					OldObject: runtime.RawExtension{Raw: podRaw("AlibabaCloud-SLB")},
+				},
+			},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{gameKruiseV1alpha1.GameServerNetworkType: "AlibabaCloud-SLB"},
+				},
+			},
+			migrated: false,
+		},
+		// case 2
+		{
+			req: admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: admissionv1.Create,
+				},
+			},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{gameKruiseV1alpha1.GameServerNetworkType: "AlibabaCloud-NLB"},
+				},
+			},
+			migrated: false,
+		},
+	}
+
+	decoder, err := admission.NewDecoder(runtime.NewScheme())
+	if err != nil {
+		t.Error(err)
+	}
+
+	for i, test := range tests {
+		oldPod := getNetworkMigratedPod(test.req, decoder, test.pod)
+		if (oldPod != nil) != test.migrated {
+			t.Errorf("case %d: expect migrated %v, but actually got %v", i, test.migrated, oldPod != nil)
+		}
+	}
+}
+
+type fakeReprovisionPlugin struct {
+	deleted int
+}
+
+func (f *fakeReprovisionPlugin) Name() string {
+	return "Fake-Reprovision"
+}
+
+func (f *fakeReprovisionPlugin) Alias() string {
+	return ""
+}
+
+func (f *fakeReprovisionPlugin) Init(c client.Client, options cloudprovider.CloudProviderOptions, ctx context.Context) error {
+	return nil
+}
+
+func (f *fakeReprovisionPlugin) OnPodAdded(c client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, errors.PluginError) {
+	return pod, nil
+}
+
+func (f *fakeReprovisionPlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, errors.PluginError) {
+	return pod, nil
+}
+
+func (f *fakeReprovisionPlugin) OnPodDeleted(c client.Client, pod *corev1.Pod, ctx context.Context) errors.PluginError {
+	f.deleted++
+	return nil
+}
+
+func TestReprovisionNetwork(t *testing.T) {
+	now := metav1.Now()
+	ownerRefs := []metav1.OwnerReference{{Kind: "Pod", Name: "xxx-0", UID: "xxx-uid"}}
+	tests := []struct {
+		annotations    map[string]string
+		svc            *corev1.Service
+		waiting        bool
+		deleted        int
+		svcExist       bool
+		reprovisioned  string
+		statusReserved bool
+	}{
+		// case 0: not triggered
+		{
+			annotations: map[string]string{},
+			svc: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx-0", OwnerReferences: ownerRefs},
+			},
+			svcExist:       true,
+			statusReserved: true,
+		},
+		// case 1: already handled
+		{
+			annotations: map[string]string{
+				gameKruiseV1alpha1.GameServerNetworkReprovision:   "t1",
+				gameKruiseV1alpha1.GameServerNetworkReprovisioned: "t1",
+			},
+			svc: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx-0", OwnerReferences: ownerRefs},
+			},
+			svcExist:       true,
+			reprovisioned:  "t1",
+			statusReserved: true,
+		},
+		// case 2: triggered, svc deleted
+		{
+			annotations: map[string]string{
+				gameKruiseV1alpha1.GameServerNetworkReprovision:   "t2",
+				gameKruiseV1alpha1.GameServerNetworkReprovisioned: "t1",
+			},
+			svc: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx-0", OwnerReferences: ownerRefs},
+			},
+			waiting:       true,
+			deleted:       1,
+			reprovisioned: "t2",
+		},
+		// case 3: triggered without svc
+		{
+			annotations: map[string]string{
+				gameKruiseV1alpha1.GameServerNetworkReprovision: "t1",
+			},
+			deleted:       1,
+			reprovisioned: "t1",
+		},
+		// case 4: old svc is still being deleted
+		{
+			annotations: map[string]string{
+				gameKruiseV1alpha1.GameServerNetworkReprovision:   "t1",
+				gameKruiseV1alpha1.GameServerNetworkReprovisioned: "t1",
+			},
+			svc: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx-0", OwnerReferences: ownerRefs, DeletionTimestamp: &now, Finalizers: []string{"service.k8s.alibaba/resources"}},
+			},
+			waiting:        true,
+			svcExist:       true,
+			reprovisioned:  "t1",
+			statusReserved: true,
+		},
+	}
+
+	for i, test := range tests {
+		test.annotations[gameKruiseV1alpha1.GameServerNetworkStatus] = `{"currentNetworkState":"Ready"}`
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        "xxx-0",
+				UID:         "xxx-uid",
+				Annotations: test.annotations,
+			},
+		}
+		builder := fake.NewClientBuilder().WithScheme(scheme)
+		if test.svc != nil {
+			builder = builder.WithObjects(test.svc)
+		}
+		c := builder.Build()
+		plugin := &fakeReprovisionPlugin{}
+
+		waiting, pluginError := reprovisionNetwork(c, plugin, pod, context.Background())
+		if pluginError != nil {
+			t.Errorf("case %d: unexpected error %v", i, pluginError)
+		}
+		if waiting != test.waiting {
+			t.Errorf("case %d: expect waiting %v, but actually got %v", i, test.waiting, waiting)
+		}
+		if plugin.deleted != test.deleted {
+			t.Errorf("case %d: expect OnPodDeleted called %d times, but actually got %d", i, test.deleted, plugin.deleted)
+		}
+		svc := &corev1.Service{}
+		svcExist := c.Get(context.Background(), types.NamespacedName{Namespace: "xxx", Name: "xxx-0"}, svc) == nil
+		if svcExist != test.svcExist {
+			t.Errorf("case %d: expect svc exist %v, but actually got %v", i, test.svcExist, svcExist)
+		}
+		if pod.Annotations[gameKruiseV1alpha1.GameServerNetworkReprovisioned] != test.reprovisioned {
+			t.Errorf("case %d: expect reprovisioned %s, but actually got %s", i, test.reprovisioned, pod.Annotations[gameKruiseV1alpha1.GameServerNetworkReprovisioned])
+		}
+		if _, ok := pod.Annotations[gameKruiseV1alpha1.GameServerNetworkStatus]; ok != test.statusReserved {
+			t.Errorf("case %d: expect network status reserved %v, but actually got %v", i, test.statusReserved, ok)
+		}
+	}
+}
+
+func TestReportNetworkConfigError(t *testing.T) {
+	tests := []struct {
+		before string
+		conf   []gameKruiseV1alpha1.NetworkConfParams
+		isErr  bool
+	}{
+		// case 0: unknown param
+		{
+			before: "",
+			conf: []gameKruiseV1alpha1.NetworkConfParams{
+				{Name: alibabacloud.NlbIdsConfigName, Value: "nlb-xxx"},
+				{Name: alibabacloud.PortProtocolsConfigName, Value: "80/TCP"},
+				{Name: "Zonemaps", Value: "xxx"},
+			},
+			isErr: true,
+		},
+		// case 1: rejected by the plugin
+		{
+			before: "",
+			conf: []gameKruiseV1alpha1.NetworkConfParams{
+				{Name: alibabacloud.NlbIdsConfigName, Value: "nlb-xxx"},
+				{Name: alibabacloud.PortProtocolsConfigName, Value: "80/TCP"},
+				{Name: alibabacloud.LBHealthCheckUriConfigName, Value: "health"},
+			},
+			isErr: true,
+		},
+		// case 2: valid, error cleared
+		{
+			before: "invalid port",
+			conf: []gameKruiseV1alpha1.NetworkConfParams{
+				{Name: alibabacloud.NlbIdsConfigName, Value: "nlb-xxx"},
+				{Name: alibabacloud.PortProtocolsConfigName, Value: "80/TCP"},
+			},
+			isErr: false,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx",
+			},
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				Network: &gameKruiseV1alpha1.Network{
+					NetworkType: alibabacloud.NlbNetwork,
+					NetworkConf: test.conf,
+				},
+			},
+			Status: gameKruiseV1alpha1.GameServerSetStatus{
+				NetworkConfigError: test.before,
+			},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+				Labels: map[string]string{
+					gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx",
+				},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build()
+		reportNetworkConfigError(c, &alibabacloud.NlbPlugin{}, pod, context.Background())
+
+		actual := &gameKruiseV1alpha1.GameServerSet{}
+		if err := c.Get(context.Background(), types.NamespacedName{Namespace: "xxx", Name: "xxx"}, actual); err != nil {
+			t.Error(err)
+		}
+		if (actual.Status.NetworkConfigError != "") != test.isErr {
+			t.Errorf("case %d: expect network config error %v, but actually got %q", i, test.isErr, actual.Status.NetworkConfigError)
+		}
+	}
+}
+
+func TestIsGameServerSetPaused(t *testing.T) {
+	tests := []struct {
+		podLabels   map[string]string
+		annotations map[string]string
+		expect      bool
+	}{
+		// case 0: paused
+		{
+			podLabels:   map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"},
+			annotations: map[string]string{gameKruiseV1alpha1.GameServerSetPausedKey: "true"},
+			expect:      true,
+		},
+		// case 1: not paused
+		{
+			podLabels:   map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"},
+			annotations: map[string]string{gameKruiseV1alpha1.GameServerSetPausedKey: "false"},
+			expect:      false,
+		},
+		// case 2: pod not owned by GameServerSet
+		{
+			podLabels:   nil,
+			annotations: map[string]string{gameKruiseV1alpha1.GameServerSetPausedKey: "true"},
+			expect:      false,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx", Annotations: test.annotations},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx-0", Labels: test.podLabels},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build()
+		if actual := isGameServerSetPaused(c, pod, context.TODO()); actual != test.expect {
+			t.Errorf("case %d: expect paused %v but actually got %v", i, test.expect, actual)
+		}
+	}
+}
+
+func TestPatchOpsStateScheduling(t *testing.T) {
+	tests := []struct {
+		opsState     gameKruiseV1alpha1.OpsState
+		gsExist      bool
+		nodeSelector map[string]string
+		tolerations  []corev1.Toleration
+	}{
+		// case 0: GameServer in Maintaining
+		{
+			opsState:     gameKruiseV1alpha1.Maintaining,
+			gsExist:      true,
+			nodeSelector: map[string]string{"pool": "cheap"},
+			tolerations:  []corev1.Toleration{{Key: "cheap", Operator: corev1.TolerationOpExists}},
+		},
+		// case 1: no scheduling for opsState None
+		{
+			opsState:     gameKruiseV1alpha1.None,
+			gsExist:      true,
+			nodeSelector: nil,
+			tolerations:  nil,
+		},
+		// case 2: GameServer not exist when the pod is created first time
+		{
+			gsExist:      false,
+			nodeSelector: nil,
+			tolerations:  nil,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx"},
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				OpsStateScheduling: []gameKruiseV1alpha1.OpsStateScheduling{
+					{
+						OpsState:     gameKruiseV1alpha1.Maintaining,
+						NodeSelector: map[string]string{"pool": "cheap"},
+						Tolerations:  []corev1.Toleration{{Key: "cheap", Operator: corev1.TolerationOpExists}},
+					},
+				},
+			},
+		}
+		objs := []client.Object{gss}
+		if test.gsExist {
+			objs = append(objs, &gameKruiseV1alpha1.GameServer{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx-0"},
+				Spec:       gameKruiseV1alpha1.GameServerSpec{OpsState: test.opsState},
+			})
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-0",
+				Labels:    map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+		newPod, err := patchOpsStateScheduling(c, pod, context.TODO())
+		if err != nil {
+			t.Error(err)
+		}
+		if !reflect.DeepEqual(test.nodeSelector, newPod.Spec.NodeSelector) {
+			t.Errorf("case %d: expect nodeSelector %v but actually got %v", i, test.nodeSelector, newPod.Spec.NodeSelector)
+		}
+		if !reflect.DeepEqual(test.tolerations, newPod.Spec.Tolerations) {
+			t.Errorf("case %d: expect tolerations %v but actually got %v", i, test.tolerations, newPod.Spec.Tolerations)
+		}
+	}
+}
+
+type fakeCleanupPlugin struct {
+	fakeReprovisionPlugin
+	deadline time.Time
+	err      errors.PluginError
+}
+
+func (f *fakeCleanupPlugin) OnPodDeleted(c client.Client, pod *corev1.Pod, ctx context.Context) errors.PluginError {
+	f.deadline, _ = ctx.Deadline()
+	return f.err
+}
+
+func TestHandleExternalFinalizersRemoval(t *testing.T) {
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldPod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "xxx",
+			Name:              "xxx-0",
+			Labels:            map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"},
+			Annotations:       map[string]string{gameKruiseV1alpha1.GameServerNetworkType: "Fake-Reprovision"},
+			DeletionTimestamp: ptr.To(metav1.NewTime(time.Now().Add(20 * time.Second))),
+			Finalizers:        []string{gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer, gameKruiseV1alpha1.GameServerExternalFinalizersFinalizer},
+		},
+	}
+	newPod := oldPod.DeepCopy()
+	controllerutil.RemoveFinalizer(newPod, gameKruiseV1alpha1.GameServerExternalFinalizersFinalizer)
+	oldRaw, _ := json.Marshal(oldPod)
+	newRaw, _ := json.Marshal(newPod)
+
+	pmh := &PodMutatingHandler{
+		Client:  fake.NewClientBuilder().WithScheme(scheme).Build(),
+		decoder: decoder,
+		CloudProviderManager: &manager.ProviderManager{
+			CloudProviders: map[string]cloudprovider.CloudProvider{"Fake": &fakeCloudProvider{plugin: &fakeCleanupPlugin{err: errors.NewPluginError(errors.ApiCallError, "lb is deleting")}}},
+		},
+		eventRecorder:   record.NewFakeRecorder(10),
+		apiCallFailures: newApiCallFailureCounter(),
+	}
+	resp := pmh.Handle(context.TODO(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Update,
+		Object:    runtime.RawExtension{Raw: newRaw},
+		OldObject: runtime.RawExtension{Raw: oldRaw},
+	}})
+	if !resp.Allowed {
+		t.Errorf("expect the removal of %s admitted while the network cleanup fails, but actually denied: %v", gameKruiseV1alpha1.GameServerExternalFinalizersFinalizer, resp.Result)
+	}
+	for _, patch := range resp.Patches {
+		if patch.Path == "/metadata/finalizers" || strings.HasPrefix(patch.Path, "/metadata/finalizers/") {
+			t.Errorf("expect finalizers untouched by the webhook, but actually got patch %v", patch)
+		}
+	}
+}
+
+func TestCleanupNetwork(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		deletionTimestamp time.Time
+		err               errors.PluginError
+		deadline          time.Time
+		expectErr         bool
+		cleaned           bool
+	}{
+		// case 0: completed within the grace period
+		{
+			deletionTimestamp: now.Add(20 * time.Second),
+			deadline:          now.Add(20 * time.Second),
+			cleaned:           true,
+		},
+		// case 1: failed within the grace period
+		{
+			deletionTimestamp: now.Add(20 * time.Second),
+			err:               errors.NewPluginError(errors.ApiCallError, "lb is deleting"),
+			deadline:          now.Add(20 * time.Second),
+			expectErr:         true,
+		},
+		// case 2: failed beyond the grace period, extended by the finalizer
+		{
+			deletionTimestamp: now.Add(-time.Minute),
+			err:               errors.NewPluginError(errors.ApiCallError, "lb is deleting"),
+			deadline:          now.Add(-time.Minute).Add(util.NetworkCleanupMaxExtension),
+			expectErr:         true,
+		},
+		// case 3: completed beyond the grace period
+		{
+			deletionTimestamp: now.Add(-time.Minute),
+			deadline:          now.Add(-time.Minute).Add(util.NetworkCleanupMaxExtension),
+			cleaned:           true,
+		},
+	}
+
+	for i, test := range tests {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "xxx",
+				Name:              "xxx-0",
+				DeletionTimestamp: ptr.To(metav1.NewTime(test.deletionTimestamp)),
+				Finalizers:        []string{gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer},
+			},
+		}
+		plugin := &fakeCleanupPlugin{err: test.err}
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		pluginError := cleanupNetwork(c, plugin, pod, ctx, now)
+		cancel()
+
+		if (pluginError != nil) != test.expectErr {
+			t.Errorf("case %d: expect error %v but actually got %v", i, test.expectErr, pluginError)
+		}
+		if !plugin.deadline.Equal(test.deadline) {
+			t.Errorf("case %d: expect cleanup deadline %v but actually got %v", i, test.deadline, plugin.deadline)
+		}
+		if cleaned := pod.GetAnnotations()[gameKruiseV1alpha1.GameServerNetworkCleanedKey] == "true"; cleaned != test.cleaned {
+			t.Errorf("case %d: expect cleaned %v but actually got %v", i, test.cleaned, cleaned)
+		}
+		if !controllerutil.ContainsFinalizer(pod, gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer) {
+			t.Errorf("case %d: expect finalizer left to the controller but actually got %v", i, pod.GetFinalizers())
+		}
+	}
+}
+
+type fakeCloudProvider struct {
+	plugin cloudprovider.Plugin
+}
+
+func (f *fakeCloudProvider) Name() string {
+	return "Fake"
+}
+
+func (f *fakeCloudProvider) ListPlugins() (map[string]cloudprovider.Plugin, error) {
+	return map[string]cloudprovider.Plugin{f.plugin.Name(): f.plugin}, nil
+}
+
+func TestHandleNetworkCleanupFinalizer(t *testing.T) {
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPod := func(deleting bool) *corev1.Pod {
+		pod := &corev1.Pod{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        "xxx-0",
+				Labels:      map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"},
+				Annotations: map[string]string{gameKruiseV1alpha1.GameServerNetworkType: "Fake-Reprovision"},
+			},
+		}
+		if deleting {
+			pod.DeletionTimestamp = ptr.To(metav1.NewTime(time.Now().Add(20 * time.Second)))
+			pod.Finalizers = []string{gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer, "example.com/other"}
+		}
+		return pod
+	}
+	tests := []struct {
+		operation   admissionv1.Operation
+		pod         *corev1.Pod
+		annotations map[string]string
+		err         errors.PluginError
+		patchPath   string
+		patched     bool
+	}{
+		// case 0: the finalizer is not added unless GameServerSet opts in
+		{
+			operation: admissionv1.Create,
+			pod:       newPod(false),
+			patchPath: "/metadata/finalizers",
+			patched:   false,
+		},
+		// case 1: the finalizer is added if GameServerSet opts in
+		{
+			operation:   admissionv1.Create,
+			pod:         newPod(false),
+			annotations: map[string]string{gameKruiseV1alpha1.GameServerSetNetworkCleanupFinalizerKey: "true"},
+			patchPath:   "/metadata/finalizers",
+			patched:     true,
+		},
+		// case 2: the deleting pod is marked cleaned once the network is released
+		{
+			operation: admissionv1.Update,
+			pod:       newPod(true),
+			patchPath: "/metadata/annotations/game.kruise.io~1network-cleaned",
+			patched:   true,
+		},
+		// case 3: the update of the deleting pod is admitted even if the cleanup fails
+		{
+			operation: admissionv1.Update,
+			pod:       newPod(true),
+			err:       errors.NewPluginError(errors.ApiCallError, "lb is deleting"),
+			patchPath: "/metadata/annotations/game.kruise.io~1network-cleaned",
+			patched:   false,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx", Annotations: test.annotations},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build()
+		pmh := &PodMutatingHandler{
+			Client:  c,
+			decoder: decoder,
+			CloudProviderManager: &manager.ProviderManager{
+				CloudProviders: map[string]cloudprovider.CloudProvider{"Fake": &fakeCloudProvider{plugin: &fakeCleanupPlugin{err: test.err}}},
+			},
+			eventRecorder:   record.NewFakeRecorder(10),
+			apiCallFailures: newApiCallFailureCounter(),
+		}
+		raw, err := json.Marshal(test.pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: test.operation,
+			Object:    runtime.RawExtension{Raw: raw},
+			OldObject: runtime.RawExtension{Raw: raw},
+		}}
+
+		resp := pmh.Handle(context.TODO(), req)
+		if !resp.Allowed {
+			t.Errorf("case %d: expect admitted but actually denied: %v", i, resp.Result)
+			continue
+		}
+		patched := false
+		for _, patch := range resp.Patches {
+			if patch.Path == test.patchPath {
+				patched = true
+			}
+		}
+		if patched != test.patched {
+			t.Errorf("case %d: expect %s patched %v but actually got %v", i, test.patchPath, test.patched, resp.Patches)
+		}
+	}
+}
+
+func TestObservePluginError(t *testing.T) {
+	tests := []struct {
+		err             errors.PluginError
+		apiCallErrors   float64
+		parameterErrors float64
+	}{
+		// case 0: no error
+		{
+			err: nil,
+		},
+		// case 1: apiCallError
+		{
+			err:           errors.NewPluginError(errors.ApiCallError, "lb is deleting"),
+			apiCallErrors: 1,
+		},
+		// case 2: parameterError
+		{
+			err:             errors.NewPluginError(errors.ParameterError, "invalid lb id"),
+			parameterErrors: 1,
+		},
+	}
+
+	for i, test := range tests {
+		plugin := &fakeCleanupPlugin{err: test.err}
+		apiCallCounter := metrics.NetworkPluginErrorsTotal.WithLabelValues(plugin.Name(), onPodDeletedOperation, string(errors.ApiCallError))
+		parameterCounter := metrics.NetworkPluginErrorsTotal.WithLabelValues(plugin.Name(), onPodDeletedOperation, string(errors.ParameterError))
+		apiCallBefore := testutil.ToFloat64(apiCallCounter)
+		parameterBefore := testutil.ToFloat64(parameterCounter)
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "xxx",
+				Name:              "xxx-0",
+				DeletionTimestamp: ptr.To(metav1.NewTime(time.Now().Add(20 * time.Second))),
+				Finalizers:        []string{gameKruiseV1alpha1.GameServerNetworkCleanupFinalizer},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		cleanupNetwork(c, plugin, pod, context.TODO(), time.Now())
+
+		if apiCallErrors := testutil.ToFloat64(apiCallCounter) - apiCallBefore; apiCallErrors != test.apiCallErrors {
+			t.Errorf("case %d: expect %v apiCallErrors counted but actually got %v", i, test.apiCallErrors, apiCallErrors)
+		}
+		if parameterErrors := testutil.ToFloat64(parameterCounter) - parameterBefore; parameterErrors != test.parameterErrors {
+			t.Errorf("case %d: expect %v parameterErrors counted but actually got %v", i, test.parameterErrors, parameterErrors)
+		}
+	}
+}
+
+func TestPatchGameServerToken(t *testing.T) {
+	tokenEnv := corev1.EnvVar{
+		Name: gameKruiseV1alpha1.GameServerTokenEnvName,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "xxx-gameserver-tokens"},
+				Key:                  "3",
+			},
+		},
+	}
+	tests := []struct {
+		inject    bool
+		env       []corev1.EnvVar
+		expectEnv []corev1.EnvVar
+	}{
+		// case 0: token not injected
+		{
+			inject:    false,
+			expectEnv: nil,
+		},
+		// case 1: token of the ordinal injected
+		{
+			inject:    true,
+			env:       []corev1.EnvVar{{Name: "A", Value: "a"}},
+			expectEnv: []corev1.EnvVar{{Name: "A", Value: "a"}, tokenEnv},
+		},
+		// case 2: the env set by users is kept
+		{
+			inject:    true,
+			env:       []corev1.EnvVar{{Name: gameKruiseV1alpha1.GameServerTokenEnvName, Value: "a"}},
+			expectEnv: []corev1.EnvVar{{Name: gameKruiseV1alpha1.GameServerTokenEnvName, Value: "a"}},
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "xxx", Name: "xxx"},
+			Spec: gameKruiseV1alpha1.GameServerSetSpec{
+				InjectGameServerToken: test.inject,
+			},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "xxx",
+				Name:      "xxx-3",
+				Labels:    map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "game", Env: test.env}},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build()
+		newPod, err := patchGameServerToken(c, pod, context.TODO())
+		if err != nil {
+			t.Error(err)
+		}
+		if !reflect.DeepEqual(test.expectEnv, newPod.Spec.Containers[0].Env) {
+			t.Errorf("case %d: expect env %v but actually got %v", i, test.expectEnv, newPod.Spec.Containers[0].Env)
+		}
+	}
+}
+
+type fakePausePlugin struct {
+	fakeReprovisionPlugin
+	added   int
+	updated int
+}
+
+func (f *fakePausePlugin) OnPodAdded(c client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, errors.PluginError) {
+	f.added++
+	return pod, nil
+}
+
+func (f *fakePausePlugin) OnPodUpdated(c client.Client, pod *corev1.Pod, ctx context.Context) (*corev1.Pod, errors.PluginError) {
+	f.updated++
+	return pod, nil
+}
+
+func TestHandlePausedGameServerSet(t *testing.T) {
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		operation admissionv1.Operation
+		added     int
+		updated   int
+	}{
+		// case 0: network of new pods is allocated
+		{
+			operation: admissionv1.Create,
+			added:     1,
+		},
+		// case 1: network of existing pods is not updated
+		{
+			operation: admissionv1.Update,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gameKruiseV1alpha1.GameServerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        "xxx",
+				Annotations: map[string]string{gameKruiseV1alpha1.GameServerSetPausedKey: "true"},
+			},
+		}
+		pod := &corev1.Pod{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        "xxx-0",
+				Labels:      map[string]string{gameKruiseV1alpha1.GameServerOwnerGssKey: "xxx"},
+				Annotations: map[string]string{gameKruiseV1alpha1.GameServerNetworkType: "Fake-Reprovision"},
+			},
+		}
+		raw, _ := json.Marshal(pod)
+		plugin := &fakePausePlugin{}
+		pmh := &PodMutatingHandler{
+			Client:  fake.NewClientBuilder().WithScheme(scheme).WithObjects(gss).Build(),
+			decoder: decoder,
+			CloudProviderManager: &manager.ProviderManager{
+				CloudProviders: map[string]cloudprovider.CloudProvider{"Fake": &fakeCloudProvider{plugin: plugin}},
+			},
+			eventRecorder:   record.NewFakeRecorder(10),
+			apiCallFailures: newApiCallFailureCounter(),
+		}
+		req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: test.operation,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+		if test.operation == admissionv1.Update {
+			req.OldObject = runtime.RawExtension{Raw: raw}
+		}
+		resp := pmh.Handle(context.TODO(), req)
+		if !resp.Allowed {
+			t.Errorf("case %d: expect allowed but actually denied: %v", i, resp.Result)
+		}
+		if plugin.added != test.added || plugin.updated != test.updated {
+			t.Errorf("case %d: expect OnPodAdded %d and OnPodUpdated %d times but actually got %d and %d", i, test.added, test.updated, plugin.added, plugin.updated)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2024 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
+	"github.com/openkruise/kruise-game/cloudprovider/manager"
+	cputils "github.com/openkruise/kruise-game/cloudprovider/utils"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// garbageCollectorUsername is the user of the garbage collector, which deletes GameServers once their owners are deleted.
+	garbageCollectorUsername = "system:serviceaccount:kube-system:generic-garbage-collector"
+	// kubeControllerManagerUsername is the user of kube-controller-manager running without --use-service-account-credentials.
+	kubeControllerManagerUsername = "system:kube-controller-manager"
+	// kubeSystemServiceAccountsGroup is the group of the service accounts in kube-system, which the controllers of
+	// kube-controller-manager, such as the garbage collector and the namespace controller, run as.
+	kubeSystemServiceAccountsGroup = "system:serviceaccounts:kube-system"
+)
+
+// isKubeControllerManager returns whether the request is made by the controllers of kube-controller-manager,
+// which delete GameServers when their owners or namespaces are deleted.
+func isKubeControllerManager(userInfo authenticationv1.UserInfo) bool {
+	if userInfo.Username == garbageCollectorUsername || userInfo.Username == kubeControllerManagerUsername {
+		return true
+	}
+	for _, group := range userInfo.Groups {
+		if group == kubeSystemServiceAccountsGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// managerUsername returns the user of OKG manager, which turns opsState on its own, such as reverting expired PreAllocated GameServers.
+func managerUsername() string {
+	return fmt.Sprintf("system:serviceaccount:%s:%s", webhookServiceNamespace, managerServiceAccount)
+}
+
+type GsValidatingHandler struct {
+	Client               client.Client
+	decoder              *admission.Decoder
+	CloudProviderManager *manager.ProviderManager
+}
+
+func (gvh *GsValidatingHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation == admissionv1.Delete {
+		gs := &gamekruiseiov1alpha1.GameServer{}
+		if err := gvh.decoder.DecodeRaw(req.OldObject, gs); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		return validatingDelete(gs, req.UserInfo)
+	}
+	if req.Operation != admissionv1.Update {
+		return admission.ValidationResponse(true, "pass validating")
+	}
+
+	newGs := &gamekruiseiov1alpha1.GameServer{}
+	if err := gvh.decoder.Decode(req, newGs); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	oldGs := &gamekruiseiov1alpha1.GameServer{}
+	if err := gvh.decoder.DecodeRaw(req.OldObject, oldGs); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	overrideChanged := newGs.GetAnnotations()[gamekruiseiov1alpha1.GameServerNetworkConfOverride] != oldGs.GetAnnotations()[gamekruiseiov1alpha1.GameServerNetworkConfOverride]
+	if newGs.Spec.OpsState == oldGs.Spec.OpsState && !overrideChanged {
+		return admission.ValidationResponse(true, "opsState and network conf override are not changed")
+	}
+
+	gssName := newGs.GetLabels()[gamekruiseiov1alpha1.GameServerOwnerGssKey]
+	if gssName == "" {
+		return admission.ValidationResponse(true, "GameServer has no owner GameServerSet")
+	}
+	gss := &gamekruiseiov1alpha1.GameServerSet{}
+	err := gvh.Client.Get(ctx, types.NamespacedName{
+		Namespace: newGs.GetNamespace(),
+		Name:      gssName,
+	}, gss)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return admission.ValidationResponse(true, "owner GameServerSet is not found")
+		}
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if overrideChanged {
+		if resp := validatingNetworkConfOverride(newGs, gss, gvh.CloudProviderManager); !resp.Allowed {
+			return resp
+		}
+	}
+	return validatingOpsStateTransition(newGs, oldGs, gss, req.UserInfo)
+}
+
+// validatingDelete rejects deleting GameServers in opsState Allocated or Maintaining, unless annotation force-delete is set.
+// The deletions by OKG when scaling down, marked by label gs-deleting, and by kube-controller-manager, such as the garbage
+// collector and the namespace controller, are always allowed.
+func validatingDelete(gs *gamekruiseiov1alpha1.GameServer, userInfo authenticationv1.UserInfo) admission.Response {
+	if gs.Spec.OpsState != gamekruiseiov1alpha1.Allocated && gs.Spec.OpsState != gamekruiseiov1alpha1.Maintaining {
+		return admission.ValidationResponse(true, "validatingDelete success")
+	}
+	if gs.GetLabels()[gamekruiseiov1alpha1.GameServerDeletingKey] == "true" || isKubeControllerManager(userInfo) {
+		return admission.ValidationResponse(true, "GameServer is deleted by controllers")
+	}
+	if gs.GetAnnotations()[gamekruiseiov1alpha1.GameServerForceDeleteKey] == "true" {
+		return admission.ValidationResponse(true, "GameServer is force deleted")
+	}
+	return admission.ValidationResponse(false, fmt.Sprintf("GameServer %s is %s and can not be deleted, unless annotation %s=true is set",
+		gs.GetName(), gs.Spec.OpsState, gamekruiseiov1alpha1.GameServerForceDeleteKey))
+}
+
+// validatingNetworkConfOverride checks that the network conf override of GameServer is a valid list of params,
+// whose names are known for the network type of GameServerSet, and that the conf of GameServerSet merged with it
+// is accepted by the param schemas and the plugin, as the plugin will parse the merged conf for the pod.
+func validatingNetworkConfOverride(gs *gamekruiseiov1alpha1.GameServer, gss *gamekruiseiov1alpha1.GameServerSet, cpm *manager.ProviderManager) admission.Response {
+	overrideStr, ok := gs.GetAnnotations()[gamekruiseiov1alpha1.GameServerNetworkConfOverride]
+	if !ok {
+		return admission.ValidationResponse(true, "validatingNetworkConfOverride success")
+	}
+	if gss.Spec.Network == nil {
+		return admission.ValidationResponse(false, fmt.Sprintf("GameServerSet %s has no network to override", gss.GetName()))
+	}
+	var override []gamekruiseiov1alpha1.NetworkConfParams
+	if err := json.Unmarshal([]byte(overrideStr), &override); err != nil {
+		return admission.ValidationResponse(false, fmt.Sprintf("invalid %s: %s", gamekruiseiov1alpha1.GameServerNetworkConfOverride, err.Error()))
+	}
+	network := &gamekruiseiov1alpha1.Network{
+		NetworkType: gss.Spec.Network.NetworkType,
+		NetworkConf: override,
+	}
+	if err := canonicalizeNetworkConf(network); err != nil {
+		return admission.ValidationResponse(false, err.Error())
+	}
+	for _, conf := range override {
+		if conf.Name == "" {
+			return admission.ValidationResponse(false, fmt.Sprintf("invalid %s: name of network conf is required", gamekruiseiov1alpha1.GameServerNetworkConfOverride))
+		}
+	}
+
+	merged := &gamekruiseiov1alpha1.Network{
+		NetworkType: gss.Spec.Network.NetworkType,
+		NetworkConf: cputils.MergeNetworkConf(gss.Spec.Network.NetworkConf, network.NetworkConf),
+	}
+	var plugin cloudprovider.Plugin
+	if cpm != nil {
+		plugin, _ = cpm.FindPlugin(merged.NetworkType)
+	}
+	if err := validateNetworkConf(plugin, merged); err != nil {
+		return admission.ValidationResponse(false, fmt.Sprintf("invalid %s: network conf merged with GameServerSet %s is invalid: %s",
+			gamekruiseiov1alpha1.GameServerNetworkConfOverride, gss.GetName(), err.Error()))
+	}
+	return admission.ValidationResponse(true, "validatingNetworkConfOverride success")
+}
+
+// validatingOpsStateTransition rejects opsState transitions not allowed by opsStateTransitionPolicy of GameServerSet.
+// The transitions made by OKG manager itself are always allowed.
+func validatingOpsStateTransition(newGs, oldGs *gamekruiseiov1alpha1.GameServer, gss *gamekruiseiov1alpha1.GameServerSet, userInfo authenticationv1.UserInfo) admission.Response {
+	from := oldGs.Spec.OpsState
+	to := newGs.Spec.OpsState
+	if from == "" || from == to {
+		return admission.ValidationResponse(true, "validatingOpsStateTransition success")
+	}
+	if userInfo.Username == managerUsername() {
+		return admission.ValidationResponse(true, "opsState is turned by controllers")
+	}
+	for _, transition := range gss.Spec.OpsStateTransitionPolicy {
+		if transition.From != from {
+			continue
+		}
+		for _, allowed := range transition.To {
+			if allowed == to {
+				return admission.ValidationResponse(true, "validatingOpsStateTransition success")
+			}
+		}
+		return admission.ValidationResponse(false, fmt.Sprintf("opsState of GameServer %s is not allowed to turn from %s to %s, allowed opsStates are %v", newGs.GetName(), from, to, transition.To))
+	}
+	return admission.ValidationResponse(true, "validatingOpsStateTransition success")
+}
@@ -0,0 +1,291 @@
+package webhook
+
+import (
+	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
+	"github.com/openkruise/kruise-game/cloudprovider/alibabacloud"
+	"github.com/openkruise/kruise-game/cloudprovider/manager"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestValidatingOpsStateTransition(t *testing.T) {
+	policy := []gamekruiseiov1alpha1.OpsStateTransition{
+		{
+			From: gamekruiseiov1alpha1.None,
+			To:   []gamekruiseiov1alpha1.OpsState{gamekruiseiov1alpha1.WaitToDelete, gamekruiseiov1alpha1.Allocated, gamekruiseiov1alpha1.Maintaining},
+		},
+	}
+	tests := []struct {
+		policy   []gamekruiseiov1alpha1.OpsStateTransition
+		from     gamekruiseiov1alpha1.OpsState
+		to       gamekruiseiov1alpha1.OpsState
+		username string
+		allowed  bool
+	}{
+		// case 0
+		{
+			policy:  nil,
+			from:    gamekruiseiov1alpha1.None,
+			to:      gamekruiseiov1alpha1.Kill,
+			allowed: true,
+		},
+		// case 1
+		{
+			policy:  policy,
+			from:    gamekruiseiov1alpha1.None,
+			to:      gamekruiseiov1alpha1.Kill,
+			allowed: false,
+		},
+		// case 2
+		{
+			policy:  policy,
+			from:    gamekruiseiov1alpha1.None,
+			to:      gamekruiseiov1alpha1.WaitToDelete,
+			allowed: true,
+		},
+		// case 3
+		{
+			policy:  policy,
+			from:    gamekruiseiov1alpha1.WaitToDelete,
+			to:      gamekruiseiov1alpha1.Kill,
+			allowed: true,
+		},
+		// case 4
+		{
+			policy:  policy,
+			from:    gamekruiseiov1alpha1.None,
+			to:      gamekruiseiov1alpha1.None,
+			allowed: true,
+		},
+		// case 5: controller reverts an expired PreAllocated GameServer
+		{
+			policy: []gamekruiseiov1alpha1.OpsStateTransition{
+				{
+					From: gamekruiseiov1alpha1.PreAllocated,
+					To:   []gamekruiseiov1alpha1.OpsState{gamekruiseiov1alpha1.Allocated},
+				},
+			},
+			from:     gamekruiseiov1alpha1.PreAllocated,
+			to:       gamekruiseiov1alpha1.None,
+			username: "system:serviceaccount:kruise-game-system:kruise-game-controller-manager",
+			allowed:  true,
+		},
+		// case 6: user makes the same transition
+		{
+			policy: []gamekruiseiov1alpha1.OpsStateTransition{
+				{
+					From: gamekruiseiov1alpha1.PreAllocated,
+					To:   []gamekruiseiov1alpha1.OpsState{gamekruiseiov1alpha1.Allocated},
+				},
+			},
+			from:     gamekruiseiov1alpha1.PreAllocated,
+			to:       gamekruiseiov1alpha1.None,
+			username: "kubernetes-admin",
+			allowed:  false,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gamekruiseiov1alpha1.GameServerSet{
+			Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+				OpsStateTransitionPolicy: test.policy,
+			},
+		}
+		oldGs := &gamekruiseiov1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "xxx-0"},
+			Spec:       gamekruiseiov1alpha1.GameServerSpec{OpsState: test.from},
+		}
+		newGs := &gamekruiseiov1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "xxx-0"},
+			Spec:       gamekruiseiov1alpha1.GameServerSpec{OpsState: test.to},
+		}
+		actual := validatingOpsStateTransition(newGs, oldGs, gss, authenticationv1.UserInfo{Username: test.username})
+		if actual.Allowed != test.allowed {
+			t.Errorf("case %d: expect %v, got %v", i, test.allowed, actual.Allowed)
+		}
+	}
+}
+
+func TestValidatingNetworkConfOverride(t *testing.T) {
+	gss := &gamekruiseiov1alpha1.GameServerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "xxx",
+		},
+		Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+			Network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.NlbNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{Name: alibabacloud.NlbIdsConfigName, Value: "nlb-xxx"},
+					{Name: alibabacloud.PortProtocolsConfigName, Value: "80/TCP"},
+				},
+			},
+		},
+	}
+	cpm := &manager.ProviderManager{
+		CloudProviders: map[string]cloudprovider.CloudProvider{
+			"AlibabaCloud": func() cloudprovider.CloudProvider {
+				acp, _ := alibabacloud.NewAlibabaCloudProvider()
+				return acp
+			}(),
+		},
+	}
+	tests := []struct {
+		override string
+		gss      *gamekruiseiov1alpha1.GameServerSet
+		allowed  bool
+	}{
+		// case 0
+		{
+			override: `[{"name":"PortProtocols","value":"8080/UDP"}]`,
+			gss:      gss,
+			allowed:  true,
+		},
+		// case 1: name in different case
+		{
+			override: `[{"name":"portprotocols","value":"8080/UDP"}]`,
+			gss:      gss,
+			allowed:  true,
+		},
+		// case 2: unknown name
+		{
+			override: `[{"name":"Unknown","value":"xxx"}]`,
+			gss:      gss,
+			allowed:  false,
+		},
+		// case 3: invalid json
+		{
+			override: `PortProtocols=8080`,
+			gss:      gss,
+			allowed:  false,
+		},
+		// case 4: no network
+		{
+			override: `[{"name":"PortProtocols","value":"8080/UDP"}]`,
+			gss:      &gamekruiseiov1alpha1.GameServerSet{},
+			allowed:  false,
+		},
+		// case 5: merged conf out of the range of the schema
+		{
+			override: `[{"name":"LBHealthCheckInterval","value":"60"}]`,
+			gss:      gss,
+			allowed:  false,
+		},
+		// case 6: merged conf rejected by the plugin
+		{
+			override: `[{"name":"PortProtocols","value":"80/TCPSSL"}]`,
+			gss:      gss,
+			allowed:  false,
+		},
+		// case 7: merged conf accepted by the plugin
+		{
+			override: `[{"name":"PortProtocols","value":"80/TCPSSL"},{"name":"CertId","value":"cert-xxx"}]`,
+			gss:      gss,
+			allowed:  true,
+		},
+	}
+
+	for i, test := range tests {
+		gs := &gamekruiseiov1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					gamekruiseiov1alpha1.GameServerNetworkConfOverride: test.override,
+				},
+			},
+		}
+		actual := validatingNetworkConfOverride(gs, test.gss, cpm)
+		if actual.Allowed != test.allowed {
+			t.Errorf("case %d: expect allowed %v, but actually got %v", i, test.allowed, actual.Allowed)
+		}
+	}
+}
+
+func TestValidatingDelete(t *testing.T) {
+	tests := []struct {
+		opsState    gamekruiseiov1alpha1.OpsState
+		labels      map[string]string
+		annotations map[string]string
+		username    string
+		groups      []string
+		allowed     bool
+	}{
+		// case 0: user deletes a GameServer in None
+		{
+			opsState: gamekruiseiov1alpha1.None,
+			username: "kubernetes-admin",
+			allowed:  true,
+		},
+		// case 1: user deletes an Allocated GameServer
+		{
+			opsState: gamekruiseiov1alpha1.Allocated,
+			username: "kubernetes-admin",
+			allowed:  false,
+		},
+		// case 2: user deletes a Maintaining GameServer
+		{
+			opsState: gamekruiseiov1alpha1.Maintaining,
+			username: "kubernetes-admin",
+			allowed:  false,
+		},
+		// case 3: user force deletes an Allocated GameServer
+		{
+			opsState:    gamekruiseiov1alpha1.Allocated,
+			annotations: map[string]string{gamekruiseiov1alpha1.GameServerForceDeleteKey: "true"},
+			username:    "kubernetes-admin",
+			allowed:     true,
+		},
+		// case 4: controller deletes an Allocated GameServer when scaling down
+		{
+			opsState: gamekruiseiov1alpha1.Allocated,
+			labels:   map[string]string{gamekruiseiov1alpha1.GameServerDeletingKey: "true"},
+			username: "system:serviceaccount:kruise-game-system:kruise-game-controller-manager",
+			allowed:  true,
+		},
+		// case 5: garbage collector deletes an Allocated GameServer
+		{
+			opsState: gamekruiseiov1alpha1.Allocated,
+			username: garbageCollectorUsername,
+			allowed:  true,
+		},
+		// case 6: namespace controller deletes an Allocated GameServer when the namespace is deleted
+		{
+			opsState: gamekruiseiov1alpha1.Allocated,
+			username: "system:serviceaccount:kube-system:namespace-controller",
+			groups:   []string{"system:serviceaccounts", "system:serviceaccounts:kube-system", "system:authenticated"},
+			allowed:  true,
+		},
+		// case 7: kube-controller-manager not using service account credentials deletes an Allocated GameServer
+		{
+			opsState: gamekruiseiov1alpha1.Allocated,
+			username: "system:kube-controller-manager",
+			groups:   []string{"system:authenticated"},
+			allowed:  true,
+		},
+		// case 8: service account of another namespace deletes an Allocated GameServer
+		{
+			opsState: gamekruiseiov1alpha1.Allocated,
+			username: "system:serviceaccount:default:deployer",
+			groups:   []string{"system:serviceaccounts", "system:serviceaccounts:default", "system:authenticated"},
+			allowed:  false,
+		},
+	}
+
+	for i, test := range tests {
+		gs := &gamekruiseiov1alpha1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "xxx",
+				Name:        "xxx-0",
+				Labels:      test.labels,
+				Annotations: test.annotations,
+			},
+			Spec: gamekruiseiov1alpha1.GameServerSpec{
+				OpsState: test.opsState,
+			},
+		}
+		actual := validatingDelete(gs, authenticationv1.UserInfo{Username: test.username, Groups: test.groups})
+		if actual.Allowed != test.allowed {
+			t.Errorf("case %d: expect allowed %v but actually got %v", i, test.allowed, actual.Allowed)
+		}
+	}
+}
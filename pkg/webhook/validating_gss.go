@@ -20,10 +20,14 @@ import (
 	"context"
 	"fmt"
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
+	"github.com/openkruise/kruise-game/cloudprovider"
 	"github.com/openkruise/kruise-game/cloudprovider/manager"
 	"github.com/openkruise/kruise-game/pkg/util"
 	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"net/http"
+	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -41,18 +45,25 @@ func (gvh *GssValidaatingHandler) Handle(ctx context.Context, req admission.Requ
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 
-	if allowed, reason := validatingGss(gss, gvh.Client); !allowed {
-		admission.ValidationResponse(allowed, reason)
+	var oldGss *gamekruiseiov1alpha1.GameServerSet
+	if req.Operation == admissionv1.Update {
+		oldGss = &gamekruiseiov1alpha1.GameServerSet{}
+		if err := gvh.decoder.DecodeRaw(req.OldObject, oldGss); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+
+	if allowed, reason := validatingGss(gss, oldGss, gvh.Client); !allowed {
+		return admission.ValidationResponse(allowed, reason)
+	}
+
+	if allowed, reason := validatingNetworkConfig(gss, gvh.CloudProviderManager); !allowed {
+		return admission.ValidationResponse(allowed, reason)
 	}
 
 	switch req.Operation {
 	case admissionv1.Update:
 		newGss := gss.DeepCopy()
-		oldGss := &gamekruiseiov1alpha1.GameServerSet{}
-		err := gvh.decoder.DecodeRaw(req.OldObject, oldGss)
-		if err != nil {
-			return admission.Errored(http.StatusBadRequest, err)
-		}
 		return validatingUpdate(newGss, oldGss)
 	case admissionv1.Create:
 		newGss := gss.DeepCopy()
@@ -62,23 +73,177 @@ func (gvh *GssValidaatingHandler) Handle(ctx context.Context, req admission.Requ
 	return admission.ValidationResponse(true, "pass validating")
 }
 
-func validatingGss(gss *gamekruiseiov1alpha1.GameServerSet, client client.Client) (bool, string) {
+// validatingGss validates the fields of gss. On update oldGss is not nil, and only the fields changed
+// from oldGss are validated, so that GameServerSets admitted before a check was added can still be updated.
+func validatingGss(gss, oldGss *gamekruiseiov1alpha1.GameServerSet, client client.Client) (bool, string) {
+	changed := func(field func(*gamekruiseiov1alpha1.GameServerSet) interface{}) bool {
+		return oldGss == nil || !reflect.DeepEqual(field(gss), field(oldGss))
+	}
+
 	// validate reserveGameServerIds
 	rgsIds := gss.Spec.ReserveGameServerIds
-	if util.IsRepeat(rgsIds) {
-		return false, fmt.Sprintf("reserveGameServerIds should not be repeat. Now it is %v", rgsIds)
+	if changed(func(g *gamekruiseiov1alpha1.GameServerSet) interface{} { return g.Spec.ReserveGameServerIds }) {
+		if util.IsRepeat(rgsIds) {
+			return false, fmt.Sprintf("reserveGameServerIds should not be repeat. Now it is %v", rgsIds)
+		}
+		if util.IsHasNegativeNum(rgsIds) {
+			return false, fmt.Sprintf("reserveGameServerIds should be greater or equal to 0. Now it is %v", rgsIds)
+		}
+	}
+
+	// validate excludedGameServerIds
+	egsIds := gss.Spec.ExcludedGameServerIds
+	if changed(func(g *gamekruiseiov1alpha1.GameServerSet) interface{} { return g.Spec.ExcludedGameServerIds }) {
+		if util.IsRepeat(egsIds) {
+			return false, fmt.Sprintf("excludedGameServerIds should not be repeat. Now it is %v", egsIds)
+		}
+		if util.IsHasNegativeNum(egsIds) {
+			return false, fmt.Sprintf("excludedGameServerIds should be greater or equal to 0. Now it is %v", egsIds)
+		}
+	}
+
+	// validate topologySpread
+	if ts := gss.Spec.TopologySpread; ts != nil && changed(func(g *gamekruiseiov1alpha1.GameServerSet) interface{} { return g.Spec.TopologySpread }) {
+		if ts.TopologyKey == "" {
+			return false, "topologySpread.topologyKey is required"
+		}
+		if ts.MaxSkew != nil && *ts.MaxSkew < 1 {
+			return false, fmt.Sprintf("topologySpread.maxSkew should be greater than 0. Now it is %d", *ts.MaxSkew)
+		}
+		if ts.WhenUnsatisfiable != "" && ts.WhenUnsatisfiable != corev1.DoNotSchedule && ts.WhenUnsatisfiable != corev1.ScheduleAnyway {
+			return false, fmt.Sprintf("topologySpread.whenUnsatisfiable should be %s or %s. Now it is %s", corev1.DoNotSchedule, corev1.ScheduleAnyway, ts.WhenUnsatisfiable)
+		}
+	}
+
+	// validate podDisruptionBudget
+	if pdb := gss.Spec.PodDisruptionBudget; pdb != nil && (pdb.MinAvailable == nil) == (pdb.MaxUnavailable == nil) &&
+		changed(func(g *gamekruiseiov1alpha1.GameServerSet) interface{} { return g.Spec.PodDisruptionBudget }) {
+		return false, "exactly one of podDisruptionBudget.minAvailable and podDisruptionBudget.maxUnavailable should be set"
+	}
+
+	// validate killMaxUnavailable, which is scaled against 100 replicas here, as it is rounded up when applied
+	if kmu := gss.Spec.KillMaxUnavailable; kmu != nil && changed(func(g *gamekruiseiov1alpha1.GameServerSet) interface{} { return g.Spec.KillMaxUnavailable }) {
+		maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(kmu, 100, true)
+		if err != nil {
+			return false, fmt.Sprintf("killMaxUnavailable is invalid: %s", err.Error())
+		}
+		if maxUnavailable < 1 {
+			return false, fmt.Sprintf("killMaxUnavailable should be greater than 0. Now it is %s", kmu.String())
+		}
 	}
-	if util.IsHasNegativeNum(rgsIds) {
-		return false, fmt.Sprintf("reserveGameServerIds should be greater or equal to 0. Now it is %v", rgsIds)
+
+	// validate scalingSchedule
+	if changed(func(g *gamekruiseiov1alpha1.GameServerSet) interface{} { return g.Spec.ScalingSchedule }) {
+		for i, window := range gss.Spec.ScalingSchedule {
+			if _, err := util.ParseCronSchedule(window.Schedule); err != nil {
+				return false, fmt.Sprintf("scalingSchedule[%d].schedule is invalid: %s", i, err.Error())
+			}
+			if window.DurationSeconds < 60 {
+				return false, fmt.Sprintf("scalingSchedule[%d].durationSeconds should be at least 60. Now it is %d", i, window.DurationSeconds)
+			}
+			if window.MinReplicas < 0 {
+				return false, fmt.Sprintf("scalingSchedule[%d].minReplicas should not be negative. Now it is %d", i, window.MinReplicas)
+			}
+		}
+	}
+
+	// validate opsStateScheduling
+	if changed(func(g *gamekruiseiov1alpha1.GameServerSet) interface{} { return g.Spec.OpsStateScheduling }) ||
+		changed(func(g *gamekruiseiov1alpha1.GameServerSet) interface{} {
+			return g.Spec.GameServerTemplate.ReclaimPolicy
+		}) {
+		if len(gss.Spec.OpsStateScheduling) != 0 && gss.Spec.GameServerTemplate.ReclaimPolicy != gamekruiseiov1alpha1.DeleteGameServerReclaimPolicy {
+			return false, fmt.Sprintf("opsStateScheduling requires gameServerTemplate.reclaimPolicy to be %s, so that GameServers keep their opsState when pods are rescheduled", gamekruiseiov1alpha1.DeleteGameServerReclaimPolicy)
+		}
+		opsStates := make(map[gamekruiseiov1alpha1.OpsState]bool)
+		for i, scheduling := range gss.Spec.OpsStateScheduling {
+			if opsStates[scheduling.OpsState] {
+				return false, fmt.Sprintf("opsStateScheduling[%d].opsState %s is repeated", i, scheduling.OpsState)
+			}
+			opsStates[scheduling.OpsState] = true
+		}
+	}
+
+	// validate maintenanceWindows
+	if changed(func(g *gamekruiseiov1alpha1.GameServerSet) interface{} {
+		return g.Spec.UpdateStrategy.MaintenanceWindows
+	}) {
+		for i, window := range gss.Spec.UpdateStrategy.MaintenanceWindows {
+			if _, err := util.ParseCronSchedule(window.Schedule); err != nil {
+				return false, fmt.Sprintf("updateStrategy.maintenanceWindows[%d].schedule is invalid: %s", i, err.Error())
+			}
+			if window.DurationSeconds < 60 {
+				return false, fmt.Sprintf("updateStrategy.maintenanceWindows[%d].durationSeconds should be at least 60. Now it is %d", i, window.DurationSeconds)
+			}
+		}
+	}
+
+	// validate network conf against the params registered by the plugin
+	if network := gss.Spec.Network; network != nil && changed(func(g *gamekruiseiov1alpha1.GameServerSet) interface{} { return g.Spec.Network }) {
+		if schemas, ok := cloudprovider.GetParamSchemas(network.NetworkType); ok {
+			if err := cloudprovider.ValidateNetworkConf(schemas, network.NetworkConf); err != nil {
+				return false, fmt.Sprintf("invalid network conf of network type %s: %s", network.NetworkType, err.Error())
+			}
+		}
+	}
+
+	// validate network poll interval
+	if changed(func(g *gamekruiseiov1alpha1.GameServerSet) interface{} {
+		return g.GetAnnotations()[gamekruiseiov1alpha1.GameServerSetNetworkPollIntervalKey]
+	}) {
+		if _, err := util.GetNetworkPollInterval(gss); err != nil {
+			return false, fmt.Sprintf("annotation %s is invalid: %s", gamekruiseiov1alpha1.GameServerSetNetworkPollIntervalKey, err.Error())
+		}
+	}
+
+	// validate maintain ids
+	if changed(func(g *gamekruiseiov1alpha1.GameServerSet) interface{} {
+		return g.GetAnnotations()[gamekruiseiov1alpha1.GameServerSetMaintainIdsKey]
+	}) {
+		if _, err := util.ParseIdRanges(gss.GetAnnotations()[gamekruiseiov1alpha1.GameServerSetMaintainIdsKey]); err != nil {
+			return false, fmt.Sprintf("annotation %s is invalid: %s", gamekruiseiov1alpha1.GameServerSetMaintainIdsKey, err.Error())
+		}
+	}
+
+	// validate serverNameFormat
+	if gss.Spec.ServerNameFormat != "" && changed(func(g *gamekruiseiov1alpha1.GameServerSet) interface{} { return g.Spec.ServerNameFormat }) {
+		if err := util.ValidateServerNameFormat(gss.Spec.ServerNameFormat); err != nil {
+			return false, err.Error()
+		}
 	}
 
 	return true, "general validating success"
 }
 
+// validatingNetworkConfig validates the network conf by the plugin of the network type, if it implements
+// cloudprovider.ConfigValidator or cloudprovider.PodTemplateValidator.
+func validatingNetworkConfig(gss *gamekruiseiov1alpha1.GameServerSet, cpm *manager.ProviderManager) (bool, string) {
+	if gss.Spec.Network == nil || cpm == nil {
+		return true, "no network config to validate"
+	}
+	plugin, ok := cpm.FindPlugin(gss.Spec.Network.NetworkType)
+	if !ok {
+		return true, "no plugin to validate network config"
+	}
+	if validator, ok := plugin.(cloudprovider.ConfigValidator); ok {
+		if err := validator.ValidateConfig(gss.Spec.Network.NetworkConf); err != nil {
+			return false, fmt.Sprintf("invalid network conf of network type %s: %s", gss.Spec.Network.NetworkType, err.Error())
+		}
+	}
+	if validator, ok := plugin.(cloudprovider.PodTemplateValidator); ok {
+		if err := validator.ValidatePodTemplate(gss.Spec.Network.NetworkConf, &gss.Spec.GameServerTemplate.PodTemplateSpec); err != nil {
+			return false, fmt.Sprintf("network conf of network type %s mismatches the gameServerTemplate: %s", gss.Spec.Network.NetworkType, err.Error())
+		}
+	}
+	return true, "network config validating success"
+}
+
 func validatingUpdate(newGss, oldGss *gamekruiseiov1alpha1.GameServerSet) admission.Response {
 	if oldGss.Spec.Network != nil && newGss.Spec.Network != nil {
-		if oldGss.Spec.Network.NetworkType != "" && newGss.Spec.Network.NetworkType != oldGss.Spec.Network.NetworkType {
-			return admission.ValidationResponse(false, "change network type is not allowed")
+		if oldGss.Spec.Network.NetworkType != "" && newGss.Spec.Network.NetworkType != oldGss.Spec.Network.NetworkType &&
+			newGss.GetAnnotations()[gamekruiseiov1alpha1.GameServerSetAllowNetworkMigrationKey] != "true" {
+			return admission.ValidationResponse(false, fmt.Sprintf("change network type is not allowed, unless annotation %s=true is set. Network resources of existing pods are released by %s when migrating",
+				gamekruiseiov1alpha1.GameServerSetAllowNetworkMigrationKey, oldGss.Spec.Network.NetworkType))
 		}
 	}
 	return admission.ValidationResponse(true, "validatingUpdate success")
@@ -4,7 +4,12 @@ import (
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	"github.com/openkruise/kruise-game/cloudprovider"
 	"github.com/openkruise/kruise-game/cloudprovider/alibabacloud"
+	"github.com/openkruise/kruise-game/cloudprovider/kubernetes"
 	"github.com/openkruise/kruise-game/cloudprovider/manager"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
 	"testing"
 )
 
@@ -59,3 +64,522 @@ func TestValidatingCreate(t *testing.T) {
 		}
 	}
 }
+
+func TestValidatingUpdate(t *testing.T) {
+	tests := []struct {
+		newGss  *gamekruiseiov1alpha1.GameServerSet
+		oldGss  *gamekruiseiov1alpha1.GameServerSet
+		allowed bool
+	}{
+		// case 0: network type changed without annotation
+		{
+			newGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					Network: &gamekruiseiov1alpha1.Network{
+						NetworkType: alibabacloud.NlbNetwork,
+					},
+				},
+			},
+			oldGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					Network: &gamekruiseiov1alpha1.Network{
+						NetworkType: alibabacloud.SlbNetwork,
+					},
+				},
+			},
+			allowed: false,
+		},
+		// case 1: network type changed with annotation
+		{
+			newGss: &gamekruiseiov1alpha1.GameServerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						gamekruiseiov1alpha1.GameServerSetAllowNetworkMigrationKey: "true",
+					},
+				},
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					Network: &gamekruiseiov1alpha1.Network{
+						NetworkType: alibabacloud.NlbNetwork,
+					},
+				},
+			},
+			oldGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					Network: &gamekruiseiov1alpha1.Network{
+						NetworkType: alibabacloud.SlbNetwork,
+					},
+				},
+			},
+			allowed: true,
+		},
+		// case 2: network type unchanged
+		{
+			newGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					Network: &gamekruiseiov1alpha1.Network{
+						NetworkType: alibabacloud.SlbNetwork,
+					},
+				},
+			},
+			oldGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					Network: &gamekruiseiov1alpha1.Network{
+						NetworkType: alibabacloud.SlbNetwork,
+					},
+				},
+			},
+			allowed: true,
+		},
+	}
+
+	for i, test := range tests {
+		actual := validatingUpdate(test.newGss, test.oldGss)
+		if actual.Allowed != test.allowed {
+			t.Errorf("case %d: expect %v, got %v", i, test.allowed, actual.Allowed)
+		}
+	}
+}
+
+func TestValidatingGssOnUpdate(t *testing.T) {
+	tests := []struct {
+		newGss  *gamekruiseiov1alpha1.GameServerSet
+		oldGss  *gamekruiseiov1alpha1.GameServerSet
+		allowed bool
+	}{
+		// case 0: create with repeated reserveGameServerIds
+		{
+			newGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					ReserveGameServerIds: []int{1, 1},
+				},
+			},
+			allowed: false,
+		},
+		// case 1: repeated reserveGameServerIds unchanged, replicas changed
+		{
+			newGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					Replicas:             ptr.To[int32](3),
+					ReserveGameServerIds: []int{1, 1},
+				},
+			},
+			oldGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					Replicas:             ptr.To[int32](2),
+					ReserveGameServerIds: []int{1, 1},
+				},
+			},
+			allowed: true,
+		},
+		// case 2: reserveGameServerIds changed to repeated
+		{
+			newGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					ReserveGameServerIds: []int{1, 1},
+				},
+			},
+			oldGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					ReserveGameServerIds: []int{1},
+				},
+			},
+			allowed: false,
+		},
+		// case 3: invalid scalingSchedule unchanged
+		{
+			newGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					ScalingSchedule:  []gamekruiseiov1alpha1.ScalingScheduleWindow{{Schedule: "0 20 * * *", DurationSeconds: 10}},
+					ServerNameFormat: "game-{ordinal}",
+				},
+			},
+			oldGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					ScalingSchedule: []gamekruiseiov1alpha1.ScalingScheduleWindow{{Schedule: "0 20 * * *", DurationSeconds: 10}},
+				},
+			},
+			allowed: true,
+		},
+		// case 4: unknown network conf unchanged
+		{
+			newGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					Replicas: ptr.To[int32](3),
+					Network: &gamekruiseiov1alpha1.Network{
+						NetworkType: alibabacloud.NlbNetwork,
+						NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{{Name: "Zonemaps", Value: "xxx"}},
+					},
+				},
+			},
+			oldGss: &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					Replicas: ptr.To[int32](2),
+					Network: &gamekruiseiov1alpha1.Network{
+						NetworkType: alibabacloud.NlbNetwork,
+						NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{{Name: "Zonemaps", Value: "xxx"}},
+					},
+				},
+			},
+			allowed: true,
+		},
+	}
+
+	for i, test := range tests {
+		allowed, reason := validatingGss(test.newGss, test.oldGss, nil)
+		if allowed != test.allowed {
+			t.Errorf("case %d: expect allowed %v but actually got %v, because of %s", i, test.allowed, allowed, reason)
+		}
+	}
+}
+
+func TestValidatingGssKillMaxUnavailable(t *testing.T) {
+	tests := []struct {
+		newKmu  *intstr.IntOrString
+		oldKmu  *intstr.IntOrString
+		update  bool
+		allowed bool
+	}{
+		// case 0: absolute number
+		{
+			newKmu:  ptr.To(intstr.FromInt(5)),
+			allowed: true,
+		},
+		// case 1: percentage
+		{
+			newKmu:  ptr.To(intstr.FromString("10%")),
+			allowed: true,
+		},
+		// case 2: zero
+		{
+			newKmu:  ptr.To(intstr.FromInt(0)),
+			allowed: false,
+		},
+		// case 3: zero percentage
+		{
+			newKmu:  ptr.To(intstr.FromString("0%")),
+			allowed: false,
+		},
+		// case 4: negative
+		{
+			newKmu:  ptr.To(intstr.FromInt(-1)),
+			allowed: false,
+		},
+		// case 5: string not a percentage
+		{
+			newKmu:  ptr.To(intstr.FromString("10")),
+			allowed: false,
+		},
+		// case 6: malformed percentage
+		{
+			newKmu:  ptr.To(intstr.FromString("abc%")),
+			allowed: false,
+		},
+		// case 7: invalid value unchanged
+		{
+			newKmu:  ptr.To(intstr.FromInt(0)),
+			oldKmu:  ptr.To(intstr.FromInt(0)),
+			update:  true,
+			allowed: true,
+		},
+		// case 8: changed to invalid value
+		{
+			newKmu:  ptr.To(intstr.FromString("abc%")),
+			oldKmu:  ptr.To(intstr.FromInt(1)),
+			update:  true,
+			allowed: false,
+		},
+	}
+
+	for i, test := range tests {
+		newGss := &gamekruiseiov1alpha1.GameServerSet{
+			Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+				Replicas:           ptr.To[int32](3),
+				KillMaxUnavailable: test.newKmu,
+			},
+		}
+		var oldGss *gamekruiseiov1alpha1.GameServerSet
+		if test.update {
+			oldGss = &gamekruiseiov1alpha1.GameServerSet{
+				Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+					Replicas:           ptr.To[int32](2),
+					KillMaxUnavailable: test.oldKmu,
+				},
+			}
+		}
+		allowed, reason := validatingGss(newGss, oldGss, nil)
+		if allowed != test.allowed {
+			t.Errorf("case %d: expect allowed %v but actually got %v, because of %s", i, test.allowed, allowed, reason)
+		}
+	}
+}
+
+func TestValidatingGssNetworkConf(t *testing.T) {
+	tests := []struct {
+		network *gamekruiseiov1alpha1.Network
+		allowed bool
+	}{
+		// case 0: valid
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.NlbNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{Name: alibabacloud.NlbIdsConfigName, Value: "nlb-xxx"},
+					{Name: alibabacloud.PortProtocolsConfigName, Value: "80/TCP"},
+					{Name: alibabacloud.FixedConfigName, Value: "true"},
+					{Name: alibabacloud.LBHealthCheckFlagConfigName, Value: "On"},
+					{Name: alibabacloud.LBHealthCheckConnectPortConfigName, Value: "6000"},
+				},
+			},
+			allowed: true,
+		},
+		// case 1: unknown param
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.NlbNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{Name: alibabacloud.NlbIdsConfigName, Value: "nlb-xxx"},
+					{Name: alibabacloud.PortProtocolsConfigName, Value: "80/TCP"},
+					{Name: "Zonemaps", Value: "xxx"},
+				},
+			},
+			allowed: false,
+		},
+		// case 2: required param missing
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.NlbNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{Name: alibabacloud.PortProtocolsConfigName, Value: "80/TCP"},
+				},
+			},
+			allowed: false,
+		},
+		// case 3: invalid bool
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.NlbNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{Name: alibabacloud.NlbIdsConfigName, Value: "nlb-xxx"},
+					{Name: alibabacloud.PortProtocolsConfigName, Value: "80/TCP"},
+					{Name: alibabacloud.FixedConfigName, Value: "yes"},
+				},
+			},
+			allowed: false,
+		},
+		// case 4: int out of range
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.NlbNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{Name: alibabacloud.NlbIdsConfigName, Value: "nlb-xxx"},
+					{Name: alibabacloud.PortProtocolsConfigName, Value: "80/TCP"},
+					{Name: alibabacloud.LBHealthCheckIntervalConfigName, Value: "60"},
+				},
+			},
+			allowed: false,
+		},
+		// case 5: invalid enum
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.NlbNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{Name: alibabacloud.NlbIdsConfigName, Value: "nlb-xxx"},
+					{Name: alibabacloud.PortProtocolsConfigName, Value: "80/TCP"},
+					{Name: alibabacloud.LBHealthCheckTypeConfigName, Value: "udp"},
+				},
+			},
+			allowed: false,
+		},
+		// case 6: no schema registered
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: "Kubernetes-HostPort",
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{Name: "whatever", Value: "xxx"},
+				},
+			},
+			allowed: true,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gamekruiseiov1alpha1.GameServerSet{
+			Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+				Network: test.network,
+			},
+		}
+		allowed, reason := validatingGss(gss, nil, nil)
+		if allowed != test.allowed {
+			t.Errorf("case %d: expect allowed %v but actually got %v, because of %s", i, test.allowed, allowed, reason)
+		}
+	}
+}
+
+func TestValidatingNetworkConfig(t *testing.T) {
+	cpm := &manager.ProviderManager{
+		CloudProviders: map[string]cloudprovider.CloudProvider{
+			"AlibabaCloud": func() cloudprovider.CloudProvider {
+				acp, _ := alibabacloud.NewAlibabaCloudProvider()
+				return acp
+			}(),
+		},
+	}
+	tests := []struct {
+		network *gamekruiseiov1alpha1.Network
+		allowed bool
+	}{
+		// case 0: valid
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.NlbNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{Name: alibabacloud.NlbIdsConfigName, Value: "nlb-xxx"},
+					{Name: alibabacloud.PortProtocolsConfigName, Value: "80/TCPSSL"},
+					{Name: alibabacloud.CertIdConfigName, Value: "cert-xxx"},
+				},
+			},
+			allowed: true,
+		},
+		// case 1: CertId missing for TCPSSL
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.NlbNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{Name: alibabacloud.NlbIdsConfigName, Value: "nlb-xxx"},
+					{Name: alibabacloud.PortProtocolsConfigName, Value: "80/TCPSSL"},
+				},
+			},
+			allowed: false,
+		},
+		// case 2: invalid health check uri
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.NlbNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{Name: alibabacloud.NlbIdsConfigName, Value: "nlb-xxx"},
+					{Name: alibabacloud.PortProtocolsConfigName, Value: "80/TCP"},
+					{Name: alibabacloud.LBHealthCheckUriConfigName, Value: "health"},
+				},
+			},
+			allowed: false,
+		},
+		// case 3: plugin not validating config
+		{
+			network: &gamekruiseiov1alpha1.Network{
+				NetworkType: alibabacloud.EIPNetwork,
+				NetworkConf: []gamekruiseiov1alpha1.NetworkConfParams{
+					{Name: "whatever", Value: "xxx"},
+				},
+			},
+			allowed: true,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gamekruiseiov1alpha1.GameServerSet{
+			Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+				Network: test.network,
+			},
+		}
+		allowed, reason := validatingNetworkConfig(gss, cpm)
+		if allowed != test.allowed {
+			t.Errorf("case %d: expect allowed %v but actually got %v, because of %s", i, test.allowed, allowed, reason)
+		}
+	}
+}
+
+func TestValidatingNetworkConfigContainerPorts(t *testing.T) {
+	cpm := &manager.ProviderManager{
+		CloudProviders: map[string]cloudprovider.CloudProvider{
+			"Kubernetes": func() cloudprovider.CloudProvider {
+				kp, _ := kubernetes.NewKubernetesProvider()
+				return kp
+			}(),
+		},
+	}
+	tests := []struct {
+		networkType string
+		conf        []gamekruiseiov1alpha1.NetworkConfParams
+		containers  []corev1.Container
+		allowed     bool
+	}{
+		// case 0: HostPort ports declared
+		{
+			networkType: kubernetes.HostPortNetwork,
+			conf:        []gamekruiseiov1alpha1.NetworkConfParams{{Name: kubernetes.ContainerPortsKey, Value: "game:80,90/UDP"}},
+			containers: []corev1.Container{{
+				Name:  "game",
+				Ports: []corev1.ContainerPort{{ContainerPort: 80}, {ContainerPort: 90, Protocol: corev1.ProtocolUDP}},
+			}},
+			allowed: true,
+		},
+		// case 1: HostPort container declares no ports
+		{
+			networkType: kubernetes.HostPortNetwork,
+			conf:        []gamekruiseiov1alpha1.NetworkConfParams{{Name: kubernetes.ContainerPortsKey, Value: "game:80"}},
+			containers:  []corev1.Container{{Name: "game"}},
+			allowed:     true,
+		},
+		// case 2: HostPort port not declared
+		{
+			networkType: kubernetes.HostPortNetwork,
+			conf:        []gamekruiseiov1alpha1.NetworkConfParams{{Name: kubernetes.ContainerPortsKey, Value: "game:80,90/UDP"}},
+			containers: []corev1.Container{{
+				Name:  "game",
+				Ports: []corev1.ContainerPort{{ContainerPort: 80}, {ContainerPort: 90}},
+			}},
+			allowed: false,
+		},
+		// case 3: HostPort container not found
+		{
+			networkType: kubernetes.HostPortNetwork,
+			conf:        []gamekruiseiov1alpha1.NetworkConfParams{{Name: kubernetes.ContainerPortsKey, Value: "gameserver:80"}},
+			containers:  []corev1.Container{{Name: "game"}},
+			allowed:     false,
+		},
+		// case 4: NodePort ports declared by different containers
+		{
+			networkType: kubernetes.NodePortNetwork,
+			conf:        []gamekruiseiov1alpha1.NetworkConfParams{{Name: kubernetes.PortProtocolsConfigName, Value: "80/TCP,90/UDP"}},
+			containers: []corev1.Container{
+				{Name: "game", Ports: []corev1.ContainerPort{{ContainerPort: 80}}},
+				{Name: "sidecar", Ports: []corev1.ContainerPort{{ContainerPort: 90, Protocol: corev1.ProtocolUDP}}},
+			},
+			allowed: true,
+		},
+		// case 5: NodePort port not declared
+		{
+			networkType: kubernetes.NodePortNetwork,
+			conf:        []gamekruiseiov1alpha1.NetworkConfParams{{Name: kubernetes.PortProtocolsConfigName, Value: "80/TCP,8080/TCP"}},
+			containers:  []corev1.Container{{Name: "game", Ports: []corev1.ContainerPort{{ContainerPort: 80}}}},
+			allowed:     false,
+		},
+		// case 6: NodePort containers declare no ports
+		{
+			networkType: kubernetes.NodePortNetwork,
+			conf:        []gamekruiseiov1alpha1.NetworkConfParams{{Name: kubernetes.PortProtocolsConfigName, Value: "80/TCP"}},
+			containers:  []corev1.Container{{Name: "game"}},
+			allowed:     true,
+		},
+	}
+
+	for i, test := range tests {
+		gss := &gamekruiseiov1alpha1.GameServerSet{
+			Spec: gamekruiseiov1alpha1.GameServerSetSpec{
+				Network: &gamekruiseiov1alpha1.Network{
+					NetworkType: test.networkType,
+					NetworkConf: test.conf,
+				},
+				GameServerTemplate: gamekruiseiov1alpha1.GameServerTemplate{
+					PodTemplateSpec: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{Containers: test.containers},
+					},
+				},
+			},
+		}
+		allowed, reason := validatingNetworkConfig(gss, cpm)
+		if allowed != test.allowed {
+			t.Errorf("case %d: expect allowed %v but actually got %v, because of %s", i, test.allowed, allowed, reason)
+		}
+	}
+}
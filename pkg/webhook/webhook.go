@@ -21,6 +21,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"time"
 
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -34,13 +35,16 @@ import (
 
 	gamekruiseiov1alpha1 "github.com/openkruise/kruise-game/apis/v1alpha1"
 	manager2 "github.com/openkruise/kruise-game/cloudprovider/manager"
+	cputils "github.com/openkruise/kruise-game/cloudprovider/utils"
 	"github.com/openkruise/kruise-game/pkg/webhook/util/generator"
 	"github.com/openkruise/kruise-game/pkg/webhook/util/writer"
 )
 
 var (
 	mutatePodPath                      = "/mutate-v1-pod"
+	mutateGssPath                      = "/mutate-v1alpha1-gss"
 	validateGssPath                    = "/validate-v1alpha1-gss"
+	validateGsPath                     = "/validate-v1alpha1-gs"
 	mutatingWebhookConfigurationName   = "kruise-game-mutating-webhook"
 	validatingWebhookConfigurationName = "kruise-game-validating-webhook"
 )
@@ -50,6 +54,9 @@ var (
 	webhookCertDir          string
 	webhookServiceNamespace string
 	webhookServiceName      string
+	managerServiceAccount   string
+	apiCallRetryBudget      int
+	apiCallMaxBackoff       time.Duration
 )
 
 func init() {
@@ -57,6 +64,9 @@ func init() {
 	flag.StringVar(&webhookCertDir, "webhook-server-certs-dir", "/tmp/webhook-certs/", "Path to the X.509-formatted webhook certificate.")
 	flag.StringVar(&webhookServiceNamespace, "webhook-service-namespace", "kruise-game-system", "kruise game webhook service namespace.")
 	flag.StringVar(&webhookServiceName, "webhook-service-name", "kruise-game-webhook-service", "kruise game wehook service name.")
+	flag.StringVar(&managerServiceAccount, "manager-service-account", "kruise-game-controller-manager", "kruise game manager service account in the webhook service namespace, whose opsState writes are exempted from opsStateTransitionPolicy.")
+	flag.IntVar(&apiCallRetryBudget, "api-call-retry-budget", 5, "The number of consecutive apiCallErrors of a pod retried at the network interval. Beyond it, the network of the pod is retried with exponential backoff and an event is emitted. 0 means unlimited.")
+	flag.DurationVar(&apiCallMaxBackoff, "api-call-max-backoff", 5*time.Minute, "The maximal backoff of retrying the network of a pod failing with apiCallErrors beyond api-call-retry-budget.")
 }
 
 // +kubebuilder:rbac:groups=apps.kruise.io,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
@@ -109,9 +119,12 @@ func (ws *Webhook) SetupWithManager(mgr manager.Manager) *Webhook {
 	if err != nil {
 		log.Fatalln(err)
 	}
-	recorder := mgr.GetEventRecorderFor("kruise-game-webhook")
+	recorder := cputils.NewRateLimitedEventRecorder(mgr.GetEventRecorderFor("kruise-game-webhook"), pluginEventInterval)
+	ws.cpm.SetEventRecorder(recorder)
 	server.Register(mutatePodPath, &webhook.Admission{Handler: NewPodMutatingHandler(mgr.GetClient(), decoder, ws.cpm, recorder)})
+	server.Register(mutateGssPath, &webhook.Admission{Handler: &GssMutatingHandler{Client: mgr.GetClient(), decoder: decoder}})
 	server.Register(validateGssPath, &webhook.Admission{Handler: &GssValidaatingHandler{Client: mgr.GetClient(), decoder: decoder, CloudProviderManager: ws.cpm}})
+	server.Register(validateGsPath, &webhook.Admission{Handler: &GsValidatingHandler{Client: mgr.GetClient(), decoder: decoder, CloudProviderManager: ws.cpm}})
 	return ws
 }
 
@@ -249,6 +262,30 @@ func getValidatingWebhookConf(dnsName string, caBundle []byte) []admissionregist
 				},
 			},
 		},
+		{
+			Name:                    "gs." + dnsName,
+			SideEffects:             &sideEffectClassNone,
+			FailurePolicy:           &fail,
+			AdmissionReviewVersions: []string{"v1", "v1beta1"},
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{
+					Namespace: webhookServiceNamespace,
+					Name:      webhookServiceName,
+					Path:      &validateGsPath,
+				},
+				CABundle: caBundle,
+			},
+			Rules: []admissionregistrationv1.RuleWithOperations{
+				{
+					Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Update, admissionregistrationv1.Delete},
+					Rule: admissionregistrationv1.Rule{
+						APIGroups:   []string{"game.kruise.io"},
+						APIVersions: []string{"v1alpha1"},
+						Resources:   []string{"gameservers"},
+					},
+				},
+			},
+		},
 	}
 }
 
@@ -289,5 +326,29 @@ func getMutatingWebhookConf(dnsName string, caBundle []byte) []admissionregistra
 				},
 			},
 		},
+		{
+			Name:                    "gss." + dnsName,
+			SideEffects:             &sideEffectClassNone,
+			FailurePolicy:           &fail,
+			AdmissionReviewVersions: []string{"v1", "v1beta1"},
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{
+					Namespace: webhookServiceNamespace,
+					Name:      webhookServiceName,
+					Path:      &mutateGssPath,
+				},
+				CABundle: caBundle,
+			},
+			Rules: []admissionregistrationv1.RuleWithOperations{
+				{
+					Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+					Rule: admissionregistrationv1.Rule{
+						APIGroups:   []string{"game.kruise.io"},
+						APIVersions: []string{"v1alpha1"},
+						Resources:   []string{"gameserversets"},
+					},
+				},
+			},
+		},
 	}
 }